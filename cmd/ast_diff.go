@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/astdiff"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func astDiff(args []string) {
+	astDiffCmd := flag.NewFlagSet("ast-diff", flag.ExitOnError)
+	diffJSON := astDiffCmd.Bool("json", false, "Output the edit script as JSON")
+	kindTrees := astDiffCmd.Bool("kind-trees", false, "Output kind trees matching the code before the change, in the find-kind-trees format")
+	color := astDiffCmd.Bool("color", false, "Mark the edited nodes of the annotated view with colors instead of markers")
+	minHeight := astDiffCmd.Int("min-height", astdiff.DefaultOptions.MinHeight, "The minimal height of the subtrees matched in the top-down phase")
+	minDice := astDiffCmd.Float64("min-dice", astdiff.DefaultOptions.MinDice, "The minimal ratio of common descendants to match two containers")
+	astDiffHelp := astDiffCmd.Bool("help", false, "Show help for the ast-diff command")
+	astDiffCmd.Parse(args[1:])
+
+	if *astDiffHelp {
+		fmt.Println("Computes the structural edit script between two versions of a file")
+		fmt.Println("Usage: go-php-parser ast-diff [flags] <old.php|old.ast.json> <new.php|new.ast.json>")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the ast-diff command")
+		fmt.Println("  --json - Output the edit script as JSON")
+		fmt.Println("  --kind-trees - Output kind trees matching the code before the change, in the find-kind-trees format")
+		fmt.Println("  --color - Mark the edited nodes of the annotated view with colors instead of markers")
+		fmt.Println("  --min-height - The minimal height of the subtrees matched in the top-down phase (default 2)")
+		fmt.Println("  --min-dice - The minimal ratio of common descendants to match two containers (default 0.5)")
+		fmt.Println("  The edit script is made of insert, delete, update and move actions. The annotated view pretty")
+		fmt.Println("  prints both versions with the edited nodes marked: {+ +} inserted, [- -] deleted, {~ ~} updated")
+		fmt.Println("  and {> <} moved")
+		os.Exit(0)
+	}
+
+	if len(astDiffCmd.Args()) < 2 {
+		fmt.Println("Please provide the old and new files. Type --help for more information")
+		os.Exit(1)
+	}
+	oldTree := loadSource(astDiffCmd.Args()[0])
+	newTree := loadSource(astDiffCmd.Args()[1])
+
+	mapping := astdiff.Match(oldTree, newTree, astdiff.Options{MinHeight: *minHeight, MinDice: *minDice})
+	actions := mapping.EditScript()
+
+	switch {
+	case *diffJSON:
+		printJSON(actions)
+	case *kindTrees:
+		printJSON(mapping.KindTrees(actions))
+	default:
+		for _, a := range actions {
+			fmt.Println(describeAction(a))
+		}
+		oldView, newView := astdiff.Annotate(oldTree, newTree, actions, *color)
+		fmt.Printf("----------------------\n%s :\n%s\n", astDiffCmd.Args()[0], oldView)
+		fmt.Printf("----------------------\n%s :\n%s\n", astDiffCmd.Args()[1], newView)
+	}
+	os.Exit(0)
+}
+
+// loadSource parses a PHP file, or loads an AST file
+func loadSource(fileName string) *ast.Node {
+	file, err := os.ReadFile(fileName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var treeNode *ast.Node
+	if strings.HasSuffix(fileName, ".ast.json") {
+		treeNode = &ast.Node{}
+		if err := json.Unmarshal(file, treeNode); err != nil {
+			fmt.Printf("Error parsing tree in file %s : %s\n", fileName, err)
+			os.Exit(1)
+		}
+	} else {
+		treeNode = ast.ParseSource(file)
+	}
+	treeNode.SetParents()
+	return treeNode
+}
+
+func printJSON(v any) {
+	result, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(result))
+}
+
+func describeAction(a *astdiff.Action) string {
+	at := func(n *ast.Node) string {
+		return fmt.Sprintf("%s %q at line %d", n.Kind, shorten(n.Text), n.StartPosition.Row+1)
+	}
+	switch a.Type {
+	case astdiff.Insert:
+		return fmt.Sprintf("insert %s into %s at position %d", at(a.New), a.Parent.Kind, a.Position)
+	case astdiff.Delete:
+		return fmt.Sprintf("delete %s", at(a.Old))
+	case astdiff.Update:
+		return fmt.Sprintf("update %s to %q", at(a.Old), a.New.Text)
+	default:
+		return fmt.Sprintf("move %s into %s at line %d, position %d", at(a.Old), a.Parent.Kind, a.Parent.StartPosition.Row+1, a.Position)
+	}
+}
+
+// shorten keeps the first line of a node text for the summaries
+func shorten(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i] + "..."
+	}
+	return text
+}
@@ -21,6 +21,7 @@ func Main() {
 		fmt.Println("  parse - Parse a PHP file and output a JSON file with the tree")
 		fmt.Println("  show - Show the tree of a JSON file")
 		fmt.Println("  operations - Input a JSON tree file and then perform some operations on it")
+		fmt.Println("  ast-diff - Compute the structural edit script between two versions of a file")
 		fmt.Println("Type ./go-php-parser [command] --help for more information on a command")
 		os.Exit(0)
 	}
@@ -43,6 +44,8 @@ func Main() {
 		showTree(args)
 	case "operations":
 		operations.Main(args)
+	case "ast-diff":
+		astDiff(args)
 	default:
 		fmt.Println("Please provide a valid command. Type --help for more information")
 		os.Exit(1)
@@ -0,0 +1,121 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func classHierarchy(fileName string, args []string, directory, recursive bool) {
+	classHierarchyOperation := flag.NewFlagSet("class-hierarchy", flag.ExitOnError)
+	classHierarchyJSON := classHierarchyOperation.Bool("json", false, "Output the hierarchy as JSON")
+	classHierarchyDOT := classHierarchyOperation.Bool("dot", false, "Output the hierarchy in the DOT format")
+	calls := classHierarchyOperation.Bool("calls", false, "Print the methods each $this->, self::, parent:: and static:: call resolves to")
+	classHierarchyHelp := classHierarchyOperation.Bool("help", false, "Show help for the class-hierarchy operation")
+	classHierarchyOperation.Parse(args[2:])
+
+	if *classHierarchyHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> class-hierarchy [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the class-hierarchy operation")
+		fmt.Println("  --json - Output the hierarchy as JSON")
+		fmt.Println("  --dot - Output the hierarchy in the DOT format")
+		fmt.Println("  --calls - Print the methods each $this->, self::, parent:: and static:: call resolves to")
+		fmt.Println("  Builds the inheritance and implementation graph of the classes, interfaces, traits and enums")
+		fmt.Println("  and computes the effective methods and properties of each class, including trait")
+		fmt.Println("  insteadof/as rules")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	hierarchy := classes.New()
+	trees := make(map[string]*ast.Node)
+	for _, file := range files {
+		trees[file] = loadTree(file)
+		hierarchy.AddFile(file, trees[file])
+	}
+	hierarchy.Resolve()
+
+	switch {
+	case *classHierarchyJSON:
+		result, err := json.Marshal(hierarchy)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+	case *classHierarchyDOT:
+		fmt.Print(hierarchy.DOT())
+	case *calls:
+		for _, file := range files {
+			printResolvedCalls(file, hierarchy.ResolveCalls(trees[file]))
+		}
+	default:
+		printHierarchy(hierarchy)
+	}
+}
+
+func printHierarchy(hierarchy *classes.Hierarchy) {
+	for _, c := range hierarchy.SortedClasses() {
+		fmt.Printf("%s %s (%s:%d)", c.Kind, c.Name, c.File, c.Line())
+		if c.Parent != nil {
+			fmt.Printf(" extends %s", c.Parent.Name)
+		}
+		for i, iface := range c.Interfaces {
+			if i == 0 {
+				fmt.Print(" implements ")
+			} else {
+				fmt.Print(", ")
+			}
+			fmt.Print(iface.Name)
+		}
+		fmt.Println()
+		for _, m := range c.SortedMethods() {
+			fmt.Printf("  %s function %s() - %s", m.Visibility, m.Name, m.Origin)
+			if m.Origin != "declared" {
+				fmt.Printf(" from %s::%s", m.From.Name, m.Declaration.Name)
+			}
+			fmt.Println()
+		}
+		for _, p := range c.SortedProperties() {
+			fmt.Printf("  %s $%s - %s", p.Visibility, p.Name, p.Origin)
+			if p.Origin != "declared" {
+				fmt.Printf(" from %s", p.From.Name)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func printResolvedCalls(fileName string, calls map[*ast.Node][]*classes.Method) {
+	if len(calls) == 0 {
+		return
+	}
+	var nodes []*ast.Node
+	for n := range calls {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].StartByte < nodes[j].StartByte
+	})
+	fmt.Printf("Results for file %s:\n", fileName)
+	for _, n := range nodes {
+		fmt.Printf("Line %d: %s ->", n.StartPosition.Row+1, n.Text)
+		if len(calls[n]) == 0 {
+			fmt.Print(" unresolved")
+		}
+		for _, m := range calls[n] {
+			fmt.Printf(" %s::%s (line %d)", m.Class.Name, m.Name, m.Node.StartPosition.Row+1)
+		}
+		fmt.Println()
+	}
+	fmt.Print("----------------------\n")
+}
@@ -0,0 +1,80 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/clones"
+)
+
+func detectClones(fileName string, args []string, directory, recursive bool) {
+	clonesOperation := flag.NewFlagSet("clones", flag.ExitOnError)
+	minSize := clonesOperation.Int("min-size", 30, "The minimal number of AST nodes of a Type-1 or Type-2 clone")
+	nearMissSize := clonesOperation.Int("near-miss-size", 60, "The minimal number of AST nodes of a Type-3 clone, 0 to disable them")
+	similarity := clonesOperation.Float64("similarity", 0.8, "The minimal similarity of Type-3 clones, between 0 and 1")
+	clonesJSON := clonesOperation.Bool("json", false, "Output the clone classes as JSON")
+	clonesHTML := clonesOperation.Bool("html", false, "Output an HTML report with the fragments side by side")
+	clonesHelp := clonesOperation.Bool("help", false, "Show help for the clones operation")
+	clonesOperation.Parse(args[2:])
+
+	if *clonesHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> clones [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the clones operation")
+		fmt.Println("  --min-size <n> - The minimal number of AST nodes of a Type-1 or Type-2 clone (default 30)")
+		fmt.Println("  --near-miss-size <n> - The minimal number of AST nodes of a Type-3 clone, 0 to disable them (default 60)")
+		fmt.Println("  --similarity <s> - The minimal similarity of Type-3 clones, between 0 and 1 (default 0.8)")
+		fmt.Println("  --json - Output the clone classes as JSON")
+		fmt.Println("  --html - Output an HTML report with the fragments side by side")
+		fmt.Println("  Type-1 clones are identical subtrees, Type-2 clones differ in identifiers and literals,")
+		fmt.Println("  Type-3 clones are near-miss copies with added, removed or changed statements")
+		os.Exit(0)
+	}
+	if *similarity <= 0 || *similarity > 1 {
+		fmt.Println("Please provide a similarity between 0 and 1. Type --help for more information")
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	detector := clones.New(clones.Options{MinSize: *minSize, NearMissSize: *nearMissSize, Similarity: *similarity})
+	for _, file := range files {
+		detector.AddFile(file, loadTree(file))
+	}
+	classes := detector.Detect()
+
+	switch {
+	case *clonesJSON:
+		result, err := json.Marshal(classes)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+	case *clonesHTML:
+		if err := clones.HTML(os.Stdout, classes); err != nil {
+			fmt.Printf("Error writing the report: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		printClones(classes)
+	}
+}
+
+func printClones(classes []*clones.Class) {
+	for _, c := range classes {
+		fmt.Printf("Clone class %d: Type-%d, %d fragments", c.ID, c.Type, len(c.Fragments))
+		if c.Type == clones.Type3 {
+			fmt.Printf(", similarity %.3f", c.Similarity)
+		}
+		fmt.Println()
+		for _, f := range c.Fragments {
+			fmt.Printf("  %s:%d-%d %s (%d nodes)\n", f.File, f.StartLine, f.EndLine, f.Kind, f.Size)
+		}
+	}
+	fmt.Printf("%d clone classes found\n", len(classes))
+}
@@ -0,0 +1,96 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/compat"
+)
+
+func compatibility(fileName string, args []string, directory, recursive bool) {
+	compatOperation := flag.NewFlagSet("compat", flag.ExitOnError)
+	minVersion := compatOperation.String("min", "7.4", "Oldest PHP version the code must run on")
+	maxVersion := compatOperation.String("max", "8.4", "Newest PHP version the code must run on")
+	only := compatOperation.String("only", "", "Comma separated list of the categories to report")
+	compatJSON := compatOperation.Bool("json", false, "Output the findings as JSON")
+	compatHelp := compatOperation.Bool("help", false, "Show help for the compat operation")
+	compatOperation.Parse(args[2:])
+
+	if *compatHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> compat [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the compat operation")
+		fmt.Println("  --min <version> - Oldest PHP version the code must run on (default 7.4)")
+		fmt.Println("  --max <version> - Newest PHP version the code must run on (default 8.4)")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report:")
+		fmt.Println("    new-syntax, new-function, removed, deprecated")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Reports the syntax and functions newer than the minimum version, and the syntax, functions and")
+		fmt.Println("  constants removed or deprecated up to the maximum version, according to the bundled version database")
+		os.Exit(0)
+	}
+
+	min, err := compat.ParseVersion(*minVersion)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	max, err := compat.ParseVersion(*maxVersion)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if max.Less(min) {
+		fmt.Println("The --max version must not be older than the --min version")
+		os.Exit(1)
+	}
+	categories := parseOnly(*only, compat.Categories)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	checker := compat.New(compat.Bundled(), min, max)
+	for _, file := range files {
+		checker.AddFile(file, loadTree(file))
+	}
+	all := checker.Check()
+	findings := []*compat.Finding{}
+	for _, finding := range all {
+		if len(categories) == 0 || categories[finding.Category] {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *compatJSON {
+		result, err := json.Marshal(map[string]any{
+			"min":      min,
+			"max":      max,
+			"required": compat.Required(all, min),
+			"findings": findings,
+		})
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s] %s\n", finding.Line, finding.Category, finding.Message)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+	fmt.Printf("Requires PHP >= %s\n", compat.Required(all, min))
+}
@@ -0,0 +1,103 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/dataflow"
+)
+
+func dataflowAnalysis(fileName string, args []string, directory, recursive bool) {
+	dataflowOperation := flag.NewFlagSet("dataflow", flag.ExitOnError)
+	analysis := dataflowOperation.String("analysis", "reaching-definitions", "The analysis to run")
+	function := dataflowOperation.String("function", "", "Only analyze the functions with this name")
+	dataflowJSON := dataflowOperation.Bool("json", false, "Output the results as JSON")
+	dataflowDOT := dataflowOperation.Bool("dot", false, "Output the control flow graphs in the DOT format instead")
+	dataflowHelp := dataflowOperation.Bool("help", false, "Show help for the dataflow operation")
+	dataflowOperation.Parse(args[2:])
+
+	if *dataflowHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> dataflow [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the dataflow operation")
+		fmt.Println("  --analysis - The analysis to run on the control flow graph of each function:")
+		fmt.Println("    reaching-definitions (default), live-variables, available-expressions")
+		fmt.Println("  --function - Only analyze the functions with this name (use Class::method for methods)")
+		fmt.Println("  --json - Output the results as JSON")
+		fmt.Println("  --dot - Output the control flow graphs in the DOT format instead")
+		os.Exit(0)
+	}
+
+	switch *analysis {
+	case "reaching-definitions", "live-variables", "available-expressions":
+	default:
+		fmt.Println("Please provide a valid analysis. Type --help for more information")
+		os.Exit(1)
+	}
+
+	if directory {
+		var wg sync.WaitGroup
+		for _, file := range astFiles(fileName, recursive) {
+			wg.Add(1)
+			go func(fileName string) {
+				defer wg.Done()
+				dataflowFile(fileName, *analysis, *function, *dataflowJSON, *dataflowDOT)
+			}(file)
+		}
+		wg.Wait()
+		return
+	}
+	dataflowFile(fileName, *analysis, *function, *dataflowJSON, *dataflowDOT)
+}
+
+func dataflowFile(fileName, analysis, function string, outputJSON, outputDOT bool) {
+	treeNode := loadTree(fileName)
+	var output strings.Builder
+	var results []json.Marshaler
+	for _, g := range cfg.BuildAll(treeNode) {
+		if function != "" && g.Name != function {
+			continue
+		}
+		if outputDOT {
+			output.WriteString(g.DOT())
+			continue
+		}
+		var result *dataflow.Result[dataflow.Set]
+		switch analysis {
+		case "reaching-definitions":
+			result = dataflow.Solve[dataflow.Set](g, dataflow.ReachingDefinitions{})
+		case "live-variables":
+			result = dataflow.Solve[dataflow.Set](g, dataflow.LiveVariables{})
+		case "available-expressions":
+			result = dataflow.Solve[dataflow.Set](g, dataflow.NewAvailableExpressions(g))
+		}
+		if outputJSON {
+			results = append(results, result)
+			continue
+		}
+		fmt.Fprintf(&output, "Function %s (line %d):\n", g.Name, g.Function.StartPosition.Row+1)
+		for _, n := range g.Nodes {
+			fmt.Fprintf(&output, "  [%d] line %d %s\n", n.ID, n.Line(), n)
+			fmt.Fprintf(&output, "    in: %v\n    out: %v\n", result.In[n].Sorted(), result.Out[n].Sorted())
+		}
+	}
+	if outputJSON {
+		encoded, err := json.Marshal(map[string]any{"file": fileName, "results": results})
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(encoded))
+		return
+	}
+	if outputDOT {
+		fmt.Print(output.String())
+		return
+	}
+	fmt.Printf("Results for file %s:\n%s----------------------\n", fileName, output.String())
+}
@@ -0,0 +1,81 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/deadcode"
+)
+
+func deadCode(fileName string, args []string, directory, recursive bool) {
+	deadCodeOperation := flag.NewFlagSet("dead-code", flag.ExitOnError)
+	stringCallables := deadCodeOperation.Bool("string-callables", true, "Count string literals naming a function, method or class as references")
+	callUserFunc := deadCodeOperation.Bool("call-user-func", true, "Count the callables passed to call_user_func, array_map, usort... as references")
+	wordPressHooks := deadCodeOperation.Bool("wordpress-hooks", true, "Count the callbacks registered with add_action, add_filter... as references")
+	only := deadCodeOperation.String("only", "", "Comma separated list of the categories to report")
+	deadCodeJSON := deadCodeOperation.Bool("json", false, "Output the findings as JSON")
+	deadCodeHelp := deadCodeOperation.Bool("help", false, "Show help for the dead-code operation")
+	deadCodeOperation.Parse(args[2:])
+
+	if *deadCodeHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> dead-code [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the dead-code operation")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report:")
+		fmt.Println("    unreferenced-function, unreferenced-method, unreferenced-class, unreachable,")
+		fmt.Println("    unused-variable, unused-parameter, unused-import")
+		fmt.Println("  --string-callables=false - Do not count string literals naming a function, method or class as references")
+		fmt.Println("  --call-user-func=false - Do not count the callables passed to call_user_func, array_map, usort... as references")
+		fmt.Println("  --wordpress-hooks=false - Do not count the callbacks registered with add_action, add_filter... as references")
+		fmt.Println("  Functions, methods and classes are searched for references in all the files of the directory")
+		os.Exit(0)
+	}
+
+	categories := parseOnly(*only, deadcode.Categories)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := deadcode.New(deadcode.Options{
+		StringCallables: *stringCallables,
+		CallUserFunc:    *callUserFunc,
+		WordPressHooks:  *wordPressHooks,
+	})
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	var findings []*deadcode.Finding
+	for _, finding := range analyzer.Analyze() {
+		if len(categories) == 0 || categories[finding.Category] {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *deadCodeJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s] %s\n", finding.Line, finding.Category, finding.Message)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
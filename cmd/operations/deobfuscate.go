@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/deobfuscate"
+)
+
+func deobfuscatePayloads(fileName string, args []string, directory, recursive bool) {
+	deobfuscateOperation := flag.NewFlagSet("deobfuscate", flag.ExitOnError)
+	maxLayers := deobfuscateOperation.Int("max-layers", deobfuscate.DefaultMaxLayers, "Number of nested payloads decoded")
+	deobfuscateJSON := deobfuscateOperation.Bool("json", false, "Output the payloads as JSON")
+	deobfuscateHelp := deobfuscateOperation.Bool("help", false, "Show help for the deobfuscate operation")
+	deobfuscateOperation.Parse(args[2:])
+
+	if *deobfuscateHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> deobfuscate [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the deobfuscate operation")
+		fmt.Printf("  --max-layers <n> - Number of nested payloads decoded (default %d)\n", deobfuscate.DefaultMaxLayers)
+		fmt.Println("  --json - Output the payloads as JSON")
+		fmt.Println("  Decodes the code evaluated by eval, assert and create_function when it is made of literals")
+		fmt.Println("  and base64_decode, gzinflate, gzuncompress, str_rot13, strrev, chr, ord or hex2bin, without")
+		fmt.Println("  executing PHP. Each decoded payload is parsed and pretty printed, and the payloads it")
+		fmt.Println("  evaluates are decoded in turn.")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	d := deobfuscate.New()
+	d.MaxLayers = *maxLayers
+	results := []*deobfuscate.Result{}
+	for _, file := range files {
+		if result := d.Deobfuscate(file, loadTree(file)); len(result.Layers) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	if *deobfuscateJSON {
+		result, err := json.Marshal(results)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	for _, result := range results {
+		fmt.Printf("Results for file %s:\n", result.File)
+		for _, layer := range result.Layers {
+			printLayer(layer)
+		}
+		fmt.Print("----------------------\n")
+	}
+}
+
+func printLayer(layer *deobfuscate.Layer) {
+	fmt.Printf("Layer %d, line %d: %s of %s\n", layer.Depth, layer.Line, layer.Sink, strings.Join(layer.Functions, ", "))
+	if layer.Printed != "" {
+		fmt.Println(layer.Printed)
+	} else {
+		fmt.Printf("(does not parse)\n%s\n", layer.Code)
+	}
+	for _, inner := range layer.Layers {
+		printLayer(inner)
+	}
+}
@@ -0,0 +1,80 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/deserialize"
+)
+
+func objectInjection(fileName string, args []string, directory, recursive bool) {
+	deserializeOperation := flag.NewFlagSet("deserialize", flag.ExitOnError)
+	deserializeJSON := deserializeOperation.Bool("json", false, "Output the findings and chains as JSON")
+	deserializeHelp := deserializeOperation.Bool("help", false, "Show help for the deserialize operation")
+	deserializeOperation.Parse(args[2:])
+
+	if *deserializeHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> deserialize [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the deserialize operation")
+		fmt.Println("  --json - Output the findings and chains as JSON")
+		fmt.Println("  Finds the unserialize calls and the file operations, which deserialize phar:// archives,")
+		fmt.Println("  reachable with request data. Then lists the candidate gadget chains: from the __wakeup,")
+		fmt.Println("  __unserialize and __destruct methods of each class, inherited ones included, through the")
+		fmt.Println("  methods called on $this and on its properties, __call and __toString, to a command, code,")
+		fmt.Println("  file, include or SQL sink fed with the properties of the object.")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := deserialize.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	result, err := analyzer.Analyze()
+	if err != nil {
+		fmt.Printf("Error loading the sink rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *deserializeJSON {
+		output, err := json.Marshal(result)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(output))
+		return
+	}
+	current := ""
+	for _, finding := range result.Findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s\n", finding.Line, finding.Category, finding.Severity, finding.Message)
+		printTaint(finding.Taint)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+	if len(result.Chains) == 0 {
+		return
+	}
+	fmt.Printf("Gadget chains (%d):\n", len(result.Chains))
+	for _, chain := range result.Chains {
+		fmt.Printf("[%s] %s\n", chain.Sink, chain)
+		for _, gadget := range chain.Gadgets {
+			fmt.Printf("    %s::%s (%s:%d)\n", gadget.Class, gadget.Method, gadget.File, gadget.Line)
+		}
+		fmt.Printf("    sink at %s:%d\n", chain.File, chain.Line)
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"os"
 	"sync"
 
+	// The value, type and doc tag attributes of kind trees are implemented by these packages
+	_ "github.com/28Pollux28/log6302-parser/internal/analysis/phpdoc"
+	_ "github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	_ "github.com/28Pollux28/log6302-parser/internal/analysis/values"
 	"github.com/28Pollux28/log6302-parser/internal/ast"
 	"github.com/28Pollux28/log6302-parser/utils"
 )
@@ -118,6 +122,8 @@ func findKindTreesFile(fileName string, kindTrees map[string]ast.KindTree) {
 		fmt.Printf("Error parsing tree in file %s : %s\n", fileName, err)
 		os.Exit(1)
 	}
+	// Value attributes evaluate expressions through their parents
+	treeNode.SetParents()
 
 	// Find kind tree in tree
 	v := &ast.VisitorFinds{
@@ -0,0 +1,94 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/wordpress"
+)
+
+func hookGraph(fileName string, args []string, directory, recursive bool) {
+	hooksOperation := flag.NewFlagSet("hooks", flag.ExitOnError)
+	hooksJSON := hooksOperation.Bool("json", false, "Output the hook graph as JSON")
+	hooksDOT := hooksOperation.Bool("dot", false, "Output the hook graph in the DOT format")
+	from := hooksOperation.String("from", "", "Print what is reachable from a hook (hook:<name>), a function, a method (Class::method) or a file (file:<path>)")
+	to := hooksOperation.String("to", "", "Print the hooks, functions and files from which a hook, function, method or file is reachable")
+	hooksHelp := hooksOperation.Bool("help", false, "Show help for the hooks operation")
+	hooksOperation.Parse(args[2:])
+
+	if *hooksHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> hooks [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the hooks operation")
+		fmt.Println("  --json - Output the hook graph as JSON")
+		fmt.Println("  --dot - Output the hook graph in the DOT format")
+		fmt.Println("  --from <vertex> - Print what is reachable from a hook (hook:<name>), a function, a method")
+		fmt.Println("    (Class::method) or the top level code of a file (file:<path>)")
+		fmt.Println("  --to <vertex> - Print the hooks, functions and files from which a vertex is reachable")
+		fmt.Println("  Builds the WordPress hook graph: the handlers registered with add_action and add_filter, with")
+		fmt.Println("  their resolved callbacks and priorities, connected to the do_action and apply_filters calls")
+		fmt.Println("  running them. Unknown parts of dynamic hook names are printed as *.")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	graph := wordpress.New()
+	for _, file := range files {
+		graph.AddFile(file, loadTree(file))
+	}
+	graph.Build()
+
+	switch {
+	case *hooksJSON:
+		result, err := json.Marshal(graph)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+	case *hooksDOT:
+		fmt.Print(graph.DOT())
+	case *from != "":
+		printReachability(graph, *from, "reachable from", graph.Reachable)
+	case *to != "":
+		printReachability(graph, *to, "reaching", graph.Reaching)
+	default:
+		printHooks(graph)
+	}
+}
+
+func printHooks(graph *wordpress.Graph) {
+	for _, hook := range graph.Hooks() {
+		fmt.Printf("Hook %s: %d registrations, %d dispatches\n", hook.Name, len(hook.Registrations), len(hook.Dispatches))
+		for _, d := range hook.Dispatches {
+			caller := "top level"
+			if d.Caller != "" {
+				caller = d.Caller
+			}
+			fmt.Printf("  %s at %s:%d in %s -> %d handlers\n", d.Function, d.File, d.Line, caller, len(d.Handlers))
+		}
+		for _, r := range hook.Registrations {
+			fmt.Printf("  %s %s (%s, priority %d, %d args) at %s:%d\n", r.Kind, r.Callback.Name, r.Callback.Kind,
+				r.Priority, r.AcceptedArgs, r.File, r.Line)
+		}
+	}
+}
+
+func printReachability(graph *wordpress.Graph, query, relation string, search func(wordpress.Vertex) []*wordpress.Step) {
+	vertex, ok := graph.Vertex(query)
+	if !ok {
+		fmt.Printf("%s is not part of the hook graph\n", query)
+		os.Exit(1)
+	}
+	steps := search(vertex)
+	fmt.Printf("%d vertices %s %s:\n", len(steps), relation, vertex)
+	for _, step := range steps {
+		fmt.Printf("%s%s (%s %s:%d)\n", strings.Repeat("  ", step.Depth), step.Vertex, step.Edge.Kind, step.Edge.File, step.Edge.Line)
+	}
+}
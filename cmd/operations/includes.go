@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/include"
+)
+
+func includes(fileName string, args []string, directory, recursive bool) {
+	includesOperation := flag.NewFlagSet("includes", flag.ExitOnError)
+	root := includesOperation.String("root", ".", "The root directory of the PHP project the AST files were parsed from")
+	entry := includesOperation.String("entry", "", "Print the files executed when running this PHP file, relative to the root")
+	includesJSON := includesOperation.Bool("json", false, "Output the file graph as JSON")
+	includesDOT := includesOperation.Bool("dot", false, "Output the file graph in the DOT format")
+	includesHelp := includesOperation.Bool("help", false, "Show help for the includes operation")
+	includesOperation.Parse(args[2:])
+
+	if *includesHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> includes [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the includes operation")
+		fmt.Println("  --root - The root directory of the PHP project the AST files were parsed from (default .)")
+		fmt.Println("    The PHP path of each AST file is its path relative to the AST directory, without .ast.json")
+		fmt.Println("  --entry - Print the files executed when running this PHP file, relative to the root")
+		fmt.Println("  --json - Output the file graph as JSON")
+		fmt.Println("  --dot - Output the file graph in the DOT format")
+		fmt.Println("  Resolves include/require paths built from literals, __DIR__, dirname(__FILE__), constants")
+		fmt.Println("  and concatenations, and flags unresolvable, user-controlled and remote includes")
+		os.Exit(0)
+	}
+
+	graph := include.New(*root)
+	if directory {
+		for _, file := range astFiles(fileName, recursive) {
+			phpFile := strings.TrimSuffix(strings.TrimPrefix(file, strings.TrimSuffix(fileName, "/")+"/"), ".ast.json")
+			graph.AddFile(phpFile, loadTree(file))
+		}
+	} else {
+		graph.AddFile(strings.TrimSuffix(path.Base(fileName), ".ast.json"), loadTree(fileName))
+	}
+	graph.Resolve()
+
+	switch {
+	case *includesJSON:
+		result, err := json.Marshal(graph)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+	case *includesDOT:
+		fmt.Print(graph.DOT())
+	case *entry != "":
+		files := graph.Program(*entry)
+		if files == nil {
+			fmt.Printf("File %s is not part of the project\n", *entry)
+			os.Exit(1)
+		}
+		fmt.Printf("Files executed by %s:\n", *entry)
+		for _, f := range files {
+			fmt.Println(graph.Relative(f.Path))
+		}
+	default:
+		printIncludes(graph)
+	}
+}
+
+func printIncludes(graph *include.Graph) {
+	counts := make(map[include.Status]int)
+	for _, f := range graph.SortedFiles() {
+		if len(f.Includes) == 0 {
+			continue
+		}
+		fmt.Printf("Results for file %s:\n", graph.Relative(f.Path))
+		for _, i := range f.Includes {
+			counts[i.Status]++
+			switch i.Status {
+			case include.Resolved:
+				fmt.Printf("Line %d: %s %s -> %s\n", i.Line(), i.Kind, i.Expression(), graph.Relative(i.Value))
+			case include.UserControlled:
+				fmt.Printf("Line %d: %s %s -> user-controlled path, possible file inclusion\n", i.Line(), i.Kind, i.Expression())
+			case include.Remote:
+				fmt.Printf("Line %d: %s %s -> remote file inclusion of %s\n", i.Line(), i.Kind, i.Expression(), i.Value)
+			default:
+				fmt.Printf("Line %d: %s %s -> %s (%s)\n", i.Line(), i.Kind, i.Expression(), i.Status, i.Value)
+			}
+		}
+		fmt.Print("----------------------\n")
+	}
+	fmt.Println("Total includes for all files:")
+	for _, status := range []include.Status{include.Resolved, include.Missing, include.Unresolved, include.UserControlled, include.Remote} {
+		fmt.Printf("%s: %d\n", status, counts[status])
+	}
+}
@@ -0,0 +1,78 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/injection"
+)
+
+func codeInjection(fileName string, args []string, directory, recursive bool) {
+	injectionOperation := flag.NewFlagSet("injection", flag.ExitOnError)
+	minSeverity := injectionOperation.String("min-severity", "low", "Lowest severity reported: low, medium or high")
+	injectionJSON := injectionOperation.Bool("json", false, "Output the findings as JSON")
+	injectionHelp := injectionOperation.Bool("help", false, "Show help for the injection operation")
+	injectionOperation.Parse(args[2:])
+
+	if *injectionHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> injection [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the injection operation")
+		fmt.Println("  --min-severity <low|medium|high> - Lowest severity reported (default low)")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Finds the request data reaching the shell through exec, system, passthru, shell_exec, popen,")
+		fmt.Println("  proc_open, pcntl_exec and backticks, the code evaluated by eval, assert and create_function,")
+		fmt.Println("  and the functions chosen by variable calls such as $f($x) and call_user_func. Each escaped")
+		fmt.Println("  value is checked where it lands in the command: escapeshellarg between quotes, on the program")
+		fmt.Println("  or under escapeshellcmd, and escapeshellcmd alone, do not protect it.")
+		os.Exit(0)
+	}
+
+	rank, ok := severityRank(*minSeverity)
+	if !ok {
+		fmt.Printf("Invalid severity %s, expected low, medium or high\n", *minSeverity)
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := injection.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	findings := []*injection.Finding{}
+	for _, finding := range analyzer.Analyze() {
+		if r, _ := severityRank(string(finding.Severity)); r >= rank {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *injectionJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s: %s\n", finding.Line, finding.Category, finding.Severity, finding.Sink, finding.Message)
+		printTaint(finding.Taint)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
@@ -0,0 +1,84 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/inventory"
+)
+
+func dangerousInventory(fileName string, args []string, directory, recursive bool) {
+	inventoryOperation := flag.NewFlagSet("inventory", flag.ExitOnError)
+	only := inventoryOperation.String("only", "", "Comma separated list of the categories to report")
+	summary := inventoryOperation.Bool("summary", false, "Only print the usage counts, without the locations")
+	inventoryJSON := inventoryOperation.Bool("json", false, "Output the inventory as JSON")
+	inventoryHelp := inventoryOperation.Bool("help", false, "Show help for the inventory operation")
+	inventoryOperation.Parse(args[2:])
+
+	if *inventoryHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> inventory [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the inventory operation")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report:")
+		fmt.Println("    code-execution, command-execution, file-operation, deserialization, weak-crypto, info-disclosure")
+		fmt.Println("  --summary - Only print the usage counts, without the locations")
+		fmt.Println("  --json - Output the inventory as JSON")
+		fmt.Println("  Counts and locates the calls to the dangerous functions of the built-in catalog, by category")
+		os.Exit(0)
+	}
+
+	categories := parseOnly(*only, inventory.Categories)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	inv := inventory.New()
+	for _, file := range files {
+		inv.AddFile(file, loadTree(file))
+	}
+	reports := []*inventory.Report{}
+	for _, r := range inventory.Reports(inv.Usages()) {
+		if len(categories) == 0 || categories[r.Category] {
+			if *summary {
+				r.Usages = nil
+			}
+			reports = append(reports, r)
+		}
+	}
+
+	if *inventoryJSON {
+		result, err := json.Marshal(reports)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	for _, r := range reports {
+		fmt.Printf("Category %s: %d\n", r.Category, r.Count)
+		var functions []string
+		for function := range r.Functions {
+			functions = append(functions, function)
+		}
+		sort.Strings(functions)
+		for _, function := range functions {
+			fmt.Printf("  %s: %d\n", function, r.Functions[function])
+			for _, u := range r.Usages {
+				if u.Function != function {
+					continue
+				}
+				if u.Via != "" {
+					fmt.Printf("    %s:%d (via %s)\n", u.File, u.Line, u.Via)
+				} else {
+					fmt.Printf("    %s:%d\n", u.File, u.Line)
+				}
+			}
+		}
+		fmt.Print("----------------------\n")
+	}
+}
@@ -0,0 +1,71 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+	"github.com/28Pollux28/log6302-parser/utils"
+)
+
+// loadTree reads an AST JSON file and restores its parent links
+func loadTree(fileName string) *ast.Node {
+	fileJSON, err := os.ReadFile(fileName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var treeNode ast.Node
+	err = json.Unmarshal(fileJSON, &treeNode)
+	if err != nil {
+		fmt.Printf("Error parsing tree in file %s : %s\n", fileName, err)
+		os.Exit(1)
+	}
+	treeNode.SetParents()
+	return &treeNode
+}
+
+// astFiles lists the AST JSON files of a directory, for operations that need the whole project at once
+func astFiles(directory string, recursive bool) []string {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+	var result []string
+	for _, file := range files {
+		if file.IsDir() && recursive {
+			result = append(result, astFiles(directory+"/"+file.Name(), recursive)...)
+		} else if file.IsDir() {
+			continue
+		}
+		if utils.FileExtension(file.Name(), 2) != ".ast.json" {
+			continue
+		}
+		result = append(result, directory+"/"+file.Name())
+	}
+	return result
+}
+
+// parseOnly parses the comma separated categories of an --only flag, exiting on an unknown one
+func parseOnly[C ~string](only string, valid []C) map[C]bool {
+	categories := make(map[C]bool)
+	for _, category := range strings.Split(only, ",") {
+		if category = strings.TrimSpace(category); category == "" {
+			continue
+		}
+		if !slices.Contains(valid, C(category)) {
+			names := make([]string, len(valid))
+			for i, name := range valid {
+				names[i] = string(name)
+			}
+			fmt.Printf("Invalid category %s, expected one of %s\n", category, strings.Join(names, ", "))
+			os.Exit(1)
+		}
+		categories[C(category)] = true
+	}
+	return categories
+}
@@ -26,6 +26,30 @@ func Main(args []string) {
 		fmt.Println("  find-kind-tree - Find the tree of nodes of a specific kind")
 		fmt.Println("  find-kind-trees - Find the trees of nodes of a specific kind")
 		fmt.Println("  pretty-print - Pretty print the AST tree back to PHP code")
+		fmt.Println("  scopes - Show the variable scopes and def-use chains of each function")
+		fmt.Println("  dataflow - Run a dataflow analysis on the control flow graph of each function")
+		fmt.Println("  includes - Resolve include/require expressions and build the file dependency graph")
+		fmt.Println("  class-hierarchy - Build the class hierarchy and resolve the members of each class")
+		fmt.Println("  metrics - Compute size, complexity, Halstead and maintainability metrics")
+		fmt.Println("  clones - Find Type-1, Type-2 and near-miss Type-3 code clones")
+		fmt.Println("  dead-code - Find unreferenced declarations, unreachable statements and unused variables and imports")
+		fmt.Println("  string-values - Approximate the strings the arguments of function and method calls may evaluate to")
+		fmt.Println("  sql-lint - Extract the SQL queries sent to the database and check them")
+		fmt.Println("  types - Infer the types of the variables and resolve method calls on them")
+		fmt.Println("  phpdoc - Parse the doc comments of the declarations and check them")
+		fmt.Println("  compat - Report the syntax and functions incompatible with a range of PHP versions")
+		fmt.Println("  inventory - Count and locate the calls to dangerous functions by category")
+		fmt.Println("  secrets - Find the hardcoded secrets and credentials of the string literals")
+		fmt.Println("  hooks - Build the WordPress hook graph from add_action/add_filter to do_action/apply_filters")
+		fmt.Println("  wp-security - Check the nonces, capabilities, queries, output and options of a WordPress project")
+		fmt.Println("  xss - Find the request data printed without the escaping of its HTML context")
+		fmt.Println("  injection - Find the request data reaching shell commands, evaluated code and dynamic calls")
+		fmt.Println("  deserialize - Find the deserializations reachable with request data and the gadget chains")
+		fmt.Println("  path-traversal - Find the request data reaching file paths and includes, and the unvalidated uploads")
+		fmt.Println("  webshell-scan - Score each file on the obfuscation and execution features of webshells")
+		fmt.Println("  deobfuscate - Decode and pretty print the layered payloads evaluated by eval, assert and create_function")
+		fmt.Println("  weak-crypto - Find the weak password hashes, predictable tokens, ECB modes, static IVs and hardcoded keys")
+		fmt.Println("  type-juggling - Find the loose comparisons of request data with hashes, tokens and magic strings")
 		os.Exit(0)
 	}
 
@@ -51,6 +75,54 @@ func Main(args []string) {
 		findKindTrees(fileName, operationsCmd.Args(), *directory, *recursive)
 	case "pretty-print":
 		prettyPrint(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "scopes":
+		scopes(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "dataflow":
+		dataflowAnalysis(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "includes":
+		includes(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "class-hierarchy":
+		classHierarchy(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "metrics":
+		codeMetrics(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "clones":
+		detectClones(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "dead-code":
+		deadCode(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "string-values":
+		stringValues(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "sql-lint":
+		sqlLint(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "types":
+		inferTypes(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "phpdoc":
+		phpDoc(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "compat":
+		compatibility(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "inventory":
+		dangerousInventory(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "secrets":
+		hardcodedSecrets(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "hooks":
+		hookGraph(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "wp-security":
+		wpSecurity(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "xss":
+		crossSiteScripting(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "injection":
+		codeInjection(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "deserialize":
+		objectInjection(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "path-traversal":
+		pathTraversal(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "webshell-scan":
+		webshellScan(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "deobfuscate":
+		deobfuscatePayloads(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "weak-crypto":
+		weakCrypto(fileName, operationsCmd.Args(), *directory, *recursive)
+	case "type-juggling":
+		typeJuggling(fileName, operationsCmd.Args(), *directory, *recursive)
 	default:
 		fmt.Println("Please provide a valid operation. Type --help for more information")
 		os.Exit(1)
@@ -0,0 +1,150 @@
+package operations
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/metrics"
+)
+
+func codeMetrics(fileName string, args []string, directory, recursive bool) {
+	metricsOperation := flag.NewFlagSet("metrics", flag.ExitOnError)
+	metricsJSON := metricsOperation.Bool("json", false, "Output the metrics as JSON")
+	metricsCSV := metricsOperation.Bool("csv", false, "Output the metrics as CSV, one line per unit")
+	var thresholds metrics.Thresholds
+	metricsOperation.IntVar(&thresholds.Cyclomatic, "max-cyclomatic", 0, "Fail if a function has a higher cyclomatic complexity")
+	metricsOperation.IntVar(&thresholds.Cognitive, "max-cognitive", 0, "Fail if a function has a higher cognitive complexity")
+	metricsOperation.IntVar(&thresholds.Nesting, "max-nesting", 0, "Fail if a function has a deeper nesting")
+	metricsOperation.IntVar(&thresholds.Parameters, "max-params", 0, "Fail if a function has more parameters")
+	metricsOperation.IntVar(&thresholds.LOC, "max-loc", 0, "Fail if a function spans more lines")
+	metricsOperation.Float64Var(&thresholds.Maintenance, "min-mi", 0, "Fail if a function or file has a lower maintainability index")
+	metricsHelp := metricsOperation.Bool("help", false, "Show help for the metrics operation")
+	metricsOperation.Parse(args[2:])
+
+	if *metricsHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> metrics [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the metrics operation")
+		fmt.Println("  --json - Output the metrics as JSON")
+		fmt.Println("  --csv - Output the metrics as CSV, one line per unit")
+		fmt.Println("  --max-cyclomatic <n> - Fail if a function has a higher cyclomatic complexity")
+		fmt.Println("  --max-cognitive <n> - Fail if a function has a higher cognitive complexity")
+		fmt.Println("  --max-nesting <n> - Fail if a function has a deeper nesting")
+		fmt.Println("  --max-params <n> - Fail if a function has more parameters")
+		fmt.Println("  --max-loc <n> - Fail if a function spans more lines")
+		fmt.Println("  --min-mi <n> - Fail if a function or file has a lower maintainability index (0-100)")
+		fmt.Println("  Computes per file, class, function and method: physical, source, comment and logical lines,")
+		fmt.Println("  cyclomatic and cognitive complexity, nesting depth, parameter count, Halstead measures and")
+		fmt.Println("  maintainability index, with project aggregates. Exits with status 1 if a threshold is exceeded.")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	reports := make([]*metrics.Report, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			reports[i] = metrics.Compute(file, loadTree(file))
+		}(i, file)
+	}
+	wg.Wait()
+
+	summary := metrics.Summarize(reports)
+	var violations []metrics.Violation
+	for _, report := range reports {
+		for _, unit := range report.Units {
+			violations = append(violations, thresholds.Check(unit)...)
+		}
+	}
+
+	switch {
+	case *metricsJSON:
+		var units []*metrics.Unit
+		for _, report := range reports {
+			units = append(units, report.Units...)
+		}
+		result, err := json.Marshal(struct {
+			Units      []*metrics.Unit     `json:"units"`
+			Summary    metrics.Summary     `json:"summary"`
+			Violations []metrics.Violation `json:"violations"`
+		}{units, summary, violations})
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+	case *metricsCSV:
+		printMetricsCSV(reports)
+	default:
+		printMetrics(reports, summary, violations)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printMetrics(reports []*metrics.Report, summary metrics.Summary, violations []metrics.Violation) {
+	for _, report := range reports {
+		fmt.Printf("Results for file %s:\n", report.File)
+		for _, unit := range report.Units {
+			if unit.Kind == metrics.FileUnit {
+				fmt.Printf("File: %d lines (%d source, %d comment, %d blank), %d logical\n",
+					unit.PhysicalLOC, unit.SourceLOC, unit.CommentLOC, unit.BlankLOC, unit.LogicalLOC)
+				fmt.Printf("  cyclomatic %d, cognitive %d, nesting %d, volume %.2f, maintainability %.2f\n",
+					unit.Cyclomatic, unit.Cognitive, unit.MaxNesting, unit.Halstead.Volume, unit.Maintenance)
+				continue
+			}
+			fmt.Printf("Line %d: %s %s - %d lines, %d logical, cyclomatic %d, cognitive %d, nesting %d",
+				unit.Line, unit.Kind, unit.Name, unit.PhysicalLOC, unit.LogicalLOC, unit.Cyclomatic, unit.Cognitive, unit.MaxNesting)
+			if unit.Kind != metrics.ClassUnit {
+				fmt.Printf(", %d parameters", unit.Parameters)
+			}
+			fmt.Printf(", volume %.2f, difficulty %.2f, effort %.2f, maintainability %.2f\n",
+				unit.Halstead.Volume, unit.Halstead.Difficulty, unit.Halstead.Effort, unit.Maintenance)
+		}
+		fmt.Print("----------------------\n")
+	}
+	fmt.Printf("Project: %d files, %d classes, %d functions\n", summary.Files, summary.Classes, summary.Functions)
+	fmt.Printf("  %d lines (%d source, %d comment), %d logical\n",
+		summary.PhysicalLOC, summary.SourceLOC, summary.CommentLOC, summary.LogicalLOC)
+	fmt.Printf("  cyclomatic average %.2f, max %d\n", summary.AverageCyclomatic, summary.MaxCyclomatic)
+	fmt.Printf("  cognitive average %.2f, max %d\n", summary.AverageCognitive, summary.MaxCognitive)
+	fmt.Printf("  max nesting %d\n", summary.MaxNesting)
+	fmt.Printf("  maintainability average %.2f, min %.2f\n", summary.AverageMaintenance, summary.MinMaintenance)
+	if len(violations) > 0 {
+		fmt.Printf("%d threshold violations:\n", len(violations))
+		for _, v := range violations {
+			fmt.Printf("%s:%d: %s %s\n", v.Unit.File, v.Unit.Line, v.Unit.Name, v.Message)
+		}
+	}
+}
+
+func printMetricsCSV(reports []*metrics.Report) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"file", "kind", "name", "line", "physical_loc", "source_loc", "comment_loc", "blank_loc",
+		"logical_loc", "cyclomatic", "cognitive", "max_nesting", "parameters", "halstead_vocabulary",
+		"halstead_length", "halstead_volume", "halstead_difficulty", "halstead_effort", "maintainability_index"})
+	for _, report := range reports {
+		for _, u := range report.Units {
+			w.Write([]string{
+				u.File, string(u.Kind), u.Name, strconv.Itoa(int(u.Line)),
+				strconv.Itoa(u.PhysicalLOC), strconv.Itoa(u.SourceLOC), strconv.Itoa(u.CommentLOC), strconv.Itoa(u.BlankLOC),
+				strconv.Itoa(u.LogicalLOC), strconv.Itoa(u.Cyclomatic), strconv.Itoa(u.Cognitive), strconv.Itoa(u.MaxNesting),
+				strconv.Itoa(u.Parameters), strconv.Itoa(u.Halstead.Vocabulary), strconv.Itoa(u.Halstead.Length),
+				strconv.FormatFloat(u.Halstead.Volume, 'f', 2, 64), strconv.FormatFloat(u.Halstead.Difficulty, 'f', 2, 64),
+				strconv.FormatFloat(u.Halstead.Effort, 'f', 2, 64), strconv.FormatFloat(u.Maintenance, 'f', 2, 64),
+			})
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/traversal"
+)
+
+func pathTraversal(fileName string, args []string, directory, recursive bool) {
+	traversalOperation := flag.NewFlagSet("path-traversal", flag.ExitOnError)
+	minSeverity := traversalOperation.String("min-severity", "low", "Lowest severity reported: low, medium or high")
+	traversalJSON := traversalOperation.Bool("json", false, "Output the findings as JSON")
+	traversalHelp := traversalOperation.Bool("help", false, "Show help for the path-traversal operation")
+	traversalOperation.Parse(args[2:])
+
+	if *traversalHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> path-traversal [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the path-traversal operation")
+		fmt.Println("  --min-severity <low|medium|high> - Lowest severity reported (default low)")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Finds the request data reaching the paths of file_get_contents, fopen, readfile,")
+		fmt.Println("  file_put_contents, unlink, move_uploaded_file and the other file operations, and of include")
+		fmt.Println("  and require, without basename, or realpath followed by a check of the base directory. Also")
+		fmt.Println("  reports the move_uploaded_file calls not preceded by a check of the extension or content type.")
+		os.Exit(0)
+	}
+
+	rank, ok := severityRank(*minSeverity)
+	if !ok {
+		fmt.Printf("Invalid severity %s, expected low, medium or high\n", *minSeverity)
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := traversal.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	findings := []*traversal.Finding{}
+	for _, finding := range analyzer.Analyze() {
+		if r, _ := severityRank(string(finding.Severity)); r >= rank {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *traversalJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s: %s\n", finding.Line, finding.Category, finding.Severity, finding.Sink, finding.Message)
+		printTaint(finding.Taint)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
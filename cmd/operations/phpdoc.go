@@ -0,0 +1,190 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/phpdoc"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type documentedDeclaration struct {
+	File string           `json:"file"`
+	Line uint             `json:"line"`
+	Kind string           `json:"kind"`
+	Name string           `json:"name"`
+	Doc  *phpdoc.DocBlock `json:"doc"`
+}
+
+func phpDoc(fileName string, args []string, directory, recursive bool) {
+	phpdocOperation := flag.NewFlagSet("phpdoc", flag.ExitOnError)
+	lint := phpdocOperation.Bool("lint", false, "Check the doc comments and report the calls to deprecated code instead of listing the doc comments")
+	only := phpdocOperation.String("only", "", "Comma separated list of the categories to report with --lint")
+	phpdocJSON := phpdocOperation.Bool("json", false, "Output the doc comments or the findings as JSON")
+	phpdocHelp := phpdocOperation.Bool("help", false, "Show help for the phpdoc operation")
+	phpdocOperation.Parse(args[2:])
+
+	if *phpdocHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> phpdoc [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the phpdoc operation")
+		fmt.Println("  --lint - Check the doc comments and report the calls to deprecated code instead of listing the doc comments")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report with --lint:")
+		fmt.Println("    deprecated-call, missing-return, missing-param, unknown-param")
+		fmt.Println("  --json - Output the doc comments or the findings as JSON")
+		fmt.Println("  Parses the /** */ doc comments of the functions, methods, classes, properties and constants")
+		fmt.Println("  and their @param, @return, @var, @throws, @deprecated, @since and custom tags")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	trees := make([]*ast.Node, len(files))
+	for i, file := range files {
+		trees[i] = loadTree(file)
+		phpdoc.Attach(trees[i])
+	}
+
+	if *lint {
+		phpdocLint(files, trees, *only, *phpdocJSON)
+		return
+	}
+
+	var declarations []documentedDeclaration
+	for i, file := range files {
+		v := &documentedVisitor{}
+		trees[i].WalkPrefix(v)
+		for _, n := range v.nodes {
+			declarations = append(declarations, documentedDeclaration{
+				File: file,
+				Line: n.StartPosition.Row + 1,
+				Kind: strings.TrimSuffix(strings.TrimSuffix(n.Kind, "_declaration"), "_definition"),
+				Name: declarationName(n),
+				Doc:  phpdoc.Of(n),
+			})
+		}
+	}
+
+	if *phpdocJSON {
+		result, err := json.Marshal(declarations)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, d := range declarations {
+		if d.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = d.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: %s %s", d.Line, d.Kind, d.Name)
+		if d.Doc.Summary != "" {
+			fmt.Printf(" - %s", d.Doc.Summary)
+		}
+		fmt.Println()
+		for _, tag := range d.Doc.Tags {
+			parts := []string{"@" + tag.Name}
+			if tag.Type != "" {
+				parts = append(parts, tag.Type)
+			}
+			if tag.Variable != "" {
+				parts = append(parts, "$"+tag.Variable)
+			}
+			if tag.Description != "" {
+				parts = append(parts, strings.ReplaceAll(tag.Description, "\n", " "))
+			}
+			fmt.Printf("  %s\n", strings.Join(parts, " "))
+		}
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+func phpdocLint(files []string, trees []*ast.Node, only string, asJSON bool) {
+	categories := parseOnly(only, phpdoc.Categories)
+
+	inferer := types.New()
+	for i, file := range files {
+		inferer.AddFile(file, trees[i])
+	}
+	inferer.Resolve()
+	checker := phpdoc.New(inferer.Hierarchy(), inferer.ResolveCall)
+	for i, file := range files {
+		checker.AddFile(file, trees[i])
+	}
+	findings := []*phpdoc.Finding{}
+	for _, finding := range checker.Check() {
+		if len(categories) == 0 || categories[finding.Category] {
+			findings = append(findings, finding)
+		}
+	}
+
+	if asJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s] %s\n", finding.Line, finding.Category, finding.Message)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+// documentedVisitor collects the declarations with a doc comment
+type documentedVisitor struct {
+	nodes []*ast.Node
+}
+
+func (v *documentedVisitor) VisitNode(n *ast.Node) {
+	if n.Kind != "expression_statement" && phpdoc.Kinds[n.Kind] && phpdoc.Of(n) != nil {
+		v.nodes = append(v.nodes, n)
+	}
+}
+
+// declarationName returns the names declared by a declaration, such as $a, $b for a property declaration
+func declarationName(n *ast.Node) string {
+	if name := n.ChildOfKind("name"); name != nil {
+		return name.Text
+	}
+	var names []string
+	for _, element := range n.NamedChildren() {
+		switch element.Kind {
+		case "property_element":
+			if variable := element.ChildOfKind("variable_name"); variable != nil {
+				names = append(names, variable.Text)
+			}
+		case "const_element":
+			if name := element.ChildOfKind("name"); name != nil {
+				names = append(names, name.Text)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,76 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+)
+
+func scopes(fileName string, args []string, directory, recursive bool) {
+	scopesOperation := flag.NewFlagSet("scopes", flag.ExitOnError)
+	scopesJSON := scopesOperation.Bool("json", false, "Output the scopes as JSON")
+	scopesHelp := scopesOperation.Bool("help", false, "Show help for the scopes operation")
+	scopesOperation.Parse(args[2:])
+
+	if *scopesHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> scopes [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the scopes operation")
+		fmt.Println("  --json - Output the scopes as JSON")
+		fmt.Println("  Prints the local scope of each function, method and closure with the variables it declares")
+		fmt.Println("  (parameters, global, static, captured) and the def-use chains of each variable")
+		os.Exit(0)
+	}
+
+	if directory {
+		var wg sync.WaitGroup
+		for _, file := range astFiles(fileName, recursive) {
+			wg.Add(1)
+			go func(fileName string) {
+				defer wg.Done()
+				scopesFile(fileName, *scopesJSON)
+			}(file)
+		}
+		wg.Wait()
+		return
+	}
+	scopesFile(fileName, *scopesJSON)
+}
+
+func scopesFile(fileName string, outputJSON bool) {
+	treeNode := loadTree(fileName)
+	root := scope.Analyze(treeNode)
+	if outputJSON {
+		result, err := json.Marshal(map[string]any{"file": fileName, "scope": root})
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	output := fmt.Sprintf("Results for file %s:\n", fileName)
+	root.Walk(func(s *scope.Scope) {
+		output += fmt.Sprintf("Scope %s (%s) line %d\n", s.Name, s.Node.Kind, s.Node.StartPosition.Row+1)
+		for _, v := range s.SortedVariables() {
+			byRef := ""
+			if v.ByRef {
+				byRef = " by reference"
+			}
+			output += fmt.Sprintf("  $%s [%s%s]\n", v.Name, v.Kind, byRef)
+			for _, def := range v.Defs {
+				output += fmt.Sprintf("    def line %d -> uses at lines %v\n", def.StartPosition.Row+1, scope.Lines(s.UsesOf(def)))
+			}
+			for _, use := range v.Uses {
+				if v.Kind != scope.Superglobal && v.Kind != scope.This && len(s.DefsOf(use)) == 0 {
+					output += fmt.Sprintf("    use line %d has no reaching definition\n", use.StartPosition.Row+1)
+				}
+			}
+		}
+	})
+	fmt.Print(output + "----------------------\n")
+}
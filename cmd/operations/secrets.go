@@ -0,0 +1,128 @@
+package operations
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/secrets"
+)
+
+func hardcodedSecrets(fileName string, args []string, directory, recursive bool) {
+	secretsOperation := flag.NewFlagSet("secrets", flag.ExitOnError)
+	minConfidence := secretsOperation.String("min-confidence", "low", "Lowest confidence reported: low, medium or high")
+	allowlist := secretsOperation.String("allowlist", "", "File of regular expressions, one per line, of the values to ignore; name: prefixes a pattern of names to ignore")
+	only := secretsOperation.String("only", "", "Comma separated list of the rules to report")
+	secretsJSON := secretsOperation.Bool("json", false, "Output the findings as JSON")
+	secretsHelp := secretsOperation.Bool("help", false, "Show help for the secrets operation")
+	secretsOperation.Parse(args[2:])
+
+	if *secretsHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> secrets [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the secrets operation")
+		fmt.Println("  --min-confidence <low|medium|high> - Lowest confidence reported (default low)")
+		fmt.Println("  --allowlist <file> - File of regular expressions, one per line, of the values to ignore;")
+		fmt.Println("    a name: prefix makes the pattern apply to the variable, constant and key names instead")
+		fmt.Println("  --only <rules> - Comma separated list of the rules to report: private-key, aws-access-key, jwt,")
+		fmt.Println("    github-token, slack-token, stripe-key, google-api-key, url-credentials, connection-password,")
+		fmt.Println("    named-credential, high-entropy")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Finds the hardcoded secrets of the string literals: private keys, AWS keys, JWTs and other known")
+		fmt.Println("  token formats, credentials in URLs, values assigned to password, secret, key and token names,")
+		fmt.Println("  and high entropy strings. The values are redacted.")
+		os.Exit(0)
+	}
+
+	options := secrets.Options{MinConfidence: secrets.Confidence(*minConfidence)}
+	switch options.MinConfidence {
+	case secrets.Low, secrets.Medium, secrets.High:
+	default:
+		fmt.Printf("Invalid confidence %s, expected low, medium or high\n", *minConfidence)
+		os.Exit(1)
+	}
+	if *allowlist != "" {
+		if err := readAllowlist(*allowlist, &options); err != nil {
+			fmt.Printf("Error reading allowlist: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	rules := parseOnly(*only, secrets.Rules)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	scanner := secrets.New(options)
+	for _, file := range files {
+		scanner.AddFile(file, loadTree(file))
+	}
+	findings := []*secrets.Finding{}
+	for _, finding := range scanner.Scan() {
+		if len(rules) == 0 || rules[finding.Rule] {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *secretsJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		name := ""
+		if finding.Name != "" {
+			name = finding.Name + " = "
+		}
+		fmt.Printf("Line %d:%d: [%s, %s] %s%s (%d chars, entropy %.2f)\n", finding.Line, finding.Column,
+			finding.Rule, finding.Confidence, name, finding.Value, finding.Length, finding.Entropy)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+// readAllowlist reads the value and name patterns of an allowlist file. Empty lines and lines
+// starting with # are ignored.
+func readAllowlist(path string, options *secrets.Options) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, isName := strings.CutPrefix(line, "name:")
+		pattern, err := regexp.Compile(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		if isName {
+			options.AllowNames = append(options.AllowNames, pattern)
+		} else {
+			options.AllowValues = append(options.AllowValues, pattern)
+		}
+	}
+	return lines.Err()
+}
@@ -0,0 +1,85 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/sqlquery"
+)
+
+func sqlLint(fileName string, args []string, directory, recursive bool) {
+	sqlLintOperation := flag.NewFlagSet("sql-lint", flag.ExitOnError)
+	all := sqlLintOperation.Bool("all", false, "Also print the queries without findings")
+	only := sqlLintOperation.String("only", "", "Comma separated list of the categories to report")
+	sqlLintJSON := sqlLintOperation.Bool("json", false, "Output the queries and their findings as JSON")
+	sqlLintHelp := sqlLintOperation.Bool("help", false, "Show help for the sql-lint operation")
+	sqlLintOperation.Parse(args[2:])
+
+	if *sqlLintHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> sql-lint [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the sql-lint operation")
+		fmt.Println("  --all - Also print the queries without findings")
+		fmt.Println("  --json - Output the queries and their findings as JSON")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report:")
+		fmt.Println("    interpolated-value, quote-breakout, missing-prepare, missing-where, destructive-statement,")
+		fmt.Println("    placeholder-mismatch")
+		fmt.Println("  Extracts the SQL passed to mysql_query, mysqli_query, PDO, mysqli and $wpdb methods, with {int} and")
+		fmt.Println("  {var} standing for its numeric and other dynamic parts, and checks it")
+		os.Exit(0)
+	}
+
+	categories := parseOnly(*only, sqlquery.Categories)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := sqlquery.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	var queries []*sqlquery.Query
+	for _, q := range analyzer.Analyze() {
+		findings := []*sqlquery.Finding{}
+		for _, finding := range q.Findings {
+			if len(categories) == 0 || categories[finding.Category] {
+				findings = append(findings, finding)
+			}
+		}
+		q.Findings = findings
+		if *all || len(findings) > 0 {
+			queries = append(queries, q)
+		}
+	}
+
+	if *sqlLintJSON {
+		result, err := json.Marshal(queries)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, q := range queries {
+		if q.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = q.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: %s %s\n", q.Line, q.Function, strings.Join(q.SQL, " | "))
+		for _, finding := range q.Findings {
+			fmt.Printf("  [%s] %s\n", finding.Category, finding.Message)
+		}
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
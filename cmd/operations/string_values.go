@@ -0,0 +1,144 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type argumentValue struct {
+	File     string        `json:"file"`
+	Line     uint          `json:"line"`
+	Function string        `json:"function"`
+	Index    int           `json:"argument"`
+	Text     string        `json:"expression"`
+	Value    *values.Value `json:"value"`
+}
+
+func stringValues(fileName string, args []string, directory, recursive bool) {
+	stringValuesOperation := flag.NewFlagSet("string-values", flag.ExitOnError)
+	functions := stringValuesOperation.String("functions", "", "Comma separated list of the functions and methods whose arguments are evaluated")
+	stringValuesJSON := stringValuesOperation.Bool("json", false, "Output the values as JSON")
+	stringValuesHelp := stringValuesOperation.Bool("help", false, "Show help for the string-values operation")
+	stringValuesOperation.Parse(args[2:])
+
+	if *stringValuesHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> string-values [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the string-values operation")
+		fmt.Println("  --functions <names> - Comma separated list of the functions and methods whose arguments are evaluated (default all)")
+		fmt.Println("  --json - Output the values as JSON")
+		fmt.Println("  Approximates the strings each call argument may evaluate to, following concatenations, interpolations,")
+		fmt.Println("  sprintf, implode, variables, define() and const constants and class constants")
+		os.Exit(0)
+	}
+
+	names := make(map[string]bool)
+	for _, name := range strings.Split(*functions, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[strings.ToLower(name)] = true
+		}
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	evaluator := values.New()
+	trees := make([]*ast.Node, len(files))
+	for i, file := range files {
+		trees[i] = loadTree(file)
+		evaluator.AddFile(file, trees[i])
+	}
+	evaluator.Resolve()
+
+	var results []argumentValue
+	for i, file := range files {
+		v := &callVisitor{names: names}
+		trees[i].WalkPrefix(v)
+		for _, call := range v.calls {
+			for index, argument := range call.Arguments() {
+				results = append(results, argumentValue{
+					File:     file,
+					Line:     argument.StartPosition.Row + 1,
+					Function: calleeName(call),
+					Index:    index + 1,
+					Text:     argument.Text,
+					Value:    evaluator.Eval(argument),
+				})
+			}
+		}
+	}
+
+	if *stringValuesJSON {
+		result, err := json.Marshal(results)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, r := range results {
+		if r.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = r.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		var flags []string
+		if r.Value.IsConstant() {
+			flags = append(flags, "constant")
+		}
+		if r.Value.MayContainQuote() {
+			flags = append(flags, "may contain quote")
+		}
+		fmt.Printf("Line %d: %s argument %d %s = %s", r.Line, r.Function, r.Index, r.Text, r.Value)
+		if len(flags) > 0 {
+			fmt.Printf(" (%s)", strings.Join(flags, ", "))
+		}
+		fmt.Println()
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+type callVisitor struct {
+	names map[string]bool
+	calls []*ast.Node
+}
+
+func (v *callVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "function_call_expression", "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		if len(v.names) == 0 || v.names[strings.ToLower(calleeName(n))] {
+			v.calls = append(v.calls, n)
+		}
+	}
+}
+
+// calleeName returns the name of the function or method called, without receiver
+func calleeName(call *ast.Node) string {
+	if call.Kind == "function_call_expression" {
+		if children := call.NamedChildren(); len(children) > 0 {
+			return children[0].Text
+		}
+		return ""
+	}
+	// The class of a scoped call is also a name
+	name := ""
+	for _, child := range call.NamedChildren() {
+		if child.Kind == "name" {
+			name = child.Text
+		}
+	}
+	return name
+}
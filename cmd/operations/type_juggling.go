@@ -0,0 +1,98 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/juggling"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func typeJuggling(fileName string, args []string, directory, recursive bool) {
+	jugglingOperation := flag.NewFlagSet("type-juggling", flag.ExitOnError)
+	minSeverity := jugglingOperation.String("min-severity", "low", "Lowest severity reported: low, medium or high")
+	jugglingJSON := jugglingOperation.Bool("json", false, "Output the findings as JSON")
+	jugglingFix := jugglingOperation.Bool("fix", false, "Print the sources with the loose comparisons made strict")
+	jugglingHelp := jugglingOperation.Bool("help", false, "Show help for the type-juggling operation")
+	jugglingOperation.Parse(args[2:])
+
+	if *jugglingHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> type-juggling [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the type-juggling operation")
+		fmt.Println("  --min-severity <low|medium|high> - Lowest severity reported (default low)")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  --fix - Print the sources with the loose comparisons made strict")
+		fmt.Println("  Finds the request data compared with ==, != or <>, switched on, or searched with in_array")
+		fmt.Println("  or array_search without strict mode, where the other side is a hash, a token, a password or")
+		fmt.Println("  a \"0e...\" magic string that PHP compares as a number. Each finding shows the strict form of")
+		fmt.Println("  the comparison.")
+		os.Exit(0)
+	}
+
+	rank, ok := severityRank(*minSeverity)
+	if !ok {
+		fmt.Printf("Invalid severity %s, expected low, medium or high\n", *minSeverity)
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := juggling.New()
+	roots := make(map[string]*ast.Node)
+	for _, file := range files {
+		roots[file] = loadTree(file)
+		analyzer.AddFile(file, roots[file])
+	}
+	findings := []*juggling.Finding{}
+	for _, finding := range analyzer.Analyze() {
+		if r, _ := severityRank(string(finding.Severity)); r >= rank {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *jugglingFix {
+		edits := make(map[string][]*juggling.Edit)
+		var fixed []string
+		for _, finding := range findings {
+			if _, ok := edits[finding.File]; !ok {
+				fixed = append(fixed, finding.File)
+			}
+			edits[finding.File] = append(edits[finding.File], finding.Edits...)
+		}
+		for _, file := range fixed {
+			root := roots[file]
+			fmt.Printf("%s :\n%s\n", file, juggling.Apply(root.Text, root.StartByte, edits[file]))
+		}
+		return
+	}
+	if *jugglingJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s\n", finding.Line, finding.Category, finding.Severity, finding.Message)
+		printTaint(finding.Taint)
+		fmt.Printf("    fix: %s\n", finding.Fix)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
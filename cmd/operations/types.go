@@ -0,0 +1,127 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type inferredType struct {
+	File       string    `json:"file"`
+	Line       uint      `json:"line"`
+	Expression string    `json:"expression"`
+	Types      types.Set `json:"types"`
+	Calls      []string  `json:"calls,omitempty"`
+}
+
+func inferTypes(fileName string, args []string, directory, recursive bool) {
+	typesOperation := flag.NewFlagSet("types", flag.ExitOnError)
+	calls := typesOperation.Bool("calls", false, "Print the methods each method call may dispatch to instead of the variable types")
+	typesJSON := typesOperation.Bool("json", false, "Output the inferred types as JSON")
+	typesHelp := typesOperation.Bool("help", false, "Show help for the types operation")
+	typesOperation.Parse(args[2:])
+
+	if *typesHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> types [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the types operation")
+		fmt.Println("  --calls - Print the methods each method call may dispatch to instead of the variable types")
+		fmt.Println("  --json - Output the inferred types as JSON")
+		fmt.Println("  Infers the types of the variables from literals, casts, declared types, @param, @return and @var")
+		fmt.Println("  doc comments and the signatures of the PHP and WordPress builtins")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	inferer := types.New()
+	trees := make([]*ast.Node, len(files))
+	for i, file := range files {
+		trees[i] = loadTree(file)
+		inferer.AddFile(file, trees[i])
+	}
+	inferer.Resolve()
+
+	var results []inferredType
+	for i, file := range files {
+		v := &typedVisitor{calls: *calls}
+		trees[i].WalkPrefix(v)
+		seen := make(map[string]bool)
+		for _, n := range v.nodes {
+			result := inferredType{File: file, Line: n.StartPosition.Row + 1, Expression: n.Text, Types: inferer.TypeOf(n)}
+			if *calls {
+				result.Types = nil
+				if arguments := n.ChildOfKind("arguments"); arguments != nil {
+					result.Expression = strings.TrimSuffix(n.Text, arguments.Text) + "()"
+				}
+				for _, m := range inferer.ResolveCall(n) {
+					result.Calls = append(result.Calls, m.Class.Name+"::"+m.Name)
+				}
+			} else {
+				key := fmt.Sprintf("%d %s %s", result.Line, result.Expression, result.Types)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+		}
+	}
+
+	if *typesJSON {
+		result, err := json.Marshal(results)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, r := range results {
+		if r.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = r.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		switch {
+		case !*calls:
+			fmt.Printf("Line %d: %s : %s\n", r.Line, r.Expression, r.Types)
+		case len(r.Calls) == 0:
+			fmt.Printf("Line %d: %s -> unresolved\n", r.Line, r.Expression)
+		default:
+			fmt.Printf("Line %d: %s -> %s\n", r.Line, r.Expression, strings.Join(r.Calls, ", "))
+		}
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+// typedVisitor collects the variable occurrences, or the method calls
+type typedVisitor struct {
+	calls bool
+	nodes []*ast.Node
+}
+
+func (v *typedVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "variable_name":
+		if !v.calls && n.Parent != nil && n.Parent.Kind != "property_element" {
+			v.nodes = append(v.nodes, n)
+		}
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		if v.calls {
+			v.nodes = append(v.nodes, n)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/crypto"
+)
+
+func weakCrypto(fileName string, args []string, directory, recursive bool) {
+	cryptoOperation := flag.NewFlagSet("weak-crypto", flag.ExitOnError)
+	minSeverity := cryptoOperation.String("min-severity", "low", "Lowest severity reported: low, medium or high")
+	cryptoJSON := cryptoOperation.Bool("json", false, "Output the findings as JSON")
+	cryptoHelp := cryptoOperation.Bool("help", false, "Show help for the weak-crypto operation")
+	cryptoOperation.Parse(args[2:])
+
+	if *cryptoHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> weak-crypto [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the weak-crypto operation")
+		fmt.Println("  --min-severity <low|medium|high> - Lowest severity reported (default low)")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Finds the misuses of cryptography: passwords hashed with md5, sha1 or crc32, tokens generated")
+		fmt.Println("  with rand, mt_rand or uniqid, openssl_encrypt in ECB mode or with a constant IV, hardcoded")
+		fmt.Println("  keys, the mcrypt functions and hashes or tokens compared with == instead of hash_equals.")
+		fmt.Println("  Cipher names and keys are evaluated through variables and constants, and the names of the")
+		fmt.Println("  variables tell the passwords and tokens.")
+		os.Exit(0)
+	}
+
+	rank, ok := severityRank(*minSeverity)
+	if !ok {
+		fmt.Printf("Invalid severity %s, expected low, medium or high\n", *minSeverity)
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := crypto.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	findings := []*crypto.Finding{}
+	for _, finding := range analyzer.Analyze() {
+		if r, _ := severityRank(string(finding.Severity)); r >= rank {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *cryptoJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s: %s\n", finding.Line, finding.Category, finding.Severity, finding.Function, finding.Message)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
@@ -0,0 +1,65 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/webshell"
+)
+
+func webshellScan(fileName string, args []string, directory, recursive bool) {
+	webshellOperation := flag.NewFlagSet("webshell-scan", flag.ExitOnError)
+	minScore := webshellOperation.Int("min-score", 1, "Lowest score reported, from 0 to 100")
+	webshellJSON := webshellOperation.Bool("json", false, "Output the reports as JSON")
+	webshellHelp := webshellOperation.Bool("help", false, "Show help for the webshell-scan operation")
+	webshellOperation.Parse(args[2:])
+
+	if *webshellHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> webshell-scan [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the webshell-scan operation")
+		fmt.Println("  --min-score <n> - Lowest score reported, from 0 to 100 (default 1)")
+		fmt.Println("  --json - Output the reports as JSON")
+		fmt.Println("  Scores each file from 0 to 100 on the features of webshells: eval or assert of data decoded")
+		fmt.Println("  with base64_decode, gzinflate or str_rot13, variable functions built from strings,")
+		fmt.Println("  preg_replace with /e, create_function, very long string literals, obfuscated identifiers")
+		fmt.Println("  and request data reaching the execution sinks. Files scoring 60 or more are likely webshells,")
+		fmt.Println("  30 or more are suspicious.")
+		os.Exit(0)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	scanner := webshell.New()
+	for _, file := range files {
+		scanner.AddFile(file, loadTree(file))
+	}
+	reports := []*webshell.Report{}
+	for _, report := range scanner.Scan() {
+		if report.Score >= *minScore {
+			reports = append(reports, report)
+		}
+	}
+
+	if *webshellJSON {
+		result, err := json.Marshal(reports)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	for _, report := range reports {
+		fmt.Printf("Results for file %s:\n", report.File)
+		fmt.Printf("Score %d/100: %s\n", report.Score, report.Verdict)
+		for _, evidence := range report.Evidence {
+			fmt.Printf("  %s\n", evidence)
+		}
+		fmt.Print("----------------------\n")
+	}
+}
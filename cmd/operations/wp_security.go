@@ -0,0 +1,102 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/wordpress"
+)
+
+func wpSecurity(fileName string, args []string, directory, recursive bool) {
+	wpSecurityOperation := flag.NewFlagSet("wp-security", flag.ExitOnError)
+	only := wpSecurityOperation.String("only", "", "Comma separated list of the categories to report")
+	wpSecurityJSON := wpSecurityOperation.Bool("json", false, "Output the findings as JSON")
+	wpSecurityHelp := wpSecurityOperation.Bool("help", false, "Show help for the wp-security operation")
+	wpSecurityOperation.Parse(args[2:])
+
+	if *wpSecurityHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> wp-security [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the wp-security operation")
+		fmt.Println("  --only <categories> - Comma separated list of the categories to report: missing-nonce,")
+		fmt.Println("    missing-capability, unprepared-query, unescaped-output, option-injection")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Checks a WordPress plugin or theme: the wp_ajax_ and admin_post_ handlers without a nonce or")
+		fmt.Println("  capability check, the $wpdb queries built from request data or interpolated values without")
+		fmt.Println("  prepare, the request data printed without esc_html, esc_attr or wp_kses, and the options")
+		fmt.Println("  updated from request data. Analyze the files of the project together with --directory.")
+		os.Exit(0)
+	}
+
+	categories := parseOnly(*only, wordpress.Categories)
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	checker := wordpress.NewChecker()
+	for _, file := range files {
+		checker.AddFile(file, loadTree(file))
+	}
+	all, err := checker.Check()
+	if err != nil {
+		fmt.Printf("Error loading the security rules: %v\n", err)
+		os.Exit(1)
+	}
+	findings := []*wordpress.Finding{}
+	for _, finding := range all {
+		if len(categories) == 0 || categories[finding.Category] {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *wpSecurityJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s\n", finding.Line, finding.Category, finding.Severity, finding.Message)
+		printTaint(finding.Taint)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+// printTaint prints the path of request data from its source, and the sanitizers applied on the way
+func printTaint(t *taint.Taint) {
+	if t == nil {
+		return
+	}
+	for i, step := range t.Path {
+		arrow := "from"
+		if i > 0 {
+			arrow = "  ->"
+		}
+		fmt.Printf("    %s %s:%d %s\n", arrow, step.File, step.Line, step.Text)
+	}
+	if len(t.Sanitizers) > 0 {
+		var names []string
+		for _, s := range t.Sanitizers {
+			names = append(names, s.Name)
+		}
+		fmt.Printf("    sanitized by %s\n", strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/xss"
+)
+
+func crossSiteScripting(fileName string, args []string, directory, recursive bool) {
+	xssOperation := flag.NewFlagSet("xss", flag.ExitOnError)
+	minSeverity := xssOperation.String("min-severity", "low", "Lowest severity reported: low, medium or high")
+	xssJSON := xssOperation.Bool("json", false, "Output the findings as JSON")
+	xssHelp := xssOperation.Bool("help", false, "Show help for the xss operation")
+	xssOperation.Parse(args[2:])
+
+	if *xssHelp {
+		fmt.Println("Usage: go-php-parser operations [OPFlags] <file.ast.json|directory> xss [flags]")
+		fmt.Println("Flags:")
+		fmt.Println("  --help - Show help for the xss operation")
+		fmt.Println("  --min-severity <low|medium|high> - Lowest severity reported (default low)")
+		fmt.Println("  --json - Output the findings as JSON")
+		fmt.Println("  Finds the request data printed by echo, print, <?= and printf. The HTML context of each")
+		fmt.Println("  value (text, attribute, URL, event handler, script...) is told from the inline HTML and the")
+		fmt.Println("  literal strings printed before it, and the escapers applied to the value are checked against")
+		fmt.Println("  it: unescaped output is high, an escaper for another context is a context mismatch.")
+		os.Exit(0)
+	}
+
+	rank, ok := severityRank(*minSeverity)
+	if !ok {
+		fmt.Printf("Invalid severity %s, expected low, medium or high\n", *minSeverity)
+		os.Exit(1)
+	}
+
+	files := []string{fileName}
+	if directory {
+		files = astFiles(fileName, recursive)
+	}
+	analyzer := xss.New()
+	for _, file := range files {
+		analyzer.AddFile(file, loadTree(file))
+	}
+	findings := []*xss.Finding{}
+	for _, finding := range analyzer.Analyze() {
+		if r, _ := severityRank(string(finding.Severity)); r >= rank {
+			findings = append(findings, finding)
+		}
+	}
+
+	if *xssJSON {
+		result, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	current := ""
+	for _, finding := range findings {
+		if finding.File != current {
+			if current != "" {
+				fmt.Print("----------------------\n")
+			}
+			current = finding.File
+			fmt.Printf("Results for file %s:\n", current)
+		}
+		fmt.Printf("Line %d: [%s, %s] %s: %s\n", finding.Line, finding.Category, finding.Severity, finding.Sink, finding.Message)
+		printTaint(finding.Taint)
+	}
+	if current != "" {
+		fmt.Print("----------------------\n")
+	}
+}
+
+// severityRank orders the severities from low to high
+func severityRank(severity string) (int, bool) {
+	switch taint.Severity(strings.ToLower(severity)) {
+	case taint.Low:
+		return 0, true
+	case taint.Medium:
+		return 1, true
+	case taint.High:
+		return 2, true
+	}
+	return 0, false
+}
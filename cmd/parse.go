@@ -9,8 +9,6 @@ import (
 	"sync"
 
 	"github.com/28Pollux28/log6302-parser/internal/ast"
-	ts "github.com/tree-sitter/go-tree-sitter"
-	tree_sitter_php "github.com/tree-sitter/tree-sitter-php/bindings/go"
 )
 
 func parsePHP(args []string) {
@@ -91,16 +89,7 @@ func parseDir(directory, output string, recursive, prettyPrint bool, wg *sync.Wa
 }
 
 func parseFile(filePHP []byte, outputFile string, prettyPrint bool) {
-	parser := ts.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(ts.NewLanguage(tree_sitter_php.LanguagePHP()))
-
-	treesitterTree := parser.Parse(filePHP, nil)
-	defer treesitterTree.Close()
-
-	root := treesitterTree.RootNode()
-
-	treeNode := ast.WalkTreeSitterTree(root, &filePHP)
+	treeNode := ast.ParseSource(filePHP)
 
 	dir := path.Dir(outputFile)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
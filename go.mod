@@ -3,8 +3,8 @@ module github.com/28Pollux28/log6302-parser
 go 1.23
 
 require (
-	github.com/mattn/go-pointer v0.0.1 // indirect
-	github.com/tree-sitter/go-tree-sitter v0.24.0 // indirect
-	github.com/tree-sitter/tree-sitter-php v0.23.11 // indirect
+	github.com/tree-sitter/go-tree-sitter v0.24.0
+	github.com/tree-sitter/tree-sitter-php v0.23.11
 )
 
+require github.com/mattn/go-pointer v0.0.1 // indirect
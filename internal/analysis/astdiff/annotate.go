@@ -0,0 +1,80 @@
+package astdiff
+
+import (
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+	"github.com/28Pollux28/log6302-parser/internal/ast/pretty_print"
+)
+
+// markers delimit the annotated nodes of the pretty-printed trees
+var markers = map[ActionType][2]string{
+	Insert: {"{+", "+}"},
+	Delete: {"[-", "-]"},
+	Update: {"{~", "~}"},
+	Move:   {"{>", "<}"},
+}
+
+var colors = map[ActionType]string{
+	Insert: "\033[0;32m",
+	Delete: "\033[0;31m",
+	Update: "\033[0;33m",
+	Move:   "\033[0;34m",
+}
+
+const colorReset = "\033[0m"
+
+// markedBlock wraps the block of an edited node with markers. It keeps the type of the wrapped
+// block so that the renders of the parent nodes are not affected.
+type markedBlock struct {
+	pretty_print.IBlock
+	open, close string
+}
+
+func (b *markedBlock) Render(indentLvl int) string {
+	return b.open + b.IBlock.Render(indentLvl) + b.close
+}
+
+// annotatingVisitor pretty prints a tree, marking the nodes touched by the edit script
+type annotatingVisitor struct {
+	*ast.PrettyPrintVisitor
+	marks map[*ast.Node]ActionType
+	color bool
+}
+
+func (v *annotatingVisitor) VisitNode(n *ast.Node) {
+	v.PrettyPrintVisitor.VisitNode(n)
+	action, ok := v.marks[n]
+	if !ok {
+		return
+	}
+	block := v.Result.Pop().(pretty_print.IBlock)
+	marked := &markedBlock{IBlock: block, open: markers[action][0], close: markers[action][1]}
+	if v.color {
+		marked.open, marked.close = colors[action], colorReset
+	}
+	v.Result.Push(marked)
+}
+
+// Annotate pretty prints the old tree with its deleted, updated and moved nodes marked, and the
+// new tree with its inserted, updated and moved nodes marked. Markers are {+ +} for insertions,
+// [- -] for deletions, {~ ~} for updates and {> <} for moves, or ANSI colors.
+func Annotate(old, new *ast.Node, actions []*Action, color bool) (string, string) {
+	oldMarks := make(map[*ast.Node]ActionType)
+	newMarks := make(map[*ast.Node]ActionType)
+	for _, a := range actions {
+		if a.Old != nil {
+			if _, ok := oldMarks[a.Old]; !ok {
+				oldMarks[a.Old] = a.Type
+			}
+		}
+		if a.New != nil {
+			if _, ok := newMarks[a.New]; !ok {
+				newMarks[a.New] = a.Type
+			}
+		}
+	}
+	oldVisitor := &annotatingVisitor{PrettyPrintVisitor: ast.NewPrettyPrintVisitor(), marks: oldMarks, color: color}
+	old.WalkPostfix(oldVisitor)
+	newVisitor := &annotatingVisitor{PrettyPrintVisitor: ast.NewPrettyPrintVisitor(), marks: newMarks, color: color}
+	new.WalkPostfix(newVisitor)
+	return oldVisitor.Print(), newVisitor.Print()
+}
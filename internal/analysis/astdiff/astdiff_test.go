@@ -0,0 +1,76 @@
+package astdiff
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// describe summarizes an action by its type and the kind and text of its node
+func describe(a *Action) string {
+	switch a.Type {
+	case Insert:
+		return "insert " + a.New.Kind + " " + a.New.Text
+	case Delete:
+		return "delete " + a.Old.Kind + " " + a.Old.Text
+	case Update:
+		return "update " + a.Old.Text + " -> " + a.New.Text
+	}
+	return "move " + a.Old.Kind + " " + a.Old.Text
+}
+
+func TestEditScript(t *testing.T) {
+	const old = "<?php\n$a = 1;\n$b = foo($a);\necho $b;\n"
+	tests := []struct {
+		name    string
+		new     string
+		actions []string
+	}{
+		{
+			name: "identical",
+			new:  "<?php\n$a = 1;\n\n$b = foo($a);\necho $b;\n",
+		},
+		{
+			name:    "changed literal",
+			new:     "<?php\n$a = 2;\n$b = foo($a);\necho $b;\n",
+			actions: []string{"update 1 -> 2"},
+		},
+		{
+			name:    "renamed function",
+			new:     "<?php\n$a = 1;\n$b = bar($a);\necho $b;\n",
+			actions: []string{"update foo -> bar"},
+		},
+		{
+			name:    "inserted statement",
+			new:     "<?php\n$a = 1;\n$b = foo($a);\nunset($a);\necho $b;\n",
+			actions: []string{"insert unset_statement unset($a);"},
+		},
+		{
+			name:    "deleted statement",
+			new:     "<?php\n$a = 1;\n$b = foo($a);\n",
+			actions: []string{"delete echo_statement echo $b;"},
+		},
+		{
+			name:    "swapped statements",
+			new:     "<?php\n$b = foo($a);\n$a = 1;\necho $b;\n",
+			actions: []string{"move expression_statement $a = 1;"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldTree := ast.ParseSource([]byte(old))
+			newTree := ast.ParseSource([]byte(tt.new))
+			oldTree.SetParents()
+			newTree.SetParents()
+			var got []string
+			for _, a := range Match(oldTree, newTree, DefaultOptions).EditScript() {
+				got = append(got, describe(a))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.actions) {
+				t.Errorf("actions = %q, want %q", got, tt.actions)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package astdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// operatorParents keep their anonymous operator tokens in the derived kind trees, as == and === differ
+var operatorParents = map[string]bool{
+	"binary_expression":               true,
+	"unary_op_expression":             true,
+	"augmented_assignment_expression": true,
+}
+
+// literalKinds are generalized to their kind in the derived kind trees
+var literalKinds = map[string]bool{
+	"string": true, "encapsed_string": true, "heredoc": true, "nowdoc": true,
+	"integer": true, "float": true, "boolean": true, "null": true,
+}
+
+// KindTrees derives kind trees matching the code before the patch: the nodes of the old tree
+// deleted, updated or moved, and the old nodes receiving insertions, are grouped by their enclosing
+// expression or statement, which is generalized into a kind tree. Variable names and literal values
+// are abstracted while function, method and class names are kept. The result uses the format of
+// the find-kind-trees operation.
+func (m *Mapping) KindTrees(actions []*Action) map[string]*ast.KindTree {
+	var changed []*ast.Node
+	for _, a := range actions {
+		switch {
+		case a.Old != nil:
+			changed = append(changed, a.Old)
+		case a.Type == Insert && a.Parent != nil && m.dstToSrc[a.Parent] != nil:
+			changed = append(changed, m.dstToSrc[a.Parent])
+		}
+	}
+
+	var anchors []*ast.Node
+	seen := make(map[*ast.Node]bool)
+	for _, n := range changed {
+		anchor := enclosingPattern(n)
+		if !seen[anchor] {
+			seen[anchor] = true
+			anchors = append(anchors, anchor)
+		}
+	}
+	// Only the outermost anchors are kept, the nested ones are part of their kind trees
+	var roots []*ast.Node
+	for _, a := range anchors {
+		nested := false
+		for _, b := range anchors {
+			if a != b && m.src.isDescendant(a, b) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			roots = append(roots, a)
+		}
+	}
+
+	result := make(map[string]*ast.KindTree)
+	derived := make(map[string]bool)
+	for _, root := range roots {
+		kt := kindTree(root)
+		// Identical changes at several places give the same kind tree
+		key, _ := json.Marshal(kt)
+		if derived[string(key)] {
+			continue
+		}
+		derived[string(key)] = true
+		kt.Name = fmt.Sprintf("change_%d", len(result)+1)
+		result[kt.Name] = kt
+	}
+	return result
+}
+
+// enclosingPattern returns the closest expression or statement containing a node
+func enclosingPattern(n *ast.Node) *ast.Node {
+	for cur := n; cur != nil; cur = cur.Parent {
+		switch {
+		case cur.Kind == "compound_statement", cur.Kind == "expression_statement", cur.Kind == "parenthesized_expression":
+		case strings.HasSuffix(cur.Kind, "_expression"), strings.HasSuffix(cur.Kind, "_statement"):
+			return cur
+		}
+	}
+	return n
+}
+
+func kindTree(n *ast.Node) *ast.KindTree {
+	kt := ast.NewKindTree(n.Kind, nil)
+	switch {
+	case n.Kind == "name":
+		text := n.Text
+		kt.Attributes = &ast.KindTreeAttributes{Text: &text}
+		return kt
+	case n.Kind == "variable_name", literalKinds[n.Kind]:
+		return kt
+	}
+	for _, child := range n.Descendants {
+		switch {
+		case child.Kind == "comment":
+		case child.IsNamed:
+			kt.AddChildTree(kindTree(child))
+		case operatorParents[n.Kind]:
+			kt.AddChild(child.Kind, nil)
+		}
+	}
+	return kt
+}
@@ -0,0 +1,275 @@
+package astdiff
+
+import (
+	"sort"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Options configures the matching
+type Options struct {
+	// MinHeight is the minimal height of the subtrees matched in the top-down phase
+	MinHeight int
+	// MinDice is the minimal ratio of common descendants for two containers to be matched in the bottom-up phase
+	MinDice float64
+}
+
+// DefaultOptions are the GumTree defaults
+var DefaultOptions = Options{MinHeight: 2, MinDice: 0.5}
+
+// Mapping associates the nodes of the old tree to the nodes of the new one
+type Mapping struct {
+	src, dst *tree
+	srcToDst map[*ast.Node]*ast.Node
+	dstToSrc map[*ast.Node]*ast.Node
+	options  Options
+}
+
+// Match computes the mapping between two trees in two phases, as GumTree does: the top-down
+// phase matches the largest isomorphic subtrees, the bottom-up phase matches the containers
+// sharing many matched descendants and recovers the unmatched nodes among their children
+func Match(old, new *ast.Node, options Options) *Mapping {
+	m := &Mapping{
+		src:      newTree(old),
+		dst:      newTree(new),
+		srcToDst: make(map[*ast.Node]*ast.Node),
+		dstToSrc: make(map[*ast.Node]*ast.Node),
+		options:  options,
+	}
+	m.topDown()
+	m.bottomUp()
+	return m
+}
+
+// Partner returns the node of the other tree a node is matched to, or nil
+func (m *Mapping) Partner(n *ast.Node) *ast.Node {
+	if p, ok := m.srcToDst[n]; ok {
+		return p
+	}
+	return m.dstToSrc[n]
+}
+
+func (m *Mapping) link(a, b *ast.Node) {
+	m.srcToDst[a] = b
+	m.dstToSrc[b] = a
+}
+
+// linkSubtrees matches two isomorphic subtrees node by node
+func (m *Mapping) linkSubtrees(a, b *ast.Node) {
+	m.link(a, b)
+	for i := range a.Descendants {
+		m.linkSubtrees(a.Descendants[i], b.Descendants[i])
+	}
+}
+
+func (m *Mapping) topDown() {
+	l1, l2 := newHeightQueue(m.src), newHeightQueue(m.dst)
+	var candidates [][2]*ast.Node
+	for min(l1.peek(), l2.peek()) >= m.options.MinHeight {
+		if h1, h2 := l1.peek(), l2.peek(); h1 != h2 {
+			if h1 > h2 {
+				for _, n := range l1.pop() {
+					l1.open(n)
+				}
+			} else {
+				for _, n := range l2.pop() {
+					l2.open(n)
+				}
+			}
+			continue
+		}
+		h1, h2 := l1.pop(), l2.pop()
+		count1, count2 := make(map[uint64]int), make(map[uint64]int)
+		for _, n := range h1 {
+			count1[m.src.hash[n]]++
+		}
+		for _, n := range h2 {
+			count2[m.dst.hash[n]]++
+		}
+		handled := make(map[*ast.Node]bool)
+		for _, a := range h1 {
+			for _, b := range h2 {
+				hash := m.src.hash[a]
+				if hash != m.dst.hash[b] {
+					continue
+				}
+				if count1[hash] > 1 || count2[hash] > 1 {
+					// Ambiguous: decided later on the similarity of the parents
+					candidates = append(candidates, [2]*ast.Node{a, b})
+				} else {
+					m.linkSubtrees(a, b)
+				}
+				handled[a], handled[b] = true, true
+			}
+		}
+		for _, n := range h1 {
+			if !handled[n] {
+				l1.open(n)
+			}
+		}
+		for _, n := range h2 {
+			if !handled[n] {
+				l2.open(n)
+			}
+		}
+	}
+
+	dice := make([]float64, len(candidates))
+	for i, c := range candidates {
+		if c[0].Parent != nil && c[1].Parent != nil {
+			dice[i] = m.dice(c[0].Parent, c[1].Parent)
+		}
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return dice[order[i]] > dice[order[j]]
+	})
+	for _, i := range order {
+		a, b := candidates[i][0], candidates[i][1]
+		if m.srcToDst[a] == nil && m.dstToSrc[b] == nil {
+			m.linkSubtrees(a, b)
+		}
+	}
+}
+
+// dice returns the ratio of the descendants of a and b that are matched together
+func (m *Mapping) dice(a, b *ast.Node) float64 {
+	total := m.src.size[a] - 1 + m.dst.size[b] - 1
+	if total == 0 {
+		return 0
+	}
+	common := 0
+	for _, d := range m.src.descendants(a) {
+		if p := m.srcToDst[d]; p != nil && m.dst.isDescendant(p, b) {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(total)
+}
+
+func (m *Mapping) bottomUp() {
+	for _, a := range m.src.postorder {
+		if a == m.src.root {
+			if m.srcToDst[a] == nil && m.dstToSrc[m.dst.root] == nil {
+				m.link(a, m.dst.root)
+				m.recovery(a, m.dst.root)
+			}
+			break
+		}
+		if m.srcToDst[a] != nil || len(a.Descendants) == 0 {
+			continue
+		}
+		var best *ast.Node
+		bestDice := m.options.MinDice
+		for _, b := range m.containerCandidates(a) {
+			if d := m.dice(a, b); d > bestDice {
+				best, bestDice = b, d
+			}
+		}
+		if best != nil {
+			m.link(a, best)
+			m.recovery(a, best)
+		}
+	}
+}
+
+// containerCandidates returns the unmatched nodes of the new tree of the same kind as a that
+// contain partners of its descendants
+func (m *Mapping) containerCandidates(a *ast.Node) []*ast.Node {
+	var candidates []*ast.Node
+	seen := make(map[*ast.Node]bool)
+	for _, d := range m.src.descendants(a) {
+		p := m.srcToDst[d]
+		if p == nil {
+			continue
+		}
+		for cur := p.Parent; cur != nil && cur != m.dst.root; cur = cur.Parent {
+			if seen[cur] {
+				break
+			}
+			seen[cur] = true
+			if cur.Kind == a.Kind && m.dstToSrc[cur] == nil {
+				candidates = append(candidates, cur)
+			}
+		}
+	}
+	return candidates
+}
+
+// recovery matches the unmatched children of two matched nodes: isomorphic subtrees first, then
+// nodes with the same kind and label, then nodes whose kind is unique on both sides
+func (m *Mapping) recovery(a, b *ast.Node) {
+	unmatched := func(children []*ast.Node, mapping map[*ast.Node]*ast.Node) []*ast.Node {
+		var result []*ast.Node
+		for _, c := range children {
+			if mapping[c] == nil {
+				result = append(result, c)
+			}
+		}
+		return result
+	}
+
+	c1, c2 := unmatched(a.Descendants, m.srcToDst), unmatched(b.Descendants, m.dstToSrc)
+	for _, pair := range lcs(c1, c2, func(x, y *ast.Node) bool { return m.src.hash[x] == m.dst.hash[y] }) {
+		m.linkSubtrees(pair[0], pair[1])
+	}
+
+	c1, c2 = unmatched(a.Descendants, m.srcToDst), unmatched(b.Descendants, m.dstToSrc)
+	for _, pair := range lcs(c1, c2, func(x, y *ast.Node) bool { return x.Kind == y.Kind && label(x) == label(y) }) {
+		m.link(pair[0], pair[1])
+		m.recovery(pair[0], pair[1])
+	}
+
+	c1, c2 = unmatched(a.Descendants, m.srcToDst), unmatched(b.Descendants, m.dstToSrc)
+	kinds1, kinds2 := make(map[string][]*ast.Node), make(map[string][]*ast.Node)
+	for _, c := range c1 {
+		kinds1[c.Kind] = append(kinds1[c.Kind], c)
+	}
+	for _, c := range c2 {
+		kinds2[c.Kind] = append(kinds2[c.Kind], c)
+	}
+	for _, x := range c1 {
+		if len(kinds1[x.Kind]) == 1 && len(kinds2[x.Kind]) == 1 {
+			y := kinds2[x.Kind][0]
+			m.link(x, y)
+			m.recovery(x, y)
+		}
+	}
+}
+
+// lcs returns the pairs of the longest common subsequence of two node lists
+func lcs(a, b []*ast.Node, equal func(x, y *ast.Node) bool) [][2]*ast.Node {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if equal(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	var pairs [][2]*ast.Node
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case equal(a[i], b[j]):
+			pairs = append(pairs, [2]*ast.Node{a[i], b[j]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,137 @@
+package astdiff
+
+import (
+	"encoding/json"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// ActionType is the type of an edit action
+type ActionType string
+
+const (
+	Insert ActionType = "insert"
+	Delete ActionType = "delete"
+	Update ActionType = "update"
+	Move   ActionType = "move"
+)
+
+// Action is an edit of the old tree. Insert and delete act on whole subtrees: the descendants of
+// an inserted or deleted node are not reported, except the ones moved from or to it.
+type Action struct {
+	Type ActionType
+	// Old is the node of the old tree deleted, updated or moved, nil for an insertion
+	Old *ast.Node
+	// New is the node of the new tree inserted, or the new version of the updated or moved node, nil for a deletion
+	New *ast.Node
+	// Parent is the parent in the new tree of an inserted or moved node
+	Parent *ast.Node
+	// Position is the index of an inserted or moved node among the children of its new parent
+	Position int
+}
+
+type nodeJSON struct {
+	Kind  string    `json:"kind"`
+	Text  string    `json:"text"`
+	Start ast.Point `json:"start"`
+	End   ast.Point `json:"end"`
+}
+
+func toNodeJSON(n *ast.Node) *nodeJSON {
+	if n == nil {
+		return nil
+	}
+	return &nodeJSON{Kind: n.Kind, Text: n.Text, Start: n.StartPosition, End: n.EndPosition}
+}
+
+func (a *Action) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Type     ActionType `json:"action"`
+		Old      *nodeJSON  `json:"old,omitempty"`
+		New      *nodeJSON  `json:"new,omitempty"`
+		Parent   *nodeJSON  `json:"parent,omitempty"`
+		Position *int       `json:"position,omitempty"`
+	}{Type: a.Type, Old: toNodeJSON(a.Old), New: toNodeJSON(a.New)}
+	if a.Type == Insert || a.Type == Move {
+		out.Parent = toNodeJSON(a.Parent)
+		out.Position = &a.Position
+	}
+	return json.Marshal(out)
+}
+
+// EditScript derives the actions turning the old tree into the new one from the mapping, following
+// Chawathe et al.: the new tree is walked breadth first to insert, update and move its nodes,
+// the children of matched nodes are aligned, then the unmatched nodes of the old tree are deleted
+func (m *Mapping) EditScript() []*Action {
+	var actions []*Action
+	moved := make(map[*ast.Node]bool)
+	queue := []*ast.Node{m.dst.root}
+	for len(queue) > 0 {
+		x := queue[0]
+		queue = queue[1:]
+		queue = append(queue, x.Descendants...)
+		w := m.dstToSrc[x]
+		if w == nil {
+			if x.Parent == nil || m.dstToSrc[x.Parent] != nil {
+				actions = append(actions, &Action{Type: Insert, New: x, Parent: x.Parent, Position: position(x)})
+			}
+			continue
+		}
+		if len(x.Descendants) == 0 && len(w.Descendants) == 0 && w.Text != x.Text {
+			actions = append(actions, &Action{Type: Update, Old: w, New: x})
+		}
+		if x.Parent != nil && m.srcToDst[w.Parent] != x.Parent {
+			actions = append(actions, &Action{Type: Move, Old: w, New: x, Parent: x.Parent, Position: position(x)})
+			moved[w] = true
+		}
+	}
+
+	// Children kept under the same parent but reordered
+	for _, w := range m.src.preorder {
+		x := m.srcToDst[w]
+		if x == nil || len(w.Descendants) == 0 {
+			continue
+		}
+		var s1, s2 []*ast.Node
+		for _, c := range w.Descendants {
+			if p := m.srcToDst[c]; p != nil && p.Parent == x {
+				s1 = append(s1, c)
+			}
+		}
+		for _, c := range x.Descendants {
+			if p := m.dstToSrc[c]; p != nil && p.Parent == w {
+				s2 = append(s2, c)
+			}
+		}
+		aligned := make(map[*ast.Node]bool)
+		for _, pair := range lcs(s1, s2, func(a, b *ast.Node) bool { return m.srcToDst[a] == b }) {
+			aligned[pair[0]] = true
+		}
+		for _, c := range s1 {
+			if !aligned[c] && !moved[c] {
+				p := m.srcToDst[c]
+				actions = append(actions, &Action{Type: Move, Old: c, New: p, Parent: x, Position: position(p)})
+				moved[c] = true
+			}
+		}
+	}
+
+	for _, w := range m.src.preorder {
+		if m.srcToDst[w] == nil && (w.Parent == nil || m.srcToDst[w.Parent] != nil) {
+			actions = append(actions, &Action{Type: Delete, Old: w})
+		}
+	}
+	return actions
+}
+
+func position(n *ast.Node) int {
+	if n.Parent == nil {
+		return 0
+	}
+	for i, sibling := range n.Parent.Descendants {
+		if sibling == n {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package astdiff
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// tree indexes the nodes of an AST for the matching
+type tree struct {
+	root *ast.Node
+	// preorder and postorder list the nodes in both traversal orders
+	preorder  []*ast.Node
+	postorder []*ast.Node
+	// index is the preorder index: the descendants of n have indexes in ]index[n], index[n]+size[n][
+	index  map[*ast.Node]int
+	size   map[*ast.Node]int
+	height map[*ast.Node]int
+	// hash identifies isomorphic subtrees: same kinds and same leaf labels
+	hash map[*ast.Node]uint64
+}
+
+func newTree(root *ast.Node) *tree {
+	t := &tree{
+		root:   root,
+		index:  make(map[*ast.Node]int),
+		size:   make(map[*ast.Node]int),
+		height: make(map[*ast.Node]int),
+		hash:   make(map[*ast.Node]uint64),
+	}
+	t.visit(root)
+	return t
+}
+
+func (t *tree) visit(n *ast.Node) {
+	t.index[n] = len(t.preorder)
+	t.preorder = append(t.preorder, n)
+	h := fnv.New64a()
+	h.Write([]byte(n.Kind))
+	h.Write([]byte{0})
+	h.Write([]byte(label(n)))
+	size, height := 1, 1
+	buf := make([]byte, 8)
+	for _, child := range n.Descendants {
+		t.visit(child)
+		size += t.size[child]
+		height = max(height, t.height[child]+1)
+		binary.LittleEndian.PutUint64(buf, t.hash[child])
+		h.Write(buf)
+	}
+	t.size[n] = size
+	t.height[n] = height
+	t.hash[n] = h.Sum64()
+	t.postorder = append(t.postorder, n)
+}
+
+// label is the value of a node compared by the update actions: the text of the leaves
+func label(n *ast.Node) string {
+	if len(n.Descendants) == 0 {
+		return n.Text
+	}
+	return ""
+}
+
+// isDescendant reports whether d is a strict descendant of a
+func (t *tree) isDescendant(d, a *ast.Node) bool {
+	i, j := t.index[d], t.index[a]
+	return i > j && i < j+t.size[a]
+}
+
+// descendants returns the strict descendants of a node in preorder
+func (t *tree) descendants(n *ast.Node) []*ast.Node {
+	i := t.index[n]
+	return t.preorder[i+1 : i+t.size[n]]
+}
+
+// heightQueue pops the nodes of a tree by decreasing height
+type heightQueue struct {
+	t       *tree
+	buckets map[int][]*ast.Node
+	max     int
+}
+
+func newHeightQueue(t *tree) *heightQueue {
+	q := &heightQueue{t: t, buckets: make(map[int][]*ast.Node)}
+	q.push(t.root)
+	return q
+}
+
+func (q *heightQueue) push(n *ast.Node) {
+	h := q.t.height[n]
+	q.buckets[h] = append(q.buckets[h], n)
+	q.max = max(q.max, h)
+}
+
+// peek returns the greatest height of the queue, 0 if it is empty
+func (q *heightQueue) peek() int {
+	for q.max > 0 && len(q.buckets[q.max]) == 0 {
+		q.max--
+	}
+	return q.max
+}
+
+// pop removes the nodes of the greatest height
+func (q *heightQueue) pop() []*ast.Node {
+	h := q.peek()
+	nodes := q.buckets[h]
+	delete(q.buckets, h)
+	return nodes
+}
+
+// open pushes the children of a node
+func (q *heightQueue) open(n *ast.Node) {
+	for _, child := range n.Descendants {
+		q.push(child)
+	}
+}
@@ -0,0 +1,308 @@
+package cfg
+
+import (
+	"strconv"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// loopFrame collects the nodes leaving a loop or a switch through break and continue
+type loopFrame struct {
+	breaks    []*Node
+	continues []*Node
+}
+
+type builder struct {
+	g       *Graph
+	loops   []*loopFrame
+	returns []*Node
+}
+
+// Build builds the control flow graph of a function-like node or of a program node
+func Build(fn *ast.Node) *Graph {
+	g := &Graph{
+		Function: fn,
+		Name:     FunctionName(fn),
+		nodeOf:   make(map[*ast.Node]*Node),
+	}
+	g.Entry = g.newNode(Entry, nil)
+	b := &builder{g: g}
+	preds := []*Node{g.Entry}
+	if fn.Kind == "program" {
+		preds = b.sequence(fn.NamedChildren(), preds)
+	} else {
+		// Parameters and captured variables are defined when entering the function
+		for _, kind := range []string{"formal_parameters", "anonymous_function_use_clause"} {
+			if header := fn.ChildOfKind(kind); header != nil {
+				preds = b.node(Statement, header, preds)
+			}
+		}
+		children := fn.NamedChildren()
+		if body := children[len(children)-1]; body.Kind == "compound_statement" {
+			preds = b.stmt(body, preds)
+		} else if fn.Kind == "arrow_function" {
+			preds = b.node(Statement, body, preds)
+		}
+	}
+	g.Exit = g.newNode(Exit, nil)
+	connectAll(preds, g.Exit)
+	connectAll(b.returns, g.Exit)
+	return g
+}
+
+// node creates a node reached from preds and returns it as the only open end
+func (b *builder) node(kind NodeKind, n *ast.Node, preds []*Node) []*Node {
+	node := b.g.newNode(kind, n)
+	connectAll(preds, node)
+	return []*Node{node}
+}
+
+// first returns the node created first since start: since nodes are created in execution order,
+// it is the node through which control enters a statement, or nil if the statement created none
+func (b *builder) first(start int) *Node {
+	if len(b.g.Nodes) > start {
+		return b.g.Nodes[start]
+	}
+	return nil
+}
+
+func (b *builder) sequence(nodes []*ast.Node, preds []*Node) []*Node {
+	for _, n := range nodes {
+		preds = b.stmt(n, preds)
+	}
+	return preds
+}
+
+// stmt adds the nodes of a statement reached from preds and returns the nodes control leaves it from
+func (b *builder) stmt(n *ast.Node, preds []*Node) []*Node {
+	switch n.Kind {
+	case "compound_statement", "colon_block":
+		return b.sequence(n.NamedChildren(), preds)
+	case "function_definition", "class_declaration", "interface_declaration", "trait_declaration",
+		"enum_declaration", "comment", "php_tag":
+		// Declarations are not executed in place, functions get their own graph
+		return preds
+	case "namespace_definition":
+		if body := n.ChildOfKind("compound_statement"); body != nil {
+			return b.stmt(body, preds)
+		}
+		return b.node(Statement, n, preds)
+	case "if_statement":
+		return b.ifStatement(n, preds)
+	case "while_statement":
+		return b.whileStatement(n, preds)
+	case "do_statement":
+		return b.doStatement(n, preds)
+	case "for_statement":
+		return b.forStatement(n, preds)
+	case "foreach_statement":
+		return b.foreachStatement(n, preds)
+	case "switch_statement":
+		return b.switchStatement(n, preds)
+	case "try_statement":
+		return b.tryStatement(n, preds)
+	case "return_statement", "exit_statement":
+		b.returns = append(b.returns, b.node(Statement, n, preds)...)
+		return nil
+	case "expression_statement":
+		node := b.node(Statement, n, preds)
+		if scope.IsTerminating(n) {
+			b.returns = append(b.returns, node...)
+			return nil
+		}
+		return node
+	case "break_statement", "continue_statement":
+		node := b.node(Statement, n, preds)
+		level := 1
+		if integer := n.ChildOfKind("integer"); integer != nil {
+			if l, err := strconv.Atoi(integer.Text); err == nil && l > 0 {
+				level = l
+			}
+		}
+		if level <= len(b.loops) {
+			frame := b.loops[len(b.loops)-level]
+			if n.Kind == "break_statement" {
+				frame.breaks = append(frame.breaks, node...)
+			} else {
+				frame.continues = append(frame.continues, node...)
+			}
+		}
+		return nil
+	default:
+		return b.node(Statement, n, preds)
+	}
+}
+
+func (b *builder) pushLoop() *loopFrame {
+	frame := &loopFrame{}
+	b.loops = append(b.loops, frame)
+	return frame
+}
+
+func (b *builder) popLoop() {
+	b.loops = b.loops[:len(b.loops)-1]
+}
+
+func (b *builder) ifStatement(n *ast.Node, preds []*Node) []*Node {
+	var outs []*Node
+	falseBranch := preds
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "parenthesized_expression":
+			falseBranch = b.node(Condition, child, falseBranch)
+		case "else_if_clause":
+			for _, part := range child.NamedChildren() {
+				if part.Kind == "parenthesized_expression" {
+					falseBranch = b.node(Condition, part, falseBranch)
+				} else {
+					outs = append(outs, b.stmt(part, falseBranch)...)
+				}
+			}
+		case "else_clause":
+			for _, part := range child.NamedChildren() {
+				outs = append(outs, b.stmt(part, falseBranch)...)
+			}
+			falseBranch = nil
+		default:
+			outs = append(outs, b.stmt(child, falseBranch)...)
+		}
+	}
+	return append(outs, falseBranch...)
+}
+
+func (b *builder) whileStatement(n *ast.Node, preds []*Node) []*Node {
+	condition := b.node(Condition, n.ChildOfKind("parenthesized_expression"), preds)
+	children := n.NamedChildren()
+	frame := b.pushLoop()
+	outs := b.stmt(children[len(children)-1], condition)
+	b.popLoop()
+	connectAll(outs, condition[0])
+	connectAll(frame.continues, condition[0])
+	return append(condition, frame.breaks...)
+}
+
+func (b *builder) doStatement(n *ast.Node, preds []*Node) []*Node {
+	start := len(b.g.Nodes)
+	frame := b.pushLoop()
+	outs := b.stmt(n.NamedChildren()[0], preds)
+	b.popLoop()
+	head := b.first(start)
+	condition := b.node(Condition, n.ChildOfKind("parenthesized_expression"), append(outs, frame.continues...))
+	if head == nil {
+		head = condition[0]
+	}
+	connect(condition[0], head)
+	return append(condition, frame.breaks...)
+}
+
+func (b *builder) forStatement(n *ast.Node, preds []*Node) []*Node {
+	clauses, body := n.ForClauses()
+	for _, init := range clauses[0] {
+		preds = b.node(Statement, init, preds)
+	}
+	var conditionNode *ast.Node
+	if len(clauses[1]) > 0 {
+		conditionNode = clauses[1][len(clauses[1])-1]
+	}
+	condition := b.node(Condition, conditionNode, preds)
+	frame := b.pushLoop()
+	outs := condition
+	if body != nil {
+		outs = b.stmt(body, condition)
+	}
+	b.popLoop()
+	outs = append(outs, frame.continues...)
+	for _, update := range clauses[2] {
+		outs = b.node(Statement, update, outs)
+	}
+	connectAll(outs, condition[0])
+	if conditionNode == nil {
+		// for (;;) only ends through break
+		return frame.breaks
+	}
+	return append(condition, frame.breaks...)
+}
+
+func (b *builder) foreachStatement(n *ast.Node, preds []*Node) []*Node {
+	subject, binding, body := n.ForeachParts()
+	if subject != nil {
+		preds = b.node(Statement, subject, preds)
+	}
+	head := b.node(Foreach, binding, preds)
+	frame := b.pushLoop()
+	outs := head
+	if body != nil {
+		outs = b.stmt(body, head)
+	}
+	b.popLoop()
+	connectAll(outs, head[0])
+	connectAll(frame.continues, head[0])
+	return append(head, frame.breaks...)
+}
+
+func (b *builder) switchStatement(n *ast.Node, preds []*Node) []*Node {
+	condition := b.node(Condition, n.ChildOfKind("parenthesized_expression"), preds)
+	block := n.ChildOfKind("switch_block")
+	if block == nil {
+		return condition
+	}
+	frame := b.pushLoop()
+	var fallthroughs []*Node
+	hasDefault := false
+	for _, c := range block.NamedChildren() {
+		statements := c.NamedChildren()
+		var caseNode *ast.Node
+		switch c.Kind {
+		case "case_statement":
+			if len(statements) == 0 {
+				continue
+			}
+			caseNode = statements[0]
+			statements = statements[1:]
+		case "default_statement":
+			// The label is the default keyword, so that the case node neither defines nor uses
+			// the variables of the statements following it
+			hasDefault = true
+			caseNode = c.ChildOfKind("default")
+		default:
+			continue
+		}
+		entry := b.node(Case, caseNode, append(condition, fallthroughs...))
+		fallthroughs = b.sequence(statements, entry)
+	}
+	b.popLoop()
+	outs := append(append(fallthroughs, frame.breaks...), frame.continues...)
+	if !hasDefault {
+		outs = append(outs, condition...)
+	}
+	return outs
+}
+
+func (b *builder) tryStatement(n *ast.Node, preds []*Node) []*Node {
+	var outs, throwers []*Node
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "compound_statement":
+			start := len(b.g.Nodes)
+			outs = b.stmt(child, preds)
+			// An exception may be thrown before or by any statement of the body
+			throwers = append(append(throwers, preds...), b.g.Nodes[start:]...)
+		case "catch_clause":
+			caught := child
+			if variable := child.ChildOfKind("variable_name"); variable != nil {
+				caught = variable
+			}
+			entry := b.node(Catch, caught, throwers)
+			if body := child.ChildOfKind("compound_statement"); body != nil {
+				entry = b.stmt(body, entry)
+			}
+			outs = append(outs, entry...)
+		case "finally_clause":
+			if body := child.ChildOfKind("compound_statement"); body != nil {
+				outs = b.stmt(body, outs)
+			}
+		}
+	}
+	return outs
+}
@@ -0,0 +1,183 @@
+package cfg
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type NodeKind string
+
+const (
+	Entry     NodeKind = "entry"
+	Exit      NodeKind = "exit"
+	Statement NodeKind = "statement"
+	Condition NodeKind = "condition"
+	Foreach   NodeKind = "foreach"
+	Case      NodeKind = "case"
+	Catch     NodeKind = "catch"
+)
+
+// Node is a node of the control flow graph. AST is the statement or expression evaluated by the node:
+// the condition of a branch, the binding of a foreach, the parameters of a function, etc.
+type Node struct {
+	ID    int
+	Kind  NodeKind
+	AST   *ast.Node
+	Succs []*Node
+	Preds []*Node
+}
+
+// Line returns the 1-based line of the node, or 0 for the entry and exit nodes
+func (n *Node) Line() uint {
+	if n.AST == nil {
+		return 0
+	}
+	return n.AST.StartPosition.Row + 1
+}
+
+func (n *Node) String() string {
+	if n.AST == nil {
+		return string(n.Kind)
+	}
+	text := strings.Join(strings.Fields(n.AST.Text), " ")
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	return fmt.Sprintf("%s %s", n.Kind, text)
+}
+
+// Graph is the statement level control flow graph of a function-like node or of the main program.
+// Short-circuit evaluation inside expressions is not represented.
+type Graph struct {
+	Function *ast.Node
+	Name     string
+	Entry    *Node
+	Exit     *Node
+	Nodes    []*Node
+	nodeOf   map[*ast.Node]*Node
+}
+
+func (g *Graph) newNode(kind NodeKind, n *ast.Node) *Node {
+	node := &Node{ID: len(g.Nodes), Kind: kind, AST: n}
+	g.Nodes = append(g.Nodes, node)
+	if n != nil {
+		g.nodeOf[n] = node
+	}
+	return node
+}
+
+func connect(from *Node, to *Node) {
+	if slices.Contains(from.Succs, to) {
+		return
+	}
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func connectAll(from []*Node, to *Node) {
+	for _, n := range from {
+		connect(n, to)
+	}
+}
+
+// NodeOf returns the CFG node evaluating the given AST node, or the one of its closest ancestor.
+// Parent links must be set.
+func (g *Graph) NodeOf(n *ast.Node) *Node {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if node, ok := g.nodeOf[cur]; ok {
+			return node
+		}
+		if cur == g.Function {
+			break
+		}
+	}
+	return nil
+}
+
+// Reachable returns the nodes reachable from the entry node
+func (g *Graph) Reachable() map[*Node]bool {
+	reachable := map[*Node]bool{g.Entry: true}
+	stack := []*Node{g.Entry}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, succ := range n.Succs {
+			if !reachable[succ] {
+				reachable[succ] = true
+				stack = append(stack, succ)
+			}
+		}
+	}
+	return reachable
+}
+
+// DOT exports the graph in the Graphviz format
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", g.Name)
+	for _, n := range g.Nodes {
+		label := n.String()
+		if line := n.Line(); line != 0 {
+			label = fmt.Sprintf("%d: %s", line, label)
+		}
+		fmt.Fprintf(&sb, "  n%d [label=%q];\n", n.ID, label)
+	}
+	for _, n := range g.Nodes {
+		for _, succ := range n.Succs {
+			fmt.Fprintf(&sb, "  n%d -> n%d;\n", n.ID, succ.ID)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// BuildAll builds the graph of the main program and of every function-like node of the tree, the
+// nodes opening a local scope
+func BuildAll(root *ast.Node) []*Graph {
+	root.SetParents()
+	graphs := []*Graph{Build(root)}
+	v := &functionVisitor{}
+	root.WalkPrefix(v)
+	for _, fn := range v.functions {
+		graphs = append(graphs, Build(fn))
+	}
+	return graphs
+}
+
+type functionVisitor struct {
+	functions []*ast.Node
+}
+
+func (v *functionVisitor) VisitNode(n *ast.Node) {
+	if slices.Contains(scope.FunctionKinds, n.Kind) {
+		v.functions = append(v.functions, n)
+	}
+}
+
+// FunctionName returns a readable name for a function-like node
+func FunctionName(n *ast.Node) string {
+	switch n.Kind {
+	case "program":
+		return "{main}"
+	case "anonymous_function":
+		return fmt.Sprintf("{closure}@%d", n.StartPosition.Row+1)
+	case "arrow_function":
+		return fmt.Sprintf("{fn}@%d", n.StartPosition.Row+1)
+	}
+	name := ""
+	if nameNode := n.ChildOfKind("name"); nameNode != nil {
+		name = nameNode.Text
+	}
+	if n.Kind == "method_declaration" {
+		for cur := n.Parent; cur != nil; cur = cur.Parent {
+			if className := cur.ChildOfKind("name"); className != nil && strings.HasSuffix(cur.Kind, "_declaration") {
+				return className.Text + "::" + name
+			}
+		}
+	}
+	return name
+}
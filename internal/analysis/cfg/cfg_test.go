@@ -0,0 +1,134 @@
+package cfg
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// nodeAt returns the first node of a graph evaluating a statement or expression on a line
+func nodeAt(t *testing.T, g *Graph, line uint) *Node {
+	t.Helper()
+	for _, n := range g.Nodes {
+		if n.AST != nil && n.Line() == line {
+			return n
+		}
+	}
+	t.Fatalf("no node on line %d", line)
+	return nil
+}
+
+// lines returns the sorted lines of nodes, 0 standing for the entry and exit nodes
+func lines(nodes []*Node) []uint {
+	result := []uint{}
+	for _, n := range nodes {
+		result = append(result, n.Line())
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestSuccessors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   uint
+		succs  []uint
+	}{
+		{
+			name:   "sequence",
+			source: "<?php\n$a = 1;\n$b = 2;",
+			line:   2, succs: []uint{3},
+		},
+		{
+			name:   "last statement reaches the exit",
+			source: "<?php\n$a = 1;\n$b = 2;",
+			line:   3, succs: []uint{0},
+		},
+		{
+			name:   "if without else",
+			source: "<?php\nif ($c) {\n$a = 1;\n}\n$b = 2;",
+			line:   2, succs: []uint{3, 5},
+		},
+		{
+			name:   "if with else",
+			source: "<?php\nif ($c) {\n$a = 1;\n} else {\n$a = 2;\n}",
+			line:   2, succs: []uint{3, 5},
+		},
+		{
+			name:   "while condition enters the body or leaves the loop",
+			source: "<?php\nwhile ($c) {\n$a = 1;\n}\n$b = 2;",
+			line:   2, succs: []uint{3, 5},
+		},
+		{
+			name:   "loop body goes back to the condition",
+			source: "<?php\nwhile ($c) {\n$a = 1;\n}\n$b = 2;",
+			line:   3, succs: []uint{2},
+		},
+		{
+			name:   "break leaves the loop",
+			source: "<?php\nwhile ($c) {\nbreak;\n}\n$b = 2;",
+			line:   3, succs: []uint{5},
+		},
+		{
+			name:   "return reaches the exit",
+			source: "<?php\nfunction f() {\nreturn 1;\n$a = 2;\n}",
+			line:   3, succs: []uint{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graphs := BuildAll(ast.ParseSource([]byte(tt.source)))
+			g := graphs[len(graphs)-1]
+			if got := lines(nodeAt(t, g, tt.line).Succs); !slices.Equal(got, tt.succs) {
+				t.Errorf("successors of line %d = %v, want %v", tt.line, got, tt.succs)
+			}
+		})
+	}
+}
+
+func TestReachable(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		unreachable []uint
+	}{
+		{
+			name:        "straight line",
+			source:      "<?php\n$a = 1;\necho $a;",
+			unreachable: []uint{},
+		},
+		{
+			name:        "after return",
+			source:      "<?php\nfunction f() {\nreturn 1;\necho 2;\n}",
+			unreachable: []uint{4},
+		},
+		{
+			name:        "after both branches throw",
+			source:      "<?php\nfunction f($c) {\nif ($c) {\nthrow new E();\n} else {\nreturn;\n}\necho 2;\n}",
+			unreachable: []uint{8},
+		},
+		{
+			name:        "after break in a loop body",
+			source:      "<?php\nwhile ($c) {\nbreak;\n$a = 1;\n}\necho 2;",
+			unreachable: []uint{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graphs := BuildAll(ast.ParseSource([]byte(tt.source)))
+			g := graphs[len(graphs)-1]
+			reachable := g.Reachable()
+			var dead []*Node
+			for _, n := range g.Nodes {
+				if !reachable[n] && n.AST != nil {
+					dead = append(dead, n)
+				}
+			}
+			if got := lines(dead); !slices.Equal(got, tt.unreachable) {
+				t.Errorf("unreachable lines = %v, want %v", got, tt.unreachable)
+			}
+		})
+	}
+}
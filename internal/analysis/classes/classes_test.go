@@ -0,0 +1,126 @@
+package classes
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+const traitsSource = `<?php
+namespace App;
+
+trait Hello { public function hello() {} public function bye() {} }
+trait World { public function hello() {} }
+class Base { public function base() {} private function secret() {} }
+class Greeter extends Base {
+	use Hello, World {
+		Hello::hello insteadof World;
+		World::hello as protected worldHello;
+		bye as private;
+	}
+}
+`
+
+// hierarchy builds the resolved hierarchy of a source and returns it with its tree
+func hierarchy(source string) (*Hierarchy, *ast.Node) {
+	root := ast.ParseSource([]byte(source))
+	root.SetParents()
+	h := New()
+	h.AddFile("test.php", root)
+	h.Resolve()
+	return h, root
+}
+
+func TestEffectiveMethods(t *testing.T) {
+	h, _ := hierarchy(traitsSource)
+	greeter := h.Lookup("App\\Greeter")
+	if greeter == nil {
+		t.Fatal("App\\Greeter not found")
+	}
+	if greeter.Parent == nil || greeter.Parent.Name != "App\\Base" {
+		t.Errorf("parent = %v, want App\\Base", greeter.Parent)
+	}
+	tests := []struct {
+		method     string
+		from       string
+		origin     string
+		visibility string
+	}{
+		{method: "hello", from: "App\\Hello", origin: "trait", visibility: "public"},
+		{method: "worldHello", from: "App\\World", origin: "trait", visibility: "protected"},
+		{method: "bye", from: "App\\Hello", origin: "trait", visibility: "private"},
+		{method: "base", from: "App\\Base", origin: "inherited", visibility: "public"},
+		{method: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			m := greeter.Method(tt.method)
+			if tt.from == "" {
+				if m != nil {
+					t.Errorf("%s is visible from %s, want hidden", tt.method, m.From.Name)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatalf("%s not found", tt.method)
+			}
+			if m.From.Name != tt.from || m.Origin != tt.origin || m.Visibility != tt.visibility {
+				t.Errorf("%s = %s %s from %s, want %s %s from %s", tt.method,
+					m.Visibility, m.Origin, m.From.Name, tt.visibility, tt.origin, tt.from)
+			}
+		})
+	}
+}
+
+const callsSource = `<?php
+class A {
+	public function run() {
+		$this->step();
+		self::make();
+		static::make();
+		A::make();
+	}
+	public function step() {}
+	public static function make() {}
+}
+class B extends A {
+	public function step() { parent::step(); }
+	public static function make() {}
+}
+class C extends B {}
+`
+
+func TestResolveCall(t *testing.T) {
+	h, root := hierarchy(callsSource)
+	calls := h.ResolveCalls(root)
+	tests := []struct {
+		call    string
+		methods []string
+	}{
+		{call: "$this->step()", methods: []string{"A::step", "B::step"}},
+		{call: "self::make()", methods: []string{"A::make"}},
+		{call: "static::make()", methods: []string{"A::make", "B::make"}},
+		{call: "A::make()", methods: []string{"A::make"}},
+		{call: "parent::step()", methods: []string{"A::step"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			for call, methods := range calls {
+				if call.Text != tt.call {
+					continue
+				}
+				var got []string
+				for _, m := range methods {
+					got = append(got, m.Class.Name+"::"+m.Name)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, tt.methods) {
+					t.Errorf("%s resolves to %v, want %v", tt.call, got, tt.methods)
+				}
+				return
+			}
+			t.Fatalf("call %s not found", tt.call)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package classes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DOT exports the hierarchy in the Graphviz format: solid edges for extends, dashed edges for
+// implements and dotted edges for trait uses
+func (h *Hierarchy) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph hierarchy {\n  rankdir=BT;\n")
+	for _, c := range h.SortedClasses() {
+		shape := "box"
+		switch c.Kind {
+		case InterfaceKind:
+			shape = "ellipse"
+		case TraitKind:
+			shape = "hexagon"
+		case EnumKind:
+			shape = "octagon"
+		}
+		fmt.Fprintf(&sb, "  %q [shape=%s];\n", c.Name, shape)
+	}
+	for _, c := range h.SortedClasses() {
+		if c.Parent != nil {
+			fmt.Fprintf(&sb, "  %q -> %q;\n", c.Name, c.Parent.Name)
+		}
+		for _, i := range c.Interfaces {
+			style := "dashed"
+			if c.Kind == InterfaceKind {
+				style = "solid"
+			}
+			fmt.Fprintf(&sb, "  %q -> %q [style=%s];\n", c.Name, i.Name, style)
+		}
+		for _, t := range c.Traits {
+			fmt.Fprintf(&sb, "  %q -> %q [style=dotted];\n", c.Name, t.Name)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+type memberJSON struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+	Origin     string `json:"origin"`
+	From       string `json:"from"`
+	Line       uint   `json:"line"`
+	Static     bool   `json:"static,omitempty"`
+	Abstract   bool   `json:"abstract,omitempty"`
+}
+
+type classJSON struct {
+	Name       string       `json:"name"`
+	Kind       Kind         `json:"kind"`
+	File       string       `json:"file"`
+	Line       uint         `json:"line"`
+	Abstract   bool         `json:"abstract,omitempty"`
+	Final      bool         `json:"final,omitempty"`
+	Parent     string       `json:"parent,omitempty"`
+	Extends    []string     `json:"extends,omitempty"`
+	Implements []string     `json:"implements,omitempty"`
+	Traits     []string     `json:"traits,omitempty"`
+	Methods    []memberJSON `json:"methods"`
+	Properties []memberJSON `json:"properties"`
+}
+
+// MarshalJSON exports the class with its effective members
+func (c *Class) MarshalJSON() ([]byte, error) {
+	out := classJSON{
+		Name:       c.Name,
+		Kind:       c.Kind,
+		File:       c.File,
+		Line:       c.Line(),
+		Abstract:   c.Abstract,
+		Final:      c.Final,
+		Extends:    c.Extends,
+		Implements: c.Implements,
+		Methods:    []memberJSON{},
+		Properties: []memberJSON{},
+	}
+	if c.Parent != nil {
+		out.Parent = c.Parent.Name
+	}
+	for _, t := range c.Traits {
+		out.Traits = append(out.Traits, t.Name)
+	}
+	for _, m := range c.SortedMethods() {
+		out.Methods = append(out.Methods, memberJSON{
+			Name:       m.Name,
+			Visibility: m.Visibility,
+			Origin:     m.Origin,
+			From:       m.Declaration.Class.Name,
+			Line:       m.Declaration.Node.StartPosition.Row + 1,
+			Static:     m.Declaration.Static,
+			Abstract:   m.Declaration.Abstract,
+		})
+	}
+	for _, p := range c.SortedProperties() {
+		out.Properties = append(out.Properties, memberJSON{
+			Name:       "$" + p.Name,
+			Visibility: p.Visibility,
+			Origin:     p.Origin,
+			From:       p.Declaration.Class.Name,
+			Line:       p.Declaration.Node.StartPosition.Row + 1,
+			Static:     p.Declaration.Static,
+		})
+	}
+	return json.Marshal(out)
+}
+
+func (h *Hierarchy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.SortedClasses())
+}
@@ -0,0 +1,415 @@
+package classes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the class of a class-like declaration is stored
+const AttributeKey = "class"
+
+type Kind string
+
+const (
+	ClassKind     Kind = "class"
+	InterfaceKind Kind = "interface"
+	TraitKind     Kind = "trait"
+	EnumKind      Kind = "enum"
+)
+
+var declarationKinds = map[string]Kind{
+	"class_declaration":     ClassKind,
+	"interface_declaration": InterfaceKind,
+	"trait_declaration":     TraitKind,
+	"enum_declaration":      EnumKind,
+}
+
+// Method is a method declared in a class-like declaration
+type Method struct {
+	Name       string
+	Class      *Class
+	Node       *ast.Node
+	Visibility string
+	Static     bool
+	Abstract   bool
+	Final      bool
+}
+
+// Property is a property declared in a class-like declaration, including promoted constructor parameters
+type Property struct {
+	Name       string
+	Class      *Class
+	Node       *ast.Node
+	Visibility string
+	Static     bool
+	Readonly   bool
+}
+
+// Member is a method or property as seen from a class: it may be declared by the class itself,
+// imported from a trait (possibly renamed or with another visibility) or inherited
+type Member[M any] struct {
+	Name        string
+	Declaration M
+	Visibility  string
+	// Origin describes where the member comes from: declared, trait or inherited
+	Origin string
+	// From is the class the member was inherited or imported from
+	From *Class
+}
+
+// TraitUse is a use declaration of traits in a class body, with its conflict resolution rules
+type TraitUse struct {
+	Traits []string
+	// InsteadOf maps a method name to the trait chosen for it
+	InsteadOf map[string]string
+	Aliases   []TraitAlias
+}
+
+// TraitAlias is an `as` clause: it renames a trait method and/or changes its visibility
+type TraitAlias struct {
+	Trait      string
+	Method     string
+	Alias      string
+	Visibility string
+}
+
+// Class is a class, interface, trait or enum of the project
+type Class struct {
+	Name       string
+	Kind       Kind
+	Node       *ast.Node
+	File       string
+	Abstract   bool
+	Final      bool
+	Extends    []string
+	Implements []string
+	TraitUses  []*TraitUse
+	Methods    map[string]*Method
+	Properties map[string]*Property
+	Constants  map[string]*ast.Node
+
+	// Resolved by Hierarchy.Resolve
+	Parent           *Class
+	Interfaces       []*Class
+	Traits           []*Class
+	Children         []*Class
+	EffectiveMethods map[string]*Member[*Method]
+	EffectiveProps   map[string]*Member[*Property]
+	resolving        bool
+}
+
+// ShortName returns the name of the class without its namespace
+func (c *Class) ShortName() string {
+	return c.Name[strings.LastIndex(c.Name, "\\")+1:]
+}
+
+// Line returns the 1-based line of the declaration
+func (c *Class) Line() uint {
+	return c.Node.StartPosition.Row + 1
+}
+
+// Method returns the effective method of the class with the given name, case-insensitively
+func (c *Class) Method(name string) *Member[*Method] {
+	return c.EffectiveMethods[strings.ToLower(name)]
+}
+
+// SortedMethods returns the effective methods of the class ordered by name
+func (c *Class) SortedMethods() []*Member[*Method] {
+	var members []*Member[*Method]
+	for _, m := range c.EffectiveMethods {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// SortedProperties returns the effective properties of the class ordered by name
+func (c *Class) SortedProperties() []*Member[*Property] {
+	var members []*Member[*Property]
+	for _, p := range c.EffectiveProps {
+		members = append(members, p)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// Descendants returns all the classes extending or implementing this one, transitively
+func (c *Class) Descendants() []*Class {
+	var result []*Class
+	seen := map[*Class]bool{c: true}
+	stack := append([]*Class{}, c.Children...)
+	for len(stack) > 0 {
+		child := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		result = append(result, child)
+		stack = append(stack, child.Children...)
+	}
+	return result
+}
+
+// IsSubclassOf reports whether the class extends or implements the other one, transitively
+func (c *Class) IsSubclassOf(other *Class) bool {
+	seen := make(map[*Class]bool)
+	var visit func(*Class) bool
+	visit = func(cur *Class) bool {
+		if cur == nil || seen[cur] {
+			return false
+		}
+		seen[cur] = true
+		if cur == other {
+			return true
+		}
+		if visit(cur.Parent) {
+			return true
+		}
+		for _, i := range cur.Interfaces {
+			if visit(i) {
+				return true
+			}
+		}
+		return false
+	}
+	return c != other && visit(c)
+}
+
+// Hierarchy is the class hierarchy of a project
+type Hierarchy struct {
+	// Classes maps the lowercase fully qualified names to the classes
+	Classes map[string]*Class
+	// namespaces holds the namespace context of the top level statements of each file
+	namespaces map[*ast.Node]*Namespace
+}
+
+func New() *Hierarchy {
+	return &Hierarchy{
+		Classes:    make(map[string]*Class),
+		namespaces: make(map[*ast.Node]*Namespace),
+	}
+}
+
+// Lookup returns the class with the given fully qualified name, case-insensitively
+func (h *Hierarchy) Lookup(name string) *Class {
+	return h.Classes[strings.ToLower(strings.TrimPrefix(name, "\\"))]
+}
+
+// LookupShort returns the classes whose name without namespace matches, for code that does not use namespaces consistently
+func (h *Hierarchy) LookupShort(name string) []*Class {
+	var result []*Class
+	for _, c := range h.SortedClasses() {
+		if strings.EqualFold(c.ShortName(), name) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// SortedClasses returns the classes ordered by name
+func (h *Hierarchy) SortedClasses() []*Class {
+	var classes []*Class
+	for _, c := range h.Classes {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+	return classes
+}
+
+// Namespace returns the namespace context of a node of a file added to the hierarchy
+func (h *Hierarchy) Namespace(n *ast.Node) *Namespace {
+	return NamespaceOf(n, h.namespaces)
+}
+
+// AddFile collects the class-like declarations of a file
+func (h *Hierarchy) AddFile(file string, root *ast.Node) {
+	root.SetParents()
+	for n, ns := range namespaces(root) {
+		h.namespaces[n] = ns
+	}
+	v := &declarationVisitor{}
+	root.WalkPrefix(v)
+	for _, n := range v.declarations {
+		c := h.declaration(file, n)
+		if c == nil {
+			continue
+		}
+		h.Classes[strings.ToLower(c.Name)] = c
+		n.SetAttribute(AttributeKey, c)
+	}
+}
+
+type declarationVisitor struct {
+	declarations []*ast.Node
+}
+
+func (v *declarationVisitor) VisitNode(n *ast.Node) {
+	if _, ok := declarationKinds[n.Kind]; ok {
+		v.declarations = append(v.declarations, n)
+	}
+}
+
+func (h *Hierarchy) declaration(file string, n *ast.Node) *Class {
+	name := n.ChildOfKind("name")
+	if name == nil {
+		return nil
+	}
+	ns := h.Namespace(n)
+	c := &Class{
+		Name:       ns.Resolve(name.Text),
+		Kind:       declarationKinds[n.Kind],
+		Node:       n,
+		File:       file,
+		Abstract:   n.ChildOfKind("abstract_modifier") != nil,
+		Final:      n.ChildOfKind("final_modifier") != nil,
+		Methods:    make(map[string]*Method),
+		Properties: make(map[string]*Property),
+		Constants:  make(map[string]*ast.Node),
+	}
+	if base := n.ChildOfKind("base_clause"); base != nil {
+		for _, parent := range base.NamedChildren() {
+			c.Extends = append(c.Extends, ns.Resolve(parent.Text))
+		}
+	}
+	if interfaces := n.ChildOfKind("class_interface_clause"); interfaces != nil {
+		for _, i := range interfaces.NamedChildren() {
+			c.Implements = append(c.Implements, ns.Resolve(i.Text))
+		}
+	}
+	body := n.ChildOfKind("declaration_list", "enum_declaration_list")
+	if body == nil {
+		return c
+	}
+	for _, member := range body.NamedChildren() {
+		switch member.Kind {
+		case "method_declaration":
+			m := &Method{
+				Name:       member.ChildOfKind("name").Text,
+				Class:      c,
+				Node:       member,
+				Visibility: visibility(member),
+				Static:     member.ChildOfKind("static_modifier") != nil,
+				Abstract:   member.ChildOfKind("abstract_modifier") != nil || c.Kind == InterfaceKind,
+				Final:      member.ChildOfKind("final_modifier") != nil,
+			}
+			c.Methods[strings.ToLower(m.Name)] = m
+			if strings.EqualFold(m.Name, "__construct") {
+				c.promotedProperties(member)
+			}
+		case "property_declaration":
+			for _, element := range member.ChildrenOfKind("property_element") {
+				variable := element.ChildOfKind("variable_name")
+				if variable == nil {
+					continue
+				}
+				p := &Property{
+					Name:       variable.ChildOfKind("name").Text,
+					Class:      c,
+					Node:       element,
+					Visibility: visibility(member),
+					Static:     member.ChildOfKind("static_modifier") != nil,
+					Readonly:   member.ChildOfKind("readonly_modifier") != nil || n.ChildOfKind("readonly_modifier") != nil,
+				}
+				c.Properties[p.Name] = p
+			}
+		case "const_declaration":
+			for _, element := range member.ChildrenOfKind("const_element") {
+				if constName := element.ChildOfKind("name"); constName != nil {
+					c.Constants[constName.Text] = element
+				}
+			}
+		case "enum_case":
+			if caseName := member.ChildOfKind("name"); caseName != nil {
+				c.Constants[caseName.Text] = member
+			}
+		case "use_declaration":
+			c.TraitUses = append(c.TraitUses, traitUse(member, ns))
+		}
+	}
+	return c
+}
+
+// promotedProperties adds the properties declared by the parameters of a constructor
+func (c *Class) promotedProperties(constructor *ast.Node) {
+	parameters := constructor.ChildOfKind("formal_parameters")
+	if parameters == nil {
+		return
+	}
+	for _, parameter := range parameters.ChildrenOfKind("property_promotion_parameter") {
+		variable := parameter.ChildOfKind("variable_name")
+		if variable == nil {
+			continue
+		}
+		p := &Property{
+			Name:       variable.ChildOfKind("name").Text,
+			Class:      c,
+			Node:       parameter,
+			Visibility: visibility(parameter),
+			Readonly:   parameter.ChildOfKind("readonly_modifier") != nil,
+		}
+		c.Properties[p.Name] = p
+	}
+}
+
+func visibility(n *ast.Node) string {
+	if modifier := n.ChildOfKind("visibility_modifier"); modifier != nil {
+		return strings.ToLower(strings.Fields(modifier.Text)[0])
+	}
+	return "public"
+}
+
+func traitUse(n *ast.Node, ns *Namespace) *TraitUse {
+	use := &TraitUse{InsteadOf: make(map[string]string)}
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "name", "qualified_name":
+			use.Traits = append(use.Traits, ns.Resolve(child.Text))
+		case "use_list":
+			for _, rule := range child.NamedChildren() {
+				parts := rule.NamedChildren()
+				switch rule.Kind {
+				case "use_instead_of_clause":
+					// T::m insteadof U, V
+					if len(parts) > 0 && parts[0].Kind == "class_constant_access_expression" {
+						trait, method := splitScoped(parts[0], ns)
+						use.InsteadOf[strings.ToLower(method)] = trait
+					}
+				case "use_as_clause":
+					// [T::]m as [visibility] [alias]
+					alias := TraitAlias{}
+					for i, part := range parts {
+						switch {
+						case i == 0 && part.Kind == "class_constant_access_expression":
+							alias.Trait, alias.Method = splitScoped(part, ns)
+						case i == 0:
+							alias.Method = part.Text
+						case part.Kind == "visibility_modifier":
+							alias.Visibility = strings.ToLower(part.Text)
+						default:
+							alias.Alias = part.Text
+						}
+					}
+					use.Aliases = append(use.Aliases, alias)
+				}
+			}
+		}
+	}
+	return use
+}
+
+func splitScoped(n *ast.Node, ns *Namespace) (class string, member string) {
+	parts := n.NamedChildren()
+	if len(parts) < 2 {
+		return "", n.Text
+	}
+	return ns.Resolve(parts[0].Text), parts[len(parts)-1].Text
+}
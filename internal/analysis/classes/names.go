@@ -0,0 +1,112 @@
+package classes
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Namespace is the namespace context of a part of a file: the current namespace and the
+// class aliases imported with use
+type Namespace struct {
+	Name    string
+	Imports map[string]string
+}
+
+// Resolve returns the fully qualified name of a class name written in this namespace, without leading \
+func (ns *Namespace) Resolve(name string) string {
+	name = strings.Join(strings.Fields(name), "")
+	if strings.HasPrefix(name, "\\") {
+		return name[1:]
+	}
+	switch strings.ToLower(name) {
+	case "self", "static", "parent":
+		return name
+	}
+	if strings.HasPrefix(strings.ToLower(name), "namespace\\") {
+		name = name[len("namespace\\"):]
+	} else {
+		first, rest, qualified := strings.Cut(name, "\\")
+		if imported, ok := ns.Imports[strings.ToLower(first)]; ok {
+			if qualified {
+				return imported + "\\" + rest
+			}
+			return imported
+		}
+	}
+	if ns.Name == "" {
+		return name
+	}
+	return ns.Name + "\\" + name
+}
+
+// namespaces walks the top level statements of a file and returns the namespace context of each
+// of them, following namespace definitions and use declarations
+func namespaces(root *ast.Node) map[*ast.Node]*Namespace {
+	result := make(map[*ast.Node]*Namespace)
+	current := &Namespace{Imports: make(map[string]string)}
+	var walk func(statements []*ast.Node)
+	walk = func(statements []*ast.Node) {
+		for _, statement := range statements {
+			switch statement.Kind {
+			case "namespace_definition":
+				name := ""
+				if nameNode := statement.ChildOfKind("namespace_name"); nameNode != nil {
+					name = strings.Join(strings.Fields(nameNode.Text), "")
+				}
+				current = &Namespace{Name: name, Imports: make(map[string]string)}
+				if body := statement.ChildOfKind("compound_statement"); body != nil {
+					walk(body.NamedChildren())
+					current = &Namespace{Imports: make(map[string]string)}
+				}
+			case "namespace_use_declaration":
+				addImports(current, statement)
+			}
+			result[statement] = current
+		}
+	}
+	walk(root.NamedChildren())
+	return result
+}
+
+func addImports(ns *Namespace, declaration *ast.Node) {
+	// Function and constant imports do not apply to class names
+	for _, child := range declaration.Descendants {
+		if child.Kind == "function" || child.Kind == "const" {
+			return
+		}
+	}
+	prefix := ""
+	if group := declaration.ChildOfKind("namespace_name"); group != nil {
+		// use Foo\{Bar, Baz as Qux};
+		prefix = strings.Join(strings.Fields(group.Text), "") + "\\"
+	}
+	var clauses []*ast.Node
+	clauses = append(clauses, declaration.ChildrenOfKind("namespace_use_clause")...)
+	if group := declaration.ChildOfKind("namespace_use_group"); group != nil {
+		clauses = append(clauses, group.ChildrenOfKind("namespace_use_clause")...)
+		clauses = append(clauses, group.ChildrenOfKind("namespace_use_group_clause")...)
+	}
+	for _, clause := range clauses {
+		children := clause.NamedChildren()
+		if len(children) == 0 {
+			continue
+		}
+		full := prefix + strings.TrimPrefix(strings.Join(strings.Fields(children[0].Text), ""), "\\")
+		alias := full[strings.LastIndex(full, "\\")+1:]
+		if len(children) > 1 {
+			alias = children[len(children)-1].Text
+		}
+		ns.Imports[strings.ToLower(alias)] = full
+	}
+}
+
+// NamespaceOf returns the namespace context of a node. Parent links must be set.
+func NamespaceOf(n *ast.Node, contexts map[*ast.Node]*Namespace) *Namespace {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if ns, ok := contexts[cur]; ok {
+			return ns
+		}
+	}
+	return &Namespace{Imports: make(map[string]string)}
+}
@@ -0,0 +1,263 @@
+package classes
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// CallsAttributeKey is the node attribute under which the methods a call may dispatch to are stored
+const CallsAttributeKey = "methods"
+
+// Resolve links the classes to their parents, interfaces and traits, then computes the effective
+// methods and properties of each class
+func (h *Hierarchy) Resolve() {
+	for _, c := range h.SortedClasses() {
+		switch c.Kind {
+		case InterfaceKind:
+			for _, name := range c.Extends {
+				if parent := h.Lookup(name); parent != nil {
+					c.Interfaces = append(c.Interfaces, parent)
+					parent.Children = append(parent.Children, c)
+				}
+			}
+		default:
+			if len(c.Extends) > 0 {
+				if parent := h.Lookup(c.Extends[0]); parent != nil && parent != c {
+					c.Parent = parent
+					parent.Children = append(parent.Children, c)
+				}
+			}
+		}
+		for _, name := range c.Implements {
+			if i := h.Lookup(name); i != nil {
+				c.Interfaces = append(c.Interfaces, i)
+				i.Children = append(i.Children, c)
+			}
+		}
+		for _, use := range c.TraitUses {
+			for _, name := range use.Traits {
+				if t := h.Lookup(name); t != nil {
+					c.Traits = append(c.Traits, t)
+				}
+			}
+		}
+	}
+	for _, c := range h.SortedClasses() {
+		h.effectiveMembers(c)
+	}
+}
+
+// effectiveMembers computes the members of a class: its own declarations override the methods
+// imported from traits, which override the inherited ones
+func (h *Hierarchy) effectiveMembers(c *Class) {
+	if c.EffectiveMethods != nil || c.resolving {
+		return
+	}
+	c.resolving = true
+	defer func() { c.resolving = false }()
+	methods := make(map[string]*Member[*Method])
+	properties := make(map[string]*Member[*Property])
+
+	// Inherited members, interfaces first so that implementations take precedence
+	inherited := append([]*Class{}, c.Interfaces...)
+	if c.Parent != nil {
+		inherited = append(inherited, c.Parent)
+	}
+	for _, parent := range inherited {
+		h.effectiveMembers(parent)
+		for key, m := range parent.EffectiveMethods {
+			if m.Visibility == "private" && m.Origin == "declared" {
+				continue
+			}
+			methods[key] = &Member[*Method]{Name: m.Name, Declaration: m.Declaration, Visibility: m.Visibility, Origin: "inherited", From: parent}
+		}
+		for key, p := range parent.EffectiveProps {
+			if p.Visibility == "private" && p.Origin == "declared" {
+				continue
+			}
+			properties[key] = &Member[*Property]{Name: p.Name, Declaration: p.Declaration, Visibility: p.Visibility, Origin: "inherited", From: parent}
+		}
+	}
+
+	for _, use := range c.TraitUses {
+		h.traitMembers(use, methods, properties)
+	}
+
+	for key, m := range c.Methods {
+		methods[key] = &Member[*Method]{Name: m.Name, Declaration: m, Visibility: m.Visibility, Origin: "declared", From: c}
+	}
+	for key, p := range c.Properties {
+		properties[key] = &Member[*Property]{Name: p.Name, Declaration: p, Visibility: p.Visibility, Origin: "declared", From: c}
+	}
+	c.EffectiveMethods = methods
+	c.EffectiveProps = properties
+}
+
+// traitMembers imports the members of the traits of a use declaration, applying its insteadof and as rules
+func (h *Hierarchy) traitMembers(use *TraitUse, methods map[string]*Member[*Method], properties map[string]*Member[*Property]) {
+	for _, name := range use.Traits {
+		t := h.Lookup(name)
+		if t == nil {
+			continue
+		}
+		h.effectiveMembers(t)
+		for key, m := range t.EffectiveMethods {
+			if chosen, ok := use.InsteadOf[key]; ok && !strings.EqualFold(chosen, t.Name) {
+				continue
+			}
+			methods[key] = &Member[*Method]{Name: m.Name, Declaration: m.Declaration, Visibility: m.Visibility, Origin: "trait", From: t}
+		}
+		for key, p := range t.EffectiveProps {
+			properties[key] = &Member[*Property]{Name: p.Name, Declaration: p.Declaration, Visibility: p.Visibility, Origin: "trait", From: t}
+		}
+	}
+	for _, alias := range use.Aliases {
+		var source *Member[*Method]
+		for _, name := range use.Traits {
+			if alias.Trait != "" && !strings.EqualFold(alias.Trait, name) {
+				continue
+			}
+			if t := h.Lookup(name); t != nil && t.Method(alias.Method) != nil {
+				source = t.Method(alias.Method)
+				source = &Member[*Method]{Name: source.Name, Declaration: source.Declaration, Visibility: source.Visibility, Origin: "trait", From: t}
+				break
+			}
+		}
+		if source == nil {
+			continue
+		}
+		if alias.Visibility != "" {
+			source.Visibility = alias.Visibility
+		}
+		if alias.Alias == "" {
+			// Only the visibility of the imported method changes
+			methods[strings.ToLower(alias.Method)] = source
+			continue
+		}
+		source.Name = alias.Alias
+		methods[strings.ToLower(alias.Alias)] = source
+	}
+}
+
+// EnclosingClass returns the class-like declaration containing a node. Parent links must be set.
+func (h *Hierarchy) EnclosingClass(n *ast.Node) *Class {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if c, ok := cur.GetAttribute(AttributeKey).(*Class); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// ResolveCall returns the methods a call may dispatch to, for calls on $this and for self::,
+// parent::, static:: and ClassName:: calls. Late static binding ($this, static::) includes the
+// overriding methods of the subclasses. The result is stored in the call attributes under CallsAttributeKey.
+func (h *Hierarchy) ResolveCall(call *ast.Node) []*Method {
+	name := call.MemberName()
+	if name == "" {
+		return nil
+	}
+	children := call.NamedChildren()
+	if len(children) == 0 {
+		return nil
+	}
+	receiver := children[0]
+	enclosing := h.EnclosingClass(call)
+
+	var classes []*Class
+	lateBinding := false
+	switch call.Kind {
+	case "member_call_expression", "nullsafe_member_call_expression":
+		if receiver.Kind != "variable_name" || receiver.Text != "$this" || enclosing == nil {
+			return nil
+		}
+		classes = h.receivers(enclosing)
+		lateBinding = true
+	case "scoped_call_expression":
+		switch strings.ToLower(receiver.Text) {
+		case "self":
+			classes = h.receivers(enclosing)
+		case "static":
+			classes = h.receivers(enclosing)
+			lateBinding = true
+		case "parent":
+			if enclosing != nil && enclosing.Parent != nil {
+				classes = []*Class{enclosing.Parent}
+			}
+		default:
+			if receiver.Kind == "name" || receiver.Kind == "qualified_name" {
+				if c := h.Lookup(h.Namespace(call).Resolve(receiver.Text)); c != nil {
+					classes = []*Class{c}
+				}
+			}
+		}
+	default:
+		return nil
+	}
+
+	var result []*Method
+	add := func(m *Method) {
+		for _, existing := range result {
+			if existing == m {
+				return
+			}
+		}
+		result = append(result, m)
+	}
+	for _, c := range classes {
+		if m := c.Method(name); m != nil {
+			add(m.Declaration)
+		}
+		if !lateBinding {
+			continue
+		}
+		for _, sub := range c.Descendants() {
+			if m := sub.Method(name); m != nil && m.Origin != "inherited" {
+				add(m.Declaration)
+			}
+		}
+	}
+	call.SetAttribute(CallsAttributeKey, result)
+	return result
+}
+
+// receivers returns the classes a self reference may denote: the class itself, or the classes using a trait
+func (h *Hierarchy) receivers(c *Class) []*Class {
+	if c == nil {
+		return nil
+	}
+	if c.Kind != TraitKind {
+		return []*Class{c}
+	}
+	var result []*Class
+	for _, user := range h.SortedClasses() {
+		for _, t := range user.Traits {
+			if t == c {
+				result = append(result, user)
+			}
+		}
+	}
+	return result
+}
+
+// ResolveCalls resolves all the method calls of a tree
+func (h *Hierarchy) ResolveCalls(root *ast.Node) map[*ast.Node][]*Method {
+	v := &callVisitor{hierarchy: h, calls: make(map[*ast.Node][]*Method)}
+	root.WalkPrefix(v)
+	return v.calls
+}
+
+type callVisitor struct {
+	hierarchy *Hierarchy
+	calls     map[*ast.Node][]*Method
+}
+
+func (v *callVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		if methods := v.hierarchy.ResolveCall(n); methods != nil {
+			v.calls[n] = methods
+		}
+	}
+}
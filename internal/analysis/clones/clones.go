@@ -0,0 +1,311 @@
+package clones
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Type is the type of a clone class
+type Type int
+
+const (
+	// Type1 clones are identical except for layout and comments
+	Type1 Type = 1
+	// Type2 clones differ in identifier names and literal values
+	Type2 Type = 2
+	// Type3 clones are near-miss copies with added, removed or changed nodes
+	Type3 Type = 3
+)
+
+// Options configures the detection
+type Options struct {
+	// MinSize is the minimal number of named nodes of a Type-1 or Type-2 clone
+	MinSize int
+	// NearMissSize is the minimal number of named nodes of a Type-3 clone, 0 disables their detection
+	NearMissSize int
+	// Similarity is the minimal similarity between 0 and 1 of Type-3 clones
+	Similarity float64
+}
+
+// Fragment is a cloned subtree
+type Fragment struct {
+	File      string    `json:"file"`
+	Kind      string    `json:"kind"`
+	StartLine uint      `json:"start_line"`
+	EndLine   uint      `json:"end_line"`
+	Size      int       `json:"size"`
+	Text      string    `json:"text"`
+	Node      *ast.Node `json:"-"`
+}
+
+// Class is a set of fragments that are clones of each other
+type Class struct {
+	ID   int  `json:"id"`
+	Type Type `json:"type"`
+	// Similarity is the lowest similarity between two fragments of a Type-3 class, 1 otherwise
+	Similarity float64     `json:"similarity"`
+	Fragments  []*Fragment `json:"fragments"`
+}
+
+// Detector finds the clones among the files added to it
+type Detector struct {
+	options      Options
+	fragments    []*Fragment
+	fingerprints map[*ast.Node]fingerprint
+}
+
+func New(options Options) *Detector {
+	return &Detector{options: options, fingerprints: make(map[*ast.Node]fingerprint)}
+}
+
+// AddFile collects the candidate fragments of a file. Parent links must be set.
+func (d *Detector) AddFile(file string, root *ast.Node) {
+	fingerprints(root, d.fingerprints)
+	minSize := d.options.MinSize
+	if d.options.NearMissSize > 0 {
+		minSize = min(minSize, d.options.NearMissSize)
+	}
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		f := d.fingerprints[n]
+		if f.size < minSize {
+			return
+		}
+		if candidate(n) {
+			d.fragments = append(d.fragments, &Fragment{
+				File:      file,
+				Kind:      n.Kind,
+				StartLine: n.StartPosition.Row + 1,
+				EndLine:   n.EndPosition.Row + 1,
+				Size:      f.size,
+				Text:      n.Text,
+				Node:      n,
+			})
+		}
+		for _, child := range n.NamedChildren() {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// candidate reports whether a subtree may be reported as a clone: whole files and nodes that only
+// wrap a single child are left out, the child being reported instead
+func candidate(n *ast.Node) bool {
+	switch n.Kind {
+	case "program", "parenthesized_expression", "expression_statement", "arguments", "argument":
+		return false
+	}
+	return !ignored(n)
+}
+
+// Detect returns the clone classes, largest first
+func (d *Detector) Detect() []*Class {
+	sort.SliceStable(d.fragments, func(i, j int) bool {
+		return d.fragments[i].Size > d.fragments[j].Size
+	})
+	covered := make(map[*ast.Node]bool)
+	var classes []*Class
+	classes = append(classes, d.exact(covered)...)
+	if d.options.NearMissSize > 0 {
+		classes = append(classes, d.nearMiss()...)
+	}
+	sort.SliceStable(classes, func(i, j int) bool {
+		if classes[i].Fragments[0].Size != classes[j].Fragments[0].Size {
+			return classes[i].Fragments[0].Size > classes[j].Fragments[0].Size
+		}
+		return classes[i].Type < classes[j].Type
+	})
+	for i, c := range classes {
+		c.ID = i + 1
+		sortFragments(c.Fragments)
+	}
+	return classes
+}
+
+// exact groups the fragments with the same hash into Type-1 and Type-2 classes. Fragments are
+// processed from the largest, and a class whose fragments all lie inside already reported clones
+// is subsumed by them.
+func (d *Detector) exact(covered map[*ast.Node]bool) []*Class {
+	groups := make(map[uint64][]*Fragment)
+	var order []uint64
+	for _, f := range d.fragments {
+		if f.Size < d.options.MinSize {
+			continue
+		}
+		key := d.fingerprints[f.Node].normalized
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	var classes []*Class
+	for _, key := range order {
+		group := groups[key]
+		if len(group) < 2 || allCovered(group, covered) {
+			continue
+		}
+		// Split the Type-2 group into its Type-1 subgroups
+		exact := make(map[uint64][]*Fragment)
+		for _, f := range group {
+			exact[d.fingerprints[f.Node].exact] = append(exact[d.fingerprints[f.Node].exact], f)
+		}
+		c := &Class{Type: Type1, Similarity: 1, Fragments: group}
+		if len(exact) > 1 {
+			c.Type = Type2
+		}
+		classes = append(classes, c)
+		for _, f := range group {
+			cover(f.Node, covered)
+		}
+	}
+	return classes
+}
+
+// nearMiss pairs the fragments whose similarity is above the threshold and groups the pairs into
+// Type-3 classes. Fragments that are Type-1 or Type-2 clones of each other are not paired again,
+// but a near-miss class may include an exact class.
+func (d *Detector) nearMiss() []*Class {
+	type candidate struct {
+		fragment *Fragment
+		labels   map[uint64]int
+		size     int
+	}
+	var candidates []*candidate
+	for _, f := range d.fragments {
+		if f.Size < d.options.NearMissSize || !nearMissKind(f.Node.Kind) {
+			continue
+		}
+		bag := labels(f.Node)
+		size := 0
+		for _, count := range bag {
+			size += count
+		}
+		candidates = append(candidates, &candidate{fragment: f, labels: bag, size: size})
+	}
+
+	parent := make(map[*Fragment]*Fragment)
+	var find func(*Fragment) *Fragment
+	find = func(f *Fragment) *Fragment {
+		if parent[f] == nil || parent[f] == f {
+			return f
+		}
+		parent[f] = find(parent[f])
+		return parent[f]
+	}
+	lowest := make(map[*Fragment]float64)
+	matched := make(map[*ast.Node]bool)
+	for i, a := range candidates {
+		if inside(a.fragment.Node.Parent, matched) {
+			continue
+		}
+		for _, b := range candidates[i+1:] {
+			// The similarity cannot exceed 2 min / (min + max)
+			if 2*float64(b.size)/float64(a.size+b.size) < d.options.Similarity {
+				break
+			}
+			if inside(b.fragment.Node.Parent, matched) || related(a.fragment.Node, b.fragment.Node) {
+				continue
+			}
+			s := similarity(a.labels, b.labels, a.size, b.size)
+			if s < d.options.Similarity || s == 1 && d.fingerprints[a.fragment.Node].normalized == d.fingerprints[b.fragment.Node].normalized {
+				continue
+			}
+			ra, rb := find(a.fragment), find(b.fragment)
+			parent[a.fragment], parent[b.fragment] = ra, rb
+			root := ra
+			if ra != rb {
+				parent[rb] = ra
+				if l, ok := lowest[rb]; ok {
+					lowest[ra] = minSimilarity(lowest[ra], l)
+				}
+			}
+			lowest[root] = minSimilarity(lowest[root], s)
+			matched[a.fragment.Node] = true
+			matched[b.fragment.Node] = true
+		}
+	}
+
+	groups := make(map[*Fragment][]*Fragment)
+	var order []*Fragment
+	for _, c := range candidates {
+		if !matched[c.fragment.Node] {
+			continue
+		}
+		root := find(c.fragment)
+		if _, ok := groups[root]; !ok {
+			order = append(order, root)
+		}
+		groups[root] = append(groups[root], c.fragment)
+	}
+	var classes []*Class
+	for _, root := range order {
+		classes = append(classes, &Class{Type: Type3, Similarity: roundSimilarity(lowest[root]), Fragments: groups[root]})
+	}
+	return classes
+}
+
+func minSimilarity(a, b float64) float64 {
+	if a == 0 {
+		return b
+	}
+	return min(a, b)
+}
+
+func roundSimilarity(s float64) float64 {
+	return float64(int(s*1000)) / 1000
+}
+
+// nearMissKind restricts the near-miss comparison to functions and statements, where copies are made
+func nearMissKind(kind string) bool {
+	switch kind {
+	case "function_definition", "method_declaration", "anonymous_function", "compound_statement",
+		"class_declaration", "trait_declaration":
+		return true
+	}
+	return strings.HasSuffix(kind, "_statement") && kind != "expression_statement"
+}
+
+// cover marks a subtree as part of a reported clone
+func cover(n *ast.Node, covered map[*ast.Node]bool) {
+	covered[n] = true
+	for _, child := range n.Descendants {
+		cover(child, covered)
+	}
+}
+
+func allCovered(fragments []*Fragment, covered map[*ast.Node]bool) bool {
+	for _, f := range fragments {
+		if !covered[f.Node] {
+			return false
+		}
+	}
+	return true
+}
+
+// inside reports whether a node or one of its ancestors is in the set
+func inside(n *ast.Node, set map[*ast.Node]bool) bool {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if set[cur] {
+			return true
+		}
+	}
+	return false
+}
+
+// related reports whether one node contains the other
+func related(a, b *ast.Node) bool {
+	return inside(a, map[*ast.Node]bool{b: true}) || inside(b, map[*ast.Node]bool{a: true})
+}
+
+func sortFragments(fragments []*Fragment) {
+	sort.SliceStable(fragments, func(i, j int) bool {
+		if fragments[i].File != fragments[j].File {
+			return fragments[i].File < fragments[j].File
+		}
+		return fragments[i].StartLine < fragments[j].StartLine
+	})
+}
@@ -0,0 +1,103 @@
+package clones
+
+import (
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+const original = `<?php
+function total($items) {
+	$sum = 0;
+	foreach ($items as $item) {
+		if ($item->price > 10) {
+			$sum += $item->price * 2;
+		}
+	}
+	return $sum;
+}
+`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		copy  string
+		clone bool
+		typ   Type
+	}{
+		{
+			name: "identical but for layout and comments",
+			copy: `<?php
+// Sums the prices
+function total($items) { $sum = 0;
+	foreach ($items as $item) { if ($item->price > 10) { $sum += $item->price * 2; } }
+	return $sum; }
+`,
+			clone: true, typ: Type1,
+		},
+		{
+			name: "renamed identifiers and changed literals",
+			copy: `<?php
+function amount($rows) {
+	$acc = 0;
+	foreach ($rows as $row) {
+		if ($row->cost > 99) {
+			$acc += $row->cost * 3;
+		}
+	}
+	return $acc;
+}
+`,
+			clone: true, typ: Type2,
+		},
+		{
+			name: "an added statement",
+			copy: `<?php
+function total($items) {
+	$sum = 0;
+	foreach ($items as $item) {
+		if ($item->price > 10) {
+			$sum += $item->price * 2;
+		}
+		$count++;
+	}
+	return $sum;
+}
+`,
+			clone: true, typ: Type3,
+		},
+		{
+			name: "unrelated code",
+			copy: `<?php
+class Mailer {
+	public function send(string $to, string $body): bool {
+		return mail($to, 'Hello', $body, ['From' => 'me@example.com']);
+	}
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(Options{MinSize: 15, NearMissSize: 30, Similarity: 0.8})
+			for file, source := range map[string]string{"a.php": original, "b.php": tt.copy} {
+				root := ast.ParseSource([]byte(source))
+				root.SetParents()
+				d.AddFile(file, root)
+			}
+			var found *Class
+			for _, c := range d.Detect() {
+				if len(c.Fragments) == 2 && c.Fragments[0].File != c.Fragments[1].File {
+					found = c
+					break
+				}
+			}
+			if (found != nil) != tt.clone {
+				t.Fatalf("clone found = %t, want %t", found != nil, tt.clone)
+			}
+			if found != nil && found.Type != tt.typ {
+				t.Errorf("type = %d, want %d", found.Type, tt.typ)
+			}
+		})
+	}
+}
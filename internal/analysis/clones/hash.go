@@ -0,0 +1,146 @@
+package clones
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// identifierKinds and literalKinds are abstracted by the normalized hash: Type-2 clones may
+// differ in names and constant values
+var identifierKinds = map[string]bool{
+	"name": true, "variable_name": true, "qualified_name": true, "namespace_name": true,
+}
+
+var literalKinds = map[string]bool{
+	"string": true, "encapsed_string": true, "heredoc": true, "nowdoc": true,
+	"integer": true, "float": true, "boolean": true, "null": true,
+}
+
+// ignored nodes do not take part in the comparison
+func ignored(n *ast.Node) bool {
+	return n.Kind == "comment"
+}
+
+// fingerprint holds the hashes of a subtree
+type fingerprint struct {
+	// exact hashes the kinds and the token texts, identifying Type-1 clones
+	exact uint64
+	// normalized hashes the kinds only, with identifiers and literals abstracted, identifying Type-2 clones
+	normalized uint64
+	// size is the number of named nodes of the subtree
+	size int
+}
+
+func hash(parts ...string) uint64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+func hashChildren(kind string, children []uint64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(kind))
+	buf := make([]byte, 8)
+	for _, c := range children {
+		for i := range 8 {
+			buf[i] = byte(c >> (8 * i))
+		}
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// fingerprints computes the fingerprints of all the subtrees of a tree
+func fingerprints(root *ast.Node, result map[*ast.Node]fingerprint) fingerprint {
+	if len(root.Descendants) == 0 {
+		f := fingerprint{exact: hash(root.Kind, root.Text), normalized: hash(normalizedKind(root), normalizedText(root))}
+		if root.IsNamed {
+			f.size = 1
+		}
+		result[root] = f
+		return f
+	}
+	var exact, normalized []uint64
+	size := 1
+	for _, child := range root.Descendants {
+		if ignored(child) {
+			continue
+		}
+		f := fingerprints(child, result)
+		exact = append(exact, f.exact)
+		normalized = append(normalized, f.normalized)
+		size += f.size
+	}
+	f := fingerprint{exact: hashChildren(root.Kind, exact), size: size}
+	if identifierKinds[root.Kind] || literalKinds[root.Kind] {
+		f.normalized = hash(normalizedKind(root))
+	} else {
+		f.normalized = hashChildren(root.Kind, normalized)
+	}
+	result[root] = f
+	return f
+}
+
+func normalizedKind(n *ast.Node) string {
+	switch {
+	case identifierKinds[n.Kind]:
+		return "identifier"
+	case literalKinds[n.Kind]:
+		return "literal"
+	}
+	return n.Kind
+}
+
+func normalizedText(n *ast.Node) string {
+	if n.IsNamed {
+		// Names and literal contents
+		return ""
+	}
+	// Keywords and operators are case-insensitive tokens
+	return strings.ToLower(n.Text)
+}
+
+// labels returns the bag of shallow node labels of a subtree, a node and the kinds of its children,
+// with identifiers and literals abstracted. The similarity of two bags approximates the share of
+// identical nodes of two subtrees.
+func labels(root *ast.Node) map[uint64]int {
+	bag := make(map[uint64]int)
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		if ignored(n) || !n.IsNamed {
+			return
+		}
+		parts := []string{normalizedKind(n)}
+		if !identifierKinds[n.Kind] && !literalKinds[n.Kind] {
+			for _, child := range n.Descendants {
+				if !ignored(child) {
+					parts = append(parts, normalizedKind(child))
+				}
+			}
+			for _, child := range n.Descendants {
+				walk(child)
+			}
+		}
+		bag[hash(parts...)]++
+	}
+	walk(root)
+	return bag
+}
+
+// similarity returns the Dice coefficient 2S / (2S + L + R) of two bags of labels, where S is the
+// number of shared labels and L and R the number of labels found on one side only
+func similarity(a, b map[uint64]int, sizeA, sizeB int) float64 {
+	shared := 0
+	for label, countA := range a {
+		shared += min(countA, b[label])
+	}
+	if sizeA+sizeB == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(sizeA+sizeB)
+}
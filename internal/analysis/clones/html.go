@@ -0,0 +1,45 @@
+package clones
+
+import (
+	"html/template"
+	"io"
+)
+
+var reportTemplate = template.Must(template.New("clones").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Clone report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.class { margin-bottom: 2em; border-top: 1px solid #ccc; }
+.fragments { display: flex; gap: 1em; overflow-x: auto; }
+.fragment { flex: 1; min-width: 30em; }
+.fragment h3 { font-size: 0.9em; font-family: monospace; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>Clone report</h1>
+<p>{{len .}} clone classes</p>
+{{range .}}
+<div class="class" id="class-{{.ID}}">
+<h2>Class {{.ID}}: Type-{{.Type}}, {{len .Fragments}} fragments{{if eq .Type 3}}, similarity {{.Similarity}}{{end}}</h2>
+<div class="fragments">
+{{range .Fragments}}
+<div class="fragment">
+<h3>{{.File}}:{{.StartLine}}-{{.EndLine}} ({{.Kind}}, {{.Size}} nodes)</h3>
+<pre>{{.Text}}</pre>
+</div>
+{{end}}
+</div>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// HTML writes a report showing the fragments of each class side by side
+func HTML(w io.Writer, classes []*Class) error {
+	return reportTemplate.Execute(w, classes)
+}
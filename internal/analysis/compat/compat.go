@@ -0,0 +1,181 @@
+package compat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of incompatibility of a finding
+type Category string
+
+const (
+	// NewSyntax is a syntax feature introduced after the minimum version
+	NewSyntax Category = "new-syntax"
+	// NewFunction is a call to a function introduced after the minimum version, and not defined by the project
+	NewFunction Category = "new-function"
+	// Removed is a syntax feature, function or constant removed in the target range
+	Removed Category = "removed"
+	// Deprecated is a syntax feature, function or constant deprecated in the target range
+	Deprecated Category = "deprecated"
+)
+
+// Categories are all the categories of the findings
+var Categories = []Category{NewSyntax, NewFunction, Removed, Deprecated}
+
+// Finding is a construct that does not run, or is deprecated, on a version of the target range
+type Finding struct {
+	Category Category `json:"category"`
+	File     string   `json:"file"`
+	Line     uint     `json:"line"`
+	// Feature is the name of the syntax feature, function or constant
+	Feature string `json:"feature"`
+	// Version is the version that introduced, removed or deprecated the feature
+	Version Version   `json:"version"`
+	Message string    `json:"message"`
+	Node    *ast.Node `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Checker checks the files added to it against a range of PHP versions
+type Checker struct {
+	db        *Database
+	min       Version
+	max       Version
+	files     []*file
+	functions map[string]bool
+}
+
+// New returns a checker of the versions from min to max, both included, using a version database
+func New(db *Database, min, max Version) *Checker {
+	return &Checker{
+		db:        db,
+		min:       min,
+		max:       max,
+		functions: make(map[string]bool),
+	}
+}
+
+// AddFile collects the functions a file defines, which may be polyfills of newer builtins
+func (c *Checker) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	c.files = append(c.files, &file{path: path, root: root})
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+	root.WalkPrefix(v)
+	for _, fn := range v.Nodes {
+		if name := fn.ChildOfKind("name"); name != nil {
+			c.functions[strings.ToLower(name.Text)] = true
+		}
+	}
+}
+
+// Check returns the findings of all the files, ordered by file and line
+func (c *Checker) Check() []*Finding {
+	var findings []*Finding
+	for _, f := range c.files {
+		v := &checkVisitor{checker: c, file: f}
+		f.root.WalkPrefix(v)
+		findings = append(findings, v.findings...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// Required returns the oldest version supporting all the new syntax and functions of the findings
+func Required(findings []*Finding, min Version) Version {
+	required := min
+	for _, finding := range findings {
+		if (finding.Category == NewSyntax || finding.Category == NewFunction) && required.Less(finding.Version) {
+			required = finding.Version
+		}
+	}
+	return required
+}
+
+type checkVisitor struct {
+	checker  *Checker
+	file     *file
+	findings []*Finding
+}
+
+func (v *checkVisitor) VisitNode(n *ast.Node) {
+	c := v.checker
+	for _, feature := range features(n) {
+		if e, ok := c.db.Syntax[feature]; ok {
+			v.check(n, feature, e.Description, e, NewSyntax)
+		}
+	}
+	switch n.Kind {
+	case "function_call_expression":
+		name := n.ChildOfKind("name", "qualified_name")
+		if name == nil {
+			return
+		}
+		short := strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+		if e := c.db.Function(short); e != nil && !c.functions[short] {
+			v.check(n, short, short+"()", e, NewFunction)
+		}
+	case "name":
+		if e, ok := c.db.Constants[n.Text]; ok && isConstantUse(n) {
+			v.check(n, n.Text, "constant "+n.Text, e, NewSyntax)
+		}
+	}
+}
+
+// check reports a feature added after the minimum version, or removed or deprecated before the
+// maximum version
+func (v *checkVisitor) check(n *ast.Node, feature, description string, e *Entry, added Category) {
+	c := v.checker
+	report := func(category Category, version Version, message string) {
+		v.findings = append(v.findings, &Finding{
+			Category: category,
+			File:     v.file.path,
+			Line:     n.StartPosition.Row + 1,
+			Feature:  feature,
+			Version:  version,
+			Message:  message,
+			Node:     n,
+		})
+	}
+	replacement := ""
+	if e.Replacement != "" {
+		replacement = ", use " + e.Replacement
+	}
+	switch {
+	case !e.Added.IsZero() && c.min.Less(e.Added):
+		report(added, e.Added, description+" requires PHP "+e.Added.String())
+	case !e.Removed.IsZero() && !c.max.Less(e.Removed):
+		report(Removed, e.Removed, description+" was removed in PHP "+e.Removed.String()+replacement)
+	case !e.Deprecated.IsZero() && !c.max.Less(e.Deprecated):
+		report(Deprecated, e.Deprecated, description+" is deprecated since PHP "+e.Deprecated.String()+replacement)
+	}
+}
+
+// isConstantUse reports whether a name node is a global constant in an expression, rather than the
+// name of a declaration, a function, a class or a member
+func isConstantUse(n *ast.Node) bool {
+	if n.Parent == nil {
+		return false
+	}
+	if parts := n.Parent.Descendants; n.Parent.Kind == "argument" && len(parts) > 1 && parts[0] == n && parts[1].Kind == ":" {
+		// Named argument
+		return false
+	}
+	switch n.Parent.Kind {
+	case "argument", "binary_expression", "unary_op_expression", "assignment_expression", "array_element_initializer",
+		"pair", "parenthesized_expression", "return_statement", "conditional_expression", "match_condition_list",
+		"case_statement", "expression_statement":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package compat
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		min, max string
+		category Category
+		features []string
+	}{
+		{
+			name:   "syntax newer than the minimum version",
+			source: "<?php\n$a = $b ?? $c;\n$f = fn($x) => $x?->y;\n$r = match ($a) { default => 1 };\n",
+			min:    "7.0", max: "8.3", category: NewSyntax,
+			features: []string{"arrow-function", "nullsafe", "match"},
+		},
+		{
+			name:   "syntax supported by the minimum version",
+			source: "<?php\n$a = $b ?? $c;\nfunction f(): int { return 1; }\n",
+			min:    "7.0", max: "8.3", category: NewSyntax,
+		},
+		{
+			name:   "enum and readonly property",
+			source: "<?php\nenum Suit {}\nclass P { public readonly int $x; }\n",
+			min:    "8.0", max: "8.3", category: NewSyntax,
+			features: []string{"enum", "readonly-property"},
+		},
+		{
+			name:   "function newer than the minimum version",
+			source: "<?php\nstr_contains($a, 'b');\n\\array_key_first($a);\n",
+			min:    "7.2", max: "8.3", category: NewFunction,
+			features: []string{"str_contains", "array_key_first"},
+		},
+		{
+			name:   "polyfill defined by the project",
+			source: "<?php\nfunction str_contains($h, $n) { return strpos($h, $n) !== false; }\nstr_contains($a, 'b');\n",
+			min:    "7.2", max: "8.3", category: NewFunction,
+		},
+		{
+			name:   "removed functions",
+			source: "<?php\nmysql_query('x');\neach($a);\ncreate_function('', '');\n",
+			min:    "5.6", max: "8.0", category: Removed,
+			features: []string{"mysql_query", "each", "create_function"},
+		},
+		{
+			name:   "removed after the maximum version",
+			source: "<?php\neach($a);\n",
+			min:    "5.6", max: "7.4", category: Removed,
+		},
+		{
+			name:   "deprecated function and constant",
+			source: "<?php\nutf8_encode($a);\nfilter_var($a, FILTER_SANITIZE_STRING);\n",
+			min:    "7.4", max: "8.3", category: Deprecated,
+			features: []string{"utf8_encode", "FILTER_SANITIZE_STRING"},
+		},
+		{
+			name:   "named argument is not a constant",
+			source: "<?php\nf(E_STRICT: 1);\n",
+			min:    "8.0", max: "8.4", category: Deprecated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, err := ParseVersion(tt.min)
+			if err != nil {
+				t.Fatal(err)
+			}
+			max, err := ParseVersion(tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := New(Bundled(), min, max)
+			c.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var features []string
+			for _, finding := range c.Check() {
+				if finding.Category == tt.category {
+					features = append(features, finding.Feature)
+				}
+			}
+			if !slices.Equal(features, tt.features) {
+				t.Errorf("%s features = %v, want %v", tt.category, features, tt.features)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	min := Version{Major: 7}
+	c := New(Bundled(), min, Version{Major: 8, Minor: 3})
+	c.AddFile("test.php", ast.ParseSource([]byte("<?php\n$f = fn() => 1;\nstr_contains($a, 'b');\neach($a);\n")))
+	if got := Required(c.Check(), min).String(); got != "8.0" {
+		t.Errorf("Required() = %s, want 8.0", got)
+	}
+}
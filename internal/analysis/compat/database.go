@@ -0,0 +1,105 @@
+package compat
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a PHP major.minor version
+type Version struct {
+	Major int
+	Minor int
+}
+
+// ParseVersion parses a version such as 7.4 or 8. The patch number is ignored.
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid PHP version %q", s)
+	}
+	v := Version{Major: major}
+	if len(parts) > 1 {
+		if v.Minor, err = strconv.Atoi(parts[1]); err != nil {
+			return Version{}, fmt.Errorf("invalid PHP version %q", s)
+		}
+	}
+	return v, nil
+}
+
+// Less reports whether the version is older than another one
+func (v Version) Less(other Version) bool {
+	return v.Major < other.Major || (v.Major == other.Major && v.Minor < other.Minor)
+}
+
+// IsZero reports whether the version is unset
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func (v Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+func (v *Version) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseVersion(s)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// Entry is the history of a syntax feature, function or constant
+type Entry struct {
+	Added       Version `json:"added"`
+	Deprecated  Version `json:"deprecated"`
+	Removed     Version `json:"removed"`
+	Description string  `json:"description"`
+	Replacement string  `json:"replacement"`
+}
+
+// Database holds the versions in which the syntax features, functions and constants were added,
+// deprecated and removed. Function names ending with * match a prefix, as mysql_*.
+type Database struct {
+	Syntax    map[string]*Entry `json:"syntax"`
+	Functions map[string]*Entry `json:"functions"`
+	Constants map[string]*Entry `json:"constants"`
+}
+
+//go:embed versions.json
+var bundled []byte
+
+// Bundled returns the version database shipped with the parser
+func Bundled() *Database {
+	db := &Database{}
+	if err := json.Unmarshal(bundled, db); err != nil {
+		panic("compat: invalid bundled version database: " + err.Error())
+	}
+	return db
+}
+
+// Function returns the entry of a function, case-insensitively, or nil
+func (db *Database) Function(name string) *Entry {
+	name = strings.ToLower(strings.TrimPrefix(name, "\\"))
+	if e, ok := db.Functions[name]; ok {
+		return e
+	}
+	for pattern, e := range db.Functions {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(name, prefix) {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package compat
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// typeKinds are the node kinds of type declarations
+var typeKinds = map[string]bool{
+	"primitive_type":               true,
+	"named_type":                   true,
+	"optional_type":                true,
+	"union_type":                   true,
+	"intersection_type":            true,
+	"disjunctive_normal_form_type": true,
+	"bottom_type":                  true,
+}
+
+// features returns the syntax features of the database a node uses
+func features(n *ast.Node) []string {
+	var result []string
+	add := func(feature string) {
+		result = append(result, feature)
+	}
+	switch n.Kind {
+	case "binary_expression":
+		switch operator(n) {
+		case "??":
+			add("null-coalescing")
+		case "<=>":
+			add("spaceship")
+		}
+	case "augmented_assignment_expression":
+		if operator(n) == "??=" {
+			add("null-coalescing-assignment")
+		}
+	case "anonymous_class":
+		add("anonymous-class")
+	case "function_definition", "method_declaration", "anonymous_function", "arrow_function":
+		if n.Kind == "arrow_function" {
+			add("arrow-function")
+		}
+		if returnType(n) != nil {
+			add("return-type")
+		}
+	case "primitive_type":
+		switch strings.ToLower(n.Text) {
+		case "int", "float", "bool", "string":
+			add("scalar-type")
+		case "iterable":
+			add("iterable-type")
+		case "object":
+			add("object-type")
+		case "mixed":
+			add("mixed-type")
+		case "void":
+			add("void-type")
+		case "true", "false", "null":
+			if n.Parent != nil && n.Parent.Kind != "union_type" && n.Parent.Kind != "disjunctive_normal_form_type" {
+				add("standalone-type")
+			}
+		}
+	case "named_type":
+		if strings.EqualFold(n.Text, "static") {
+			add("static-return-type")
+		}
+	case "optional_type":
+		add("nullable-type")
+	case "union_type":
+		add("union-type")
+	case "intersection_type":
+		if n.Parent != nil && n.Parent.Kind != "disjunctive_normal_form_type" {
+			add("intersection-type")
+		}
+	case "disjunctive_normal_form_type":
+		add("dnf-type")
+	case "bottom_type":
+		add("never-type")
+	case "yield_expression":
+		if n.ChildOfKind("from") != nil {
+			add("yield-from")
+		}
+	case "namespace_use_group":
+		add("group-use")
+	case "const_declaration":
+		if n.ChildOfKind("visibility_modifier") != nil {
+			add("constant-visibility")
+		}
+		for _, child := range n.NamedChildren() {
+			if typeKinds[child.Kind] {
+				add("typed-constant")
+			}
+		}
+	case "catch_clause":
+		if types := n.ChildOfKind("type_list"); types != nil && len(types.NamedChildren()) > 1 {
+			add("multi-catch")
+		}
+		if n.ChildOfKind("variable_name") == nil {
+			add("catch-without-variable")
+		}
+	case "assignment_expression":
+		if children := n.NamedChildren(); len(children) > 0 && children[0].Kind == "array_creation_expression" {
+			add("short-list")
+		}
+	case "arguments":
+		if trailingComma(n) {
+			add("trailing-comma-call")
+		}
+	case "formal_parameters":
+		if trailingComma(n) {
+			add("trailing-comma-parameters")
+		}
+	case "property_declaration":
+		for _, child := range n.NamedChildren() {
+			if typeKinds[child.Kind] {
+				add("typed-property")
+			}
+		}
+	case "array_element_initializer":
+		if n.ChildOfKind("variadic_unpacking") != nil {
+			add("array-spread")
+		}
+	case "integer", "float":
+		if strings.Contains(n.Text, "_") {
+			add("numeric-separator")
+		}
+		if strings.HasPrefix(strings.ToLower(n.Text), "0o") {
+			add("explicit-octal")
+		}
+	case "match_expression":
+		add("match")
+	case "nullsafe_member_access_expression", "nullsafe_member_call_expression":
+		add("nullsafe")
+	case "argument":
+		if len(n.Descendants) > 1 && n.Descendants[0].Kind == "name" && n.Descendants[1].Kind == ":" {
+			add("named-argument")
+		}
+	case "property_promotion_parameter":
+		add("constructor-promotion")
+	case "attribute_list":
+		add("attribute")
+	case "throw_expression":
+		if n.Parent != nil && n.Parent.Kind != "expression_statement" {
+			add("throw-expression")
+		}
+	case "enum_declaration":
+		add("enum")
+	case "readonly_modifier":
+		if n.Parent != nil && n.Parent.Kind == "class_declaration" {
+			add("readonly-class")
+		} else {
+			add("readonly-property")
+		}
+	case "variadic_placeholder":
+		add("first-class-callable")
+	case "object_creation_expression":
+		if n.Parent == nil {
+			break
+		}
+		switch n.Parent.Kind {
+		case "simple_parameter", "property_promotion_parameter", "static_variable_declaration", "const_element":
+			add("new-in-initializer")
+		case "member_access_expression", "member_call_expression", "nullsafe_member_access_expression",
+			"nullsafe_member_call_expression", "subscript_expression":
+			if n.Parent.Descendants[0] == n {
+				add("new-without-parentheses")
+			}
+		}
+	case "class_constant_access_expression":
+		if n.ChildOfKind("{") != nil {
+			add("dynamic-constant-fetch")
+		}
+	case "property_hook_list":
+		add("property-hook")
+	case "visibility_modifier":
+		if strings.Contains(n.Text, "(") {
+			add("asymmetric-visibility")
+		}
+	case "cast_expression":
+		if cast := n.ChildOfKind("cast_type"); cast != nil {
+			switch strings.ToLower(strings.TrimSpace(cast.Text)) {
+			case "real":
+				add("real-cast")
+			case "unset":
+				add("unset-cast")
+			}
+		}
+	case "conditional_expression":
+		if children := n.NamedChildren(); len(children) > 0 && children[0].Kind == "conditional_expression" {
+			add("nested-ternary")
+		}
+	case "simple_parameter":
+		if implicitNullable(n) {
+			add("implicit-nullable")
+		}
+		if requiredAfterOptional(n) {
+			add("required-after-optional")
+		}
+	case "dynamic_variable_name":
+		if n.Parent != nil && (n.Parent.Kind == "encapsed_string" || n.Parent.Kind == "heredoc_body") {
+			add("dollar-brace-interpolation")
+		}
+	}
+	return result
+}
+
+// operator returns the operator token of a binary or assignment expression
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
+
+// returnType returns the declared return type of a function, or nil
+func returnType(fn *ast.Node) *ast.Node {
+	afterColon := false
+	for _, child := range fn.Descendants {
+		switch {
+		case child.Kind == ":":
+			afterColon = true
+		case afterColon && typeKinds[child.Kind]:
+			return child
+		case child.Kind == "compound_statement" || child.Kind == "=>":
+			return nil
+		}
+	}
+	return nil
+}
+
+// trailingComma reports whether a parenthesized list ends with a comma
+func trailingComma(n *ast.Node) bool {
+	count := len(n.Descendants)
+	return count >= 2 && n.Descendants[count-1].Kind == ")" && n.Descendants[count-2].Kind == ","
+}
+
+// defaultValue returns the default value of a parameter, or nil
+func defaultValue(parameter *ast.Node) *ast.Node {
+	for i, child := range parameter.Descendants {
+		if child.Kind == "=" && i+1 < len(parameter.Descendants) {
+			return parameter.Descendants[i+1]
+		}
+	}
+	return nil
+}
+
+func isNull(n *ast.Node) bool {
+	return n != nil && n.Kind == "null"
+}
+
+// implicitNullable reports whether a parameter with a non-nullable type defaults to null, which
+// makes its type nullable implicitly
+func implicitNullable(parameter *ast.Node) bool {
+	if !isNull(defaultValue(parameter)) {
+		return false
+	}
+	for _, child := range parameter.NamedChildren() {
+		if !typeKinds[child.Kind] {
+			continue
+		}
+		if child.Kind == "optional_type" || strings.EqualFold(child.Text, "mixed") {
+			return false
+		}
+		for _, part := range strings.Split(child.Text, "|") {
+			if strings.EqualFold(strings.TrimSpace(part), "null") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// requiredAfterOptional reports whether a parameter without default value follows a parameter
+// with one. Parameters defaulting to null are not counted as optional, as PHP does.
+func requiredAfterOptional(parameter *ast.Node) bool {
+	if defaultValue(parameter) != nil || parameter.Parent == nil {
+		return false
+	}
+	for _, sibling := range parameter.Parent.NamedChildren() {
+		if sibling == parameter {
+			return false
+		}
+		if value := defaultValue(sibling); value != nil && !isNull(value) && sibling.Kind != "variadic_parameter" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,433 @@
+package crypto
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// WeakPasswordHash is a password hashed with a fast digest such as md5 or sha1
+	WeakPasswordHash Category = "weak-password-hash"
+	// WeakRandomness is a token, key or salt generated with a predictable generator
+	WeakRandomness Category = "weak-randomness"
+	// WeakCipher is a cipher in ECB mode, or a broken cipher such as DES or RC4
+	WeakCipher Category = "weak-cipher"
+	// StaticIV is an initialization vector that is constant or missing
+	StaticIV Category = "static-iv"
+	// HardcodedKey is an encryption or signing key written in the code
+	HardcodedKey Category = "hardcoded-key"
+	// Mcrypt is a use of the mcrypt extension, removed in PHP 7.2
+	Mcrypt Category = "mcrypt"
+	// HashComparison is a hash or token compared with == or === instead of hash_equals
+	HashComparison Category = "hash-comparison"
+)
+
+// digests are the fast hashes unfit for passwords
+var digests = map[string]bool{"md5": true, "sha1": true, "crc32": true, "hash": true}
+
+// weakAlgorithms are the hash algorithms, given to hash(), unfit for passwords
+var weakAlgorithms = regexp.MustCompile(`(?i)^(md[245]|sha1|sha224|sha256|sha384|sha512(/2\d\d)?|sha3-\d+|crc32[bc]?|adler32|fnv.*|joaat|murmur.*|xxh.*|ripemd\d+|whirlpool|tiger.*)$`)
+
+// generators are the predictable random generators
+var generators = map[string]bool{
+	"rand": true, "mt_rand": true, "uniqid": true, "lcg_value": true, "microtime": true, "str_shuffle": true,
+	"array_rand": true, "shuffle": true,
+}
+
+// wrappers are the functions keeping the predictability of their argument
+var wrappers = map[string]bool{
+	"md5": true, "sha1": true, "hash": true, "crc32": true, "substr": true, "base64_encode": true, "bin2hex": true,
+	"dechex": true, "base_convert": true, "strval": true, "str_pad": true, "strtoupper": true, "strtolower": true,
+	"str_shuffle": true, "intval": true, "sprintf": true, "implode": true, "uniqid": true,
+}
+
+// ciphers maps the encryption functions to the indexes of their cipher, key and IV arguments,
+// -1 when they have none
+var ciphers = map[string]struct{ cipher, key, iv int }{
+	"openssl_encrypt":                      {1, 2, 4},
+	"openssl_decrypt":                      {1, 2, 4},
+	"mcrypt_encrypt":                       {0, 1, 4},
+	"mcrypt_decrypt":                       {0, 1, 4},
+	"hash_hmac":                            {-1, 2, -1},
+	"hash_hkdf":                            {-1, 1, -1},
+	"sodium_crypto_secretbox":              {-1, 2, -1},
+	"sodium_crypto_secretbox_open":         {-1, 2, -1},
+	"sodium_crypto_aead_aes256gcm_encrypt": {-1, 3, -1},
+}
+
+// brokenCiphers matches the cipher names of broken algorithms
+var brokenCiphers = regexp.MustCompile(`(?i)^(des|des-.*|rc2.*|rc4.*|bf.*|blowfish|cast5.*|idea.*|seed.*|rijndael-\d+-ecb|tripledes)$`)
+
+var (
+	passwordName = regexp.MustCompile(`(?i)(pass(wd|word)?|pwd|passphrase)`)
+	tokenName    = regexp.MustCompile(`(?i)(token|nonce|secret|salt|pass(wd|word)?|pwd|otp|csrf|xsrf|sess(ion)?_?id|reset|activation|verification|(api|auth|private|secret|encryption|signing)_?key|signature)`)
+	hashName     = regexp.MustCompile(`(?i)(hash|digest|signature|hmac|mac|token|checksum|sig)$|^(hash|digest|signature|hmac|token|sig)`)
+)
+
+// Finding is a misuse of cryptography or randomness
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	// Function is the function or operator misused
+	Function string    `json:"function"`
+	File     string    `json:"file"`
+	Line     uint      `json:"line"`
+	Message  string    `json:"message"`
+	Node     *ast.Node `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the misuses of cryptography of a project: fast digests of passwords, predictable
+// tokens, ECB mode, constant IVs, hardcoded keys, mcrypt and hashes compared without hash_equals.
+// The arguments of the calls are evaluated to constants to read the cipher names and tell the
+// hardcoded keys, and the names of the variables give the context of the values.
+type Analyzer struct {
+	evaluator *values.Evaluator
+	files     []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{evaluator: values.New()}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.evaluator.AddFile(path, root)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.evaluator.Resolve()
+	var findings []*Finding
+	for _, f := range a.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true, "binary_expression": true}}
+		f.root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			if n.Kind == "binary_expression" {
+				if finding := a.comparison(f.path, n); finding != nil {
+					findings = append(findings, finding)
+				}
+				continue
+			}
+			findings = append(findings, a.call(f.path, n)...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func (a *Analyzer) call(path string, n *ast.Node) []*Finding {
+	name := taint.FunctionName(n)
+	arguments := n.Arguments()
+	var findings []*Finding
+	add := func(category Category, severity taint.Severity, message string) {
+		findings = append(findings, &Finding{
+			Category: category,
+			Severity: severity,
+			Function: name,
+			File:     path,
+			Line:     n.StartPosition.Row + 1,
+			Message:  message,
+			Node:     n,
+		})
+	}
+	if strings.HasPrefix(name, "mcrypt_") {
+		add(Mcrypt, taint.Medium, fmt.Sprintf("%s belongs to the mcrypt extension, unmaintained and removed in PHP 7.2, use sodium or openssl", name))
+	}
+	if digests[name] && len(arguments) > 0 {
+		data := arguments[0]
+		if name == "hash" {
+			algorithm, ok := a.evaluator.Eval(arguments[0]).Constant()
+			if !ok || !weakAlgorithms.MatchString(algorithm) || len(arguments) < 2 {
+				data = nil
+			} else {
+				data = arguments[1]
+			}
+		}
+		if data != nil && (mentions(data, passwordName) || passwordName.MatchString(resultName(n))) {
+			add(WeakPasswordHash, taint.High, fmt.Sprintf("password hashed with %s, a fast digest easy to brute force, use password_hash and password_verify", name))
+		}
+	}
+	// The generators nested in another one, as in uniqid(mt_rand()), are reported once
+	if generators[name] && !generators[enclosingCall(n)] {
+		if target := resultName(n); target != "" && tokenName.MatchString(target) {
+			add(WeakRandomness, taint.Medium, fmt.Sprintf("%s generates %s, but its output is predictable, use random_bytes or random_int", name, target))
+		}
+	}
+	if function, ok := ciphers[name]; ok {
+		findings = append(findings, a.cipher(path, name, n, function.cipher, function.key, function.iv)...)
+	}
+	return findings
+}
+
+// cipher checks the cipher, key and IV arguments of an encryption function
+func (a *Analyzer) cipher(path, name string, n *ast.Node, cipher, key, iv int) []*Finding {
+	arguments := n.Arguments()
+	var findings []*Finding
+	add := func(category Category, severity taint.Severity, message string) {
+		findings = append(findings, &Finding{
+			Category: category,
+			Severity: severity,
+			Function: name,
+			File:     path,
+			Line:     n.StartPosition.Row + 1,
+			Message:  message,
+			Node:     n,
+		})
+	}
+	ecb := false
+	if cipher >= 0 && cipher < len(arguments) {
+		names, _ := a.evaluator.Eval(arguments[cipher]).Constants()
+		for _, method := range names {
+			method = strings.ToLower(strings.TrimSpace(method))
+			if strings.HasSuffix(method, "-ecb") || method == "ecb" {
+				ecb = true
+				add(WeakCipher, taint.Medium, fmt.Sprintf("cipher %s uses ECB mode, which encrypts equal blocks to equal blocks, use an authenticated mode such as aes-256-gcm", method))
+				break
+			}
+			if brokenCiphers.MatchString(method) {
+				add(WeakCipher, taint.High, fmt.Sprintf("cipher %s is broken, use aes-256-gcm", method))
+				break
+			}
+		}
+		// mcrypt takes the mode as a separate argument
+		if strings.HasPrefix(name, "mcrypt_") && len(arguments) > 3 {
+			if mode, ok := a.evaluator.Eval(arguments[3]).Constant(); ok && strings.EqualFold(mode, "ecb") {
+				ecb = true
+				add(WeakCipher, taint.Medium, "mcrypt in ECB mode encrypts equal blocks to equal blocks")
+			}
+		}
+	}
+	if key >= 0 && key < len(arguments) {
+		if value, ok := a.evaluator.Eval(arguments[key]).Constant(); ok && value != "" {
+			add(HardcodedKey, taint.High, fmt.Sprintf("the key of %s is written in the code%s, load it from the environment or a key store", name, origin(arguments[key])))
+		}
+	}
+	if iv >= 0 && !ecb && cipher >= 0 {
+		if iv >= len(arguments) {
+			if name == "openssl_encrypt" {
+				add(StaticIV, taint.Medium, "openssl_encrypt called without an IV, which is then empty, use random_bytes(openssl_cipher_iv_length($cipher))")
+			}
+		} else if value, ok := a.evaluator.Eval(arguments[iv]).Constant(); ok && name != "openssl_decrypt" && name != "mcrypt_decrypt" {
+			message := fmt.Sprintf("the IV of %s is constant%s, use random_bytes(openssl_cipher_iv_length($cipher)) for each message", name, origin(arguments[iv]))
+			if value == "" {
+				message = fmt.Sprintf("the IV of %s is empty, use random_bytes(openssl_cipher_iv_length($cipher)) for each message", name)
+			}
+			add(StaticIV, taint.High, message)
+		}
+	}
+	return findings
+}
+
+// comparison checks a hash, signature or token compared with an operator instead of hash_equals,
+// which leaks the length of the common prefix through timing, and juggles types with ==
+func (a *Analyzer) comparison(path string, n *ast.Node) *Finding {
+	op := operator(n)
+	switch op {
+	case "==", "!=", "<>", "===", "!==":
+	default:
+		return nil
+	}
+	children := n.NamedChildren()
+	if len(children) != 2 {
+		return nil
+	}
+	for _, side := range children {
+		if !a.hashLike(side) {
+			continue
+		}
+		other := children[0]
+		if other == side {
+			other = children[1]
+		}
+		// Comparisons with literals such as '' or null check presence rather than equality
+		if _, ok := a.evaluator.Eval(other).Constant(); ok || other.Kind == "null" || other.Kind == "boolean" {
+			return nil
+		}
+		finding := &Finding{
+			Category: HashComparison,
+			Severity: taint.Low,
+			Function: op,
+			File:     path,
+			Line:     n.StartPosition.Row + 1,
+			Message:  fmt.Sprintf("%s compared with %s, which leaks timing, use hash_equals", shorten(side.Text), op),
+			Node:     n,
+		}
+		if op == "==" || op == "!=" || op == "<>" {
+			finding.Severity = taint.Medium
+			finding.Message = fmt.Sprintf("%s compared with %s, which leaks timing and juggles types such as \"0e...\" hashes, use hash_equals", shorten(side.Text), op)
+		}
+		return finding
+	}
+	return nil
+}
+
+// hashLike reports whether an expression is a digest or a MAC, by the function computing it or
+// by the name of the variable, property or key holding it
+func (a *Analyzer) hashLike(n *ast.Node) bool {
+	switch n.Kind {
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return a.hashLike(children[0])
+		}
+	case "function_call_expression":
+		switch taint.FunctionName(n) {
+		case "md5", "sha1", "hash", "hash_hmac", "crypt", "crc32", "hash_final", "md5_file", "sha1_file", "hash_file":
+			return true
+		}
+		return false
+	}
+	name := targetName(n)
+	return name != "" && hashName.MatchString(name) && !passwordName.MatchString(name)
+}
+
+// resultName returns the name the result of a call is stored in: the variable, property or key it
+// is assigned to, the function it is returned from, through the calls that keep its predictability
+func resultName(n *ast.Node) string {
+	current := n
+	for parent := n.Parent; parent != nil; current, parent = parent, parent.Parent {
+		children := parent.NamedChildren()
+		switch parent.Kind {
+		case "parenthesized_expression", "arguments", "argument":
+			continue
+		case "binary_expression":
+			if op := operator(parent); op == "." || op == "+" || op == "*" || op == "^" {
+				continue
+			}
+			return ""
+		case "function_call_expression":
+			if wrappers[taint.FunctionName(parent)] {
+				continue
+			}
+			return ""
+		case "assignment_expression", "augmented_assignment_expression":
+			if len(children) == 2 && children[1] == current {
+				return targetName(children[0])
+			}
+			return ""
+		case "pair", "array_element_initializer":
+			if len(children) == 2 && children[1] == current {
+				return keyName(children[0])
+			}
+			return ""
+		case "return_statement":
+			for cur := parent.Parent; cur != nil; cur = cur.Parent {
+				if cur.Kind == "function_definition" || cur.Kind == "method_declaration" {
+					if name := cur.ChildOfKind("name"); name != nil {
+						return name.Text
+					}
+					return ""
+				}
+			}
+			return ""
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// enclosingCall returns the name of the function a call is an argument of
+func enclosingCall(n *ast.Node) string {
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		switch parent.Kind {
+		case "argument", "arguments", "parenthesized_expression":
+			continue
+		case "function_call_expression":
+			return taint.FunctionName(parent)
+		}
+		return ""
+	}
+	return ""
+}
+
+// mentions reports whether an expression uses a variable, property or key whose name matches
+func mentions(n *ast.Node, pattern *regexp.Regexp) bool {
+	if name := targetName(n); name != "" && pattern.MatchString(name) {
+		return true
+	}
+	for _, child := range n.NamedChildren() {
+		if mentions(child, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// origin names the constant or variable holding a hardcoded value, if it is not a literal
+func origin(n *ast.Node) string {
+	switch n.Kind {
+	case "name", "class_constant_access_expression":
+		return " by the constant " + n.Text
+	case "variable_name", "member_access_expression":
+		return " through " + n.Text
+	}
+	return ""
+}
+
+// targetName returns the name of a variable, a property, a constant or the string key of an array
+// element
+func targetName(n *ast.Node) string {
+	switch n.Kind {
+	case "variable_name", "name":
+		return strings.TrimPrefix(n.Text, "$")
+	case "member_access_expression", "nullsafe_member_access_expression", "scoped_property_access_expression", "class_constant_access_expression":
+		children := n.NamedChildren()
+		return targetName(children[len(children)-1])
+	case "subscript_expression":
+		children := n.NamedChildren()
+		if len(children) == 2 {
+			if key := keyName(children[1]); key != "" {
+				return key
+			}
+			return targetName(children[0])
+		}
+	}
+	return ""
+}
+
+func keyName(n *ast.Node) string {
+	if n.Kind == "string" || n.Kind == "encapsed_string" {
+		return strings.Trim(n.Text, `'"`)
+	}
+	if n.Kind == "name" {
+		return n.Text
+	}
+	return ""
+}
+
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 40 {
+		return text[:37] + "..."
+	}
+	return text
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// want maps the categories reported to the functions or operators of their findings
+		want map[Category][]string
+	}{
+		{
+			name:   "password hashed with md5",
+			source: "<?php\n$hash = md5($_POST['password']);",
+			want:   map[Category][]string{WeakPasswordHash: {"md5"}},
+		},
+		{
+			name:   "password hashed with a fast algorithm of hash",
+			source: "<?php\n$stored = hash('sha256', $salt . $password);",
+			want:   map[Category][]string{WeakPasswordHash: {"hash"}},
+		},
+		{
+			name:   "password hashed with password_hash",
+			source: "<?php\n$hash = password_hash($_POST['password'], PASSWORD_DEFAULT);",
+		},
+		{
+			name:   "token from rand",
+			source: "<?php\n$token = md5(rand());",
+			want:   map[Category][]string{WeakRandomness: {"rand"}},
+		},
+		{
+			name:   "nested generators reported once",
+			source: "<?php\n$nonce = uniqid(mt_rand(), true);",
+			want:   map[Category][]string{WeakRandomness: {"uniqid"}},
+		},
+		{
+			name:   "token from random_bytes",
+			source: "<?php\n$token = bin2hex(random_bytes(16));",
+		},
+		{
+			name:   "ECB mode needs no IV",
+			source: "<?php\n$c = openssl_encrypt($data, 'aes-128-ecb', $key);",
+			want:   map[Category][]string{WeakCipher: {"openssl_encrypt"}},
+		},
+		{
+			name:   "broken cipher with a constant IV",
+			source: "<?php\n$iv = '1234567812345678';\n$c = openssl_encrypt($data, 'des-cbc', $key, 0, $iv);",
+			want:   map[Category][]string{WeakCipher: {"openssl_encrypt"}, StaticIV: {"openssl_encrypt"}},
+		},
+		{
+			name:   "missing IV",
+			source: "<?php\n$c = openssl_encrypt($data, 'aes-256-cbc', $key);",
+			want:   map[Category][]string{StaticIV: {"openssl_encrypt"}},
+		},
+		{
+			name:   "GCM mode with a random IV",
+			source: "<?php\n$iv = random_bytes(12);\n$c = openssl_encrypt($data, 'aes-256-gcm', $key, 0, $iv, $tag);",
+		},
+		{
+			name:   "hardcoded keys",
+			source: "<?php\nconst KEY = 'secret-key-1234';\n$c = openssl_encrypt($data, 'aes-256-cbc', 'secret-key-1234', 0, $iv);\n$mac = hash_hmac('sha256', $data, KEY);",
+			want:   map[Category][]string{HardcodedKey: {"openssl_encrypt", "hash_hmac"}},
+		},
+		{
+			name:   "key from the environment",
+			source: "<?php\n$c = openssl_encrypt($data, 'aes-256-cbc', getenv('KEY'), 0, $iv);",
+		},
+		{
+			name:   "mcrypt in ECB mode",
+			source: "<?php\n$c = mcrypt_encrypt(MCRYPT_RIJNDAEL_128, $key, $data, 'ecb');",
+			want:   map[Category][]string{Mcrypt: {"mcrypt_encrypt"}, WeakCipher: {"mcrypt_encrypt"}},
+		},
+		{
+			name:   "signatures compared with operators",
+			source: "<?php\nif ($_GET['sig'] == hash_hmac('sha256', $data, $key)) {}\nif ($expected_hash !== $hash) {}\nif ($token === '') {}\nif (hash_equals($expected_hash, $hash)) {}",
+			want:   map[Category][]string{HashComparison: {"==", "!=="}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			got := make(map[Category][]string)
+			for _, f := range a.Analyze() {
+				got[f.Category] = append(got[f.Category], f.Function)
+			}
+			want := tt.want
+			if want == nil {
+				want = map[Category][]string{}
+			}
+			if !maps.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("findings = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package dataflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// DefinitionID identifies a definition in the facts of ReachingDefinitions, as $name@line:column
+func DefinitionID(d Def) string {
+	return fmt.Sprintf("$%s@%d:%d", d.Name, d.Node.StartPosition.Row+1, d.Node.StartPosition.Column+1)
+}
+
+// ReachingDefinitions computes the definitions that may reach each node
+type ReachingDefinitions struct {
+	unionLattice
+}
+
+func (ReachingDefinitions) Name() string {
+	return "reaching-definitions"
+}
+
+func (ReachingDefinitions) Direction() Direction {
+	return Forward
+}
+
+func (ReachingDefinitions) Boundary(*cfg.Graph) Set {
+	return NewSet()
+}
+
+func (ReachingDefinitions) Initial(*cfg.Graph) Set {
+	return NewSet()
+}
+
+func (ReachingDefinitions) Transfer(n *cfg.Node, in Set) Set {
+	out := in.Copy()
+	for _, def := range Accesses(n).Defs {
+		if def.Strong {
+			prefix := "$" + def.Name + "@"
+			for id := range out {
+				if strings.HasPrefix(id, prefix) {
+					delete(out, id)
+				}
+			}
+		}
+	}
+	for _, def := range Accesses(n).Defs {
+		out[DefinitionID(def)] = struct{}{}
+	}
+	return out
+}
+
+// LiveVariables computes the variables whose value may be read after each node
+type LiveVariables struct {
+	unionLattice
+}
+
+func (LiveVariables) Name() string {
+	return "live-variables"
+}
+
+func (LiveVariables) Direction() Direction {
+	return Backward
+}
+
+func (LiveVariables) Boundary(*cfg.Graph) Set {
+	return NewSet()
+}
+
+func (LiveVariables) Initial(*cfg.Graph) Set {
+	return NewSet()
+}
+
+func (LiveVariables) Transfer(n *cfg.Node, out Set) Set {
+	access := Accesses(n)
+	in := out.Copy()
+	for _, def := range access.Defs {
+		if def.Strong {
+			delete(in, def.Name)
+		}
+	}
+	for _, name := range access.UsedNames() {
+		in[name] = struct{}{}
+	}
+	return in
+}
+
+// AvailableExpressions computes the expressions already computed on every path to each node
+// and not invalidated since by the redefinition of one of their variables
+type AvailableExpressions struct {
+	intersectionLattice
+	// universe maps each expression of the graph to the variables it reads
+	universe map[string][]string
+}
+
+// expressionKinds are the side effect free expressions tracked by AvailableExpressions
+var expressionKinds = map[string]bool{
+	"binary_expression":   true,
+	"unary_op_expression": true,
+	"cast_expression":     true,
+}
+
+// NewAvailableExpressions prepares the analysis for a graph, collecting the expressions it computes
+func NewAvailableExpressions(g *cfg.Graph) *AvailableExpressions {
+	a := &AvailableExpressions{universe: make(map[string][]string)}
+	for _, n := range g.Nodes {
+		for expression, variables := range expressions(n) {
+			a.universe[expression] = variables
+		}
+	}
+	return a
+}
+
+// ExpressionText normalizes the text of an expression to identify it in the facts
+func ExpressionText(n *ast.Node) string {
+	return strings.Join(strings.Fields(n.Text), " ")
+}
+
+// expressions returns the tracked expressions evaluated by a node along with the variables they read
+func expressions(n *cfg.Node) map[string][]string {
+	result := make(map[string][]string)
+	if n.AST == nil || n.Kind == cfg.Foreach || n.Kind == cfg.Catch {
+		return result
+	}
+	var collect func(*ast.Node)
+	collect = func(e *ast.Node) {
+		switch e.Kind {
+		case "anonymous_function", "arrow_function", "function_definition", "class_declaration":
+			return
+		}
+		if expressionKinds[e.Kind] && !hasSideEffects(e) {
+			access := &Access{}
+			access.expr(e)
+			result[ExpressionText(e)] = access.UsedNames()
+		}
+		for _, child := range e.NamedChildren() {
+			collect(child)
+		}
+	}
+	collect(n.AST)
+	return result
+}
+
+func hasSideEffects(n *ast.Node) bool {
+	switch n.Kind {
+	case "assignment_expression", "augmented_assignment_expression", "reference_assignment_expression",
+		"update_expression", "function_call_expression", "member_call_expression", "scoped_call_expression",
+		"object_creation_expression", "include_expression", "require_expression", "include_once_expression",
+		"require_once_expression", "shell_command_expression":
+		return true
+	}
+	for _, child := range n.NamedChildren() {
+		if hasSideEffects(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AvailableExpressions) Name() string {
+	return "available-expressions"
+}
+
+func (a *AvailableExpressions) Direction() Direction {
+	return Forward
+}
+
+func (a *AvailableExpressions) Boundary(*cfg.Graph) Set {
+	return NewSet()
+}
+
+// Initial is every expression of the graph, as available expressions is a must analysis
+func (a *AvailableExpressions) Initial(*cfg.Graph) Set {
+	s := NewSet()
+	for expression := range a.universe {
+		s[expression] = struct{}{}
+	}
+	return s
+}
+
+func (a *AvailableExpressions) Transfer(n *cfg.Node, in Set) Set {
+	out := in.Copy()
+	for expression := range expressions(n) {
+		out[expression] = struct{}{}
+	}
+	for _, def := range Accesses(n).Defs {
+		for expression, variables := range a.universe {
+			for _, variable := range variables {
+				if variable == def.Name {
+					delete(out, expression)
+					break
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package dataflow
+
+import (
+	"encoding/json"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type Direction int
+
+const (
+	Forward Direction = iota
+	Backward
+)
+
+func (d Direction) String() string {
+	if d == Backward {
+		return "backward"
+	}
+	return "forward"
+}
+
+// Lattice is the domain of the facts F computed by an analysis
+type Lattice[F any] interface {
+	// Join combines the facts flowing into a node from several edges
+	Join(a, b F) F
+	Equal(a, b F) bool
+}
+
+// Analysis is a monotone dataflow analysis over a control flow graph
+type Analysis[F any] interface {
+	Lattice[F]
+	Name() string
+	Direction() Direction
+	// Boundary is the fact at the entry of the graph for a forward analysis, at its exit for a backward one
+	Boundary(g *cfg.Graph) F
+	// Initial is the fact every other node starts from: the bottom for a may analysis, the top for a must one
+	Initial(g *cfg.Graph) F
+	// Transfer computes the fact after a node from the fact before it, in the direction of the analysis.
+	// It must not modify its input.
+	Transfer(n *cfg.Node, fact F) F
+}
+
+// Result holds the facts before (In) and after (Out) each CFG node, in execution order,
+// whatever the direction of the analysis
+type Result[F any] struct {
+	Analysis Analysis[F]
+	Graph    *cfg.Graph
+	In       map[*cfg.Node]F
+	Out      map[*cfg.Node]F
+}
+
+// Solve computes the fixpoint of an analysis on a graph with a worklist algorithm
+func Solve[F any](g *cfg.Graph, a Analysis[F]) *Result[F] {
+	r := &Result[F]{
+		Analysis: a,
+		Graph:    g,
+		In:       make(map[*cfg.Node]F),
+		Out:      make(map[*cfg.Node]F),
+	}
+	// before and after are In and Out for a forward analysis, Out and In for a backward one
+	before, after := r.In, r.Out
+	start := g.Entry
+	sources := func(n *cfg.Node) []*cfg.Node { return n.Preds }
+	targets := func(n *cfg.Node) []*cfg.Node { return n.Succs }
+	if a.Direction() == Backward {
+		before, after = r.Out, r.In
+		start = g.Exit
+		sources, targets = targets, sources
+	}
+
+	worklist := make([]*cfg.Node, 0, len(g.Nodes))
+	queued := make(map[*cfg.Node]bool)
+	for _, n := range g.Nodes {
+		before[n] = a.Initial(g)
+		after[n] = a.Initial(g)
+	}
+	// Visiting nodes in creation order (reversed for backward analyses) follows the flow in most cases
+	for i := range g.Nodes {
+		n := g.Nodes[i]
+		if a.Direction() == Backward {
+			n = g.Nodes[len(g.Nodes)-1-i]
+		}
+		worklist = append(worklist, n)
+		queued[n] = true
+	}
+
+	for len(worklist) > 0 {
+		n := worklist[0]
+		worklist = worklist[1:]
+		queued[n] = false
+
+		var fact F
+		if n == start {
+			fact = a.Boundary(g)
+		} else if preds := sources(n); len(preds) > 0 {
+			fact = after[preds[0]]
+			for _, pred := range preds[1:] {
+				fact = a.Join(fact, after[pred])
+			}
+		} else {
+			fact = a.Initial(g)
+		}
+		before[n] = fact
+		out := a.Transfer(n, fact)
+		if a.Equal(out, after[n]) {
+			continue
+		}
+		after[n] = out
+		for _, succ := range targets(n) {
+			if !queued[succ] {
+				queued[succ] = true
+				worklist = append(worklist, succ)
+			}
+		}
+	}
+	return r
+}
+
+// At returns the facts before and after the CFG node evaluating an AST node
+func (r *Result[F]) At(n *ast.Node) (in F, out F, ok bool) {
+	node := r.Graph.NodeOf(n)
+	if node == nil {
+		return in, out, false
+	}
+	return r.In[node], r.Out[node], true
+}
+
+type nodeJSON struct {
+	ID    int    `json:"id"`
+	Kind  string `json:"kind"`
+	Line  uint   `json:"line"`
+	Text  string `json:"text"`
+	Succs []int  `json:"succs"`
+	In    any    `json:"in"`
+	Out   any    `json:"out"`
+}
+
+type resultJSON struct {
+	Analysis  string     `json:"analysis"`
+	Direction string     `json:"direction"`
+	Function  string     `json:"function"`
+	Line      uint       `json:"line"`
+	Nodes     []nodeJSON `json:"nodes"`
+}
+
+// MarshalJSON exports the facts of every node of the graph
+func (r *Result[F]) MarshalJSON() ([]byte, error) {
+	out := resultJSON{
+		Analysis:  r.Analysis.Name(),
+		Direction: r.Analysis.Direction().String(),
+		Function:  r.Graph.Name,
+		Line:      r.Graph.Function.StartPosition.Row + 1,
+	}
+	for _, n := range r.Graph.Nodes {
+		succs := []int{}
+		for _, succ := range n.Succs {
+			succs = append(succs, succ.ID)
+		}
+		text := ""
+		if n.AST != nil {
+			text = n.AST.Text
+		}
+		out.Nodes = append(out.Nodes, nodeJSON{
+			ID:    n.ID,
+			Kind:  string(n.Kind),
+			Line:  n.Line(),
+			Text:  text,
+			Succs: succs,
+			In:    r.In[n],
+			Out:   r.Out[n],
+		})
+	}
+	return json.Marshal(out)
+}
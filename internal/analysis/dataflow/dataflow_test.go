@@ -0,0 +1,144 @@
+package dataflow
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// nodeAt returns the first node of a graph evaluating a statement or expression on a line
+func nodeAt(t *testing.T, g *cfg.Graph, line uint) *cfg.Node {
+	t.Helper()
+	for _, n := range g.Nodes {
+		if n.AST != nil && n.Line() == line {
+			return n
+		}
+	}
+	t.Fatalf("no node on line %d", line)
+	return nil
+}
+
+// mainGraph builds the graph of the last function of a source, or of the program without function
+func mainGraph(source string) *cfg.Graph {
+	graphs := cfg.BuildAll(ast.ParseSource([]byte(source)))
+	return graphs[len(graphs)-1]
+}
+
+func TestReachingDefinitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   uint
+		in     []string
+	}{
+		{
+			name:   "a strong definition kills the previous one",
+			source: "<?php\n$a = 1;\n$a = 2;\necho $a;",
+			line:   4, in: []string{"$a@3:1"},
+		},
+		{
+			name:   "both branches reach the join",
+			source: "<?php\nif ($c) {\n$a = 1;\n} else {\n$a = 2;\n}\necho $a;",
+			line:   7, in: []string{"$a@3:1", "$a@5:1"},
+		},
+		{
+			name:   "the loop definition reaches the condition",
+			source: "<?php\n$i = 0;\nwhile ($i < 3) {\n$i = $i + 1;\n}",
+			line:   3, in: []string{"$i@2:1", "$i@4:1"},
+		},
+		{
+			name:   "the default label defines nothing",
+			source: "<?php\nswitch ($c) {\ncase 1:\nbreak;\ndefault:\n$x = 5;\nbreak;\n}",
+			line:   6, in: []string{},
+		},
+		{
+			name:   "parameters are defined at the entry",
+			source: "<?php\nfunction f($p) {\necho $p;\n}",
+			line:   3, in: []string{"$p@2:12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := mainGraph(tt.source)
+			result := Solve[Set](g, ReachingDefinitions{})
+			if got := result.In[nodeAt(t, g, tt.line)].Sorted(); !slices.Equal(got, tt.in) {
+				t.Errorf("definitions reaching line %d = %v, want %v", tt.line, got, tt.in)
+			}
+		})
+	}
+}
+
+func TestLiveVariables(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   uint
+		out    []string
+	}{
+		{
+			name:   "a variable read later is live",
+			source: "<?php\n$a = 1;\n$b = 2;\necho $a;",
+			line:   2, out: []string{"a"},
+		},
+		{
+			name:   "a variable redefined before being read is dead",
+			source: "<?php\n$a = 1;\n$a = 2;\necho $a;",
+			line:   2, out: []string{},
+		},
+		{
+			name:   "a variable read in the loop is live at its end",
+			source: "<?php\n$i = 0;\nwhile ($i < 3) {\n$i = $i + 1;\n}",
+			line:   4, out: []string{"i"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := mainGraph(tt.source)
+			result := Solve[Set](g, LiveVariables{})
+			if got := result.Out[nodeAt(t, g, tt.line)].Sorted(); !slices.Equal(got, tt.out) {
+				t.Errorf("variables live after line %d = %v, want %v", tt.line, got, tt.out)
+			}
+		})
+	}
+}
+
+func TestAvailableExpressions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   uint
+		in     []string
+	}{
+		{
+			name:   "an expression computed before is available",
+			source: "<?php\n$x = $a + $b;\necho $a + $b;",
+			line:   3, in: []string{"$a + $b"},
+		},
+		{
+			name:   "redefining a variable invalidates the expression",
+			source: "<?php\n$x = $a + $b;\n$a = 1;\necho $a + $b;",
+			line:   4, in: []string{},
+		},
+		{
+			name:   "an expression computed on one branch only is not available",
+			source: "<?php\nif ($c) {\n$x = $a + $b;\n}\necho $a + $b;",
+			line:   5, in: []string{},
+		},
+		{
+			name:   "an expression computed on both branches is available",
+			source: "<?php\nif ($c) {\n$x = $a + $b;\n} else {\n$y = $a + $b;\n}\necho $a + $b;",
+			line:   7, in: []string{"$a + $b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := mainGraph(tt.source)
+			result := Solve[Set](g, NewAvailableExpressions(g))
+			if got := result.In[nodeAt(t, g, tt.line)].Sorted(); !slices.Equal(got, tt.in) {
+				t.Errorf("expressions available at line %d = %v, want %v", tt.line, got, tt.in)
+			}
+		})
+	}
+}
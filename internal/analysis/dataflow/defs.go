@@ -0,0 +1,220 @@
+package dataflow
+
+import (
+	"slices"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Def is a definition of a variable made by a CFG node. A strong definition overwrites the variable,
+// a weak one (array element write, reference) only updates it.
+type Def struct {
+	Name   string
+	Node   *ast.Node
+	Strong bool
+}
+
+// Access lists the variables defined and read by a CFG node
+type Access struct {
+	Defs []Def
+	Uses []*ast.Node
+}
+
+// UsedNames returns the names of the variables read by the node
+func (a *Access) UsedNames() []string {
+	var names []string
+	for _, use := range a.Uses {
+		if name := scope.VariableName(use); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Accesses returns the variables defined and read by a CFG node. Nested functions are not entered,
+// except for the variables they capture from the enclosing scope.
+func Accesses(n *cfg.Node) *Access {
+	a := &Access{}
+	if n.AST == nil {
+		return a
+	}
+	switch n.Kind {
+	case cfg.Foreach:
+		if n.AST.Kind == "pair" {
+			// In foreach, the key of a pair is also a binding
+			for _, part := range n.AST.NamedChildren() {
+				a.assign(part, true)
+			}
+			return a
+		}
+		a.assign(n.AST, true)
+		return a
+	case cfg.Catch:
+		if n.AST.Kind == "variable_name" {
+			a.define(n.AST, true)
+		}
+		return a
+	}
+	switch n.AST.Kind {
+	case "formal_parameters":
+		for _, parameter := range n.AST.NamedChildren() {
+			for _, part := range parameter.NamedChildren() {
+				if part.Kind == "variable_name" {
+					a.define(part, true)
+				} else {
+					a.expr(part)
+				}
+			}
+		}
+	case "anonymous_function_use_clause":
+		for _, captured := range n.AST.NamedChildren() {
+			if captured.Kind == "by_ref" {
+				captured = captured.ChildOfKind("variable_name")
+			}
+			if captured != nil && captured.Kind == "variable_name" {
+				a.define(captured, true)
+			}
+		}
+	case "global_declaration":
+		for _, variable := range n.AST.ChildrenOfKind("variable_name") {
+			a.define(variable, true)
+		}
+	case "function_static_declaration":
+		for _, declaration := range n.AST.ChildrenOfKind("static_variable_declaration") {
+			children := declaration.NamedChildren()
+			for _, value := range children[1:] {
+				a.expr(value)
+			}
+			a.define(children[0], true)
+		}
+	case "unset_statement":
+		for _, child := range n.AST.NamedChildren() {
+			if child.Kind == "variable_name" {
+				a.define(child, true)
+			} else {
+				a.expr(child)
+			}
+		}
+	default:
+		a.expr(n.AST)
+	}
+	return a
+}
+
+func (a *Access) define(n *ast.Node, strong bool) {
+	a.Defs = append(a.Defs, Def{Name: scope.VariableName(n), Node: n, Strong: strong})
+}
+
+func (a *Access) expr(n *ast.Node) {
+	switch n.Kind {
+	case "variable_name":
+		a.Uses = append(a.Uses, n)
+	case "assignment_expression", "reference_assignment_expression", "augmented_assignment_expression":
+		children := n.NamedChildren()
+		if len(children) < 2 {
+			return
+		}
+		source := children[len(children)-1]
+		a.expr(source)
+		if n.Kind == "reference_assignment_expression" && source.Kind == "variable_name" {
+			a.define(source, false)
+		}
+		if n.Kind == "augmented_assignment_expression" {
+			a.expr(children[0])
+		}
+		a.assign(children[0], true)
+	case "update_expression":
+		for _, child := range n.NamedChildren() {
+			a.expr(child)
+			a.assign(child, true)
+		}
+	case "scoped_property_access_expression":
+		if children := n.NamedChildren(); len(children) > 0 {
+			a.expr(children[0])
+		}
+	case "anonymous_function":
+		if clause := n.ChildOfKind("anonymous_function_use_clause"); clause != nil {
+			for _, captured := range clause.NamedChildren() {
+				if captured.Kind == "by_ref" {
+					captured = captured.ChildOfKind("variable_name")
+					if captured != nil {
+						a.define(captured, false)
+					}
+				}
+				if captured != nil && captured.Kind == "variable_name" {
+					a.Uses = append(a.Uses, captured)
+				}
+			}
+		}
+	case "arrow_function":
+		// Arrow functions read the variables of the enclosing scope that are not parameters
+		var parameters []string
+		if formal := n.ChildOfKind("formal_parameters"); formal != nil {
+			for _, parameter := range formal.NamedChildren() {
+				if variable := parameter.ChildOfKind("variable_name"); variable != nil {
+					parameters = append(parameters, scope.VariableName(variable))
+				}
+			}
+		}
+		children := n.NamedChildren()
+		body := &Access{}
+		body.expr(children[len(children)-1])
+		for _, use := range body.Uses {
+			if !slices.Contains(parameters, scope.VariableName(use)) {
+				a.Uses = append(a.Uses, use)
+			}
+		}
+	case "function_definition", "method_declaration", "class_declaration":
+	default:
+		for _, child := range n.NamedChildren() {
+			a.expr(child)
+		}
+	}
+}
+
+// assign records the definitions made by writing to the target of an assignment
+func (a *Access) assign(target *ast.Node, strong bool) {
+	switch target.Kind {
+	case "variable_name":
+		a.define(target, strong)
+	case "by_ref":
+		for _, child := range target.NamedChildren() {
+			a.assign(child, strong)
+		}
+	case "subscript_expression":
+		// Writing to an element reads and partially redefines the array
+		children := target.NamedChildren()
+		if len(children) == 0 {
+			return
+		}
+		for _, child := range children[1:] {
+			a.expr(child)
+		}
+		switch base := children[0]; base.Kind {
+		case "variable_name":
+			a.expr(base)
+			a.assign(base, false)
+		case "subscript_expression":
+			a.assign(base, false)
+		default:
+			a.expr(base)
+		}
+	case "list_literal", "array_creation_expression":
+		for _, element := range target.NamedChildren() {
+			a.assign(element, strong)
+		}
+	case "array_element_initializer", "pair":
+		children := target.NamedChildren()
+		if len(children) == 0 {
+			return
+		}
+		for _, key := range children[:len(children)-1] {
+			a.expr(key)
+		}
+		a.assign(children[len(children)-1], strong)
+	default:
+		a.expr(target)
+	}
+}
@@ -0,0 +1,96 @@
+package dataflow
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Set is a set of strings, the fact type of the built-in analyses
+type Set map[string]struct{}
+
+func NewSet(items ...string) Set {
+	s := make(Set, len(items))
+	for _, item := range items {
+		s[item] = struct{}{}
+	}
+	return s
+}
+
+func (s Set) Has(item string) bool {
+	_, ok := s[item]
+	return ok
+}
+
+func (s Set) Copy() Set {
+	result := make(Set, len(s))
+	for item := range s {
+		result[item] = struct{}{}
+	}
+	return result
+}
+
+func (s Set) Union(other Set) Set {
+	result := s.Copy()
+	for item := range other {
+		result[item] = struct{}{}
+	}
+	return result
+}
+
+func (s Set) Intersect(other Set) Set {
+	result := make(Set)
+	for item := range s {
+		if other.Has(item) {
+			result[item] = struct{}{}
+		}
+	}
+	return result
+}
+
+func (s Set) Equal(other Set) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for item := range s {
+		if !other.Has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sorted returns the items of the set in lexical order
+func (s Set) Sorted() []string {
+	items := make([]string, 0, len(s))
+	for item := range s {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
+
+func (s Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Sorted())
+}
+
+// unionLattice is the lattice of may analyses
+type unionLattice struct{}
+
+func (unionLattice) Join(a, b Set) Set {
+	return a.Union(b)
+}
+
+func (unionLattice) Equal(a, b Set) bool {
+	return a.Equal(b)
+}
+
+// intersectionLattice is the lattice of must analyses
+type intersectionLattice struct{}
+
+func (intersectionLattice) Join(a, b Set) Set {
+	return a.Intersect(b)
+}
+
+func (intersectionLattice) Equal(a, b Set) bool {
+	return a.Equal(b)
+}
@@ -0,0 +1,184 @@
+package deadcode
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of dead code of a finding
+type Category string
+
+const (
+	UnreferencedFunction Category = "unreferenced-function"
+	UnreferencedMethod   Category = "unreferenced-method"
+	UnreferencedClass    Category = "unreferenced-class"
+	Unreachable          Category = "unreachable"
+	UnusedVariable       Category = "unused-variable"
+	UnusedParameter      Category = "unused-parameter"
+	UnusedImport         Category = "unused-import"
+)
+
+// Categories are all the categories of dead code
+var Categories = []Category{
+	UnreferencedFunction, UnreferencedMethod, UnreferencedClass, Unreachable, UnusedVariable, UnusedParameter, UnusedImport,
+}
+
+// Options are the escape hatches for dynamic calls, which reference functions and methods
+// without naming them in a call expression
+type Options struct {
+	// StringCallables counts every string literal naming a function, method or class as a reference
+	StringCallables bool
+	// CallUserFunc counts the callables passed to call_user_func, array_map, usort and the other
+	// builtins taking callbacks as references
+	CallUserFunc bool
+	// WordPressHooks counts the callbacks registered with add_action, add_filter, add_shortcode and
+	// the other WordPress registration functions as references
+	WordPressHooks bool
+}
+
+// Finding is a piece of dead code
+type Finding struct {
+	Category Category  `json:"category"`
+	File     string    `json:"file"`
+	Line     uint      `json:"line"`
+	Name     string    `json:"name"`
+	Message  string    `json:"message"`
+	Node     *ast.Node `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the dead code of the files added to it
+type Analyzer struct {
+	options   Options
+	files     []*file
+	hierarchy *classes.Hierarchy
+	refs      *references
+}
+
+func New(options Options) *Analyzer {
+	return &Analyzer{
+		options:   options,
+		hierarchy: classes.New(),
+		refs:      newReferences(),
+	}
+}
+
+// AddFile collects the declarations and references of a file
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	a.files = append(a.files, &file{path: path, root: root})
+	a.hierarchy.AddFile(path, root)
+	a.refs.collect(root, a.options)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.hierarchy.Resolve()
+	var findings []*Finding
+	findings = append(findings, a.unreferencedClasses()...)
+	for _, f := range a.files {
+		findings = append(findings, a.unreferencedFunctions(f)...)
+		findings = append(findings, unreachable(f)...)
+		findings = append(findings, a.unusedVariables(f)...)
+		findings = append(findings, unusedImports(f)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func newFinding(category Category, path string, n *ast.Node, name, message string) *Finding {
+	return &Finding{Category: category, File: path, Line: n.StartPosition.Row + 1, Name: name, Message: message, Node: n}
+}
+
+// shortName returns the lowercase unqualified name of a possibly qualified name
+func shortName(name string) string {
+	name = strings.TrimSpace(name)
+	return strings.ToLower(name[strings.LastIndex(name, "\\")+1:])
+}
+
+// unreferencedClasses reports the classes never named outside of their declaration, and the
+// methods never called of the other classes
+func (a *Analyzer) unreferencedClasses() []*Finding {
+	var findings []*Finding
+	for _, c := range a.hierarchy.SortedClasses() {
+		if !a.refs.classes[shortName(c.Name)] {
+			findings = append(findings, newFinding(UnreferencedClass, c.File, c.Node, c.Name,
+				string(c.Kind)+" "+c.Name+" is never referenced"))
+			continue
+		}
+		if c.Kind == classes.InterfaceKind || a.hasExternalAncestor(c) {
+			// Methods may be called through a type unknown to the project
+			continue
+		}
+		for _, m := range c.SortedMethods() {
+			if m.Origin != "declared" || m.Declaration.Abstract || strings.HasPrefix(m.Name, "__") {
+				continue
+			}
+			if !a.refs.methods[strings.ToLower(m.Name)] {
+				findings = append(findings, newFinding(UnreferencedMethod, c.File, m.Declaration.Node, c.Name+"::"+m.Name,
+					"method "+c.Name+"::"+m.Name+" is never called"))
+			}
+		}
+	}
+	return findings
+}
+
+// hasExternalAncestor reports whether a class extends or implements a class-like declaration that
+// is not part of the project, such as ArrayAccess or a framework base class
+func (a *Analyzer) hasExternalAncestor(c *classes.Class) bool {
+	seen := make(map[*classes.Class]bool)
+	var visit func(*classes.Class) bool
+	visit = func(cur *classes.Class) bool {
+		if seen[cur] {
+			return false
+		}
+		seen[cur] = true
+		resolved := len(cur.Interfaces)
+		if cur.Parent != nil {
+			resolved++
+		}
+		if resolved < len(cur.Extends)+len(cur.Implements) {
+			return true
+		}
+		if cur.Parent != nil && visit(cur.Parent) {
+			return true
+		}
+		for _, i := range cur.Interfaces {
+			if visit(i) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(c)
+}
+
+// unreferencedFunctions reports the functions of a file that are never called
+func (a *Analyzer) unreferencedFunctions(f *file) []*Finding {
+	var findings []*Finding
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+	f.root.WalkPrefix(v)
+	for _, fn := range v.Nodes {
+		name := fn.ChildOfKind("name")
+		if name == nil || strings.HasPrefix(name.Text, "__") {
+			continue
+		}
+		if !a.refs.functions[strings.ToLower(name.Text)] {
+			findings = append(findings, newFinding(UnreferencedFunction, f.path, fn, name.Text,
+				"function "+name.Text+" is never called"))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,100 @@
+package deadcode
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		options  Options
+		category Category
+		lines    []uint
+	}{
+		{
+			name:     "class called like a function or method",
+			source:   "<?php\nclass Logger {}\n$o->Logger();\nLogger();\n$o->Logger;\n",
+			category: UnreferencedClass, lines: []uint{2},
+		},
+		{
+			name:     "class instantiated",
+			source:   "<?php\nclass Logger {}\n$l = new Logger();\n",
+			category: UnreferencedClass,
+		},
+		{
+			name:     "class extended and implemented",
+			source:   "<?php\ninterface I {}\nclass A {}\nclass B extends A implements I {}\nnew B();\n",
+			category: UnreferencedClass,
+		},
+		{
+			name:     "class in types, catch, instanceof and ::",
+			source:   "<?php\nclass T {}\nclass E {}\nclass I {}\nclass S {}\nfunction f(T $t) {\ntry {} catch (E $e) {}\nreturn $t instanceof I || S::X;\n}\nf(null);\n",
+			category: UnreferencedClass,
+		},
+		{
+			name:     "unreferenced function",
+			source:   "<?php\nfunction used() {}\nfunction unused() {}\nused();\n",
+			category: UnreferencedFunction, lines: []uint{3},
+		},
+		{
+			name:     "callback of call_user_func",
+			source:   "<?php\nfunction cb() {}\ncall_user_func('cb');\n",
+			options:  Options{CallUserFunc: true},
+			category: UnreferencedFunction,
+		},
+		{
+			name:     "after return",
+			source:   "<?php\nfunction f() {\nreturn 1;\necho 'a';\necho 'b';\n}\nf();\n",
+			category: Unreachable, lines: []uint{4},
+		},
+		{
+			name:     "after throw",
+			source:   "<?php\nfunction f() {\nthrow new Exception();\necho 'a';\n}\nf();\n",
+			category: Unreachable, lines: []uint{4},
+		},
+		{
+			name:     "after exit",
+			source:   "<?php\nexit(1);\necho 'a';\n",
+			category: Unreachable, lines: []uint{3},
+		},
+		{
+			name:     "reachable branches",
+			source:   "<?php\nif ($c) {\nreturn;\n}\necho 'a';\n",
+			category: Unreachable,
+		},
+		{
+			name:     "variable assigned but never read",
+			source:   "<?php\nfunction f() {\n$a = 1;\n$b = 2;\nreturn $b;\n}\nf();\n",
+			category: UnusedVariable, lines: []uint{3},
+		},
+		{
+			name:     "unused parameter",
+			source:   "<?php\nfunction f($a, $b) {\nreturn $b;\n}\nf(1, 2);\n",
+			category: UnusedParameter, lines: []uint{2},
+		},
+		{
+			name:     "unused import",
+			source:   "<?php\nnamespace App;\nuse Foo\\Bar;\nuse Foo\\Baz;\nnew Baz();\n",
+			category: UnusedImport, lines: []uint{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(tt.options)
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var lines []uint
+			for _, finding := range a.Analyze() {
+				if finding.Category == tt.category {
+					lines = append(lines, finding.Line)
+				}
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Errorf("%s lines = %v, want %v", tt.category, lines, tt.lines)
+			}
+		})
+	}
+}
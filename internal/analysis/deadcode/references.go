@@ -0,0 +1,150 @@
+package deadcode
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// callbackFunctions are the builtins taking callables as arguments
+var callbackFunctions = map[string]bool{
+	"call_user_func": true, "call_user_func_array": true, "forward_static_call": true,
+	"forward_static_call_array": true, "is_callable": true, "function_exists": true, "method_exists": true,
+	"array_map": true, "array_filter": true, "array_walk": true, "array_walk_recursive": true,
+	"array_reduce": true, "usort": true, "uasort": true, "uksort": true, "array_udiff": true,
+	"array_uintersect": true, "iterator_apply": true, "preg_replace_callback": true,
+	"register_shutdown_function": true, "register_tick_function": true, "spl_autoload_register": true,
+	"set_error_handler": true, "set_exception_handler": true, "ob_start": true, "header_register_callback": true,
+	"class_exists": true, "interface_exists": true, "trait_exists": true, "is_subclass_of": true, "is_a": true,
+}
+
+// hookFunctions are the WordPress functions registering callbacks
+var hookFunctions = map[string]bool{
+	"add_action": true, "add_filter": true, "remove_action": true, "remove_filter": true,
+	"has_action": true, "has_filter": true, "add_shortcode": true, "register_activation_hook": true,
+	"register_deactivation_hook": true, "register_uninstall_hook": true, "add_menu_page": true,
+	"add_submenu_page": true, "add_options_page": true, "add_management_page": true, "add_theme_page": true,
+	"add_meta_box": true, "add_settings_section": true, "add_settings_field": true, "register_setting": true,
+	"register_rest_route": true, "wp_register_sidebar_widget": true, "register_widget": true,
+	"add_dashboard_widget": true, "wp_add_dashboard_widget": true, "register_block_type": true,
+}
+
+// classNameParents are the nodes whose name children reference classes: new, extends, implements,
+// trait uses, attributes and types, the types of catch clauses included
+var classNameParents = map[string]bool{
+	"object_creation_expression": true, "base_clause": true, "class_interface_clause": true,
+	"use_declaration": true, "attribute": true, "named_type": true,
+}
+
+// scopedKinds are the nodes whose first child is the class before ::
+var scopedKinds = map[string]bool{
+	"scoped_call_expression": true, "class_constant_access_expression": true,
+	"scoped_property_access_expression": true,
+}
+
+// references holds the lowercase unqualified names of the symbols referenced in the project
+type references struct {
+	functions map[string]bool
+	methods   map[string]bool
+	classes   map[string]bool
+}
+
+func newReferences() *references {
+	return &references{
+		functions: make(map[string]bool),
+		methods:   make(map[string]bool),
+		classes:   make(map[string]bool),
+	}
+}
+
+func (r *references) collect(root *ast.Node, options Options) {
+	root.WalkPrefix(&referenceVisitor{refs: r, options: options})
+}
+
+type referenceVisitor struct {
+	refs    *references
+	options Options
+}
+
+func (v *referenceVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "function_call_expression":
+		children := n.NamedChildren()
+		if len(children) > 0 && (children[0].Kind == "name" || children[0].Kind == "qualified_name") {
+			name := shortName(children[0].Text)
+			v.refs.functions[name] = true
+			if v.options.CallUserFunc && callbackFunctions[name] || v.options.WordPressHooks && hookFunctions[name] {
+				for _, argument := range n.Arguments() {
+					v.refs.callable(argument)
+				}
+			}
+		}
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		if name := n.MemberName(); name != "" {
+			v.refs.methods[strings.ToLower(name)] = true
+		}
+	case "name", "qualified_name":
+		if classReference(n) {
+			v.refs.classes[shortName(n.Text)] = true
+		}
+	case "string", "encapsed_string", "heredoc", "nowdoc":
+		if v.options.StringCallables {
+			if value, ok := literal.String(n); ok {
+				v.refs.callableString(value)
+			}
+		}
+	}
+}
+
+// callable records the references of a callable expression: a string naming a function or a
+// static method, or an array holding an object or class and a method name. Arrays are searched
+// recursively, for the options arrays of register_rest_route and the like.
+func (r *references) callable(n *ast.Node) {
+	if value, ok := literal.String(n); ok {
+		r.callableString(value)
+		return
+	}
+	if n.Kind != "array_creation_expression" {
+		return
+	}
+	for _, element := range n.NamedChildren() {
+		if children := element.NamedChildren(); len(children) > 0 {
+			r.callable(children[len(children)-1])
+		}
+	}
+}
+
+func (r *references) callableString(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, " \t\n") {
+		return
+	}
+	if class, method, ok := strings.Cut(value, "::"); ok {
+		r.classes[shortName(class)] = true
+		r.methods[strings.ToLower(method)] = true
+		return
+	}
+	name := shortName(value)
+	r.functions[name] = true
+	r.methods[name] = true
+	r.classes[name] = true
+}
+
+// classReference tells whether a name is in a class position, as opposed to the names of
+// declarations, functions, methods, properties and constants
+func classReference(n *ast.Node) bool {
+	parent := n.Parent
+	switch {
+	case parent == nil:
+		return false
+	case classNameParents[parent.Kind]:
+		return true
+	case scopedKinds[parent.Kind]:
+		return parent.NamedChildren()[0] == n
+	case parent.Kind == "binary_expression" && parent.ChildOfKind("instanceof") != nil:
+		children := parent.NamedChildren()
+		return children[len(children)-1] == n
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package deadcode
+
+import (
+	"strconv"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/cfg"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// blockKinds are the nodes holding a list of statements
+var blockKinds = map[string]bool{
+	"program": true, "compound_statement": true, "colon_block": true,
+	"case_statement": true, "default_statement": true,
+}
+
+// unreachable reports the statements that control never reaches in the graphs of a file. Only the
+// first statement of a run of unreachable statements is reported.
+func unreachable(f *file) []*Finding {
+	var findings []*Finding
+	for _, g := range cfg.BuildAll(f.root) {
+		reachable := g.Reachable()
+		dead := make(map[*ast.Node]bool)
+		// statements keeps the dead statements in the order of the graph, for stable findings
+		var statements []*ast.Node
+		for _, n := range g.Nodes {
+			if reachable[n] || n.AST == nil {
+				continue
+			}
+			if statement := blockStatement(n.AST); statement != nil && !dead[statement] {
+				dead[statement] = true
+				statements = append(statements, statement)
+			}
+		}
+		for _, statement := range statements {
+			if inside(statement.Parent, dead) || dead[previousStatement(statement)] {
+				continue
+			}
+			message := "unreachable statement"
+			if previous := previousStatement(statement); previous != nil && terminates(previous) {
+				message = "unreachable statement after " + describe(previous) + " at line " + lineOf(previous)
+			}
+			findings = append(findings, newFinding(Unreachable, f.path, statement, g.Name, message))
+		}
+	}
+	return findings
+}
+
+// blockStatement returns the statement of a block containing a node
+func blockStatement(n *ast.Node) *ast.Node {
+	for cur := n; cur != nil && cur.Parent != nil; cur = cur.Parent {
+		if blockKinds[cur.Parent.Kind] {
+			switch cur.Kind {
+			case "text_interpolation", "php_tag", "comment", "empty_statement":
+				return nil
+			}
+			return cur
+		}
+	}
+	return nil
+}
+
+func previousStatement(n *ast.Node) *ast.Node {
+	if n.Parent == nil {
+		return nil
+	}
+	var previous *ast.Node
+	for _, sibling := range n.Parent.NamedChildren() {
+		if sibling == n {
+			return previous
+		}
+		if sibling.Kind != "comment" {
+			previous = sibling
+		}
+	}
+	return nil
+}
+
+func terminates(n *ast.Node) bool {
+	switch n.Kind {
+	case "return_statement", "exit_statement", "break_statement", "continue_statement", "goto_statement":
+		return true
+	case "expression_statement":
+		return scope.IsTerminating(n)
+	}
+	return false
+}
+
+func describe(n *ast.Node) string {
+	switch n.Kind {
+	case "return_statement":
+		return "return"
+	case "exit_statement":
+		return "exit"
+	case "break_statement":
+		return "break"
+	case "continue_statement":
+		return "continue"
+	case "goto_statement":
+		return "goto"
+	}
+	if children := n.NamedChildren(); len(children) > 0 && children[0].Kind == "throw_expression" {
+		return "throw"
+	}
+	return "exit"
+}
+
+func lineOf(n *ast.Node) string {
+	return strconv.FormatUint(uint64(n.StartPosition.Row+1), 10)
+}
+
+// inside reports whether a node or one of its ancestors is in the set
+func inside(n *ast.Node, set map[*ast.Node]bool) bool {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if set[cur] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package deadcode
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// dynamicFunctions access the local variables without naming them
+var dynamicFunctions = map[string]bool{
+	"compact": true, "extract": true, "get_defined_vars": true, "eval": true, "parse_str": true,
+	"func_get_args": true, "func_get_arg": true, "func_num_args": true,
+}
+
+// unusedVariables reports the local variables that are written but never read and the parameters
+// that are never read, in the functions of a file. Functions accessing their variables dynamically
+// are skipped, as are the parameters whose signature is imposed by a parent class, an interface
+// or a callback.
+func (a *Analyzer) unusedVariables(f *file) []*Finding {
+	var findings []*Finding
+	scope.Analyze(f.root).Walk(func(s *scope.Scope) {
+		if s.Node.Kind == "program" || dynamic(s.Node) {
+			// Variables of the file scope may be read by other files
+			return
+		}
+		checkParameters := a.parametersChecked(s.Node)
+		for _, v := range s.SortedVariables() {
+			if len(v.Uses) > 0 || v.ByRef || len(v.Defs) == 0 || strings.HasPrefix(v.Name, "_") {
+				continue
+			}
+			def := v.Defs[0]
+			switch {
+			case v.Kind == scope.Local:
+				findings = append(findings, newFinding(UnusedVariable, f.path, def, "$"+v.Name,
+					"variable $"+v.Name+" in "+s.Name+" is assigned but never used"))
+			case v.Kind == scope.Parameter && checkParameters:
+				if def.Parent != nil && def.Parent.Kind == "property_promotion_parameter" {
+					continue
+				}
+				findings = append(findings, newFinding(UnusedParameter, f.path, def, "$"+v.Name,
+					"parameter $"+v.Name+" of "+s.Name+" is never used"))
+			}
+		}
+	})
+	return findings
+}
+
+// dynamic reports whether a function accesses its variables through compact, extract, variable
+// variables, eval or include
+func dynamic(fn *ast.Node) bool {
+	found := false
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		if found {
+			return
+		}
+		switch n.Kind {
+		case "dynamic_variable_name", "include_expression", "include_once_expression",
+			"require_expression", "require_once_expression":
+			found = true
+			return
+		case "function_call_expression":
+			if name := n.ChildOfKind("name"); name != nil && dynamicFunctions[strings.ToLower(name.Text)] {
+				found = true
+				return
+			}
+		}
+		for _, child := range n.NamedChildren() {
+			if child.Kind != "anonymous_function" && child.Kind != "function_definition" && !strings.HasSuffix(child.Kind, "_declaration") {
+				walk(child)
+			}
+		}
+	}
+	for _, child := range fn.NamedChildren() {
+		walk(child)
+	}
+	return found
+}
+
+// parametersChecked reports whether the unused parameters of a function are reported: closures
+// usually implement a callback signature, and methods may override or implement another one
+func (a *Analyzer) parametersChecked(fn *ast.Node) bool {
+	switch fn.Kind {
+	case "anonymous_function", "arrow_function":
+		return false
+	case "function_definition":
+		return true
+	}
+	name := fn.ChildOfKind("name")
+	c := a.hierarchy.EnclosingClass(fn)
+	if name == nil || c == nil || c.Kind != classes.ClassKind && c.Kind != classes.EnumKind || a.hasExternalAncestor(c) {
+		return false
+	}
+	if fn.ChildOfKind("compound_statement") == nil {
+		// Abstract method
+		return false
+	}
+	ancestors := append([]*classes.Class{}, c.Interfaces...)
+	if c.Parent != nil {
+		ancestors = append(ancestors, c.Parent)
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.Method(name.Text) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+var words = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// unusedImports reports the names imported with use that are never referenced in a file.
+// Names in comments count as references, for the types of PHPDoc tags.
+func unusedImports(f *file) []*Finding {
+	used := make(map[string]bool)
+	var imports []*ast.Node
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		switch n.Kind {
+		case "namespace_use_declaration":
+			imports = append(imports, n)
+			return
+		case "comment":
+			for _, word := range words.FindAllString(n.Text, -1) {
+				used[strings.ToLower(word)] = true
+			}
+			return
+		case "name", "qualified_name":
+			if n.Parent != nil && !slices.Contains([]string{"namespace_definition", "namespace_name"}, n.Parent.Kind) {
+				first, _, _ := strings.Cut(strings.TrimPrefix(n.Text, "\\"), "\\")
+				used[strings.ToLower(strings.TrimSpace(first))] = true
+			}
+		}
+		for _, child := range n.Descendants {
+			walk(child)
+		}
+	}
+	walk(f.root)
+
+	var findings []*Finding
+	for _, declaration := range imports {
+		for _, clause := range importClauses(declaration) {
+			children := clause.NamedChildren()
+			if len(children) == 0 {
+				continue
+			}
+			imported := strings.TrimPrefix(strings.Join(strings.Fields(children[0].Text), ""), "\\")
+			alias := imported[strings.LastIndex(imported, "\\")+1:]
+			if len(children) > 1 {
+				alias = children[len(children)-1].Text
+			}
+			if !used[strings.ToLower(alias)] {
+				findings = append(findings, newFinding(UnusedImport, f.path, clause, imported,
+					"import "+imported+" is never used"))
+			}
+		}
+	}
+	return findings
+}
+
+func importClauses(declaration *ast.Node) []*ast.Node {
+	clauses := declaration.ChildrenOfKind("namespace_use_clause")
+	if group := declaration.ChildOfKind("namespace_use_group"); group != nil {
+		clauses = append(clauses, group.ChildrenOfKind("namespace_use_clause")...)
+		clauses = append(clauses, group.ChildrenOfKind("namespace_use_group_clause")...)
+	}
+	return clauses
+}
@@ -0,0 +1,124 @@
+package deobfuscate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// DefaultMaxLayers is the number of nested payloads decoded by default
+const DefaultMaxLayers = 8
+
+// sinks maps the functions evaluating code to the index of the code
+var sinks = map[string]int{"eval": 0, "assert": 0, "create_function": 1}
+
+// Layer is a payload decoded from the code evaluated by a sink, and the payloads it evaluates in turn
+type Layer struct {
+	// Sink is the function evaluating the payload
+	Sink string `json:"sink"`
+	// Line is the line of the sink, in the file for the outer layer or in the enclosing payload
+	Line uint `json:"line"`
+	// Depth is 1 for the payloads of the file, 2 for the payloads they evaluate, and so on
+	Depth int `json:"depth"`
+	// Functions are the decoding functions folded, innermost first
+	Functions []string `json:"functions"`
+	// Code is the decoded payload
+	Code string `json:"code"`
+	// Printed is the payload pretty printed, empty if it did not parse
+	Printed string    `json:"printed,omitempty"`
+	Layers  []*Layer  `json:"layers,omitempty"`
+	Node    *ast.Node `json:"-"`
+	// Tree is the parsed payload
+	Tree *ast.Node `json:"-"`
+}
+
+// Result is the payloads decoded in a file
+type Result struct {
+	File   string   `json:"file"`
+	Layers []*Layer `json:"layers"`
+}
+
+// Deobfuscator statically decodes the payloads evaluated by eval, assert and create_function. The
+// code is folded when it is made of literals and the pure decoding functions, such as
+// base64_decode, gzinflate, str_rot13, strrev and chr, and the decoded payload is parsed again to
+// decode the payloads it evaluates, up to MaxLayers. No PHP is executed.
+type Deobfuscator struct {
+	MaxLayers int
+}
+
+func New() *Deobfuscator {
+	return &Deobfuscator{MaxLayers: DefaultMaxLayers}
+}
+
+// Deobfuscate returns the payloads decoded in a file
+func (d *Deobfuscator) Deobfuscate(path string, root *ast.Node) *Result {
+	return &Result{File: path, Layers: d.layers(root, 1)}
+}
+
+// layers decodes the payloads evaluated in a tree, in source order
+func (d *Deobfuscator) layers(root *ast.Node, depth int) []*Layer {
+	if depth > d.MaxLayers {
+		return nil
+	}
+	root.SetParents()
+	if _, ok := root.GetAttribute(scope.AttributeKey).(*scope.Scope); !ok {
+		scope.Analyze(root)
+	}
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true}}
+	root.WalkPrefix(v)
+	sort.SliceStable(v.Nodes, func(i, j int) bool {
+		return v.Nodes[i].StartByte < v.Nodes[j].StartByte
+	})
+	layers := []*Layer{}
+	for _, call := range v.Nodes {
+		if layer := d.layer(call, depth); layer != nil {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+// layer decodes the code of a sink, nil if it is not a sink or its code is not folded by at least
+// one decoding function
+func (d *Deobfuscator) layer(call *ast.Node, depth int) *Layer {
+	callee := call.NamedChildren()[0]
+	name := strings.ToLower(strings.TrimPrefix(callee.Text, `\`))
+	index, ok := sinks[name]
+	arguments := call.Arguments()
+	if callee.Kind != "name" && callee.Kind != "qualified_name" || !ok || index >= len(arguments) {
+		return nil
+	}
+	f := &folder{}
+	code, ok := f.fold(arguments[index])
+	if !ok || len(f.applied) == 0 {
+		return nil
+	}
+	layer := &Layer{
+		Sink:      name,
+		Line:      call.StartPosition.Row + 1,
+		Depth:     depth,
+		Functions: f.applied,
+		Code:      code.String(),
+		Node:      call,
+	}
+	// Evaluated code starts in PHP mode, without an opening tag
+	source := strings.TrimSpace(layer.Code)
+	if !strings.HasPrefix(source, "<?") {
+		source = "<?php " + source
+	}
+	tree := ast.ParseSource([]byte(source))
+	if tree == nil {
+		return layer
+	}
+	layer.Tree = tree
+	// The pretty printer cannot render syntax errors
+	if !tree.HasError {
+		printer := ast.NewPrettyPrintVisitor()
+		tree.WalkPostfix(printer)
+		layer.Printed = printer.Print()
+	}
+	layer.Layers = d.layers(tree, depth+1)
+	return layer
+}
@@ -0,0 +1,66 @@
+package deobfuscate
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestDeobfuscate(t *testing.T) {
+	inner := "system($_GET['c']);"
+	encoded := base64.StdEncoding.EncodeToString([]byte(inner))
+	nested := base64.StdEncoding.EncodeToString([]byte("eval(base64_decode('" + encoded + "'));"))
+	tests := []struct {
+		name   string
+		source string
+		// codes are the decoded payloads, outermost first
+		codes     []string
+		functions []string
+	}{
+		{
+			name:      "base64 payload",
+			source:    "<?php eval(base64_decode('" + encoded + "'));",
+			codes:     []string{inner},
+			functions: []string{"base64_decode"},
+		},
+		{
+			name:      "payload evaluating another payload",
+			source:    "<?php eval(base64_decode('" + nested + "'));",
+			codes:     []string{"eval(base64_decode('" + encoded + "'));", inner},
+			functions: []string{"base64_decode"},
+		},
+		{
+			name:      "payload built from variables, reversed and rotated",
+			source:    "<?php $a = strrev(';)]\\'c\\'[GFBC_$(gerffn');\neval(str_rot13($a));",
+			codes:     []string{"assert($_POST['p']);"},
+			functions: []string{"strrev", "str_rot13"},
+		},
+		{
+			name:   "request data is not decoded",
+			source: "<?php eval($_POST['p']);",
+			codes:  []string{},
+		},
+		{
+			name:   "plain code is not a payload",
+			source: "<?php eval('return 1;');",
+			codes:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := New().Deobfuscate("test.php", ast.ParseSource([]byte(tt.source)))
+			codes := []string{}
+			for layers := result.Layers; len(layers) > 0; layers = layers[0].Layers {
+				codes = append(codes, layers[0].Code)
+			}
+			if !slices.Equal(codes, tt.codes) {
+				t.Fatalf("payloads = %q, want %q", codes, tt.codes)
+			}
+			if len(codes) > 0 && !slices.Equal(result.Layers[0].Functions, tt.functions) {
+				t.Errorf("functions = %v, want %v", result.Layers[0].Functions, tt.functions)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package deobfuscate
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// maxDecoded bounds the size of a decompressed string, against compression bombs
+const maxDecoded = 16 << 20
+
+// value is a folded string or integer
+type value struct {
+	text    string
+	integer int64
+	isInt   bool
+}
+
+func (v value) String() string {
+	if v.isInt {
+		return strconv.FormatInt(v.integer, 10)
+	}
+	return v.text
+}
+
+// functions are the pure functions folded over literal arguments. They decode strings, never
+// execute them.
+var functions = map[string]func(arguments []value) (value, bool){
+	"base64_decode": func(a []value) (value, bool) { return text(base64Decode(a[0].String())) },
+	"gzinflate": func(a []value) (value, bool) {
+		return decompress(flate.NewReader(strings.NewReader(a[0].String())), nil)
+	},
+	"gzuncompress": func(a []value) (value, bool) {
+		return decompress(zlib.NewReader(strings.NewReader(a[0].String())))
+	},
+	"gzdecode": func(a []value) (value, bool) {
+		return decompress(gzip.NewReader(strings.NewReader(a[0].String())))
+	},
+	"str_rot13": func(a []value) (value, bool) { return value{text: rot13(a[0].String())}, true },
+	"strrev": func(a []value) (value, bool) {
+		b := []byte(a[0].String())
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return value{text: string(b)}, true
+	},
+	"hex2bin": func(a []value) (value, bool) {
+		b, err := hex.DecodeString(a[0].String())
+		return value{text: string(b)}, err == nil
+	},
+	"urldecode": func(a []value) (value, bool) {
+		s, err := url.QueryUnescape(a[0].String())
+		return value{text: s}, err == nil
+	},
+	"rawurldecode": func(a []value) (value, bool) {
+		s, err := url.PathUnescape(a[0].String())
+		return value{text: s}, err == nil
+	},
+	"chr": func(a []value) (value, bool) {
+		i, ok := a[0].int()
+		return value{text: string([]byte{byte(((i % 256) + 256) % 256)})}, ok
+	},
+	"ord": func(a []value) (value, bool) {
+		s := a[0].String()
+		if s == "" {
+			return value{isInt: true}, true
+		}
+		return value{integer: int64(s[0]), isInt: true}, true
+	},
+	"strtolower": func(a []value) (value, bool) { return value{text: strings.ToLower(a[0].String())}, true },
+	"strtoupper": func(a []value) (value, bool) { return value{text: strings.ToUpper(a[0].String())}, true },
+	"trim":       func(a []value) (value, bool) { return value{text: strings.TrimSpace(a[0].String())}, true },
+}
+
+func (v value) int() (int64, bool) {
+	if v.isInt {
+		return v.integer, true
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(v.text), 10, 64)
+	return i, err == nil
+}
+
+func text(s string, ok bool) (value, bool) {
+	return value{text: s}, ok
+}
+
+// folder folds an expression to a constant, recording the functions applied
+type folder struct {
+	applied []string
+	depth   int
+}
+
+// fold returns the constant value of an expression made of literals, concatenations, integer
+// arithmetic, the variables assigned such expressions and the calls of the pure functions
+func (f *folder) fold(n *ast.Node) (value, bool) {
+	if f.depth > 64 {
+		return value{}, false
+	}
+	f.depth++
+	defer func() { f.depth-- }()
+	children := n.NamedChildren()
+	switch n.Kind {
+	case "string", "encapsed_string", "heredoc", "nowdoc":
+		s, ok := literal.String(n)
+		if ok && hexEscaped(n) {
+			f.applied = append(f.applied, "hex escapes")
+		}
+		return value{text: s}, ok
+	case "integer":
+		i, err := strconv.ParseInt(strings.ReplaceAll(n.Text, "_", ""), 0, 64)
+		return value{integer: i, isInt: true}, err == nil
+	case "parenthesized_expression", "argument":
+		if len(children) == 1 {
+			return f.fold(children[0])
+		}
+	case "binary_expression":
+		if len(children) != 2 {
+			return value{}, false
+		}
+		left, ok := f.fold(children[0])
+		if !ok {
+			return value{}, false
+		}
+		right, ok := f.fold(children[1])
+		if !ok {
+			return value{}, false
+		}
+		return arithmetic(operator(n), left, right)
+	case "variable_name":
+		return f.variable(n)
+	case "function_call_expression":
+		name := strings.ToLower(strings.TrimPrefix(children[0].Text, `\`))
+		function, ok := functions[name]
+		if children[0].Kind != "name" && children[0].Kind != "qualified_name" || !ok {
+			return value{}, false
+		}
+		var arguments []value
+		for _, argument := range n.Arguments() {
+			v, ok := f.fold(argument)
+			if !ok {
+				return value{}, false
+			}
+			arguments = append(arguments, v)
+		}
+		if len(arguments) == 0 {
+			return value{}, false
+		}
+		v, ok := function(arguments)
+		if ok {
+			f.applied = append(f.applied, name)
+		}
+		return v, ok
+	}
+	return value{}, false
+}
+
+// variable folds a variable with a single definition assigning a constant
+func (f *folder) variable(n *ast.Node) (value, bool) {
+	defs, _ := scope.DefsOf(n)
+	if len(defs) != 1 || defs[0].Parent == nil || defs[0].Parent.Kind != "assignment_expression" {
+		return value{}, false
+	}
+	values := defs[0].Parent.NamedChildren()
+	if len(values) < 2 || values[0] != defs[0] {
+		return value{}, false
+	}
+	return f.fold(values[len(values)-1])
+}
+
+// hexEscaped reports whether a double quoted string spells bytes with hexadecimal or octal escapes
+func hexEscaped(n *ast.Node) bool {
+	if n.Kind != "encapsed_string" && n.Kind != "heredoc" {
+		return false
+	}
+	for _, part := range literal.Parts(n) {
+		if part.Kind == "escape_sequence" && len(part.Text) > 1 && (part.Text[1] == 'x' || part.Text[1] >= '0' && part.Text[1] <= '7') {
+			return true
+		}
+	}
+	return false
+}
+
+func arithmetic(op string, left, right value) (value, bool) {
+	if op == "." {
+		return value{text: left.String() + right.String()}, true
+	}
+	l, ok := left.int()
+	if !ok {
+		return value{}, false
+	}
+	r, ok := right.int()
+	if !ok {
+		return value{}, false
+	}
+	switch op {
+	case "+":
+		return value{integer: l + r, isInt: true}, true
+	case "-":
+		return value{integer: l - r, isInt: true}, true
+	case "*":
+		return value{integer: l * r, isInt: true}, true
+	case "^":
+		return value{integer: l ^ r, isInt: true}, true
+	case "%":
+		if r != 0 {
+			return value{integer: l % r, isInt: true}, true
+		}
+	}
+	return value{}, false
+}
+
+// base64Decode decodes like PHP without strict mode, skipping the bytes outside the alphabet
+func base64Decode(s string) (string, bool) {
+	var clean strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' {
+			clean.WriteByte(c)
+		}
+	}
+	data := clean.String()
+	if len(data)%4 == 1 {
+		data = data[:len(data)-1]
+	}
+	b, err := base64.RawStdEncoding.DecodeString(data)
+	return string(b), err == nil
+}
+
+func decompress(r io.Reader, err error) (value, bool) {
+	if err != nil {
+		return value{}, false
+	}
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, io.LimitReader(r, maxDecoded)); err != nil {
+		return value{}, false
+	}
+	return value{text: out.String()}, true
+}
+
+func rot13(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b[i] = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			b[i] = 'A' + (c-'A'+13)%26
+		}
+	}
+	return string(b)
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
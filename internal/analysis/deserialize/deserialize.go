@@ -0,0 +1,448 @@
+package deserialize
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// UnsafeUnserialize is request data reaching unserialize
+	UnsafeUnserialize Category = "unsafe-unserialize"
+	// PharDeserialization is request data choosing the path of a file operation, which
+	// deserializes the metadata of phar:// archives
+	PharDeserialization Category = "phar-deserialization"
+)
+
+// sinks are the kind trees of the dangerous calls the gadget chains end in, see sinks.kt.json
+//
+//go:embed sinks.kt.json
+var sinks []byte
+
+// pharFunctions are the file functions opening their first argument through the stream wrappers
+var pharFunctions = map[string]bool{
+	"file_exists": true, "is_file": true, "is_dir": true, "is_link": true, "is_readable": true,
+	"is_writable": true, "is_executable": true, "file_get_contents": true, "file": true, "fopen": true,
+	"readfile": true, "filesize": true, "filemtime": true, "fileatime": true, "filectime": true,
+	"fileowner": true, "fileperms": true, "stat": true, "lstat": true, "md5_file": true, "sha1_file": true,
+	"hash_file": true, "getimagesize": true, "exif_read_data": true, "copy": true, "unlink": true,
+	"rename": true, "touch": true, "parse_ini_file": true, "opendir": true, "scandir": true,
+	"file_put_contents": true, "mkdir": true, "rmdir": true,
+}
+
+// triggers are the magic methods run on a deserialized object without any call from the application
+var triggers = []string{"__wakeup", "__unserialize", "__destruct"}
+
+// maxChainLength bounds the methods of a gadget chain
+const maxChainLength = 5
+
+// maxChains bounds the chains reported from each magic method
+const maxChains = 20
+
+// Finding is request data reaching a deserialization
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	Sink     string         `json:"sink"`
+	File     string         `json:"file"`
+	Line     uint           `json:"line"`
+	Message  string         `json:"message"`
+	Taint    *taint.Taint   `json:"taint"`
+	Node     *ast.Node      `json:"-"`
+}
+
+// Gadget is a method of a chain, run on an object of a class
+type Gadget struct {
+	// Class is the class of the object, which may inherit the method
+	Class  string `json:"class"`
+	Method string `json:"method"`
+	File   string `json:"file"`
+	Line   uint   `json:"line"`
+	// Via is how the previous gadget reaches this one: call, property-call, magic-call or to-string
+	Via string `json:"via,omitempty"`
+}
+
+// Chain is a candidate POP chain: from a magic method run on deserialization, through methods
+// of objects whose properties the attacker chooses, to a dangerous call fed with those properties
+type Chain struct {
+	Gadgets []*Gadget `json:"gadgets"`
+	// Sink is the kind of the dangerous call: command, code, file-write, file-read, include, sql...
+	Sink     string    `json:"sink"`
+	SinkText string    `json:"sink_text"`
+	File     string    `json:"file"`
+	Line     uint      `json:"line"`
+	Node     *ast.Node `json:"-"`
+}
+
+// Result holds the deserializations reachable with request data and the gadget chains of the project
+type Result struct {
+	Findings []*Finding `json:"findings"`
+	Chains   []*Chain   `json:"chains"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the object injections of a project: the unserialize calls and phar:// file
+// operations reachable with request data, and the gadget chains an injected object may run
+type Analyzer struct {
+	taint *taint.Analyzer
+	files []*file
+	// sinks maps the methods and functions to the dangerous calls they contain, by kind
+	sinks map[*ast.Node][]sink
+	// hierarchy is the class hierarchy of the project
+	hierarchy *classes.Hierarchy
+}
+
+type sink struct {
+	kind string
+	node *ast.Node
+}
+
+func New() *Analyzer {
+	return &Analyzer{taint: taint.New(), sinks: make(map[*ast.Node][]sink)}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.taint.AddFile(path, root)
+}
+
+// Analyze returns the findings and chains of all the files, ordered by file and line
+func (a *Analyzer) Analyze() (*Result, error) {
+	var kindTrees map[string]ast.KindTree
+	if err := json.Unmarshal(sinks, &kindTrees); err != nil {
+		return nil, err
+	}
+	a.taint.Resolve()
+	a.hierarchy = a.taint.Inferer().Hierarchy()
+	result := &Result{Findings: []*Finding{}, Chains: []*Chain{}}
+	for _, f := range a.files {
+		matches := &ast.VisitorFinds{KindTrees: kindTrees, Nodes: make(map[string][]*ast.Node)}
+		f.root.WalkPrefix(matches)
+		for kind, nodes := range matches.Nodes {
+			for _, n := range nodes {
+				fn := scope.EnclosingFunction(n)
+				a.sinks[fn] = append(a.sinks[fn], sink{kind: kind, node: n})
+			}
+		}
+		v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true}}
+		f.root.WalkPrefix(v)
+		for _, call := range v.Nodes {
+			if finding := a.check(f.path, call); finding != nil {
+				result.Findings = append(result.Findings, finding)
+			}
+		}
+	}
+	for _, s := range a.sinks {
+		sort.Slice(s, func(i, j int) bool { return s[i].node.StartByte < s[j].node.StartByte })
+	}
+	for _, c := range a.concreteClasses() {
+		for _, trigger := range triggers {
+			if m := c.Method(trigger); m != nil && m.Declaration.Node != nil {
+				start := a.gadget(c, m.Declaration, "")
+				result.Chains = append(result.Chains, a.chains(c, m.Declaration, []*Gadget{start}, true)...)
+			}
+		}
+	}
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		if result.Findings[i].File != result.Findings[j].File {
+			return result.Findings[i].File < result.Findings[j].File
+		}
+		return result.Findings[i].Line < result.Findings[j].Line
+	})
+	return result, nil
+}
+
+// check returns the finding of an unserialize or file operation reachable with request data
+func (a *Analyzer) check(path string, call *ast.Node) *Finding {
+	name := taint.FunctionName(call)
+	arguments := call.Arguments()
+	if len(arguments) == 0 || name != "unserialize" && name != "maybe_unserialize" && !pharFunctions[name] {
+		return nil
+	}
+	taints := a.taint.Of(arguments[0])
+	if len(taints) == 0 {
+		return nil
+	}
+	t := taints[0]
+	finding := &Finding{Sink: name, File: path, Line: call.StartPosition.Row + 1, Taint: t, Node: call}
+	switch {
+	case name == "unserialize" || name == "maybe_unserialize":
+		if len(arguments) > 1 && restricted(arguments[1]) {
+			return nil
+		}
+		finding.Category = UnsafeUnserialize
+		finding.Severity = taint.High
+		finding.Message = fmt.Sprintf("request data %s reaches %s, which instantiates any class; use json_decode or allowed_classes", t.Source.Name, name)
+	default:
+		prefix := a.taint.Evaluator().Eval(arguments[0]).Prefix()
+		finding.Category = PharDeserialization
+		switch {
+		case strings.HasPrefix(strings.ToLower(prefix), "phar://"):
+			finding.Severity = taint.High
+			finding.Message = fmt.Sprintf("request data %s chooses the phar:// archive opened by %s, whose metadata is deserialized", t.Source.Name, name)
+		case prefix == "":
+			finding.Severity = taint.Medium
+			finding.Message = fmt.Sprintf("request data %s starts the path of %s, a phar:// path deserializes the archive metadata", t.Source.Name, name)
+		default:
+			// A fixed start of the path rules out another stream wrapper
+			return nil
+		}
+	}
+	return finding
+}
+
+// restricted reports whether the options of unserialize forbid the objects
+func restricted(options *ast.Node) bool {
+	text := strings.ToLower(strings.Join(strings.Fields(options.Text), ""))
+	return strings.Contains(text, "'allowed_classes'=>false") || strings.Contains(text, "\"allowed_classes\"=>false")
+}
+
+// chains searches the gadget chains continuing with a method run on an object of class c. The
+// properties of the object are controlled by the attacker, as are the parameters when the caller
+// passes controlled arguments.
+func (a *Analyzer) chains(c *classes.Class, m *classes.Method, gadgets []*Gadget, parameters bool) []*Chain {
+	var result []*Chain
+	for _, s := range a.sinks[m.Node] {
+		if a.controlledSink(s.node, parameters) {
+			result = append(result, &Chain{
+				Gadgets:  gadgets,
+				Sink:     s.kind,
+				SinkText: shorten(s.node.Text),
+				File:     m.Class.File,
+				Line:     s.node.StartPosition.Row + 1,
+				Node:     s.node,
+			})
+		}
+	}
+	if len(gadgets) >= maxChainLength {
+		return result
+	}
+	for _, next := range a.successors(c, m, parameters) {
+		if visited(gadgets, next.class, next.method) {
+			continue
+		}
+		gadget := a.gadget(next.class, next.method, next.via)
+		path := append(append([]*Gadget{}, gadgets...), gadget)
+		result = append(result, a.chains(next.class, next.method, path, next.parameters)...)
+		if len(result) >= maxChains {
+			return result[:maxChains]
+		}
+	}
+	return result
+}
+
+type successor struct {
+	class      *classes.Class
+	method     *classes.Method
+	via        string
+	parameters bool
+}
+
+// successors returns the methods a method may run on objects controlled by the attacker: the
+// methods of $this, the methods of any class on a property, or __call when the class lacks them,
+// and __toString when a property is used as a string
+func (a *Analyzer) successors(c *classes.Class, m *classes.Method, parameters bool) []successor {
+	var result []successor
+	v := &ast.VisitorKinds{Kinds: map[string]bool{
+		"member_call_expression": true, "nullsafe_member_call_expression": true, "binary_expression": true,
+		"encapsed_string": true, "echo_statement": true,
+	}}
+	m.Node.WalkPrefix(v)
+	toString := false
+	for _, n := range v.Nodes {
+		children := n.NamedChildren()
+		switch n.Kind {
+		case "member_call_expression", "nullsafe_member_call_expression":
+			name := n.MemberName()
+			if len(children) == 0 || name == "" {
+				continue
+			}
+			arguments := false
+			for _, argument := range n.Arguments() {
+				arguments = arguments || a.controlled(argument, parameters, map[*ast.Node]bool{})
+			}
+			receiver := children[0]
+			if receiver.Kind == "variable_name" && receiver.Text == "$this" {
+				if member := c.Method(name); member != nil && member.Declaration.Node != nil {
+					result = append(result, successor{c, member.Declaration, "call", arguments})
+				}
+				continue
+			}
+			if !a.controlled(receiver, parameters, map[*ast.Node]bool{}) {
+				continue
+			}
+			for _, k := range a.concreteClasses() {
+				if member := k.Method(name); member != nil && member.Declaration.Node != nil {
+					result = append(result, successor{k, member.Declaration, "property-call", arguments})
+				} else if magic := k.Method("__call"); magic != nil && magic.Declaration.Node != nil {
+					result = append(result, successor{k, magic.Declaration, "magic-call", true})
+				}
+			}
+		case "binary_expression":
+			if operator(n) == "." {
+				for _, operand := range children {
+					toString = toString || a.controlled(operand, parameters, map[*ast.Node]bool{})
+				}
+			}
+		case "encapsed_string", "echo_statement":
+			for _, part := range children {
+				toString = toString || a.controlled(part, parameters, map[*ast.Node]bool{})
+			}
+		}
+	}
+	if toString {
+		for _, k := range a.concreteClasses() {
+			if member := k.Method("__toString"); member != nil && member.Declaration.Node != nil {
+				result = append(result, successor{k, member.Declaration, "to-string", false})
+			}
+		}
+	}
+	return result
+}
+
+func (a *Analyzer) concreteClasses() []*classes.Class {
+	var result []*classes.Class
+	for _, c := range a.hierarchy.SortedClasses() {
+		if c.Kind == classes.ClassKind && !c.Abstract {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// controlledSink reports whether a dangerous call is fed with data controlled by the attacker
+func (a *Analyzer) controlledSink(n *ast.Node, parameters bool) bool {
+	inputs := n.NamedChildren()
+	if n.Kind == "function_call_expression" {
+		inputs = n.Arguments()
+		if callee := n.NamedChildren(); len(callee) > 0 && callee[0].Kind != "name" && callee[0].Kind != "qualified_name" {
+			// The function of a dynamic call
+			inputs = append(inputs, callee[0])
+		}
+	}
+	for _, input := range inputs {
+		if a.controlled(input, parameters, map[*ast.Node]bool{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// controlled reports whether an expression derives from the properties of $this, or from the
+// parameters of the method when they are controlled, through the local variables
+func (a *Analyzer) controlled(n *ast.Node, parameters bool, visiting map[*ast.Node]bool) bool {
+	if visiting[n] {
+		return false
+	}
+	visiting[n] = true
+	switch n.Kind {
+	case "member_access_expression", "nullsafe_member_access_expression":
+		if children := n.NamedChildren(); len(children) > 0 && children[0].Text == "$this" {
+			return true
+		}
+	case "variable_name":
+		if n.Text == "$this" {
+			return false
+		}
+		s := scope.Of(n)
+		if s == nil {
+			return false
+		}
+		for _, def := range s.DefsOf(n) {
+			if def.Parent == nil {
+				continue
+			}
+			switch def.Parent.Kind {
+			case "simple_parameter", "variadic_parameter":
+				if parameters {
+					return true
+				}
+			case "assignment_expression", "augmented_assignment_expression":
+				if values := def.Parent.NamedChildren(); len(values) > 1 && a.controlled(values[len(values)-1], parameters, visiting) {
+					return true
+				}
+			case "foreach_statement", "pair":
+				foreach := def.Parent
+				if foreach.Kind == "pair" && foreach.Parent != nil {
+					foreach = foreach.Parent
+				}
+				if subject := foreach.NamedChildren(); len(subject) > 0 && a.controlled(subject[0], parameters, visiting) {
+					return true
+				}
+			}
+		}
+		return false
+	case "anonymous_function", "arrow_function":
+		return false
+	}
+	for _, child := range n.NamedChildren() {
+		if a.controlled(child, parameters, visiting) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analyzer) gadget(c *classes.Class, m *classes.Method, via string) *Gadget {
+	return &Gadget{
+		Class:  c.Name,
+		Method: m.Name,
+		File:   m.Class.File,
+		Line:   m.Node.StartPosition.Row + 1,
+		Via:    via,
+	}
+}
+
+func visited(gadgets []*Gadget, c *classes.Class, m *classes.Method) bool {
+	for _, g := range gadgets {
+		if g.Class == c.Name && strings.EqualFold(g.Method, m.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders a chain as Class::method -> Class::method -> sink
+func (c *Chain) String() string {
+	var sb strings.Builder
+	for i, g := range c.Gadgets {
+		if i > 0 {
+			sb.WriteString(" -[" + g.Via + "]-> ")
+		}
+		sb.WriteString(g.Class + "::" + g.Method)
+	}
+	sb.WriteString(" -> " + c.SinkText)
+	return sb.String()
+}
+
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 80 {
+		return text[:77] + "..."
+	}
+	return text
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
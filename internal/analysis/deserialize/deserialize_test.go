@@ -0,0 +1,137 @@
+package deserialize
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// analyze returns the result of a source as the only file of a project
+func analyze(t *testing.T, source string) *Result {
+	t.Helper()
+	a := New()
+	a.AddFile("test.php", ast.ParseSource([]byte(source)))
+	result, err := a.Analyze()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// want is the only finding expected, nil for none
+		want *Finding
+	}{
+		{
+			name:   "request data unserialized",
+			source: "<?php\n$o = unserialize($_COOKIE['state']);",
+			want:   &Finding{Category: UnsafeUnserialize, Severity: taint.High, Sink: "unserialize"},
+		},
+		{
+			name:   "request data unserialized by WordPress",
+			source: "<?php\n$o = maybe_unserialize(base64_decode($_POST['s']));",
+			want:   &Finding{Category: UnsafeUnserialize, Severity: taint.High, Sink: "maybe_unserialize"},
+		},
+		{
+			name:   "request data unserialized without classes",
+			source: "<?php\n$o = unserialize($_COOKIE['state'], ['allowed_classes' => false]);",
+		},
+		{
+			name:   "request path in a file operation",
+			source: "<?php\nfile_exists($_GET['path']);",
+			want:   &Finding{Category: PharDeserialization, Severity: taint.Medium, Sink: "file_exists"},
+		},
+		{
+			name:   "request path under the phar wrapper",
+			source: "<?php\n$size = filesize('phar://' . $_GET['archive'] . '/data');",
+			want:   &Finding{Category: PharDeserialization, Severity: taint.High, Sink: "filesize"},
+		},
+		{
+			name:   "request path under a fixed directory",
+			source: "<?php\nfile_exists('/var/data/' . $_GET['path']);",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := analyze(t, tt.source).Findings
+			if tt.want == nil {
+				if len(findings) > 0 {
+					t.Errorf("findings = %d, want none", len(findings))
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %d, want 1", len(findings))
+			}
+			got := findings[0]
+			if got.Category != tt.want.Category || got.Severity != tt.want.Severity || got.Sink != tt.want.Sink {
+				t.Errorf("finding = %s %s %s, want %s %s %s", got.Category, got.Severity, got.Sink,
+					tt.want.Category, tt.want.Severity, tt.want.Sink)
+			}
+		})
+	}
+}
+
+func TestChains(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// gadgets are the methods of the first chain and how each one is reached
+		gadgets []string
+		sink    string
+	}{
+		{
+			name:    "destructor running a property",
+			source:  "<?php\nclass Logger {\npublic $cmd;\nfunction __destruct() {\nsystem($this->cmd);\n}\n}",
+			gadgets: []string{"Logger::__destruct"},
+			sink:    "command",
+		},
+		{
+			name:   "destructor running a constant",
+			source: "<?php\nclass Logger {\nfunction __destruct() {\nsystem('true');\n}\n}",
+		},
+		{
+			name:    "method of $this",
+			source:  "<?php\nclass Cache {\npublic $file;\nfunction __wakeup() {\n$this->flush();\n}\nfunction flush() {\nunlink($this->file);\n}\n}",
+			gadgets: []string{"Cache::__wakeup", "Cache::flush call"},
+			sink:    "file-write",
+		},
+		{
+			name:    "method of a property of any class",
+			source:  "<?php\nclass Job {\npublic $runner;\nfunction __destruct() {\n$this->runner->run();\n}\n}\nclass Shell {\npublic $code;\nfunction run() {\neval($this->code);\n}\n}",
+			gadgets: []string{"Job::__destruct", "Shell::run property-call"},
+			sink:    "code",
+		},
+		{
+			name:    "property used as a string",
+			source:  "<?php\nclass Page {\npublic $title;\nfunction __destruct() {\necho 'Title: ' . $this->title;\n}\n}\nclass Path {\npublic $p;\nfunction __toString() {\nreturn file_get_contents($this->p);\n}\n}",
+			gadgets: []string{"Page::__destruct", "Path::__toString to-string"},
+			sink:    "file-read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chains := analyze(t, tt.source).Chains
+			var gadgets []string
+			sink := ""
+			if len(chains) > 0 {
+				for _, g := range chains[0].Gadgets {
+					gadget := g.Class + "::" + g.Method
+					if g.Via != "" {
+						gadget += " " + g.Via
+					}
+					gadgets = append(gadgets, gadget)
+				}
+				sink = chains[0].Sink
+			}
+			if !slices.Equal(gadgets, tt.gadgets) || sink != tt.sink {
+				t.Errorf("chain = %v to %q, want %v to %q", gadgets, sink, tt.gadgets, tt.sink)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package include
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// maxDepth bounds the evaluation through variable definitions and constants
+const maxDepth = 16
+
+// value is the result of evaluating a path expression. s holds the known parts of the value,
+// with ? standing for the unknown ones.
+type value struct {
+	s              string
+	known          bool
+	userControlled bool
+}
+
+func unknown() value {
+	return value{s: "?"}
+}
+
+func concat(a, b value) value {
+	return value{
+		s:              a.s + b.s,
+		known:          a.known && b.known,
+		userControlled: a.userControlled || b.userControlled,
+	}
+}
+
+// evaluator evaluates path expressions made of literals, magic constants, constants, dirname
+// calls and concatenations, following the definitions of local variables
+type evaluator struct {
+	graph *Graph
+	file  *File
+	depth int
+}
+
+func (e *evaluator) eval(n *ast.Node) value {
+	if e.depth > maxDepth {
+		return unknown()
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	switch n.Kind {
+	case "string", "encapsed_string", "heredoc", "nowdoc":
+		if s, ok := literal.String(n); ok {
+			return value{s: s, known: true}
+		}
+		result := value{known: true}
+		doubleQuoted := n.Kind != "string" && n.Kind != "nowdoc"
+		for _, part := range literal.Parts(n) {
+			switch part.Kind {
+			case "string_content", "nowdoc_string", "string_value":
+				result = concat(result, value{s: part.Text, known: true})
+			case "escape_sequence":
+				result = concat(result, value{s: literal.Unescape(part.Text, doubleQuoted), known: true})
+			default:
+				result = concat(result, e.eval(part))
+			}
+		}
+		return result
+	case "binary_expression":
+		children := n.Descendants
+		if len(children) == 3 && children[1].Kind == "." {
+			return concat(e.eval(children[0]), e.eval(children[2]))
+		}
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return e.eval(children[0])
+		}
+	case "name":
+		switch n.Text {
+		case "__DIR__":
+			return value{s: path.Dir(e.file.Path), known: true}
+		case "__FILE__":
+			return value{s: e.file.Path, known: true}
+		}
+		if c, ok := e.graph.constants[n.Text]; ok {
+			constantEvaluator := &evaluator{graph: e.graph, file: c.file, depth: e.depth}
+			return constantEvaluator.eval(c.value)
+		}
+		return unknown()
+	case "function_call_expression":
+		return e.call(n)
+	case "variable_name":
+		return e.variable(n)
+	}
+	result := unknown()
+	result.userControlled = readsUserInput(n)
+	return result
+}
+
+func (e *evaluator) call(n *ast.Node) value {
+	name := n.ChildOfKind("name")
+	arguments := n.Arguments()
+	var values []value
+	userControlled := false
+	for _, argument := range arguments {
+		v := e.eval(argument)
+		values = append(values, v)
+		userControlled = userControlled || v.userControlled
+	}
+	if name == nil || len(values) == 0 {
+		return value{s: "?", userControlled: userControlled || readsUserInput(n)}
+	}
+	result := value{s: "?", userControlled: userControlled}
+	switch strings.ToLower(name.Text) {
+	case "dirname":
+		levels := 1
+		if len(arguments) > 1 {
+			if l, err := strconv.Atoi(arguments[1].Text); err == nil {
+				levels = l
+			}
+		}
+		if values[0].known {
+			result.s = values[0].s
+			for range levels {
+				result.s = path.Dir(result.s)
+			}
+			result.known = true
+		}
+	case "realpath", "trailingslashit", "untrailingslashit":
+		result = values[0]
+		if strings.EqualFold(name.Text, "untrailingslashit") {
+			result.s = strings.TrimRight(result.s, "/")
+		}
+	case "basename":
+		if values[0].known {
+			result.s = path.Base(values[0].s)
+			result.known = true
+		}
+		// basename removes directory traversal but not the choice of the file
+	}
+	return result
+}
+
+// variable evaluates a variable through the definitions reaching it: it is known if all of them
+// are assignments of the same known value
+func (e *evaluator) variable(n *ast.Node) value {
+	if readsUserInput(n) {
+		return value{s: "?", userControlled: true}
+	}
+	s := scope.Of(n)
+	if s == nil {
+		return unknown()
+	}
+	defs := s.DefsOf(n)
+	if len(defs) == 0 {
+		return unknown()
+	}
+	var result *value
+	for _, def := range defs {
+		source := assignedValue(def)
+		if source == nil {
+			return unknown()
+		}
+		v := e.eval(source)
+		if result == nil {
+			result = &v
+			continue
+		}
+		if !v.known || v.s != result.s {
+			return value{s: "?", userControlled: result.userControlled || v.userControlled}
+		}
+	}
+	return *result
+}
+
+// assignedValue returns the expression assigned by a definition, or nil if it is not a plain assignment
+func assignedValue(def *ast.Node) *ast.Node {
+	assignment := def.Parent
+	if assignment == nil || assignment.Kind != "assignment_expression" {
+		return nil
+	}
+	children := assignment.NamedChildren()
+	if len(children) < 2 || children[0] != def {
+		return nil
+	}
+	return children[len(children)-1]
+}
+
+// readsUserInput reports whether an expression reads a superglobal holding request data
+func readsUserInput(n *ast.Node) bool {
+	if n.Kind == "variable_name" {
+		switch scope.VariableName(n) {
+		case "_GET", "_POST", "_REQUEST", "_COOKIE", "_FILES", "_SERVER":
+			return true
+		}
+	}
+	for _, child := range n.NamedChildren() {
+		if readsUserInput(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package include
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the resolution of an include expression is stored
+const AttributeKey = "include"
+
+// Kinds are the node kinds of file inclusions
+var Kinds = []string{"include_expression", "include_once_expression", "require_expression", "require_once_expression"}
+
+type Status string
+
+const (
+	Resolved Status = "resolved"
+	// Missing includes have a constant path that matches no file of the project
+	Missing Status = "missing"
+	// Unresolved includes have a path that cannot be evaluated statically
+	Unresolved Status = "unresolved"
+	// UserControlled includes have a path derived from request data (local or remote file inclusion)
+	UserControlled Status = "user-controlled"
+	// Remote includes load code from an URL
+	Remote Status = "remote"
+)
+
+// File is a PHP file of the project
+type File struct {
+	// Path is the absolute path of the PHP file
+	Path     string
+	Tree     *ast.Node
+	Includes []*Include
+	scope    *scope.Scope
+}
+
+// Include is an include or require expression and the file it resolves to
+type Include struct {
+	File *File
+	Node *ast.Node
+	// Kind is include, include_once, require or require_once
+	Kind string
+	// Value is the evaluated path, with ? standing for the parts that could not be evaluated
+	Value  string
+	Status Status
+	// Target is the included file, nil if it is not part of the project
+	Target *File
+}
+
+func (i *Include) Line() uint {
+	return i.Node.StartPosition.Row + 1
+}
+
+// Expression returns the path expression of the include
+func (i *Include) Expression() string {
+	children := i.Node.NamedChildren()
+	if len(children) == 0 {
+		return ""
+	}
+	return children[len(children)-1].Text
+}
+
+// Graph is the file dependency graph of a project
+type Graph struct {
+	Root      string
+	Files     map[string]*File
+	constants map[string]constant
+}
+
+type constant struct {
+	value *ast.Node
+	file  *File
+}
+
+func New(root string) *Graph {
+	if absolute, err := filepath.Abs(root); err == nil {
+		root = absolute
+	}
+	return &Graph{
+		Root:      filepath.ToSlash(root),
+		Files:     make(map[string]*File),
+		constants: make(map[string]constant),
+	}
+}
+
+// AddFile adds a PHP file to the project. The path is relative to the root of the project or absolute.
+func (g *Graph) AddFile(filePath string, tree *ast.Node) *File {
+	filePath = g.absolute(filePath)
+	f := &File{Path: filePath, Tree: tree}
+	g.Files[filePath] = f
+	return f
+}
+
+func (g *Graph) absolute(filePath string) string {
+	filePath = filepath.ToSlash(filePath)
+	if !path.IsAbs(filePath) {
+		filePath = path.Join(g.Root, filePath)
+	}
+	return path.Clean(filePath)
+}
+
+// Relative returns a path relative to the root of the project, for display
+func (g *Graph) Relative(filePath string) string {
+	if rel, err := filepath.Rel(g.Root, filePath); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filePath
+}
+
+// Resolve evaluates the include paths of every file and links them to the included files.
+// The resolution of each include node is stored in its attributes under AttributeKey.
+func (g *Graph) Resolve() {
+	for _, f := range g.SortedFiles() {
+		f.scope = scope.Analyze(f.Tree)
+		v := &collectVisitor{}
+		f.Tree.WalkPrefix(v)
+		for _, n := range v.constants {
+			g.addConstant(n, f)
+		}
+		for _, n := range v.includes {
+			f.Includes = append(f.Includes, &Include{
+				File: f,
+				Node: n,
+				Kind: strings.TrimSuffix(n.Kind, "_expression"),
+			})
+		}
+	}
+	for _, f := range g.SortedFiles() {
+		for _, i := range f.Includes {
+			g.resolve(i)
+			i.Node.SetAttribute(AttributeKey, i)
+		}
+	}
+}
+
+type collectVisitor struct {
+	includes  []*ast.Node
+	constants []*ast.Node
+}
+
+func (v *collectVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "include_expression", "include_once_expression", "require_expression", "require_once_expression":
+		v.includes = append(v.includes, n)
+	case "const_element":
+		v.constants = append(v.constants, n)
+	case "function_call_expression":
+		if name := n.ChildOfKind("name"); name != nil && strings.EqualFold(name.Text, "define") {
+			v.constants = append(v.constants, n)
+		}
+	}
+}
+
+func (g *Graph) addConstant(n *ast.Node, f *File) {
+	if n.Kind == "const_element" {
+		children := n.NamedChildren()
+		if len(children) == 2 && children[0].Kind == "name" {
+			g.constants[children[0].Text] = constant{value: children[1], file: f}
+		}
+		return
+	}
+	arguments := n.Arguments()
+	if len(arguments) < 2 {
+		return
+	}
+	e := &evaluator{graph: g, file: f}
+	if name := e.eval(arguments[0]); name.known {
+		g.constants[name.s] = constant{value: arguments[1], file: f}
+	}
+}
+
+func (g *Graph) resolve(i *Include) {
+	children := i.Node.NamedChildren()
+	if len(children) == 0 {
+		i.Status = Unresolved
+		return
+	}
+	e := &evaluator{graph: g, file: i.File}
+	v := e.eval(children[len(children)-1])
+	i.Value = v.s
+	switch {
+	case v.userControlled:
+		i.Status = UserControlled
+	case isRemote(v.s):
+		i.Status = Remote
+	case !v.known:
+		i.Status = Unresolved
+	default:
+		i.Status = Missing
+		for _, candidate := range g.candidates(i.File, v.s) {
+			if target, ok := g.Files[candidate]; ok {
+				i.Target = target
+				i.Status = Resolved
+				break
+			}
+			if _, err := os.Stat(candidate); err == nil {
+				i.Status = Resolved
+				i.Value = candidate
+				break
+			}
+		}
+		if i.Target != nil {
+			i.Value = i.Target.Path
+		}
+	}
+}
+
+func isRemote(value string) bool {
+	for _, scheme := range []string{"http://", "https://", "ftp://", "ftps://", "php://", "data:", "expect://", "phar://", "zip://"} {
+		if strings.HasPrefix(strings.ToLower(value), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the paths PHP would try for an included path: relative paths are looked up
+// in the include path (approximated by the project root) and in the directory of the including file
+func (g *Graph) candidates(f *File, value string) []string {
+	value = filepath.ToSlash(value)
+	if path.IsAbs(value) {
+		return []string{path.Clean(value)}
+	}
+	return []string{
+		path.Clean(path.Join(path.Dir(f.Path), value)),
+		path.Clean(path.Join(g.Root, value)),
+	}
+}
+
+// SortedFiles returns the files of the project ordered by path
+func (g *Graph) SortedFiles() []*File {
+	var files []*File
+	for _, f := range g.Files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// Program returns the files executed when running entry, in inclusion order, so that other
+// analyses can treat them as a single program. Each file appears once.
+func (g *Graph) Program(entry string) []*File {
+	f, ok := g.Files[g.absolute(entry)]
+	if !ok {
+		return nil
+	}
+	var result []*File
+	visited := make(map[*File]bool)
+	var visit func(*File)
+	visit = func(f *File) {
+		if visited[f] {
+			return
+		}
+		visited[f] = true
+		result = append(result, f)
+		for _, i := range f.Includes {
+			if i.Target != nil {
+				visit(i.Target)
+			}
+		}
+	}
+	visit(f)
+	return result
+}
+
+// DOT exports the file dependency graph in the Graphviz format. Unresolved includes point to a
+// node labelled with their expression.
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph includes {\n")
+	for _, f := range g.SortedFiles() {
+		fmt.Fprintf(&sb, "  %q;\n", g.Relative(f.Path))
+	}
+	for _, f := range g.SortedFiles() {
+		for _, i := range f.Includes {
+			target := g.Relative(i.Value)
+			attributes := ""
+			if i.Target == nil {
+				target = fmt.Sprintf("%s: %s", i.Status, i.Expression())
+				attributes = " [style=dashed]"
+				if i.Status == UserControlled || i.Status == Remote {
+					attributes = " [color=red]"
+				}
+			}
+			fmt.Fprintf(&sb, "  %q -> %q%s;\n", g.Relative(f.Path), target, attributes)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+type includeJSON struct {
+	File       string `json:"file"`
+	Line       uint   `json:"line"`
+	Kind       string `json:"kind"`
+	Expression string `json:"expression"`
+	Value      string `json:"value"`
+	Status     Status `json:"status"`
+	Target     string `json:"target,omitempty"`
+}
+
+func (i *Include) MarshalJSON() ([]byte, error) {
+	out := includeJSON{
+		File:       i.File.Path,
+		Line:       i.Line(),
+		Kind:       i.Kind,
+		Expression: i.Expression(),
+		Value:      i.Value,
+		Status:     i.Status,
+	}
+	if i.Target != nil {
+		out.Target = i.Target.Path
+	}
+	return json.Marshal(out)
+}
+
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	includes := []*Include{}
+	edges := make(map[string][]string)
+	for _, f := range g.SortedFiles() {
+		includes = append(includes, f.Includes...)
+		edges[f.Path] = []string{}
+		for _, i := range f.Includes {
+			if i.Target != nil {
+				edges[f.Path] = append(edges[f.Path], i.Target.Path)
+			}
+		}
+	}
+	return json.Marshal(map[string]any{
+		"root":     g.Root,
+		"includes": includes,
+		"edges":    edges,
+	})
+}
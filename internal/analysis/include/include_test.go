@@ -0,0 +1,129 @@
+package include
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// project builds the include graph of files given by their path relative to /project
+func project(files map[string]string) *Graph {
+	g := New("/project")
+	for path, source := range files {
+		g.AddFile(path, ast.ParseSource([]byte(source)))
+	}
+	g.Resolve()
+	return g
+}
+
+func TestResolve(t *testing.T) {
+	lib := map[string]string{"lib/db.php": "<?php\n"}
+	tests := []struct {
+		name   string
+		source string
+		status Status
+		target string
+	}{
+		{
+			name:   "relative to the including file",
+			source: "<?php require 'lib/db.php';",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "__DIR__",
+			source: "<?php require_once __DIR__ . '/lib/db.php';",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "dirname of __FILE__",
+			source: "<?php include dirname(__FILE__) . '/lib/db.php';",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "define constant",
+			source: "<?php\ndefine('LIB', __DIR__ . '/lib/');\nrequire LIB . 'db.php';",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "const constant",
+			source: "<?php\nconst LIB = 'lib';\nrequire LIB . '/db.php';",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "variable with a single definition",
+			source: "<?php\n$file = 'lib/db.php';\nrequire $file;",
+			status: Resolved, target: "/project/lib/db.php",
+		},
+		{
+			name:   "request data",
+			source: "<?php include $_GET['page'] . '.php';",
+			status: UserControlled,
+		},
+		{
+			name:   "request data through a variable",
+			source: "<?php\n$page = $_POST['page'];\ninclude 'pages/' . $page;",
+			status: UserControlled,
+		},
+		{
+			name:   "remote",
+			source: "<?php include 'http://example.com/code.php';",
+			status: Remote,
+		},
+		{
+			name:   "missing",
+			source: "<?php require 'lib/missing.php';",
+			status: Missing,
+		},
+		{
+			name:   "unresolved",
+			source: "<?php require get_path();",
+			status: Unresolved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"index.php": tt.source}
+			for path, source := range lib {
+				files[path] = source
+			}
+			g := project(files)
+			includes := g.Files["/project/index.php"].Includes
+			if len(includes) != 1 {
+				t.Fatalf("%d includes, want 1", len(includes))
+			}
+			i := includes[0]
+			if i.Status != tt.status {
+				t.Errorf("status = %s, want %s (value %q)", i.Status, tt.status, i.Value)
+			}
+			target := ""
+			if i.Target != nil {
+				target = i.Target.Path
+			}
+			if target != tt.target {
+				t.Errorf("target = %q, want %q", target, tt.target)
+			}
+		})
+	}
+}
+
+func TestProgram(t *testing.T) {
+	g := project(map[string]string{
+		"index.php":         "<?php\nrequire 'config.php';\nrequire 'lib/functions.php';\ninclude $_GET['p'];",
+		"config.php":        "<?php\nrequire_once __DIR__ . '/lib/db.php';",
+		"lib/db.php":        "<?php\n",
+		"lib/functions.php": "<?php\nrequire_once __DIR__ . '/db.php';",
+		"unused.php":        "<?php\n",
+	})
+	var got []string
+	for _, f := range g.Program("index.php") {
+		got = append(got, g.Relative(f.Path))
+	}
+	want := []string{"index.php", "config.php", "lib/db.php", "lib/functions.php"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Program() = %v, want %v", got, want)
+	}
+	if files := g.Program("none.php"); files != nil {
+		t.Errorf("Program() of an unknown entry = %v, want nil", files)
+	}
+}
@@ -0,0 +1,321 @@
+package injection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// CommandInjection is request data reaching a shell command
+	CommandInjection Category = "command-injection"
+	// ArgumentInjection is request data reaching the arguments of a program run without a shell,
+	// or escaped arguments that may start with - and pass options
+	ArgumentInjection Category = "argument-injection"
+	// EscapingMisuse is a shell escaper applied in a way that does not protect the command
+	EscapingMisuse Category = "escaping-misuse"
+	// CodeInjection is request data reaching eval, assert or create_function
+	CodeInjection Category = "code-injection"
+	// DynamicCall is request data choosing the function called
+	DynamicCall Category = "dynamic-call"
+)
+
+// shellFunctions maps the functions running a command through the shell to the index of the command
+var shellFunctions = map[string]int{
+	"exec": 0, "system": 0, "passthru": 0, "shell_exec": 0, "popen": 0, "proc_open": 0,
+}
+
+// codeFunctions maps the functions evaluating PHP code to the index of the code
+var codeFunctions = map[string]int{
+	"eval": 0, "assert": 0, "create_function": 1,
+}
+
+// callbackFunctions maps the functions calling a callback to the index of the callback
+var callbackFunctions = map[string]int{
+	"call_user_func": 0, "call_user_func_array": 0, "array_map": 0, "array_filter": 1, "array_walk": 1,
+	"usort": 1, "uasort": 1, "uksort": 1, "forward_static_call": 0, "forward_static_call_array": 0,
+	"register_shutdown_function": 0,
+}
+
+// Finding is request data reaching a command or code execution sink
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	// Sink is the function or construct executing the data
+	Sink    string       `json:"sink"`
+	File    string       `json:"file"`
+	Line    uint         `json:"line"`
+	Message string       `json:"message"`
+	Taint   *taint.Taint `json:"taint"`
+	Node    *ast.Node    `json:"-"`
+}
+
+// part is a literal or dynamic part of a command
+type part struct {
+	literal string
+	dynamic *ast.Node
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the command injections and code executions of a project: the request data
+// reaching the shell functions, backticks, eval, assert and dynamic calls. The commands are split
+// into their literal and dynamic parts to check where each escaped value lands.
+type Analyzer struct {
+	taint *taint.Analyzer
+	files []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{taint: taint.New()}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.taint.AddFile(path, root)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.taint.Resolve()
+	var findings []*Finding
+	for _, f := range a.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true, "shell_command_expression": true}}
+		f.root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			findings = append(findings, a.check(f.path, n)...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func (a *Analyzer) check(path string, n *ast.Node) []*Finding {
+	if n.Kind == "shell_command_expression" {
+		var parts []part
+		for _, child := range n.NamedChildren() {
+			switch child.Kind {
+			case "string_content":
+				parts = append(parts, part{literal: child.Text})
+			case "escape_sequence":
+				parts = append(parts, part{literal: literal.Unescape(child.Text, true)})
+			default:
+				parts = append(parts, part{dynamic: child})
+			}
+		}
+		return a.command(path, "backticks", n, parts)
+	}
+	arguments := n.Arguments()
+	name := taint.FunctionName(n)
+	if name == "" {
+		// Variable function such as $f($x)
+		if callee := n.NamedChildren(); len(callee) > 0 && callee[0].Kind != "arguments" {
+			return a.callback(path, callee[0].Text+"()", n, callee[0])
+		}
+		return nil
+	}
+	if index, ok := shellFunctions[name]; ok && index < len(arguments) {
+		command := arguments[index]
+		if name == "proc_open" && command.Kind == "array_creation_expression" {
+			// Since PHP 7.4 an array runs the program without a shell
+			return a.program(path, name, n, command)
+		}
+		return a.command(path, name, n, a.flatten(command))
+	}
+	if name == "pcntl_exec" && len(arguments) > 0 {
+		findings := a.program(path, name, n, arguments[1:]...)
+		if t := a.taint.Of(arguments[0]); len(t) > 0 {
+			findings = append(findings, a.finding(CommandInjection, taint.High, path, name, n, t[0],
+				fmt.Sprintf("request data %s chooses the program run by %s", t[0].Source.Name, name)))
+		}
+		return findings
+	}
+	if index, ok := codeFunctions[name]; ok && index < len(arguments) {
+		return a.code(path, name, n, arguments[index])
+	}
+	if index, ok := callbackFunctions[name]; ok && index < len(arguments) {
+		return a.callback(path, name, n, arguments[index])
+	}
+	return nil
+}
+
+// flatten splits a command into its literal and dynamic parts, through concatenations and
+// interpolated strings
+func (a *Analyzer) flatten(n *ast.Node) []part {
+	children := n.NamedChildren()
+	switch n.Kind {
+	case "argument", "parenthesized_expression":
+		if len(children) == 1 {
+			return a.flatten(children[0])
+		}
+	case "binary_expression":
+		if len(children) == 2 && operator(n) == "." {
+			return append(a.flatten(children[0]), a.flatten(children[1])...)
+		}
+	case "encapsed_string", "heredoc":
+		var parts []part
+		for _, p := range literal.Parts(n) {
+			switch p.Kind {
+			case "string_content", "string_value":
+				parts = append(parts, part{literal: p.Text})
+			case "escape_sequence":
+				parts = append(parts, part{literal: literal.Unescape(p.Text, true)})
+			default:
+				parts = append(parts, part{dynamic: p})
+			}
+		}
+		return parts
+	}
+	if value, ok := a.taint.Evaluator().Eval(n).Constant(); ok {
+		return []part{{literal: value}}
+	}
+	return []part{{dynamic: n}}
+}
+
+// command checks the request data reaching a shell command. Each dynamic part is checked for
+// the escaping it needs where it lands: escapeshellarg outside quotes, and not on the program.
+func (a *Analyzer) command(path, sink string, n *ast.Node, parts []part) []*Finding {
+	var findings []*Finding
+	quote := byte(0)
+	prefix := ""
+	for _, p := range parts {
+		if p.dynamic == nil {
+			for j := 0; j < len(p.literal); j++ {
+				switch c := p.literal[j]; {
+				case quote == 0 && (c == '\'' || c == '"'):
+					quote = c
+				case quote != 0 && c == quote:
+					quote = 0
+				}
+			}
+			prefix += p.literal
+			continue
+		}
+		program := strings.TrimSpace(lastCommand(prefix)) == ""
+		for _, t := range a.taint.Of(p.dynamic) {
+			if finding := a.argument(path, sink, n, t, quote, program, prefix); finding != nil {
+				findings = append(findings, finding)
+				break
+			}
+		}
+		prefix += "x"
+	}
+	return findings
+}
+
+// argument returns the finding of a taint reaching a part of a command, nil if it is escaped
+func (a *Analyzer) argument(path, sink string, n *ast.Node, t *taint.Taint, quote byte, program bool, prefix string) *Finding {
+	source := t.Source.Name
+	switch {
+	case t.SanitizedBy("escapeshellarg") && t.Last().Name == "escapeshellcmd":
+		// escapeshellcmd escapes the quotes added by escapeshellarg, unbalancing them
+		return a.finding(EscapingMisuse, taint.Medium, path, sink, n, t,
+			fmt.Sprintf("escapeshellcmd applied over escapeshellarg(%s) lets it break out of its quotes", source))
+	case t.SanitizedBy("escapeshellarg"):
+		switch {
+		case quote != 0:
+			return a.finding(EscapingMisuse, taint.Medium, path, sink, n, t,
+				fmt.Sprintf("escapeshellarg(%s) used between %c quotes, which end its own quoting", source, quote))
+		case program:
+			return a.finding(CommandInjection, taint.High, path, sink, n, t,
+				fmt.Sprintf("request data %s chooses the program run by %s", source, sink))
+		case !strings.Contains(prefix, " -- ") && !strings.HasSuffix(prefix, " --"):
+			return a.finding(ArgumentInjection, taint.Low, path, sink, n, t,
+				fmt.Sprintf("escaped request data %s may start with - and pass an option, add -- before it", source))
+		}
+		return nil
+	case t.SanitizedBy("escapeshellcmd"):
+		return a.finding(ArgumentInjection, taint.Medium, path, sink, n, t,
+			fmt.Sprintf("escapeshellcmd leaves %s free to add arguments and options, use escapeshellarg on each argument", source))
+	}
+	message := fmt.Sprintf("request data %s reaches %s without escapeshellarg", source, sink)
+	if len(t.Sanitizers) > 0 {
+		message += fmt.Sprintf(", %s does not escape for the shell", t.Last().Name)
+	}
+	return a.finding(CommandInjection, taint.High, path, sink, n, t, message)
+}
+
+// program checks the request data reaching the arguments of a program run without a shell, which
+// can only pass options and arguments
+func (a *Analyzer) program(path, sink string, n *ast.Node, arguments ...*ast.Node) []*Finding {
+	for _, argument := range arguments {
+		if t := a.taint.Of(argument); len(t) > 0 {
+			return []*Finding{a.finding(ArgumentInjection, taint.Medium, path, sink, n, t[0],
+				fmt.Sprintf("request data %s reaches the arguments of the program run by %s", t[0].Source.Name, sink))}
+		}
+	}
+	return nil
+}
+
+// code checks the request data reaching evaluated code, which no escaping makes safe
+func (a *Analyzer) code(path, sink string, n *ast.Node, code *ast.Node) []*Finding {
+	taints := a.taint.Of(code)
+	if len(taints) == 0 {
+		return nil
+	}
+	t := taints[0]
+	message := fmt.Sprintf("request data %s evaluated as PHP code by %s", t.Source.Name, sink)
+	if len(t.Sanitizers) > 0 {
+		message += fmt.Sprintf(", %s does not make code safe", t.Last().Name)
+	}
+	return []*Finding{a.finding(CodeInjection, taint.High, path, sink, n, t, message)}
+}
+
+// callback checks the request data choosing the function called by a variable function or a
+// function taking a callback
+func (a *Analyzer) callback(path, sink string, n *ast.Node, callee *ast.Node) []*Finding {
+	taints := a.taint.Of(callee)
+	if len(taints) == 0 {
+		return nil
+	}
+	t := taints[0]
+	return []*Finding{a.finding(DynamicCall, taint.High, path, sink, n, t,
+		fmt.Sprintf("request data %s chooses the function called by %s", t.Source.Name, sink))}
+}
+
+func (a *Analyzer) finding(category Category, severity taint.Severity, path, sink string, n *ast.Node, t *taint.Taint, message string) *Finding {
+	return &Finding{
+		Category: category,
+		Severity: severity,
+		Sink:     sink,
+		File:     path,
+		Line:     n.StartPosition.Row + 1,
+		Message:  message,
+		Taint:    t,
+		Node:     n,
+	}
+}
+
+// lastCommand returns the text of the last command of a shell command line, after the last
+// separator or pipe
+func lastCommand(prefix string) string {
+	if i := strings.LastIndexAny(prefix, ";|&\n`("); i >= 0 {
+		return prefix[i+1:]
+	}
+	return prefix
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
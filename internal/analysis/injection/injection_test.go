@@ -0,0 +1,91 @@
+package injection
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// summary is the category, severity and sink of a finding
+type summary struct {
+	category Category
+	severity taint.Severity
+	sink     string
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []summary
+	}{
+		{
+			name:   "request data in a command",
+			source: "<?php\nsystem('ping ' . $_GET['host']);",
+			want:   []summary{{CommandInjection, taint.High, "system"}},
+		},
+		{
+			name:   "request data interpolated in backticks",
+			source: "<?php\n$out = `ls {$_GET['dir']}`;",
+			want:   []summary{{CommandInjection, taint.High, "backticks"}},
+		},
+		{
+			name:   "request data escaped as an argument after --",
+			source: "<?php\nexec('grep -- ' . escapeshellarg($_GET['q']) . ' log.txt');",
+		},
+		{
+			name:   "escaped argument that may pass an option",
+			source: "<?php\nexec('grep ' . escapeshellarg($_GET['q']) . ' log.txt');",
+			want:   []summary{{ArgumentInjection, taint.Low, "exec"}},
+		},
+		{
+			name:   "escaped argument between quotes",
+			source: "<?php\nsystem(\"ping '\" . escapeshellarg($_GET['host']) . \"'\");",
+			want:   []summary{{EscapingMisuse, taint.Medium, "system"}},
+		},
+		{
+			name:   "escapeshellcmd over escapeshellarg",
+			source: "<?php\npassthru(escapeshellcmd('ping ' . escapeshellarg($_GET['host'])));",
+			want:   []summary{{EscapingMisuse, taint.Medium, "passthru"}},
+		},
+		{
+			name:   "escaped program",
+			source: "<?php\nshell_exec(escapeshellarg($_GET['tool']) . ' --version');",
+			want:   []summary{{CommandInjection, taint.High, "shell_exec"}},
+		},
+		{
+			name:   "proc_open of an array runs no shell",
+			source: "<?php\nproc_open(['git', 'log', $_GET['rev']], $spec, $pipes);",
+			want:   []summary{{ArgumentInjection, taint.Medium, "proc_open"}},
+		},
+		{
+			name:   "request data evaluated",
+			source: "<?php\neval('return ' . addslashes($_POST['expr']) . ';');",
+			want:   []summary{{CodeInjection, taint.High, "eval"}},
+		},
+		{
+			name:   "constant code evaluated",
+			source: "<?php\neval('return 1;');",
+		},
+		{
+			name:   "request data choosing the function",
+			source: "<?php\n$f = $_GET['f'];\n$f();\narray_map($_GET['g'], $items);",
+			want:   []summary{{DynamicCall, taint.High, "$f()"}, {DynamicCall, taint.High, "array_map"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var got []summary
+			for _, f := range a.Analyze() {
+				got = append(got, summary{f.Category, f.Severity, f.Sink})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package inventory
+
+// Category is a family of dangerous functions
+type Category string
+
+const (
+	CodeExecution    Category = "code-execution"
+	CommandExecution Category = "command-execution"
+	FileOperation    Category = "file-operation"
+	Deserialization  Category = "deserialization"
+	WeakCrypto       Category = "weak-crypto"
+	InfoDisclosure   Category = "info-disclosure"
+)
+
+// Categories lists the categories in report order
+var Categories = []Category{CodeExecution, CommandExecution, FileOperation, Deserialization, WeakCrypto, InfoDisclosure}
+
+// Catalog maps the lowercase names of the dangerous functions to their category. The backtick
+// operator is reported as shell. Some functions are only reported with dangerous arguments:
+// preg_replace when its pattern may use the /e modifier, mail with the additional parameters
+// passed to sendmail, print_r and var_export when they print the dump.
+var Catalog = map[string]Category{
+	"eval":                 CodeExecution,
+	"assert":               CodeExecution,
+	"create_function":      CodeExecution,
+	"preg_replace":         CodeExecution,
+	"call_user_func":       CodeExecution,
+	"call_user_func_array": CodeExecution,
+
+	"exec":         CommandExecution,
+	"system":       CommandExecution,
+	"passthru":     CommandExecution,
+	"shell_exec":   CommandExecution,
+	"popen":        CommandExecution,
+	"proc_open":    CommandExecution,
+	"pcntl_exec":   CommandExecution,
+	"expect_popen": CommandExecution,
+	"mail":         CommandExecution,
+	"shell":        CommandExecution,
+
+	"fopen":              FileOperation,
+	"file":               FileOperation,
+	"file_get_contents":  FileOperation,
+	"file_put_contents":  FileOperation,
+	"readfile":           FileOperation,
+	"fpassthru":          FileOperation,
+	"unlink":             FileOperation,
+	"rmdir":              FileOperation,
+	"mkdir":              FileOperation,
+	"rename":             FileOperation,
+	"copy":               FileOperation,
+	"chmod":              FileOperation,
+	"chown":              FileOperation,
+	"symlink":            FileOperation,
+	"tempnam":            FileOperation,
+	"move_uploaded_file": FileOperation,
+	"parse_ini_file":     FileOperation,
+	"highlight_file":     FileOperation,
+	"show_source":        FileOperation,
+
+	"unserialize":          Deserialization,
+	"maybe_unserialize":    Deserialization,
+	"igbinary_unserialize": Deserialization,
+	"yaml_parse":           Deserialization,
+	"yaml_parse_file":      Deserialization,
+
+	"md5":         WeakCrypto,
+	"md5_file":    WeakCrypto,
+	"sha1":        WeakCrypto,
+	"sha1_file":   WeakCrypto,
+	"crc32":       WeakCrypto,
+	"crypt":       WeakCrypto,
+	"rand":        WeakCrypto,
+	"mt_rand":     WeakCrypto,
+	"srand":       WeakCrypto,
+	"mt_srand":    WeakCrypto,
+	"lcg_value":   WeakCrypto,
+	"uniqid":      WeakCrypto,
+	"str_shuffle": WeakCrypto,
+
+	"phpinfo":               InfoDisclosure,
+	"phpversion":            InfoDisclosure,
+	"php_uname":             InfoDisclosure,
+	"getmypid":              InfoDisclosure,
+	"get_current_user":      InfoDisclosure,
+	"debug_print_backtrace": InfoDisclosure,
+	"debug_zval_dump":       InfoDisclosure,
+	"var_dump":              InfoDisclosure,
+	"print_r":               InfoDisclosure,
+	"var_export":            InfoDisclosure,
+	"error_log":             InfoDisclosure,
+}
+
+// mcrypt functions are reported as weak crypto by prefix
+const weakCryptoPrefix = "mcrypt_"
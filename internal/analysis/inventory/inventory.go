@@ -0,0 +1,231 @@
+package inventory
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Usage is a call to a dangerous function
+type Usage struct {
+	Category Category `json:"category"`
+	Function string   `json:"function"`
+	File     string   `json:"file"`
+	Line     uint     `json:"line"`
+	// Via is the function calling a dangerous callback, such as call_user_func
+	Via  string    `json:"via,omitempty"`
+	Node *ast.Node `json:"-"`
+}
+
+// Report is the usage of the dangerous functions of a category
+type Report struct {
+	Category Category `json:"category"`
+	Count    int      `json:"count"`
+	// Functions maps the functions used to their usage count
+	Functions map[string]int `json:"functions"`
+	Usages    []*Usage       `json:"usages"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Inventory collects the calls to the dangerous functions of the catalog in the files added to it
+type Inventory struct {
+	files     []*file
+	evaluator *values.Evaluator
+}
+
+func New() *Inventory {
+	return &Inventory{evaluator: values.New()}
+}
+
+// AddFile adds a file to the inventory
+func (i *Inventory) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	i.files = append(i.files, &file{path: path, root: root})
+	i.evaluator.AddFile(path, root)
+}
+
+// Usages returns the calls to dangerous functions of all the files, ordered by file and line
+func (i *Inventory) Usages() []*Usage {
+	i.evaluator.Resolve()
+	var usages []*Usage
+	for _, f := range i.files {
+		v := &callVisitor{inventory: i, file: f}
+		f.root.WalkPrefix(v)
+		usages = append(usages, v.usages...)
+	}
+	sort.SliceStable(usages, func(a, b int) bool {
+		if usages[a].File != usages[b].File {
+			return usages[a].File < usages[b].File
+		}
+		return usages[a].Line < usages[b].Line
+	})
+	return usages
+}
+
+// Reports groups usages by category, in the order of Categories. Categories without usage are omitted.
+func Reports(usages []*Usage) []*Report {
+	byCategory := make(map[Category]*Report)
+	for _, u := range usages {
+		r, ok := byCategory[u.Category]
+		if !ok {
+			r = &Report{Category: u.Category, Functions: make(map[string]int)}
+			byCategory[u.Category] = r
+		}
+		r.Count++
+		r.Functions[u.Function]++
+		r.Usages = append(r.Usages, u)
+	}
+	var reports []*Report
+	for _, category := range Categories {
+		if r, ok := byCategory[category]; ok {
+			reports = append(reports, r)
+		}
+	}
+	return reports
+}
+
+type callVisitor struct {
+	inventory *Inventory
+	file      *file
+	usages    []*Usage
+}
+
+func (v *callVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "shell_command_expression":
+		v.add(n, "shell", "")
+	case "function_call_expression":
+		name := n.ChildOfKind("name", "qualified_name")
+		if name == nil {
+			return
+		}
+		function := strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+		if function == "call_user_func" || function == "call_user_func_array" {
+			v.callback(n, function)
+			return
+		}
+		if v.dangerous(n, function) {
+			v.add(n, function, "")
+		}
+	}
+}
+
+func (v *callVisitor) add(n *ast.Node, function, via string) {
+	category, ok := Catalog[function]
+	if !ok && strings.HasPrefix(function, weakCryptoPrefix) {
+		category, ok = WeakCrypto, true
+	}
+	if !ok {
+		return
+	}
+	v.usages = append(v.usages, &Usage{
+		Category: category,
+		Function: function,
+		File:     v.file.path,
+		Line:     n.StartPosition.Row + 1,
+		Via:      via,
+		Node:     n,
+	})
+}
+
+// dangerous reports whether a call to a function of the catalog is dangerous given its arguments
+func (v *callVisitor) dangerous(call *ast.Node, function string) bool {
+	arguments := call.Arguments()
+	switch function {
+	case "preg_replace":
+		return len(arguments) > 0 && v.evalModifier(arguments[0])
+	case "mail":
+		// Only the additional parameters are passed to the sendmail command line
+		return len(arguments) >= 5
+	case "print_r", "var_export":
+		// The second argument returns the dump instead of printing it
+		return len(arguments) < 2 || !strings.EqualFold(strings.TrimSpace(arguments[1].Text), "true")
+	}
+	_, ok := Catalog[function]
+	return ok || strings.HasPrefix(function, weakCryptoPrefix)
+}
+
+// callback reports the dangerous functions called through call_user_func. A callback that cannot
+// be evaluated is reported as call_user_func itself.
+func (v *callVisitor) callback(call *ast.Node, via string) {
+	arguments := call.Arguments()
+	if len(arguments) == 0 {
+		return
+	}
+	value := v.inventory.evaluator.Eval(arguments[0])
+	callbacks, known := value.Constants()
+	if !known {
+		v.add(call, via, "")
+		return
+	}
+	for _, callback := range callbacks {
+		callback = strings.ToLower(strings.TrimPrefix(callback, "\\"))
+		if _, ok := Catalog[callback]; ok && callback != "preg_replace" {
+			v.add(call, callback, via)
+		}
+	}
+}
+
+// evalModifier reports whether a regular expression may use the /e modifier, which evaluates the
+// replacement as PHP code
+func (v *callVisitor) evalModifier(pattern *ast.Node) bool {
+	candidates := []*ast.Node{pattern}
+	if pattern.Kind == "array_creation_expression" {
+		candidates = nil
+		for _, element := range pattern.NamedChildren() {
+			if children := element.NamedChildren(); len(children) > 0 {
+				candidates = append(candidates, children[len(children)-1])
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		value := v.inventory.evaluator.Eval(candidate)
+		patterns, known := value.Constants()
+		if !known {
+			// The modifiers are the trailing letters of the known suffix, after the delimiter
+			suffix := value.Suffix()
+			head := strings.TrimRightFunc(suffix, unicode.IsLetter)
+			if head != "" && strings.Contains(suffix[len(head):], "e") {
+				return true
+			}
+			continue
+		}
+		for _, p := range patterns {
+			if strings.Contains(modifiers(p), "e") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// modifiers returns the modifiers following the closing delimiter of a regular expression
+func modifiers(pattern string) string {
+	pattern = strings.TrimLeft(pattern, " \t\n")
+	if pattern == "" {
+		return ""
+	}
+	closing := pattern[0]
+	switch closing {
+	case '(':
+		closing = ')'
+	case '{':
+		closing = '}'
+	case '[':
+		closing = ']'
+	case '<':
+		closing = '>'
+	}
+	end := strings.LastIndexByte(pattern, closing)
+	if end <= 0 {
+		return ""
+	}
+	return pattern[end+1:]
+}
@@ -0,0 +1,80 @@
+package inventory
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestUsages(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// usages are the functions reported, followed by the function calling them if any
+		usages []string
+	}{
+		{
+			name:   "direct calls",
+			source: "<?php\neval($c);\n\\System($c);\n`ls`;\n$h = mcrypt_encrypt($a, $b, $c, $d);\n",
+			usages: []string{"eval", "system", "shell", "mcrypt_encrypt"},
+		},
+		{
+			name:   "unrelated calls",
+			source: "<?php\nstrlen($a);\n$o->exec($c);\nhash('sha256', $a);\n",
+		},
+		{
+			name:   "preg_replace with and without /e",
+			source: "<?php\npreg_replace('/a/e', $r, $s);\npreg_replace('/a/i', $r, $s);\n$p = '#a#';\npreg_replace(array('/b/', $p . 'e'), $r, $s);\n",
+			usages: []string{"preg_replace", "preg_replace"},
+		},
+		{
+			name:   "mail with additional parameters",
+			source: "<?php\nmail($to, $s, $m);\nmail($to, $s, $m, $h, $p);\n",
+			usages: []string{"mail"},
+		},
+		{
+			name:   "dumps printed or returned",
+			source: "<?php\nprint_r($a);\n$s = print_r($a, true);\nvar_export($a, false);\n",
+			usages: []string{"print_r", "var_export"},
+		},
+		{
+			name:   "callbacks of call_user_func",
+			source: "<?php\n$f = 'system';\ncall_user_func($f, $c);\ncall_user_func_array('strlen', [$a]);\ncall_user_func($unknown);\n",
+			usages: []string{"system call_user_func", "call_user_func"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := New()
+			i.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var usages []string
+			for _, u := range i.Usages() {
+				if u.Via != "" {
+					usages = append(usages, u.Function+" "+u.Via)
+				} else {
+					usages = append(usages, u.Function)
+				}
+			}
+			if !slices.Equal(usages, tt.usages) {
+				t.Errorf("usages = %q, want %q", usages, tt.usages)
+			}
+		})
+	}
+}
+
+func TestReports(t *testing.T) {
+	i := New()
+	i.AddFile("test.php", ast.ParseSource([]byte("<?php\nmd5($a);\nsystem($c);\nmd5($b);\nphpinfo();\n")))
+	reports := Reports(i.Usages())
+	var got []Category
+	for _, r := range reports {
+		got = append(got, r.Category)
+	}
+	if want := []Category{CommandExecution, WeakCrypto, InfoDisclosure}; !slices.Equal(got, want) {
+		t.Fatalf("categories = %v, want %v", got, want)
+	}
+	if weak := reports[1]; weak.Count != 2 || weak.Functions["md5"] != 2 {
+		t.Errorf("weak crypto count = %d, md5 = %d, want 2 and 2", weak.Count, weak.Functions["md5"])
+	}
+}
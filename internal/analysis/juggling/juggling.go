@@ -0,0 +1,400 @@
+package juggling
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of loose comparison of a finding
+type Category string
+
+const (
+	// LooseComparison is request data compared with ==, != or <> to a secret or a magic string
+	LooseComparison Category = "loose-comparison"
+	// LooseSwitch is a switch on request data whose cases are secrets or magic strings
+	LooseSwitch Category = "loose-switch"
+	// LooseSearch is request data searched with in_array or array_search without strict mode
+	LooseSearch Category = "loose-search"
+)
+
+// hashFunctions are the functions whose result is a hash, keeping the request data hashed, as a
+// "0e..." hash of chosen input equals any other "0e..." hash with ==
+var hashFunctions = map[string]bool{
+	"md5": true, "sha1": true, "hash": true, "hash_hmac": true, "crypt": true, "crc32": true,
+	"md5_file": true, "sha1_file": true, "hash_file": true, "strtolower": true, "strtoupper": true,
+	"trim": true, "base64_encode": true, "bin2hex": true,
+}
+
+// searches maps the functions searching an array to the index of their needle and of their strict
+// flag
+var searches = map[string]struct{ needle, strict int }{
+	"in_array": {0, 2}, "array_search": {0, 2}, "array_keys": {1, 2},
+}
+
+var (
+	// magicString matches the strings PHP compares as numbers with ==, such as "0e1234" which
+	// equals 0 and any other hash in scientific notation
+	magicString = regexp.MustCompile(`^\s*[+-]?0*(\.0*)?[eE][+-]?\d+\s*$`)
+	secretName  = regexp.MustCompile(`(?i)(hash|digest|token|pass(wd|word)?|pwd|secret|signature|hmac|nonce|otp|api_?key|auth_?key|csrf|checksum|^sig$|^mac$)`)
+)
+
+// Edit replaces the bytes from Start to End of the source of a file
+type Edit struct {
+	Start uint   `json:"start"`
+	End   uint   `json:"end"`
+	Text  string `json:"text"`
+}
+
+// Finding is a loose comparison of request data with a secret or a magic string
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	File     string         `json:"file"`
+	Line     uint           `json:"line"`
+	Message  string         `json:"message"`
+	// Fix is the strict form of the comparison, and Edits the changes of the source making it
+	Fix   string       `json:"fix"`
+	Edits []*Edit      `json:"edits"`
+	Taint *taint.Taint `json:"taint,omitempty"`
+	Node  *ast.Node    `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the type juggling flaws of a project: the request data compared with ==, != or
+// <>, switched on or searched without strict mode, where the other side is a hash, a token, a
+// password or a string such as "0e..." that PHP compares as a number. Each finding comes with
+// the strict form of the comparison.
+type Analyzer struct {
+	taint *taint.Analyzer
+	files []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{taint: taint.New()}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.taint.AddFile(path, root)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.taint.Resolve()
+	var findings []*Finding
+	for _, f := range a.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{
+			"binary_expression": true, "switch_statement": true, "function_call_expression": true,
+		}}
+		f.root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			var finding *Finding
+			switch n.Kind {
+			case "binary_expression":
+				finding = a.comparison(n)
+			case "switch_statement":
+				finding = a.switchStatement(n)
+			default:
+				finding = a.search(n)
+			}
+			if finding != nil {
+				finding.File = f.path
+				finding.Line = n.StartPosition.Row + 1
+				finding.Node = n
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// comparison checks a comparison with ==, != or <>
+func (a *Analyzer) comparison(n *ast.Node) *Finding {
+	op := operatorNode(n)
+	if op == nil || op.Kind != "==" && op.Kind != "!=" && op.Kind != "<>" {
+		return nil
+	}
+	children := n.NamedChildren()
+	if len(children) != 2 {
+		return nil
+	}
+	for i, side := range children {
+		t, hashed := a.derived(side, 0)
+		if t == nil {
+			continue
+		}
+		other := children[1-i]
+		if derived, _ := a.derived(other, 0); derived != nil {
+			return nil
+		}
+		what, severity := a.secret(other, hashed)
+		if what == "" {
+			continue
+		}
+		strict := "==="
+		if op.Kind != "==" {
+			strict = "!=="
+		}
+		edit := &Edit{Start: op.StartByte, End: op.EndByte, Text: strict}
+		return &Finding{
+			Category: LooseComparison,
+			Severity: severity,
+			Message: fmt.Sprintf("request data %s compared with %s to %s, which PHP may compare as numbers, use %s",
+				t.Source.Name, op.Kind, what, strict),
+			Fix:   apply(n, edit),
+			Edits: []*Edit{edit},
+			Taint: t,
+		}
+	}
+	return nil
+}
+
+// switchStatement checks a switch on request data, which compares its cases with ==. The fix
+// switches on true and compares each case with ===.
+func (a *Analyzer) switchStatement(n *ast.Node) *Finding {
+	condition := n.ChildOfKind("parenthesized_expression")
+	block := n.ChildOfKind("switch_block")
+	if condition == nil || block == nil || len(condition.NamedChildren()) != 1 {
+		return nil
+	}
+	subject := condition.NamedChildren()[0]
+	t, hashed := a.derived(subject, 0)
+	if t == nil {
+		return nil
+	}
+	var edits []*Edit
+	what := ""
+	severity := taint.Medium
+	for _, c := range block.NamedChildren() {
+		if c.Kind != "case_statement" || len(c.NamedChildren()) == 0 {
+			continue
+		}
+		value := c.NamedChildren()[0]
+		if w, s := a.secret(value, hashed); w != "" && what == "" {
+			what, severity = w, s
+		}
+		edits = append(edits, &Edit{Start: value.StartByte, End: value.EndByte, Text: subject.Text + " === " + value.Text})
+	}
+	if what == "" {
+		return nil
+	}
+	edits = append([]*Edit{{Start: subject.StartByte, End: subject.EndByte, Text: "true"}}, edits...)
+	return &Finding{
+		Category: LooseSwitch,
+		Severity: severity,
+		Message: fmt.Sprintf("switch on request data %s compares its cases with ==, including %s, switch on true and compare with ===",
+			t.Source.Name, what),
+		Fix:   apply(n, edits...),
+		Edits: edits,
+		Taint: t,
+	}
+}
+
+// search checks in_array, array_search and array_keys searching request data among secrets
+// without their strict flag
+func (a *Analyzer) search(n *ast.Node) *Finding {
+	name := taint.FunctionName(n)
+	function, ok := searches[name]
+	arguments := n.Arguments()
+	if !ok || len(arguments) < 2 || function.needle >= len(arguments) {
+		return nil
+	}
+	var edit *Edit
+	if function.strict < len(arguments) {
+		strict := arguments[function.strict]
+		if value := strings.ToLower(strings.TrimSpace(strict.Text)); value != "false" && value != "0" && value != "null" {
+			return nil
+		}
+		edit = &Edit{Start: strict.StartByte, End: strict.EndByte, Text: "true"}
+	} else {
+		last := arguments[len(arguments)-1]
+		edit = &Edit{Start: last.EndByte, End: last.EndByte, Text: ", true"}
+	}
+	t, hashed := a.derived(arguments[function.needle], 0)
+	if t == nil {
+		return nil
+	}
+	haystack := arguments[function.needle+1]
+	if name == "array_keys" {
+		haystack = arguments[0]
+	}
+	what, severity := a.secret(haystack, hashed)
+	if what == "" && haystack.Kind == "array_creation_expression" {
+		for _, element := range haystack.NamedChildren() {
+			values := element.NamedChildren()
+			if len(values) == 0 {
+				continue
+			}
+			if what, severity = a.secret(values[len(values)-1], hashed); what != "" {
+				what = "an array holding " + what
+				break
+			}
+		}
+	}
+	if what == "" {
+		return nil
+	}
+	return &Finding{
+		Category: LooseSearch,
+		Severity: severity,
+		Message: fmt.Sprintf("%s searches request data %s in %s with ==, pass true as strict argument",
+			name, t.Source.Name, what),
+		Fix:   apply(n, edit),
+		Edits: []*Edit{edit},
+		Taint: t,
+	}
+}
+
+// derived returns the taint of request data reaching an expression, through the hashes of the
+// request data, which the taint analysis considers clean. hashed is true when the data is hashed.
+func (a *Analyzer) derived(n *ast.Node, depth int) (t *taint.Taint, hashed bool) {
+	if depth > 8 {
+		return nil, false
+	}
+	if taints := taint.Unsanitized(a.taint.Of(n), "intval", "floatval", "absint", "boolval"); len(taints) > 0 {
+		return taints[0], false
+	}
+	switch n.Kind {
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return a.derived(children[0], depth+1)
+		}
+	case "function_call_expression":
+		if !hashFunctions[taint.FunctionName(n)] {
+			return nil, false
+		}
+		for _, argument := range n.Arguments() {
+			if t, _ := a.derived(argument, depth+1); t != nil {
+				return t, true
+			}
+		}
+	case "variable_name":
+		s := scope.Of(n)
+		if s == nil {
+			return nil, false
+		}
+		for _, def := range s.DefsOf(n) {
+			if def.Parent == nil || def.Parent.Kind != "assignment_expression" {
+				continue
+			}
+			if values := def.Parent.NamedChildren(); len(values) == 2 && values[0] == def {
+				if t, hashed := a.derived(values[1], depth+1); t != nil {
+					return t, hashed
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// secret describes the other side of a comparison with request data when it makes the loose
+// comparison dangerous: a magic string, a hash, or a value named as a hash, token or password.
+// Hashed request data is also dangerous against any hash.
+func (a *Analyzer) secret(n *ast.Node, hashed bool) (string, taint.Severity) {
+	if n.Kind == "parenthesized_expression" {
+		if children := n.NamedChildren(); len(children) == 1 {
+			return a.secret(children[0], hashed)
+		}
+	}
+	if values, ok := a.taint.Evaluator().Eval(n).Constants(); ok {
+		for _, value := range values {
+			if magicString.MatchString(value) {
+				return fmt.Sprintf("the magic string %q", value), taint.High
+			}
+		}
+		return "", ""
+	}
+	if n.Kind == "function_call_expression" && hashFunctions[taint.FunctionName(n)] {
+		return "the hash " + shorten(n.Text), taint.High
+	}
+	if name := targetName(n); name != "" && secretName.MatchString(name) {
+		if hashed {
+			return "the hash " + shorten(n.Text), taint.High
+		}
+		return shorten(n.Text), taint.Medium
+	}
+	return "", ""
+}
+
+// apply returns the text of a node with edits made inside it
+func apply(n *ast.Node, edits ...*Edit) string {
+	return Apply(n.Text, n.StartByte, edits)
+}
+
+// Apply makes edits to a source starting at offset, ignoring the edits out of it and the edits
+// overlapping a previous one
+func Apply(source string, offset uint, edits []*Edit) string {
+	sorted := append([]*Edit{}, edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	var sb strings.Builder
+	position := offset
+	end := offset + uint(len(source))
+	for _, e := range sorted {
+		if e.Start < position || e.End > end || e.End < e.Start {
+			continue
+		}
+		sb.WriteString(source[position-offset : e.Start-offset])
+		sb.WriteString(e.Text)
+		position = e.End
+	}
+	sb.WriteString(source[position-offset:])
+	return sb.String()
+}
+
+// targetName returns the name of a variable, a property, a constant or the string key of an array
+// element
+func targetName(n *ast.Node) string {
+	switch n.Kind {
+	case "variable_name", "name":
+		return strings.TrimPrefix(n.Text, "$")
+	case "member_access_expression", "nullsafe_member_access_expression", "scoped_property_access_expression", "class_constant_access_expression":
+		children := n.NamedChildren()
+		return targetName(children[len(children)-1])
+	case "subscript_expression":
+		children := n.NamedChildren()
+		if len(children) == 2 {
+			if key := strings.Trim(children[1].Text, `'"`); children[1].Kind == "string" || children[1].Kind == "encapsed_string" {
+				return key
+			}
+			return targetName(children[0])
+		}
+	}
+	return ""
+}
+
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 40 {
+		return text[:37] + "..."
+	}
+	return text
+}
+
+// operatorNode returns the operator token of a binary expression
+func operatorNode(n *ast.Node) *ast.Node {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child
+		}
+	}
+	return nil
+}
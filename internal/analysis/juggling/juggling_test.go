@@ -0,0 +1,80 @@
+package juggling
+
+import (
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		category Category
+		want     bool
+		fix      string
+	}{
+		{
+			name:     "request data compared with a token",
+			source:   `<?php if ($_GET['token'] == $token) { echo 1; }`,
+			category: LooseComparison, want: true,
+			fix: `$_GET['token'] === $token`,
+		},
+		{
+			name:     "hashed request data compared with a magic string",
+			source:   `<?php if (md5($_GET['p']) != '0e462097431906509019562988736854') { exit; }`,
+			category: LooseComparison, want: true,
+			fix: `md5($_GET['p']) !== '0e462097431906509019562988736854'`,
+		},
+		{
+			name:     "strict comparison",
+			source:   `<?php if ($_GET['token'] === $token) { echo 1; }`,
+			category: LooseComparison, want: false,
+		},
+		{
+			name:     "request data compared with a plain string",
+			source:   `<?php if ($_GET['action'] == 'save') { echo 1; }`,
+			category: LooseComparison, want: false,
+		},
+		{
+			name:     "switch on request data with a password case",
+			source:   `<?php switch ($_POST['p']) { case $password: echo 1; break; }`,
+			category: LooseSwitch, want: true,
+			fix: `switch (true) { case $_POST['p'] === $password: echo 1; break; }`,
+		},
+		{
+			name:     "switch on request data with plain cases",
+			source:   `<?php switch ($_POST['a']) { case 'save': echo 1; break; }`,
+			category: LooseSwitch, want: false,
+		},
+		{
+			name:     "in_array of request data among tokens",
+			source:   `<?php if (in_array($_GET['t'], $tokens)) { echo 1; }`,
+			category: LooseSearch, want: true,
+			fix: `in_array($_GET['t'], $tokens, true)`,
+		},
+		{
+			name:     "strict in_array",
+			source:   `<?php if (in_array($_GET['t'], $tokens, true)) { echo 1; }`,
+			category: LooseSearch, want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var found *Finding
+			for _, finding := range a.Analyze() {
+				if finding.Category == tt.category {
+					found = finding
+				}
+			}
+			if (found != nil) != tt.want {
+				t.Fatalf("%s reported = %t, want %t", tt.category, found != nil, tt.want)
+			}
+			if found != nil && found.Fix != tt.fix {
+				t.Errorf("fix = %s, want %s", found.Fix, tt.fix)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package literal
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// StringKinds are the node kinds of PHP string literals
+var StringKinds = []string{"string", "encapsed_string", "heredoc", "nowdoc"}
+
+// IsString reports whether the node is a string literal, interpolated or not
+func IsString(n *ast.Node) bool {
+	for _, kind := range StringKinds {
+		if n.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the value of a string literal. ok is false if the node is not a string literal
+// or if it interpolates expressions.
+func String(n *ast.Node) (value string, ok bool) {
+	if !IsString(n) {
+		return "", false
+	}
+	var sb strings.Builder
+	doubleQuoted := n.Kind == "encapsed_string" || n.Kind == "heredoc"
+	for _, part := range Parts(n) {
+		switch part.Kind {
+		case "string_content", "nowdoc_string", "string_value":
+			sb.WriteString(part.Text)
+		case "escape_sequence":
+			sb.WriteString(Unescape(part.Text, doubleQuoted))
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// Parts returns the content nodes of a string literal: string contents, escape sequences and
+// interpolated expressions, without the quotes and heredoc delimiters
+func Parts(n *ast.Node) []*ast.Node {
+	var parts []*ast.Node
+	for _, child := range n.Descendants {
+		switch child.Kind {
+		case "heredoc_body", "nowdoc_body":
+			parts = append(parts, Parts(child)...)
+		case "heredoc_start", "heredoc_end":
+		default:
+			if child.IsNamed {
+				parts = append(parts, child)
+			}
+		}
+	}
+	return parts
+}
+
+// Unescape decodes a PHP escape sequence. Single quoted strings only know \' and \\.
+func Unescape(sequence string, doubleQuoted bool) string {
+	if !doubleQuoted {
+		if sequence == `\'` || sequence == `\\` {
+			return sequence[1:]
+		}
+		return sequence
+	}
+	if len(sequence) < 2 {
+		return sequence
+	}
+	switch sequence[1] {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'v':
+		return "\v"
+	case 'e':
+		return "\x1b"
+	case 'f':
+		return "\f"
+	case '\\', '$', '"':
+		return sequence[1:]
+	case 'x':
+		if value, err := strconv.ParseUint(sequence[2:], 16, 8); err == nil {
+			return string([]byte{byte(value)})
+		}
+	case 'u':
+		hex := strings.Trim(sequence[2:], "{}")
+		if value, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return string(rune(value))
+		}
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		if value, err := strconv.ParseUint(sequence[1:], 8, 8); err == nil {
+			return string([]byte{byte(value)})
+		}
+	}
+	return sequence
+}
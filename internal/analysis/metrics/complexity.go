@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+var logicalOperators = map[string]bool{
+	"&&": true, "||": true, "and": true, "or": true, "xor": true, "??": true,
+}
+
+// operator returns the operator of a binary expression
+func operator(n *ast.Node) string {
+	if n.Kind != "binary_expression" || len(n.Descendants) != 3 {
+		return ""
+	}
+	return strings.ToLower(n.Descendants[1].Kind)
+}
+
+// walkBody walks a unit in prefix order, skipping the nested units. visit returns false to skip the children of a node.
+func walkBody(n *ast.Node, visit func(*ast.Node) bool) {
+	var walk func(*ast.Node)
+	walk = func(cur *ast.Node) {
+		if !visit(cur) {
+			return
+		}
+		for _, child := range cur.NamedChildren() {
+			if nested(child) {
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+}
+
+// cyclomatic returns McCabe's complexity of a function: one plus the number of decision points.
+// Nested named functions and classes are not counted, closures are.
+func cyclomatic(n *ast.Node) int {
+	complexity := 1
+	walkBody(n, func(cur *ast.Node) bool {
+		switch cur.Kind {
+		case "if_statement", "else_if_clause", "while_statement", "do_statement", "for_statement",
+			"foreach_statement", "case_statement", "catch_clause", "conditional_expression",
+			"match_conditional_expression":
+			complexity++
+		case "binary_expression":
+			if logicalOperators[operator(cur)] {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// cognitive returns the cognitive complexity of a function: control flow breaks are counted with
+// an increment for their nesting level, sequences of the same boolean operator count once
+func cognitive(n *ast.Node) int {
+	name := ""
+	if nameNode := n.ChildOfKind("name"); nameNode != nil && n.Kind != "program" {
+		name = strings.ToLower(nameNode.Text)
+	}
+	complexity := 0
+	var walk func(cur *ast.Node, nesting int)
+	walkChildren := func(cur *ast.Node, nesting int) {
+		for _, child := range cur.NamedChildren() {
+			if !nested(child) {
+				walk(child, nesting)
+			}
+		}
+	}
+	walk = func(cur *ast.Node, nesting int) {
+		switch cur.Kind {
+		case "if_statement":
+			complexity += 1 + nesting
+			walkIf(cur, nesting, &complexity, walk)
+			return
+		case "switch_statement", "match_expression", "while_statement", "do_statement", "for_statement",
+			"foreach_statement", "catch_clause", "conditional_expression":
+			complexity += 1 + nesting
+			walkChildren(cur, nesting+1)
+			return
+		case "anonymous_function", "arrow_function":
+			walkChildren(cur, nesting+1)
+			return
+		case "break_statement", "continue_statement":
+			if len(cur.NamedChildren()) > 0 {
+				// Breaking out of several levels
+				complexity++
+			}
+		case "goto_statement":
+			complexity++
+		case "binary_expression":
+			if op := operator(cur); logicalOperators[op] && operator(cur.Parent) != op {
+				complexity++
+			}
+		case "function_call_expression":
+			if callee := cur.ChildOfKind("name"); callee != nil && name != "" && strings.ToLower(callee.Text) == name {
+				// Recursion
+				complexity++
+			}
+		case "member_call_expression", "scoped_call_expression":
+			if n.Kind == "method_declaration" && strings.ToLower(cur.MemberName()) == name {
+				complexity++
+			}
+		}
+		walkChildren(cur, nesting)
+	}
+	walkChildren(n, 0)
+	return complexity
+}
+
+// walkIf walks the branches of an if statement: else if and else add one without nesting increment
+func walkIf(n *ast.Node, nesting int, complexity *int, walk func(*ast.Node, int)) {
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "else_if_clause":
+			*complexity++
+			for _, part := range child.NamedChildren() {
+				walk(part, nesting+1)
+			}
+		case "else_clause":
+			*complexity++
+			parts := child.NamedChildren()
+			if len(parts) == 1 && parts[0].Kind == "if_statement" {
+				// else if written in two words
+				walkIf(parts[0], nesting, complexity, walk)
+				continue
+			}
+			for _, part := range parts {
+				walk(part, nesting+1)
+			}
+		default:
+			walk(child, nesting+1)
+		}
+	}
+}
+
+// nesting returns the maximum nesting depth of the control structures of a function
+func nesting(n *ast.Node) int {
+	maxDepth := 0
+	var walk func(cur *ast.Node, depth int)
+	walk = func(cur *ast.Node, depth int) {
+		switch cur.Kind {
+		case "if_statement", "switch_statement", "while_statement", "do_statement", "for_statement",
+			"foreach_statement", "try_statement", "match_expression":
+			depth++
+			maxDepth = max(maxDepth, depth)
+		case "else_clause":
+			if parts := cur.NamedChildren(); len(parts) == 1 && parts[0].Kind == "if_statement" {
+				// else if does not nest further
+				depth--
+			}
+		}
+		for _, child := range cur.NamedChildren() {
+			if !nested(child) {
+				walk(child, depth)
+			}
+		}
+	}
+	for _, child := range n.NamedChildren() {
+		if !nested(child) {
+			walk(child, 0)
+		}
+	}
+	return maxDepth
+}
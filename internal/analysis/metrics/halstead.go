@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Halstead holds the Halstead measures of a unit
+type Halstead struct {
+	DistinctOperators int     `json:"distinct_operators"`
+	DistinctOperands  int     `json:"distinct_operands"`
+	Operators         int     `json:"operators"`
+	Operands          int     `json:"operands"`
+	Vocabulary        int     `json:"vocabulary"`
+	Length            int     `json:"length"`
+	Volume            float64 `json:"volume"`
+	Difficulty        float64 `json:"difficulty"`
+	Effort            float64 `json:"effort"`
+	// Time is the estimated implementation time in seconds
+	Time float64 `json:"time"`
+	// Bugs is the estimated number of delivered bugs
+	Bugs float64 `json:"bugs"`
+}
+
+// operandKinds are the nodes counted as a single operand
+var operandKinds = map[string]bool{
+	"variable_name": true, "name": true, "qualified_name": true,
+	"integer": true, "float": true, "boolean": true, "null": true,
+	"string": true, "encapsed_string": true, "heredoc": true, "nowdoc": true,
+}
+
+// closingTokens close a pair whose opening token is already counted as the operator
+var closingTokens = map[string]bool{")": true, "]": true, "}": true}
+
+// halstead counts the operators and operands of a node. Variables, names and literals are
+// operands; keywords, operators and punctuation are operators.
+func halstead(n *ast.Node) Halstead {
+	operators := make(map[string]int)
+	operands := make(map[string]int)
+	var walk func(*ast.Node)
+	walk = func(cur *ast.Node) {
+		switch {
+		case cur.Kind == "comment" || cur.Kind == "text" || cur.Kind == "php_tag" || cur.Kind == "?>":
+			return
+		case operandKinds[cur.Kind]:
+			operands[cur.Text]++
+			for _, child := range cur.NamedChildren() {
+				// Interpolated expressions
+				if child.Kind != "string_content" && child.Kind != "escape_sequence" && child.Kind != "string_value" {
+					walk(child)
+				}
+			}
+			return
+		case len(cur.Descendants) == 0:
+			if !closingTokens[cur.Kind] {
+				operators[cur.Kind]++
+			}
+			return
+		}
+		for _, child := range cur.Descendants {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	h := Halstead{DistinctOperators: len(operators), DistinctOperands: len(operands)}
+	for _, count := range operators {
+		h.Operators += count
+	}
+	for _, count := range operands {
+		h.Operands += count
+	}
+	h.Vocabulary = h.DistinctOperators + h.DistinctOperands
+	h.Length = h.Operators + h.Operands
+	if h.Vocabulary > 0 {
+		h.Volume = float64(h.Length) * math.Log2(float64(h.Vocabulary))
+	}
+	if h.DistinctOperands > 0 {
+		h.Difficulty = float64(h.DistinctOperators) / 2 * float64(h.Operands) / float64(h.DistinctOperands)
+	}
+	h.Effort = h.Difficulty * h.Volume
+	h.Time = h.Effort / 18
+	h.Bugs = h.Volume / 3000
+	h.Volume = round(h.Volume)
+	h.Difficulty = round(h.Difficulty)
+	h.Effort = round(h.Effort)
+	h.Time = round(h.Time)
+	h.Bugs = math.Round(h.Bugs*1000) / 1000
+	return h
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
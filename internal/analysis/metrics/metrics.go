@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// UnitKind is the kind of code unit metrics are computed for
+type UnitKind string
+
+const (
+	FileUnit     UnitKind = "file"
+	ClassUnit    UnitKind = "class"
+	FunctionUnit UnitKind = "function"
+	MethodUnit   UnitKind = "method"
+)
+
+// Metrics holds the measures of a code unit
+type Metrics struct {
+	// PhysicalLOC is the number of lines spanned by the unit
+	PhysicalLOC int `json:"physical_loc"`
+	// SourceLOC is the number of lines containing code
+	SourceLOC int `json:"source_loc"`
+	// CommentLOC is the number of lines containing a comment
+	CommentLOC int `json:"comment_loc"`
+	// BlankLOC is the number of empty lines
+	BlankLOC int `json:"blank_loc"`
+	// LogicalLOC is the number of statements and declarations
+	LogicalLOC int `json:"logical_loc"`
+	// Cyclomatic is McCabe's complexity. For classes and files it is the sum over their functions.
+	Cyclomatic int `json:"cyclomatic"`
+	// Cognitive is the SonarSource cognitive complexity
+	Cognitive   int      `json:"cognitive"`
+	MaxNesting  int      `json:"max_nesting"`
+	Parameters  int      `json:"parameters"`
+	Halstead    Halstead `json:"halstead"`
+	Maintenance float64  `json:"maintainability_index"`
+}
+
+// Unit is a file, class, function or method with its metrics
+type Unit struct {
+	Kind UnitKind `json:"kind"`
+	Name string   `json:"name"`
+	File string   `json:"file"`
+	Line uint     `json:"line"`
+	Metrics
+}
+
+// Report holds the units of a file: the file itself first, then its classes, functions and methods
+type Report struct {
+	File  string
+	Units []*Unit
+}
+
+// Compute computes the metrics of a file and of the classes, functions and methods it declares
+func Compute(file string, root *ast.Node) *Report {
+	report := &Report{File: file}
+	fileUnit := &Unit{Kind: FileUnit, Name: file, File: file, Line: 1, Metrics: measure(root)}
+	report.Units = append(report.Units, fileUnit)
+
+	v := &unitVisitor{}
+	root.WalkPrefix(v)
+	// Top level code is measured as one more function
+	fileUnit.Cyclomatic = cyclomatic(root)
+	for _, n := range v.units {
+		unit := &Unit{Kind: unitKind(n), Name: unitName(n), File: file, Line: n.StartPosition.Row + 1, Metrics: measure(n)}
+		report.Units = append(report.Units, unit)
+		if unit.Kind == ClassUnit {
+			// Weighted methods per class
+			unit.Cyclomatic, unit.Cognitive = 0, 0
+			for _, method := range declaredMethods(n) {
+				unit.Cyclomatic += cyclomatic(method)
+				unit.Cognitive += cognitive(method)
+			}
+			unit.Maintenance = maintainability(unit.Metrics)
+			continue
+		}
+		fileUnit.Cyclomatic += unit.Cyclomatic - 1
+		fileUnit.Cognitive += unit.Cognitive
+		fileUnit.MaxNesting = max(fileUnit.MaxNesting, unit.MaxNesting)
+	}
+	fileUnit.Maintenance = maintainability(fileUnit.Metrics)
+	return report
+}
+
+// measure computes the metrics of a node, which is a function body for the complexity measures
+func measure(n *ast.Node) Metrics {
+	m := lines(n)
+	m.LogicalLOC = logical(n)
+	m.Cyclomatic = cyclomatic(n)
+	m.Cognitive = cognitive(n)
+	m.MaxNesting = nesting(n)
+	m.Parameters = parameters(n)
+	m.Halstead = halstead(n)
+	m.Maintenance = maintainability(m)
+	return m
+}
+
+// maintainability returns the maintainability index normalized between 0 and 100:
+// (171 - 5.2 ln(V) - 0.23 CC - 16.2 ln(SLOC)) * 100 / 171
+func maintainability(m Metrics) float64 {
+	volume := math.Max(m.Halstead.Volume, 1)
+	loc := math.Max(float64(m.SourceLOC), 1)
+	mi := (171 - 5.2*math.Log(volume) - 0.23*float64(m.Cyclomatic) - 16.2*math.Log(loc)) * 100 / 171
+	return math.Round(math.Max(0, math.Min(100, mi))*100) / 100
+}
+
+type unitVisitor struct {
+	units []*ast.Node
+}
+
+func (v *unitVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "function_definition", "method_declaration",
+		"class_declaration", "interface_declaration", "trait_declaration", "enum_declaration":
+		v.units = append(v.units, n)
+	}
+}
+
+func unitKind(n *ast.Node) UnitKind {
+	switch n.Kind {
+	case "function_definition":
+		return FunctionUnit
+	case "method_declaration":
+		return MethodUnit
+	}
+	return ClassUnit
+}
+
+func unitName(n *ast.Node) string {
+	name := ""
+	if nameNode := n.ChildOfKind("name"); nameNode != nil {
+		name = nameNode.Text
+	}
+	if n.Kind != "method_declaration" {
+		return name
+	}
+	for cur := n.Parent; cur != nil; cur = cur.Parent {
+		switch cur.Kind {
+		case "class_declaration", "interface_declaration", "trait_declaration", "enum_declaration":
+			if class := cur.ChildOfKind("name"); class != nil {
+				return class.Text + "::" + name
+			}
+		case "anonymous_class", "object_creation_expression":
+			return "class@anonymous::" + name
+		}
+	}
+	return name
+}
+
+func declaredMethods(class *ast.Node) []*ast.Node {
+	body := class.ChildOfKind("declaration_list", "enum_declaration_list")
+	if body == nil {
+		return nil
+	}
+	return body.ChildrenOfKind("method_declaration")
+}
+
+// nested reports whether a node starts a unit measured on its own, which the enclosing unit skips
+func nested(n *ast.Node) bool {
+	switch n.Kind {
+	case "function_definition", "method_declaration",
+		"class_declaration", "interface_declaration", "trait_declaration", "enum_declaration":
+		return true
+	}
+	return false
+}
+
+// lines counts the physical, source, comment and blank lines of a node
+func lines(n *ast.Node) Metrics {
+	physical := int(n.EndPosition.Row - n.StartPosition.Row + 1)
+	if n.EndPosition.Column == 0 && n.EndPosition.Row > n.StartPosition.Row {
+		// The node ends with a newline
+		physical--
+	}
+	code := make(map[uint]bool)
+	comments := make(map[uint]bool)
+	var walk func(*ast.Node)
+	walk = func(cur *ast.Node) {
+		switch {
+		case cur.Kind == "comment":
+			for row := cur.StartPosition.Row; row <= cur.EndPosition.Row; row++ {
+				comments[row] = true
+			}
+			return
+		case cur.Kind == "text" || cur.Kind == "php_tag" || cur.Kind == "?>":
+			return
+		case len(cur.Descendants) == 0:
+			for row := cur.StartPosition.Row; row <= cur.EndPosition.Row; row++ {
+				code[row] = true
+			}
+			return
+		}
+		for _, child := range cur.Descendants {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	blank := 0
+	for _, line := range strings.Split(n.Text, "\n")[:physical] {
+		if strings.TrimSpace(line) == "" {
+			blank++
+		}
+	}
+	return Metrics{PhysicalLOC: physical, SourceLOC: len(code), CommentLOC: len(comments), BlankLOC: blank}
+}
+
+// logical counts the statements and declarations of a node
+func logical(n *ast.Node) int {
+	count := 0
+	var walk func(*ast.Node)
+	walk = func(cur *ast.Node) {
+		switch {
+		case cur.Kind == "compound_statement" || cur.Kind == "empty_statement":
+		case strings.HasSuffix(cur.Kind, "_statement"), strings.HasSuffix(cur.Kind, "_declaration"),
+			cur.Kind == "function_definition":
+			count++
+		}
+		for _, child := range cur.NamedChildren() {
+			walk(child)
+		}
+	}
+	walk(n)
+	return count
+}
+
+// parameters counts the parameters of a function or method
+func parameters(n *ast.Node) int {
+	if n.Kind != "function_definition" && n.Kind != "method_declaration" {
+		return 0
+	}
+	if formal := n.ChildOfKind("formal_parameters"); formal != nil {
+		return len(formal.NamedChildren())
+	}
+	return 0
+}
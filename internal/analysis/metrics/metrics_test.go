@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		kind       UnitKind
+		cyclomatic int
+		cognitive  int
+		nesting    int
+		parameters int
+	}{
+		{
+			name:       "straight line",
+			source:     "<?php\nfunction f($a) {\nreturn $a;\n}",
+			kind:       FunctionUnit,
+			cyclomatic: 1, cognitive: 0, nesting: 0, parameters: 1,
+		},
+		{
+			name:       "if, elseif and else",
+			source:     "<?php\nfunction f($a, $b) {\nif ($a) {\n} elseif ($b) {\n} else {\n}\n}",
+			kind:       FunctionUnit,
+			cyclomatic: 3, cognitive: 3, nesting: 1, parameters: 2,
+		},
+		{
+			name:       "nested structures weigh more",
+			source:     "<?php\nfunction f($a, $b) {\nforeach ($a as $x) {\nwhile ($b) {\nif ($x) {\n}\n}\n}\n}",
+			kind:       FunctionUnit,
+			cyclomatic: 4, cognitive: 6, nesting: 3, parameters: 2,
+		},
+		{
+			name:       "sequences of boolean operators",
+			source:     "<?php\nfunction f($a, $b, $c) {\nreturn $a && $b && $c || $a;\n}",
+			kind:       FunctionUnit,
+			cyclomatic: 4, cognitive: 2, nesting: 0, parameters: 3,
+		},
+		{
+			name:       "switch cases",
+			source:     "<?php\nfunction f($a) {\nswitch ($a) {\ncase 1:\nbreak;\ncase 2:\nbreak;\ndefault:\n}\n}",
+			kind:       FunctionUnit,
+			cyclomatic: 3, cognitive: 1, nesting: 1, parameters: 1,
+		},
+		{
+			name:       "recursive method",
+			source:     "<?php\nclass C {\nfunction f($n) {\nreturn $this->f($n - 1);\n}\n}",
+			kind:       MethodUnit,
+			cyclomatic: 1, cognitive: 1, nesting: 0, parameters: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unit *Unit
+			for _, u := range Compute("test.php", ast.ParseSource([]byte(tt.source))).Units {
+				if u.Kind == tt.kind {
+					unit = u
+				}
+			}
+			if unit == nil {
+				t.Fatalf("no %s unit", tt.kind)
+			}
+			got := [4]int{unit.Cyclomatic, unit.Cognitive, unit.MaxNesting, unit.Parameters}
+			want := [4]int{tt.cyclomatic, tt.cognitive, tt.nesting, tt.parameters}
+			if got != want {
+				t.Errorf("cyclomatic, cognitive, nesting, parameters = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	unit := &Unit{Kind: FunctionUnit, Metrics: Metrics{Cyclomatic: 12, Parameters: 3, Maintenance: 40}}
+	thresholds := Thresholds{Cyclomatic: 10, Parameters: 5, Maintenance: 50}
+	var metrics []string
+	for _, violation := range thresholds.Check(unit) {
+		metrics = append(metrics, violation.Metric)
+	}
+	if len(metrics) != 2 || metrics[0] != "cyclomatic complexity" || metrics[1] != "maintainability index" {
+		t.Errorf("violations = %v, want cyclomatic complexity and maintainability index", metrics)
+	}
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+)
+
+// Summary aggregates the metrics of a project
+type Summary struct {
+	Files              int     `json:"files"`
+	Classes            int     `json:"classes"`
+	Functions          int     `json:"functions"`
+	PhysicalLOC        int     `json:"physical_loc"`
+	SourceLOC          int     `json:"source_loc"`
+	CommentLOC         int     `json:"comment_loc"`
+	LogicalLOC         int     `json:"logical_loc"`
+	AverageCyclomatic  float64 `json:"average_cyclomatic"`
+	MaxCyclomatic      int     `json:"max_cyclomatic"`
+	AverageCognitive   float64 `json:"average_cognitive"`
+	MaxCognitive       int     `json:"max_cognitive"`
+	MaxNesting         int     `json:"max_nesting"`
+	AverageMaintenance float64 `json:"average_maintainability_index"`
+	MinMaintenance     float64 `json:"min_maintainability_index"`
+}
+
+// Summarize aggregates the reports of the files of a project. Complexity averages are computed over
+// the functions and methods, maintainability averages over the files.
+func Summarize(reports []*Report) Summary {
+	s := Summary{MinMaintenance: 100}
+	cyclomatic, cognitive, maintenance := 0, 0, 0.0
+	for _, report := range reports {
+		for _, unit := range report.Units {
+			switch unit.Kind {
+			case FileUnit:
+				s.Files++
+				s.PhysicalLOC += unit.PhysicalLOC
+				s.SourceLOC += unit.SourceLOC
+				s.CommentLOC += unit.CommentLOC
+				s.LogicalLOC += unit.LogicalLOC
+				maintenance += unit.Maintenance
+				s.MinMaintenance = math.Min(s.MinMaintenance, unit.Maintenance)
+			case ClassUnit:
+				s.Classes++
+			case FunctionUnit, MethodUnit:
+				s.Functions++
+				cyclomatic += unit.Cyclomatic
+				cognitive += unit.Cognitive
+				s.MaxCyclomatic = max(s.MaxCyclomatic, unit.Cyclomatic)
+				s.MaxCognitive = max(s.MaxCognitive, unit.Cognitive)
+				s.MaxNesting = max(s.MaxNesting, unit.MaxNesting)
+			}
+		}
+	}
+	if s.Functions > 0 {
+		s.AverageCyclomatic = round(float64(cyclomatic) / float64(s.Functions))
+		s.AverageCognitive = round(float64(cognitive) / float64(s.Functions))
+	}
+	if s.Files > 0 {
+		s.AverageMaintenance = round(maintenance / float64(s.Files))
+	} else {
+		s.MinMaintenance = 0
+	}
+	return s
+}
+
+// Thresholds are the limits a unit must respect. Zero values disable a check.
+type Thresholds struct {
+	// Function limits apply to functions and methods
+	Cyclomatic int
+	Cognitive  int
+	Nesting    int
+	Parameters int
+	LOC        int
+	// Maintenance is the minimum maintainability index of functions, methods and files
+	Maintenance float64
+}
+
+// Violation is a threshold exceeded by a unit
+type Violation struct {
+	Unit    *Unit  `json:"unit"`
+	Metric  string `json:"metric"`
+	Message string `json:"message"`
+}
+
+// Check returns the thresholds exceeded by a unit
+func (t Thresholds) Check(unit *Unit) []Violation {
+	var violations []Violation
+	add := func(metric string, value, limit any) {
+		violations = append(violations, Violation{
+			Unit:    unit,
+			Metric:  metric,
+			Message: fmt.Sprintf("%s %s is %v (limit %v)", unit.Kind, metric, value, limit),
+		})
+	}
+	if unit.Kind == FunctionUnit || unit.Kind == MethodUnit {
+		if t.Cyclomatic > 0 && unit.Cyclomatic > t.Cyclomatic {
+			add("cyclomatic complexity", unit.Cyclomatic, t.Cyclomatic)
+		}
+		if t.Cognitive > 0 && unit.Cognitive > t.Cognitive {
+			add("cognitive complexity", unit.Cognitive, t.Cognitive)
+		}
+		if t.Nesting > 0 && unit.MaxNesting > t.Nesting {
+			add("nesting depth", unit.MaxNesting, t.Nesting)
+		}
+		if t.Parameters > 0 && unit.Parameters > t.Parameters {
+			add("parameter count", unit.Parameters, t.Parameters)
+		}
+		if t.LOC > 0 && unit.PhysicalLOC > t.LOC {
+			add("length", unit.PhysicalLOC, t.LOC)
+		}
+	}
+	if unit.Kind != ClassUnit && t.Maintenance > 0 && unit.Maintenance < t.Maintenance {
+		add("maintainability index", unit.Maintenance, t.Maintenance)
+	}
+	return violations
+}
@@ -0,0 +1,98 @@
+package phpdoc
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the doc comment of a declaration is stored
+const AttributeKey = "phpdoc"
+
+func init() {
+	ast.HasDocTag = func(n *ast.Node, tag string) bool {
+		doc := Of(n)
+		return doc != nil && doc.Has(strings.ToLower(tag))
+	}
+}
+
+// Kinds are the node kinds a doc comment is attached to. Expression statements carry the inline
+// @var annotations of assignments.
+var Kinds = map[string]bool{
+	"function_definition":   true,
+	"method_declaration":    true,
+	"class_declaration":     true,
+	"interface_declaration": true,
+	"trait_declaration":     true,
+	"enum_declaration":      true,
+	"enum_case":             true,
+	"property_declaration":  true,
+	"const_declaration":     true,
+	"expression_statement":  true,
+}
+
+// Attach parses the doc comments of a tree and stores each one in the attributes of the
+// declaration that immediately follows it
+func Attach(root *ast.Node) {
+	root.SetParents()
+	v := &attachVisitor{}
+	root.WalkPrefix(v)
+}
+
+type attachVisitor struct{}
+
+func (v *attachVisitor) VisitNode(n *ast.Node) {
+	for i, child := range n.Descendants {
+		if !isDocComment(child) || i+1 >= len(n.Descendants) {
+			continue
+		}
+		if next := n.Descendants[i+1]; Kinds[next.Kind] {
+			next.SetAttribute(AttributeKey, parseComment(child))
+		}
+	}
+}
+
+// Of returns the doc comment of a declaration, or nil. Declarations of trees that were not
+// passed to Attach are looked up on demand; parent links must be set.
+func Of(n *ast.Node) *DocBlock {
+	if n == nil {
+		return nil
+	}
+	if n.Attributes != nil {
+		if _, ok := n.Attributes[AttributeKey]; ok {
+			doc, _ := n.GetAttribute(AttributeKey).(*DocBlock)
+			return doc
+		}
+	}
+	var doc *DocBlock
+	if comment := previousSibling(n); comment != nil && isDocComment(comment) {
+		doc = parseComment(comment)
+	}
+	n.SetAttribute(AttributeKey, doc)
+	return doc
+}
+
+func previousSibling(n *ast.Node) *ast.Node {
+	if n.Parent == nil {
+		return nil
+	}
+	var previous *ast.Node
+	for _, sibling := range n.Parent.Descendants {
+		if sibling == n {
+			return previous
+		}
+		previous = sibling
+	}
+	return nil
+}
+
+func isDocComment(n *ast.Node) bool {
+	return n.Kind == "comment" && strings.HasPrefix(n.Text, "/**") && n.Text != "/**/"
+}
+
+func parseComment(comment *ast.Node) *DocBlock {
+	doc := Parse(comment.Text)
+	doc.Line = comment.StartPosition.Row + 1
+	doc.Node = comment
+	return doc
+}
@@ -0,0 +1,236 @@
+package phpdoc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// DeprecatedCall is a call to a function or method, or an instantiation of a class, marked @deprecated
+	DeprecatedCall Category = "deprecated-call"
+	// MissingReturn is a documented function returning a value without @return nor declared return type
+	MissingReturn Category = "missing-return"
+	// MissingParam is a parameter without @param in a doc comment documenting the other parameters
+	MissingParam Category = "missing-param"
+	// UnknownParam is a @param naming no parameter of the function
+	UnknownParam Category = "unknown-param"
+)
+
+// Categories are all the categories of the findings
+var Categories = []Category{DeprecatedCall, MissingReturn, MissingParam, UnknownParam}
+
+// Finding is a problem related to the doc comments
+type Finding struct {
+	Category Category  `json:"category"`
+	File     string    `json:"file"`
+	Line     uint      `json:"line"`
+	Name     string    `json:"name"`
+	Message  string    `json:"message"`
+	Node     *ast.Node `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Checker checks the doc comments of the files added to it and the calls to deprecated code
+type Checker struct {
+	files     []*file
+	functions map[string]*ast.Node
+	hierarchy *classes.Hierarchy
+	methods   func(call *ast.Node) []*classes.Method
+}
+
+// New returns a checker resolving the classes with a hierarchy the files are also added to, and
+// the method calls with methods. A nil methods resolves the calls on $this, self, parent and static only.
+func New(hierarchy *classes.Hierarchy, methods func(call *ast.Node) []*classes.Method) *Checker {
+	if methods == nil {
+		methods = hierarchy.ResolveCall
+	}
+	return &Checker{
+		functions: make(map[string]*ast.Node),
+		hierarchy: hierarchy,
+		methods:   methods,
+	}
+}
+
+// AddFile attaches the doc comments of a file and collects its functions
+func (c *Checker) AddFile(path string, root *ast.Node) {
+	Attach(root)
+	c.files = append(c.files, &file{path: path, root: root})
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+	root.WalkPrefix(v)
+	for _, fn := range v.Nodes {
+		if name := fn.ChildOfKind("name"); name != nil {
+			c.functions[strings.ToLower(name.Text)] = fn
+		}
+	}
+}
+
+// Check returns the findings of all the files, ordered by file and line
+func (c *Checker) Check() []*Finding {
+	var findings []*Finding
+	for _, f := range c.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{
+			"function_definition":             true,
+			"method_declaration":              true,
+			"function_call_expression":        true,
+			"member_call_expression":          true,
+			"nullsafe_member_call_expression": true,
+			"scoped_call_expression":          true,
+			"object_creation_expression":      true,
+		}}
+		f.root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			switch n.Kind {
+			case "function_definition", "method_declaration":
+				findings = append(findings, c.declaration(f, n)...)
+			default:
+				findings = append(findings, c.deprecated(f, n)...)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func newFinding(category Category, path string, n *ast.Node, name, message string) *Finding {
+	return &Finding{Category: category, File: path, Line: n.StartPosition.Row + 1, Name: name, Message: message, Node: n}
+}
+
+// deprecated reports a call or an instantiation of deprecated code
+func (c *Checker) deprecated(f *file, n *ast.Node) []*Finding {
+	var findings []*Finding
+	report := func(kind, name string, doc *DocBlock) {
+		if doc == nil || doc.Deprecated() == nil {
+			return
+		}
+		message := "call to deprecated " + kind + " " + name
+		if kind == "class" {
+			message = "instantiation of deprecated class " + name
+		}
+		if reason := strings.ReplaceAll(doc.Deprecated().Description, "\n", " "); reason != "" {
+			message += ": " + reason
+		}
+		findings = append(findings, newFinding(DeprecatedCall, f.path, n, name, message))
+	}
+	switch n.Kind {
+	case "function_call_expression":
+		name := n.ChildOfKind("name", "qualified_name")
+		if name == nil {
+			return nil
+		}
+		short := name.Text[strings.LastIndex(name.Text, "\\")+1:]
+		if fn, ok := c.functions[strings.ToLower(short)]; ok {
+			report("function", short, Of(fn))
+		}
+	case "object_creation_expression":
+		name := n.ChildOfKind("name", "qualified_name")
+		if name == nil {
+			return nil
+		}
+		if class := c.hierarchy.Lookup(c.hierarchy.Namespace(n).Resolve(name.Text)); class != nil {
+			report("class", class.Name, Of(class.Node))
+		}
+	default:
+		for _, m := range c.methods(n) {
+			report("method", m.Class.Name+"::"+m.Name, Of(m.Node))
+		}
+	}
+	return findings
+}
+
+// declaration checks the doc comment of a function or method against its signature and body
+func (c *Checker) declaration(f *file, n *ast.Node) []*Finding {
+	doc := Of(n)
+	name := n.ChildOfKind("name")
+	if doc == nil || name == nil || doc.Inherits() {
+		return nil
+	}
+	var findings []*Finding
+	parameters := make(map[string]bool)
+	var names []string
+	if formal := n.ChildOfKind("formal_parameters"); formal != nil {
+		for _, parameter := range formal.NamedChildren() {
+			if variable := parameter.ChildOfKind("variable_name"); variable != nil {
+				parameters[scope.VariableName(variable)] = true
+				names = append(names, scope.VariableName(variable))
+			}
+		}
+	}
+	for _, tag := range doc.TagsOf("param") {
+		if tag.Variable != "" && !parameters[tag.Variable] {
+			findings = append(findings, newFinding(UnknownParam, f.path, n, name.Text,
+				"@param $"+tag.Variable+" of "+name.Text+" names no parameter"))
+		}
+	}
+	if doc.Has("param") {
+		for _, parameter := range names {
+			if doc.Param(parameter) == nil {
+				findings = append(findings, newFinding(MissingParam, f.path, n, name.Text,
+					"parameter $"+parameter+" of "+name.Text+" has no @param"))
+			}
+		}
+	}
+	if !doc.Has("return") && !hasReturnType(n) && returnsValue(n) && !strings.EqualFold(name.Text, "__construct") {
+		findings = append(findings, newFinding(MissingReturn, f.path, n, name.Text,
+			name.Text+" returns a value but has no @return"))
+	}
+	return findings
+}
+
+// hasReturnType reports whether a function declares its return type
+func hasReturnType(fn *ast.Node) bool {
+	afterParameters := false
+	for _, child := range fn.NamedChildren() {
+		switch {
+		case child.Kind == "formal_parameters":
+			afterParameters = true
+		case afterParameters && child.Kind != "compound_statement":
+			return true
+		}
+	}
+	return false
+}
+
+// returnsValue reports whether the body of a function returns a value or yields, ignoring the
+// nested functions and classes
+func returnsValue(fn *ast.Node) bool {
+	body := fn.ChildOfKind("compound_statement")
+	if body == nil {
+		return false
+	}
+	var visit func(*ast.Node) bool
+	visit = func(n *ast.Node) bool {
+		switch n.Kind {
+		case "function_definition", "anonymous_function", "arrow_function", "class_declaration":
+			return false
+		case "return_statement":
+			if len(n.NamedChildren()) > 0 {
+				return true
+			}
+		case "yield_expression":
+			return true
+		}
+		for _, child := range n.Descendants {
+			if visit(child) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(body)
+}
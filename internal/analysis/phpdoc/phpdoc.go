@@ -0,0 +1,207 @@
+package phpdoc
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Tag is a block tag of a doc comment, such as @param int $count the number of rows
+type Tag struct {
+	// Name is the name of the tag without @, as written: param, phpstan-return, since...
+	Name string `json:"name"`
+	// Type is the type of the @param, @return, @var, @throws and @property tags
+	Type string `json:"type,omitempty"`
+	// Variable is the variable of the @param, @var and @property tags, without $
+	Variable    string `json:"variable,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Kind returns the lowercase name of the tag without the tool prefixes, so that @phpstan-param
+// and @psalm-param are both param
+func (t *Tag) Kind() string {
+	name := strings.ToLower(t.Name)
+	for _, prefix := range []string{"phpstan-", "psalm-", "phan-"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+// DocBlock is a parsed /** ... */ doc comment
+type DocBlock struct {
+	Summary     string    `json:"summary,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []*Tag    `json:"tags"`
+	Line        uint      `json:"line"`
+	Node        *ast.Node `json:"-"`
+}
+
+// TagsOf returns the tags of a kind, as returned by Tag.Kind
+func (d *DocBlock) TagsOf(kind string) []*Tag {
+	var tags []*Tag
+	for _, tag := range d.Tags {
+		if tag.Kind() == kind {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Has reports whether the doc comment has a tag of a kind
+func (d *DocBlock) Has(kind string) bool {
+	return len(d.TagsOf(kind)) > 0
+}
+
+// first returns the first tag of a kind with the given variable. A tag without variable matches
+// any variable when loose is set, as a @var annotating a single assignment.
+func (d *DocBlock) first(kind, variable string, loose bool) *Tag {
+	for _, tag := range d.TagsOf(kind) {
+		if tag.Variable == variable || (loose && tag.Variable == "") {
+			return tag
+		}
+	}
+	return nil
+}
+
+// Param returns the @param tag of a parameter, or nil
+func (d *DocBlock) Param(name string) *Tag {
+	return d.first("param", name, false)
+}
+
+// Return returns the @return tag, or nil
+func (d *DocBlock) Return() *Tag {
+	return d.first("return", "", false)
+}
+
+// Var returns the @var tag of a variable or property, or the @var tag without variable, or nil
+func (d *DocBlock) Var(name string) *Tag {
+	return d.first("var", name, true)
+}
+
+// Deprecated returns the @deprecated tag, or nil
+func (d *DocBlock) Deprecated() *Tag {
+	return d.first("deprecated", "", true)
+}
+
+// Inherits reports whether the documentation is inherited from the overridden method with
+// {@inheritDoc} or @inheritDoc, in which case missing tags are not errors
+func (d *DocBlock) Inherits() bool {
+	return d.Has("inheritdoc") || strings.Contains(strings.ToLower(d.Summary+" "+d.Description), "{@inheritdoc}")
+}
+
+var tagName = regexp.MustCompile(`^@([\w\\:-]+)\s*`)
+
+// typedTags are the tags starting with a type
+var typedTags = map[string]bool{
+	"param": true, "return": true, "var": true, "throws": true,
+	"property": true, "property-read": true, "property-write": true,
+}
+
+// variableTags are the tags naming a variable after their type
+var variableTags = map[string]bool{
+	"param": true, "var": true, "property": true, "property-read": true, "property-write": true,
+}
+
+// Parse parses the text of a doc comment. The summary is the first paragraph or sentence, the
+// description runs up to the first tag, and each tag extends until the next one.
+func Parse(text string) *DocBlock {
+	text = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(text), "/**"), "*/")
+	d := &DocBlock{Tags: []*Tag{}}
+	var body []string
+	var current *Tag
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		if strings.HasPrefix(line, "@") {
+			current = parseTag(line)
+			d.Tags = append(d.Tags, current)
+			continue
+		}
+		if current != nil {
+			if line != "" {
+				current.Description = strings.TrimSpace(current.Description + "\n" + line)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+
+	summary := 0
+	for summary < len(body) {
+		if body[summary] == "" {
+			if summary > 0 {
+				break
+			}
+			body = body[1:]
+			continue
+		}
+		summary++
+		if strings.HasSuffix(body[summary-1], ".") {
+			break
+		}
+	}
+	d.Summary = strings.Join(body[:summary], " ")
+	d.Description = strings.TrimSpace(strings.Join(body[summary:], "\n"))
+	return d
+}
+
+func parseTag(line string) *Tag {
+	match := tagName.FindStringSubmatch(line)
+	if match == nil {
+		return &Tag{Description: line}
+	}
+	tag := &Tag{Name: match[1]}
+	rest := line[len(match[0]):]
+	kind := tag.Kind()
+	if typedTags[kind] && !startsWithVariable(rest) {
+		tag.Type, rest = splitType(rest)
+		rest = strings.TrimSpace(rest)
+	}
+	if variableTags[kind] && startsWithVariable(rest) {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		tag.Variable = strings.TrimLeft(rest[:end], "&.$")
+		rest = strings.TrimSpace(rest[end:])
+	}
+	tag.Description = rest
+	return tag
+}
+
+func startsWithVariable(s string) bool {
+	s = strings.TrimPrefix(s, "&")
+	s = strings.TrimPrefix(s, "...")
+	return strings.HasPrefix(s, "$")
+}
+
+// splitType splits the type at the start of a tag from the rest of the tag. Types may contain
+// spaces inside brackets, as array<string, int>, and around the | and & operators.
+func splitType(s string) (string, string) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<', '(', '{', '[':
+			depth++
+		case '>', ')', '}', ']':
+			if depth > 0 {
+				depth--
+			}
+		case ' ', '\t':
+			if depth > 0 {
+				continue
+			}
+			previous := strings.TrimRight(s[:i], " \t")
+			next := strings.TrimLeft(s[i:], " \t")
+			if strings.HasSuffix(previous, "|") || strings.HasSuffix(previous, "&") ||
+				strings.HasSuffix(previous, ":") || strings.HasPrefix(next, "|") ||
+				(strings.HasPrefix(next, "&") && !startsWithVariable(next)) ||
+				(strings.HasPrefix(next, ":") && strings.HasSuffix(previous, ")")) {
+				continue
+			}
+			return strings.Join(strings.Fields(s[:i]), " "), s[i:]
+		}
+	}
+	return strings.Join(strings.Fields(s), " "), ""
+}
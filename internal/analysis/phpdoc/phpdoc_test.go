@@ -0,0 +1,191 @@
+package phpdoc
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestParse(t *testing.T) {
+	doc := Parse(`/**
+ * Counts the rows. Slowly.
+ *
+ * Runs a query per table.
+ *
+ * @param array<string, int> $tables the tables
+ *        and their weights
+ * @phpstan-param int|null $limit
+ * @return int
+ * @deprecated use countAll()
+ * @throws \RuntimeException
+ */`)
+	if doc.Summary != "Counts the rows. Slowly." {
+		t.Errorf("Summary = %q", doc.Summary)
+	}
+	tests := []struct {
+		tag                        *Tag
+		typ, variable, description string
+	}{
+		{tag: doc.Param("tables"), typ: "array<string, int>", variable: "tables", description: "the tables\nand their weights"},
+		{tag: doc.Param("limit"), typ: "int|null", variable: "limit"},
+		{tag: doc.Return(), typ: "int"},
+		{tag: doc.Deprecated(), description: "use countAll()"},
+		{tag: doc.TagsOf("throws")[0], typ: `\RuntimeException`},
+	}
+	for _, tt := range tests {
+		if tt.tag == nil {
+			t.Errorf("missing tag of type %q and variable %q", tt.typ, tt.variable)
+			continue
+		}
+		if tt.tag.Type != tt.typ || tt.tag.Variable != tt.variable || tt.tag.Description != tt.description {
+			t.Errorf("@%s = %q %q %q, want %q %q %q", tt.tag.Name, tt.tag.Type, tt.tag.Variable,
+				tt.tag.Description, tt.typ, tt.variable, tt.description)
+		}
+	}
+	if doc.Inherits() {
+		t.Error("Inherits() = true")
+	}
+	if !Parse("/** {@inheritDoc} */").Inherits() {
+		t.Error("Inherits() = false for {@inheritDoc}")
+	}
+}
+
+func TestAttach(t *testing.T) {
+	root := ast.ParseSource([]byte(`<?php
+/** @return int */
+function documented() {}
+/** A comment followed by another statement */
+echo 1;
+function undocumented() {}
+class C {
+	/** @var string */
+	public $p;
+}
+`))
+	Attach(root)
+	tests := map[string]string{
+		"documented":   "return",
+		"undocumented": "",
+		"$p":           "var",
+	}
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true, "property_declaration": true}}
+	root.WalkPrefix(v)
+	for _, n := range v.Nodes {
+		name := n.ChildOfKind("name")
+		if name == nil {
+			name = n.ChildOfKind("property_element").ChildOfKind("variable_name")
+		}
+		want, doc := tests[name.Text], Of(n)
+		switch {
+		case want == "" && doc != nil:
+			t.Errorf("%s has a doc comment", name.Text)
+		case want != "" && (doc == nil || !doc.Has(want)):
+			t.Errorf("%s has no @%s", name.Text, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		category Category
+		names    []string
+	}{
+		{
+			name:     "deprecated function, method and class",
+			source:   "<?php\n/** @deprecated */\nfunction old() {}\n/** @deprecated */\nclass Old {\n/** @deprecated */\nfunction m() {}\nfunction n() { $this->m(); }\n}\nold();\nnew Old();\n",
+			category: DeprecatedCall, names: []string{"Old::m", "old", "Old"},
+		},
+		{
+			name:     "call to current code",
+			source:   "<?php\n/** @since 1.0 */\nfunction current() {}\ncurrent();\n",
+			category: DeprecatedCall,
+		},
+		{
+			name:     "value returned without @return",
+			source:   "<?php\n/** Doubles */\nfunction a($x) { return $x * 2; }\n/** @return int */\nfunction b() { return 1; }\n/** Typed */\nfunction c(): int { return 1; }\n/** Nested */\nfunction d() { $f = function () { return 1; }; }\n",
+			category: MissingReturn, names: []string{"a"},
+		},
+		{
+			name:     "generator",
+			source:   "<?php\n/** Yields */\nfunction g() { yield 1; }\n",
+			category: MissingReturn, names: []string{"g"},
+		},
+		{
+			name:     "parameter without @param",
+			source:   "<?php\n/** @param int $a */\nfunction f($a, $b) {}\n/** Summary only */\nfunction g($a) {}\n",
+			category: MissingParam, names: []string{"f"},
+		},
+		{
+			name:     "inherited documentation",
+			source:   "<?php\nclass C {\n/** @inheritDoc */\nfunction m($a) { return $a; }\n}\n",
+			category: MissingReturn,
+		},
+		{
+			name:     "@param naming no parameter",
+			source:   "<?php\n/**\n * @param int $a\n * @param int $old\n */\nfunction f($a) {}\n",
+			category: UnknownParam, names: []string{"f"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := ast.ParseSource([]byte(tt.source))
+			root.SetParents()
+			hierarchy := classes.New()
+			hierarchy.AddFile("test.php", root)
+			hierarchy.Resolve()
+			c := New(hierarchy, nil)
+			c.AddFile("test.php", root)
+			var names []string
+			for _, finding := range c.Check() {
+				if finding.Category == tt.category {
+					names = append(names, finding.Name)
+				}
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("%s names = %v, want %v", tt.category, names, tt.names)
+			}
+		})
+	}
+}
+
+func TestKindTreeDocTag(t *testing.T) {
+	root := ast.ParseSource([]byte(`<?php
+/** @internal */
+function hidden() {}
+/** @api */
+function exposed() {}
+function bare() {}
+`))
+	root.SetParents()
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{tag: "internal", want: []string{"hidden"}},
+		{tag: "!internal", want: []string{"exposed", "bare"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			var tree ast.KindTree
+			if err := json.Unmarshal([]byte(`{"kind": "function_definition", "attributes": {"doc_tag": "`+tt.tag+`"}}`), &tree); err != nil {
+				t.Fatal(err)
+			}
+			v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+			root.WalkPrefix(v)
+			var got []string
+			for _, fn := range v.Nodes {
+				if tree.Match(fn) {
+					got = append(got, fn.ChildOfKind("name").Text)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
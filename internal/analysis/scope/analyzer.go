@@ -0,0 +1,732 @@
+package scope
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// maxIterations bounds the fixpoint computation of loops
+const maxIterations = 10
+
+// state maps each variable name to the definitions reaching the current program point.
+// A nil state means the program point is unreachable.
+type state map[string][]*ast.Node
+
+func (st state) copy() state {
+	if st == nil {
+		return nil
+	}
+	result := make(state, len(st))
+	for name, defs := range st {
+		result[name] = slices.Clone(defs)
+	}
+	return result
+}
+
+func merge(states ...state) state {
+	var result state
+	for _, st := range states {
+		if st == nil {
+			continue
+		}
+		if result == nil {
+			result = make(state)
+		}
+		for name, defs := range st {
+			result[name] = union(result[name], defs)
+		}
+	}
+	return result
+}
+
+func (st state) equal(other state) bool {
+	if (st == nil) != (other == nil) || len(st) != len(other) {
+		return false
+	}
+	for name, defs := range st {
+		otherDefs, ok := other[name]
+		if !ok || len(defs) != len(otherDefs) {
+			return false
+		}
+		for _, def := range defs {
+			if !slices.Contains(otherDefs, def) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func union(a, b []*ast.Node) []*ast.Node {
+	for _, n := range b {
+		if !slices.Contains(a, n) {
+			a = append(a, n)
+		}
+	}
+	return a
+}
+
+// loopFrame collects the states leaving a loop or switch through break and continue
+type loopFrame struct {
+	breaks    []state
+	continues []state
+}
+
+type analyzer struct {
+	scope *Scope
+	loops []*loopFrame
+	// scopes caches the scopes of nested functions, as loop bodies are analyzed several times
+	scopes map[*ast.Node]*Scope
+	// outer and outerState resolve the implicit captures of arrow functions
+	outer      *analyzer
+	outerState state
+}
+
+// Analyze computes the scopes of a program and the def-use chains of every variable occurrence.
+// The scope of each function-like node is stored in its attributes under AttributeKey.
+func Analyze(root *ast.Node) *Scope {
+	root.SetParents()
+	a := &analyzer{
+		scope:  newScope(root, "{main}", nil),
+		scopes: make(map[*ast.Node]*Scope),
+	}
+	a.sequence(root.NamedChildren(), make(state))
+	return a.scope
+}
+
+func (a *analyzer) variable(name string) *Variable {
+	v, ok := a.scope.Variables[name]
+	if !ok {
+		kind := Local
+		if superglobals[name] {
+			kind = Superglobal
+		} else if name == "this" {
+			kind = This
+		}
+		v = &Variable{Name: name, Kind: kind}
+		a.scope.Variables[name] = v
+	}
+	return v
+}
+
+// use records an occurrence of a variable read at the current program point
+func (a *analyzer) use(n *ast.Node, st state) {
+	name := VariableName(n)
+	v := a.variable(name)
+	defs, defined := st[name]
+	if a.outer != nil && !defined && v.Kind == Local {
+		// Arrow functions capture the variables of the enclosing scope by value
+		v.Kind = Capture
+	}
+	if v.Kind == Capture && a.outer != nil && !defined {
+		a.outer.use(n, a.outerState)
+		defs = a.outer.scope.UseDef[n]
+	}
+	v.Uses = union(v.Uses, []*ast.Node{n})
+	a.scope.UseDef[n] = union(a.scope.UseDef[n], defs)
+	for _, def := range defs {
+		a.scope.DefUse[def] = union(a.scope.DefUse[def], []*ast.Node{n})
+	}
+}
+
+// define records a definition of a variable. A strong definition kills the previous ones.
+func (a *analyzer) define(n *ast.Node, st state, strong bool) *Variable {
+	name := VariableName(n)
+	v := a.variable(name)
+	v.Defs = union(v.Defs, []*ast.Node{n})
+	if _, ok := a.scope.DefUse[n]; !ok {
+		a.scope.DefUse[n] = []*ast.Node{}
+	}
+	if strong {
+		st[name] = []*ast.Node{n}
+	} else {
+		st[name] = union(st[name], []*ast.Node{n})
+	}
+	return v
+}
+
+func (a *analyzer) sequence(nodes []*ast.Node, st state) state {
+	dead := false
+	for _, n := range nodes {
+		if st == nil {
+			// Unreachable code is still analyzed so that its occurrences are recorded
+			dead = true
+			st = make(state)
+		}
+		st = a.stmt(n, st)
+	}
+	if dead {
+		return nil
+	}
+	return st
+}
+
+func (a *analyzer) stmt(n *ast.Node, st state) state {
+	switch n.Kind {
+	case "compound_statement", "colon_block", "declaration_list", "namespace_definition":
+		return a.sequence(n.NamedChildren(), st)
+	case "if_statement":
+		return a.ifStatement(n, st)
+	case "while_statement":
+		return a.whileStatement(n, st)
+	case "do_statement":
+		return a.doStatement(n, st)
+	case "for_statement":
+		return a.forStatement(n, st)
+	case "foreach_statement":
+		return a.foreachStatement(n, st)
+	case "switch_statement":
+		return a.switchStatement(n, st)
+	case "try_statement":
+		return a.tryStatement(n, st)
+	case "break_statement", "continue_statement":
+		level := 1
+		if integer := n.ChildOfKind("integer"); integer != nil {
+			if l, err := strconv.Atoi(integer.Text); err == nil && l > 0 {
+				level = l
+			}
+		}
+		if level <= len(a.loops) {
+			frame := a.loops[len(a.loops)-level]
+			if n.Kind == "break_statement" {
+				frame.breaks = append(frame.breaks, st.copy())
+			} else {
+				frame.continues = append(frame.continues, st.copy())
+			}
+		}
+		return nil
+	case "return_statement", "exit_statement":
+		a.exprChildren(n, st)
+		return nil
+	case "expression_statement":
+		a.exprChildren(n, st)
+		if IsTerminating(n) {
+			return nil
+		}
+		return st
+	case "global_declaration":
+		for _, child := range n.ChildrenOfKind("variable_name") {
+			a.define(child, st, true).Kind = Global
+		}
+		return st
+	case "function_static_declaration":
+		for _, declaration := range n.ChildrenOfKind("static_variable_declaration") {
+			children := declaration.NamedChildren()
+			for _, value := range children[1:] {
+				a.expr(value, st)
+			}
+			a.define(children[0], st, true).Kind = Static
+		}
+		return st
+	case "unset_statement":
+		for _, child := range n.NamedChildren() {
+			if child.Kind == "variable_name" {
+				st[VariableName(child)] = []*ast.Node{}
+				continue
+			}
+			a.expr(child, st)
+		}
+		return st
+	case "function_definition":
+		a.function(n, nil)
+		return st
+	case "class_declaration", "trait_declaration", "interface_declaration", "enum_declaration":
+		a.members(n)
+		return st
+	default:
+		a.expr(n, st)
+		return st
+	}
+}
+
+// IsTerminating reports whether an expression statement never completes normally (throw, die, exit)
+func IsTerminating(n *ast.Node) bool {
+	children := n.NamedChildren()
+	if len(children) == 0 {
+		return false
+	}
+	switch expr := children[0]; expr.Kind {
+	case "throw_expression":
+		return true
+	case "function_call_expression":
+		name := expr.ChildOfKind("name")
+		return name != nil && (name.Text == "die" || name.Text == "exit")
+	}
+	return false
+}
+
+// members analyzes the methods of a class-like declaration
+func (a *analyzer) members(n *ast.Node) {
+	body := n.ChildOfKind("declaration_list", "enum_declaration_list")
+	if body == nil {
+		return
+	}
+	for _, member := range body.NamedChildren() {
+		if member.Kind == "method_declaration" {
+			a.function(member, nil)
+		}
+	}
+}
+
+func (a *analyzer) ifStatement(n *ast.Node, st state) state {
+	var out state
+	current := st
+	hasElse := false
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "parenthesized_expression":
+			a.expr(child, current)
+		case "else_if_clause":
+			current = current.copy()
+			for _, part := range child.NamedChildren() {
+				if part.Kind == "parenthesized_expression" {
+					a.expr(part, current)
+				} else {
+					out = merge(out, a.stmt(part, current.copy()))
+				}
+			}
+		case "else_clause":
+			hasElse = true
+			for _, part := range child.NamedChildren() {
+				out = merge(out, a.stmt(part, current.copy()))
+			}
+		default:
+			out = merge(out, a.stmt(child, current.copy()))
+		}
+	}
+	if !hasElse {
+		out = merge(out, current)
+	}
+	return out
+}
+
+// fixpoint runs the body of a loop until the state at the loop head is stable.
+// iterate returns the state reaching the loop head again after one iteration.
+func (a *analyzer) fixpoint(entry state, iterate func(head state, frame *loopFrame) state) (state, *loopFrame) {
+	head := entry
+	var frame *loopFrame
+	for i := 0; i < maxIterations; i++ {
+		frame = &loopFrame{}
+		a.loops = append(a.loops, frame)
+		back := iterate(head.copy(), frame)
+		a.loops = a.loops[:len(a.loops)-1]
+		next := merge(entry, back)
+		if next.equal(head) {
+			break
+		}
+		head = next
+	}
+	return head, frame
+}
+
+func (a *analyzer) whileStatement(n *ast.Node, st state) state {
+	condition := n.ChildOfKind("parenthesized_expression")
+	children := n.NamedChildren()
+	body := children[len(children)-1]
+	var afterCondition state
+	_, frame := a.fixpoint(st, func(head state, frame *loopFrame) state {
+		a.expr(condition, head)
+		afterCondition = head
+		out := a.stmt(body, head.copy())
+		return merge(append(frame.continues, out)...)
+	})
+	return merge(append(frame.breaks, afterCondition)...)
+}
+
+func (a *analyzer) doStatement(n *ast.Node, st state) state {
+	condition := n.ChildOfKind("parenthesized_expression")
+	body := n.NamedChildren()[0]
+	var afterCondition state
+	_, frame := a.fixpoint(st, func(head state, frame *loopFrame) state {
+		out := merge(append(frame.continues, a.stmt(body, head))...)
+		if out != nil {
+			a.expr(condition, out)
+		}
+		afterCondition = out
+		return out
+	})
+	return merge(append(frame.breaks, afterCondition)...)
+}
+
+func (a *analyzer) forStatement(n *ast.Node, st state) state {
+	clauses, body := n.ForClauses()
+	for _, init := range clauses[0] {
+		a.expr(init, st)
+	}
+	var afterCondition state
+	_, frame := a.fixpoint(st, func(head state, frame *loopFrame) state {
+		for _, condition := range clauses[1] {
+			a.expr(condition, head)
+		}
+		afterCondition = head
+		out := head.copy()
+		if body != nil {
+			out = a.stmt(body, out)
+		}
+		out = merge(append(frame.continues, out)...)
+		if out != nil {
+			for _, update := range clauses[2] {
+				a.expr(update, out)
+			}
+		}
+		return out
+	})
+	return merge(append(frame.breaks, afterCondition)...)
+}
+
+func (a *analyzer) foreachStatement(n *ast.Node, st state) state {
+	subject, binding, body := n.ForeachParts()
+	if subject != nil {
+		a.expr(subject, st)
+	}
+	head, frame := a.fixpoint(st, func(head state, frame *loopFrame) state {
+		switch {
+		case binding == nil:
+		case binding.Kind == "pair":
+			// Both the key and the value are defined by each iteration
+			for _, part := range binding.NamedChildren() {
+				a.assign(part, head, true)
+			}
+		default:
+			a.assign(binding, head, true)
+		}
+		out := head
+		if body != nil {
+			out = a.stmt(body, head)
+		}
+		return merge(append(frame.continues, out)...)
+	})
+	return merge(append(frame.breaks, head)...)
+}
+
+func (a *analyzer) switchStatement(n *ast.Node, st state) state {
+	if condition := n.ChildOfKind("parenthesized_expression"); condition != nil {
+		a.expr(condition, st)
+	}
+	block := n.ChildOfKind("switch_block")
+	if block == nil {
+		return st
+	}
+	frame := &loopFrame{}
+	a.loops = append(a.loops, frame)
+	var fallthroughState state
+	hasDefault := false
+	for _, c := range block.NamedChildren() {
+		entry := merge(st, fallthroughState)
+		statements := c.NamedChildren()
+		switch c.Kind {
+		case "case_statement":
+			if len(statements) > 0 {
+				a.expr(statements[0], entry)
+				statements = statements[1:]
+			}
+		case "default_statement":
+			hasDefault = true
+		default:
+			continue
+		}
+		fallthroughState = a.sequence(statements, entry)
+	}
+	a.loops = a.loops[:len(a.loops)-1]
+	out := merge(append(append(frame.breaks, frame.continues...), fallthroughState)...)
+	if !hasDefault {
+		out = merge(out, st)
+	}
+	return out
+}
+
+func (a *analyzer) tryStatement(n *ast.Node, st state) state {
+	var out, catchEntry state
+	for _, child := range n.NamedChildren() {
+		switch child.Kind {
+		case "compound_statement":
+			entry := st.copy()
+			out = a.stmt(child, st)
+			// An exception may be thrown anywhere in the body
+			catchEntry = merge(entry, out)
+		case "catch_clause":
+			catchState := catchEntry.copy()
+			for _, part := range child.NamedChildren() {
+				switch part.Kind {
+				case "variable_name":
+					a.define(part, catchState, true)
+				case "compound_statement":
+					out = merge(out, a.stmt(part, catchState))
+				}
+			}
+		case "finally_clause":
+			body := child.ChildOfKind("compound_statement")
+			if body == nil {
+				continue
+			}
+			if out == nil {
+				a.stmt(body, catchEntry.copy())
+				return nil
+			}
+			out = a.stmt(body, merge(out, catchEntry))
+		}
+	}
+	return out
+}
+
+func (a *analyzer) exprChildren(n *ast.Node, st state) {
+	for _, child := range n.NamedChildren() {
+		a.expr(child, st)
+	}
+}
+
+func (a *analyzer) expr(n *ast.Node, st state) {
+	switch n.Kind {
+	case "variable_name":
+		a.use(n, st)
+	case "assignment_expression":
+		children := n.NamedChildren()
+		if len(children) < 2 {
+			a.exprChildren(n, st)
+			return
+		}
+		a.expr(children[len(children)-1], st)
+		a.assign(children[0], st, true)
+	case "augmented_assignment_expression":
+		children := n.NamedChildren()
+		if len(children) < 2 {
+			a.exprChildren(n, st)
+			return
+		}
+		a.expr(children[len(children)-1], st)
+		a.expr(children[0], st)
+		a.assign(children[0], st, true)
+	case "reference_assignment_expression":
+		children := n.NamedChildren()
+		if len(children) < 2 {
+			a.exprChildren(n, st)
+			return
+		}
+		// Both sides are aliases of the same value after the assignment
+		source := children[len(children)-1]
+		a.expr(source, st)
+		if source.Kind == "variable_name" {
+			a.define(source, st, false)
+		}
+		a.assign(children[0], st, true)
+	case "update_expression":
+		for _, child := range n.NamedChildren() {
+			a.expr(child, st)
+			a.assign(child, st, true)
+		}
+	case "binary_expression":
+		a.binaryExpression(n, st)
+	case "conditional_expression":
+		children := n.NamedChildren()
+		a.expr(children[0], st)
+		var branches []state
+		for _, branch := range children[1:] {
+			branchState := st.copy()
+			a.expr(branch, branchState)
+			branches = append(branches, branchState)
+		}
+		if len(children) == 2 {
+			// Short ternary: the condition is the value of the first branch
+			branches = append(branches, st.copy())
+		}
+		replace(st, merge(branches...))
+	case "match_expression":
+		a.exprChildren(n, st)
+	case "scoped_property_access_expression":
+		// The variable_name after :: is a static property, not a local variable
+		if children := n.NamedChildren(); len(children) > 0 {
+			a.expr(children[0], st)
+		}
+	case "anonymous_function":
+		a.function(n, st)
+	case "arrow_function":
+		a.function(n, st)
+	case "function_definition":
+		a.function(n, nil)
+	case "method_declaration":
+		a.function(n, nil)
+	case "class_declaration", "declaration_list":
+		for _, member := range n.NamedChildren() {
+			if member.Kind == "method_declaration" {
+				a.function(member, nil)
+			}
+		}
+	case "property_declaration", "const_declaration":
+	default:
+		a.exprChildren(n, st)
+	}
+}
+
+func replace(st state, other state) {
+	for name := range st {
+		delete(st, name)
+	}
+	for name, defs := range other {
+		st[name] = defs
+	}
+}
+
+func (a *analyzer) binaryExpression(n *ast.Node, st state) {
+	children := n.Descendants
+	if len(children) != 3 {
+		a.exprChildren(n, st)
+		return
+	}
+	a.expr(children[0], st)
+	switch children[1].Kind {
+	case "&&", "||", "??", "and", "or":
+		// The right operand is only evaluated on some paths
+		right := st.copy()
+		a.expr(children[2], right)
+		replace(st, merge(st, right))
+	default:
+		a.expr(children[2], st)
+	}
+}
+
+// assign records the definitions made by writing to the target of an assignment
+func (a *analyzer) assign(target *ast.Node, st state, strong bool) {
+	switch target.Kind {
+	case "variable_name":
+		a.define(target, st, strong)
+	case "by_ref":
+		for _, child := range target.NamedChildren() {
+			a.assign(child, st, strong)
+		}
+	case "subscript_expression":
+		// Writing to an element reads and partially redefines the array
+		children := target.NamedChildren()
+		for _, child := range children[1:] {
+			a.expr(child, st)
+		}
+		if len(children) > 0 {
+			base := children[0]
+			a.expr(base, st)
+			if base.Kind == "variable_name" || base.Kind == "subscript_expression" {
+				a.assign(base, st, false)
+			}
+		}
+	case "list_literal", "array_creation_expression":
+		for _, element := range target.NamedChildren() {
+			a.assign(element, st, strong)
+		}
+	case "array_element_initializer", "pair":
+		children := target.NamedChildren()
+		if len(children) == 0 {
+			return
+		}
+		for _, key := range children[:len(children)-1] {
+			a.expr(key, st)
+		}
+		a.assign(children[len(children)-1], st, strong)
+	default:
+		a.expr(target, st)
+	}
+}
+
+// function analyzes a function-like node in its own scope.
+// enclosing is the state at the creation point of closures, nil for named functions.
+func (a *analyzer) function(n *ast.Node, enclosing state) {
+	s, analyzed := a.scopes[n]
+	if !analyzed {
+		s = newScope(n, functionName(n), a.scope)
+		a.scopes[n] = s
+	}
+	child := &analyzer{scope: s, scopes: a.scopes}
+	st := make(state)
+
+	if n.Kind == "arrow_function" {
+		child.outer = a
+		child.outerState = enclosing
+	} else if analyzed {
+		// Only arrow functions depend on the enclosing state
+		a.captures(n, child, enclosing, make(state))
+		return
+	}
+
+	for _, child := range n.NamedChildren() {
+		if child.Kind == "formal_parameters" {
+			a.parameters(child, s, st)
+		}
+	}
+	if n.Kind == "method_declaration" {
+		child.variable("this")
+	}
+	a.captures(n, child, enclosing, st)
+
+	children := n.NamedChildren()
+	if len(children) == 0 {
+		return
+	}
+	body := children[len(children)-1]
+	switch {
+	case body.Kind == "compound_statement":
+		child.stmt(body, st)
+	case n.Kind == "arrow_function":
+		child.expr(body, st)
+	}
+}
+
+// captures handles the use clause of an anonymous function: each captured variable is
+// read in the enclosing scope and defined in the closure scope
+func (a *analyzer) captures(n *ast.Node, child *analyzer, enclosing state, st state) {
+	clause := n.ChildOfKind("anonymous_function_use_clause")
+	if clause == nil || enclosing == nil {
+		return
+	}
+	for _, captured := range clause.NamedChildren() {
+		byRef := false
+		if captured.Kind == "by_ref" {
+			byRef = true
+			captured = captured.ChildOfKind("variable_name")
+		}
+		if captured == nil || captured.Kind != "variable_name" {
+			continue
+		}
+		a.use(captured, enclosing)
+		if byRef {
+			// The closure may write back to the enclosing variable whenever it is called
+			a.define(captured, enclosing, false)
+		}
+		v := child.define(captured, st, true)
+		v.Kind = Capture
+		v.ByRef = byRef
+	}
+}
+
+func (a *analyzer) parameters(n *ast.Node, s *Scope, st state) {
+	child := &analyzer{scope: s, scopes: a.scopes}
+	for _, parameter := range n.NamedChildren() {
+		name := parameter.ChildOfKind("variable_name")
+		if name == nil {
+			continue
+		}
+		afterName := false
+		for _, part := range parameter.Descendants {
+			if part == name {
+				afterName = true
+			} else if afterName && part.IsNamed {
+				// Default value
+				child.expr(part, st)
+			}
+		}
+		v := child.define(name, st, true)
+		v.Kind = Parameter
+		v.ByRef = parameter.ChildOfKind("reference_modifier") != nil
+	}
+}
+
+func functionName(n *ast.Node) string {
+	switch n.Kind {
+	case "anonymous_function":
+		return "{closure}"
+	case "arrow_function":
+		return "{fn}"
+	}
+	if name := n.ChildOfKind("name"); name != nil {
+		return name.Text
+	}
+	return n.Kind
+}
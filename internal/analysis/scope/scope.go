@@ -0,0 +1,205 @@
+package scope
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the scope of a function-like node is stored
+const AttributeKey = "scope"
+
+type VariableKind string
+
+const (
+	Local       VariableKind = "local"
+	Parameter   VariableKind = "parameter"
+	Global      VariableKind = "global"
+	Static      VariableKind = "static"
+	Capture     VariableKind = "capture"
+	Superglobal VariableKind = "superglobal"
+	This        VariableKind = "this"
+)
+
+var superglobals = map[string]bool{
+	"GLOBALS":  true,
+	"_SERVER":  true,
+	"_GET":     true,
+	"_POST":    true,
+	"_FILES":   true,
+	"_COOKIE":  true,
+	"_SESSION": true,
+	"_REQUEST": true,
+	"_ENV":     true,
+}
+
+// FunctionKinds are the node kinds that open a new local scope
+var FunctionKinds = []string{"function_definition", "method_declaration", "anonymous_function", "arrow_function"}
+
+// Variable is a variable of a scope along with all its definitions and uses
+type Variable struct {
+	Name  string
+	Kind  VariableKind
+	ByRef bool
+	Defs  []*ast.Node
+	Uses  []*ast.Node
+}
+
+// Scope is the local scope of a function-like node, or the file scope of a program node
+type Scope struct {
+	Node      *ast.Node
+	Name      string
+	Parent    *Scope
+	Children  []*Scope
+	Variables map[string]*Variable
+	// DefUse maps each definition to the occurrences it may reach
+	DefUse map[*ast.Node][]*ast.Node
+	// UseDef maps each occurrence to the definitions that may reach it
+	UseDef map[*ast.Node][]*ast.Node
+}
+
+func newScope(n *ast.Node, name string, parent *Scope) *Scope {
+	s := &Scope{
+		Node:      n,
+		Name:      name,
+		Parent:    parent,
+		Variables: make(map[string]*Variable),
+		DefUse:    make(map[*ast.Node][]*ast.Node),
+		UseDef:    make(map[*ast.Node][]*ast.Node),
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+	n.SetAttribute(AttributeKey, s)
+	return s
+}
+
+// Lookup returns the variable of the scope with the given name, or nil
+func (s *Scope) Lookup(name string) *Variable {
+	return s.Variables[name]
+}
+
+// DefsOf returns the definitions that may reach a variable occurrence, in source order
+func (s *Scope) DefsOf(use *ast.Node) []*ast.Node {
+	return sortedNodes(s.UseDef[use])
+}
+
+// UsesOf returns the occurrences a definition may reach, in source order
+func (s *Scope) UsesOf(def *ast.Node) []*ast.Node {
+	return sortedNodes(s.DefUse[def])
+}
+
+func sortedNodes(nodes []*ast.Node) []*ast.Node {
+	result := append([]*ast.Node{}, nodes...)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartByte < result[j].StartByte
+	})
+	return result
+}
+
+// Walk calls f on the scope and all its nested scopes
+func (s *Scope) Walk(f func(*Scope)) {
+	f(s)
+	for _, child := range s.Children {
+		child.Walk(f)
+	}
+}
+
+// SortedVariables returns the variables of the scope ordered by name
+func (s *Scope) SortedVariables() []*Variable {
+	var variables []*Variable
+	for _, v := range s.Variables {
+		variables = append(variables, v)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
+
+// Of returns the innermost scope enclosing the node. Parent links must be set.
+func Of(n *ast.Node) *Scope {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if s, ok := cur.GetAttribute(AttributeKey).(*Scope); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// EnclosingFunction returns the function, method or closure containing a node, nil at the top level
+func EnclosingFunction(n *ast.Node) *ast.Node {
+	for cur := n.Parent; cur != nil; cur = cur.Parent {
+		if slices.Contains(FunctionKinds, cur.Kind) {
+			return cur
+		}
+	}
+	return nil
+}
+
+// DefsOf returns the definitions reaching an occurrence, and whether the occurrence is recorded in
+// a scope. The occurrences captured by closures and arrow functions are recorded in the scope
+// enclosing the function.
+func DefsOf(n *ast.Node) ([]*ast.Node, bool) {
+	for s := Of(n); s != nil; s = s.Parent {
+		if _, ok := s.UseDef[n]; ok {
+			return s.DefsOf(n), true
+		}
+	}
+	return nil, false
+}
+
+// VariableName returns the name of a variable_name node without the leading $
+func VariableName(n *ast.Node) string {
+	if name := n.ChildOfKind("name"); name != nil {
+		return name.Text
+	}
+	return n.Text
+}
+
+type variableJSON struct {
+	Name  string       `json:"name"`
+	Kind  VariableKind `json:"kind"`
+	ByRef bool         `json:"by_ref,omitempty"`
+	Defs  []uint       `json:"defs"`
+	Uses  []uint       `json:"uses"`
+}
+
+type scopeJSON struct {
+	Name      string         `json:"name"`
+	Kind      string         `json:"kind"`
+	Line      uint           `json:"line"`
+	Variables []variableJSON `json:"variables"`
+	Children  []*Scope       `json:"children,omitempty"`
+}
+
+// MarshalJSON exports the scope with node positions as lines, since the scope is attached to the AST
+func (s *Scope) MarshalJSON() ([]byte, error) {
+	out := scopeJSON{
+		Name:     s.Name,
+		Kind:     s.Node.Kind,
+		Line:     s.Node.StartPosition.Row + 1,
+		Children: s.Children,
+	}
+	for _, v := range s.SortedVariables() {
+		out.Variables = append(out.Variables, variableJSON{
+			Name:  v.Name,
+			Kind:  v.Kind,
+			ByRef: v.ByRef,
+			Defs:  Lines(v.Defs),
+			Uses:  Lines(v.Uses),
+		})
+	}
+	return json.Marshal(out)
+}
+
+// Lines returns the 1-based start lines of the nodes
+func Lines(nodes []*ast.Node) []uint {
+	lines := []uint{}
+	for _, n := range nodes {
+		lines = append(lines, n.StartPosition.Row+1)
+	}
+	return lines
+}
@@ -0,0 +1,119 @@
+package scope
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// occurrence returns the occurrence of a variable read on a line
+func occurrence(t *testing.T, root *ast.Node, name string, line uint) *ast.Node {
+	t.Helper()
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"variable_name": true}}
+	root.WalkPrefix(v)
+	for _, n := range v.Nodes {
+		if VariableName(n) != name || n.StartPosition.Row+1 != line {
+			continue
+		}
+		if _, ok := DefsOf(n); ok {
+			return n
+		}
+	}
+	t.Fatalf("no use of $%s on line %d", name, line)
+	return nil
+}
+
+func TestDefsOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		variable string
+		line     uint
+		defs     []uint
+	}{
+		{
+			name:     "a strong definition kills the previous one",
+			source:   "<?php\n$a = 1;\n$a = 2;\necho $a;",
+			variable: "a", line: 4, defs: []uint{3},
+		},
+		{
+			name:     "both branches reach the join",
+			source:   "<?php\nif ($c) {\n$a = 1;\n} else {\n$a = 2;\n}\necho $a;",
+			variable: "a", line: 7, defs: []uint{3, 5},
+		},
+		{
+			name:     "a branch without definition keeps the previous one",
+			source:   "<?php\n$a = 0;\nif ($c) {\n$a = 1;\n}\necho $a;",
+			variable: "a", line: 6, defs: []uint{2, 4},
+		},
+		{
+			name:     "a loop definition reaches the condition",
+			source:   "<?php\n$i = 0;\nwhile ($i < 3) {\n$i = $i + 1;\n}",
+			variable: "i", line: 3, defs: []uint{2, 4},
+		},
+		{
+			name:     "code after return is not reached",
+			source:   "<?php\nfunction f($c) {\n$a = 1;\nif ($c) {\n$a = 2;\nreturn;\n}\necho $a;\n}",
+			variable: "a", line: 8, defs: []uint{3},
+		},
+		{
+			name:     "a parameter defines its variable",
+			source:   "<?php\nfunction f($p) {\nreturn $p;\n}",
+			variable: "p", line: 3, defs: []uint{2},
+		},
+		{
+			name:     "functions do not see the variables of the file",
+			source:   "<?php\n$a = 1;\nfunction f() {\nreturn $a;\n}",
+			variable: "a", line: 4, defs: []uint{},
+		},
+		{
+			name:     "arrow functions capture the enclosing definitions",
+			source:   "<?php\n$a = 1;\n$f = fn() => $a;",
+			variable: "a", line: 3, defs: []uint{2},
+		},
+		{
+			name:     "foreach defines its value",
+			source:   "<?php\nforeach ($items as $item) {\necho $item;\n}",
+			variable: "item", line: 3, defs: []uint{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := ast.ParseSource([]byte(tt.source))
+			Analyze(root)
+			defs, _ := DefsOf(occurrence(t, root, tt.variable, tt.line))
+			if got := Lines(defs); !slices.Equal(got, tt.defs) {
+				t.Errorf("definitions of $%s on line %d = %v, want %v", tt.variable, tt.line, got, tt.defs)
+			}
+		})
+	}
+}
+
+func TestEnclosingFunction(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		kind   string
+	}{
+		{name: "top level", source: "<?php\necho $a;", kind: ""},
+		{name: "function", source: "<?php\nfunction f() {\necho $a;\n}", kind: "function_definition"},
+		{name: "method", source: "<?php\nclass C {\nfunction m() {\necho $a;\n}\n}", kind: "method_declaration"},
+		{name: "closure", source: "<?php\nfunction f() {\n$g = function () {\necho $a;\n};\n}", kind: "anonymous_function"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := ast.ParseSource([]byte(tt.source))
+			root.SetParents()
+			v := &ast.VisitorKinds{Kinds: map[string]bool{"echo_statement": true}}
+			root.WalkPrefix(v)
+			kind := ""
+			if f := EnclosingFunction(v.Nodes[0]); f != nil {
+				kind = f.Kind
+			}
+			if kind != tt.kind {
+				t.Errorf("EnclosingFunction() = %q, want %q", kind, tt.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Confidence is how likely a finding is a real secret
+type Confidence string
+
+const (
+	Low    Confidence = "low"
+	Medium Confidence = "medium"
+	High   Confidence = "high"
+)
+
+var confidenceRanks = map[Confidence]int{Low: 0, Medium: 1, High: 2}
+
+// AtLeast reports whether the confidence is at least another one
+func (c Confidence) AtLeast(other Confidence) bool {
+	return confidenceRanks[c] >= confidenceRanks[other]
+}
+
+// Rule names
+const (
+	PrivateKey         = "private-key"
+	AWSAccessKey       = "aws-access-key"
+	JWT                = "jwt"
+	GitHubToken        = "github-token"
+	SlackToken         = "slack-token"
+	StripeKey          = "stripe-key"
+	GoogleAPIKey       = "google-api-key"
+	URLCredentials     = "url-credentials"
+	ConnectionPassword = "connection-password"
+	NamedCredential    = "named-credential"
+	HighEntropy        = "high-entropy"
+)
+
+// Rules are all the rule names
+var Rules = []string{
+	PrivateKey, AWSAccessKey, JWT, GitHubToken, SlackToken, StripeKey, GoogleAPIKey, URLCredentials,
+	ConnectionPassword, NamedCredential, HighEntropy,
+}
+
+// formatRule is a known token format. group is the submatch holding the secret, 0 for the whole match.
+type formatRule struct {
+	name       string
+	pattern    *regexp.Regexp
+	group      int
+	confidence Confidence
+}
+
+// formatRules are matched in order, the first match wins
+var formatRules = []formatRule{
+	{PrivateKey, regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`), 0, High},
+	{AWSAccessKey, regexp.MustCompile(`\b(AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`), 0, High},
+	{JWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]*`), 0, High},
+	{GitHubToken, regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), 0, High},
+	{SlackToken, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), 0, High},
+	{StripeKey, regexp.MustCompile(`\b[sr]k_live_[0-9a-zA-Z]{20,}`), 0, High},
+	{GoogleAPIKey, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`), 0, High},
+	{URLCredentials, regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`), 1, Medium},
+	{ConnectionPassword, regexp.MustCompile(`(?i)(?:^|[;\s])(?:password|pwd)\s*=\s*([^;\s'"]+)`), 1, Medium},
+}
+
+// credentialName matches the names of variables, constants, properties and keys holding credentials
+var credentialName = regexp.MustCompile(`(?i)(pass(wd|word)?|pwd|secret|api[_-]?key|apikey|access[_-]?key|auth[_-]?key|private[_-]?key|token|credential|salt|db[_-]?pass|encryption[_-]?key|signing[_-]?key)`)
+
+// ignoredName matches the names that contain a credential word without holding a credential, as
+// password_field or TOKEN_LENGTH
+var ignoredName = regexp.MustCompile(`(?i)[_-]?(label|field|name|url|uri|path|file|type|length|len|id|option|param|placeholder|message|msg|text|title|regex|pattern|expires?|expiration|ttl|header|cookie|policy|reset|strength|hint|action|nonce_action)s?$`)
+
+// placeholder matches the values that stand for a credential to fill in rather than a credential
+var placeholder = regexp.MustCompile(`(?i)^(|x+|\*+|\.+|changeme|change_me|password|passwd|secret|token|null|none|test|example|dummy|todo|fixme|%s|\?|your[_ -].*|.*_here|<[^>]*>|\{[^}]*\}|\$\{[^}]*\}|\[[^]]*\]|[a-z]+_[a-z_]+)$`)
+
+// prose matches sentences, which are messages rather than credentials
+var prose = regexp.MustCompile(`^[\p{L}\p{N},.'’!?:;()-]+( [\p{L}\p{N},.'’!?:;()%-]+){2,}$`)
+
+// Entropy returns the Shannon entropy of a string in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+var (
+	hexToken    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	base64Token = regexp.MustCompile(`^[A-Za-z0-9+/=_\-.~!@#$%^&*]+$`)
+)
+
+// highEntropy reports whether a value looks like a random token: a long hexadecimal string, or a
+// long string without spaces mixing cases, digits and symbols with a high entropy
+func highEntropy(value string) bool {
+	switch {
+	case strings.ContainsAny(value, " \t\n\\") || strings.Contains(value, "://"):
+		return false
+	case hexToken.MatchString(value):
+		return len(value) >= 32 && Entropy(value) >= 3.0
+	case base64Token.MatchString(value):
+		if len(value) < 20 || Entropy(value) < 4.0 {
+			return false
+		}
+		var upper, lower, digit bool
+		for _, r := range value {
+			upper = upper || unicode.IsUpper(r)
+			lower = lower || unicode.IsLower(r)
+			digit = digit || unicode.IsDigit(r)
+		}
+		return upper && lower && digit
+	}
+	return false
+}
+
+// plausible reports whether the value of a credential name may be a real credential
+func plausible(value string) bool {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) < 4 || placeholder.MatchString(trimmed) || prose.MatchString(trimmed) {
+		return false
+	}
+	digits := strings.TrimFunc(trimmed, func(r rune) bool { return unicode.IsDigit(r) || r == '.' || r == '-' })
+	return digits != ""
+}
+
+// Redact hides all but the first characters of a secret. Private keys keep their header only.
+func Redact(rule, value string) string {
+	if rule == PrivateKey {
+		if header := formatRules[0].pattern.FindString(value); header != "" {
+			return header + " [redacted]"
+		}
+	}
+	runes := []rune(value)
+	visible := 0
+	switch {
+	case len(runes) >= 12:
+		visible = 4
+	case len(runes) >= 6:
+		visible = 2
+	}
+	hidden := len(runes) - visible
+	if hidden > 12 {
+		hidden = 12
+	}
+	return string(runes[:visible]) + strings.Repeat("*", hidden)
+}
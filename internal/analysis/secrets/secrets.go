@@ -0,0 +1,333 @@
+package secrets
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Options are the allowlists and thresholds of a scan
+type Options struct {
+	// AllowValues are the patterns of the values never reported, such as the test fixtures
+	AllowValues []*regexp.Regexp
+	// AllowNames are the patterns of the variable, constant and key names never reported
+	AllowNames []*regexp.Regexp
+	// MinConfidence is the lowest confidence reported
+	MinConfidence Confidence
+}
+
+// Finding is a literal that looks like a hardcoded secret
+type Finding struct {
+	Rule       string     `json:"rule"`
+	Confidence Confidence `json:"confidence"`
+	File       string     `json:"file"`
+	Line       uint       `json:"line"`
+	Column     uint       `json:"column"`
+	// Kind is the kind of the literal node: string, encapsed_string, heredoc or nowdoc
+	Kind string `json:"kind"`
+	// Name is the variable, constant, property, key or parameter the literal is assigned to, if any
+	Name string `json:"name,omitempty"`
+	// Value is the redacted secret
+	Value   string    `json:"value"`
+	Length  int       `json:"length"`
+	Entropy float64   `json:"entropy"`
+	Node    *ast.Node `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Scanner finds the hardcoded secrets of the files added to it
+type Scanner struct {
+	options Options
+	files   []*file
+}
+
+func New(options Options) *Scanner {
+	return &Scanner{options: options}
+}
+
+// AddFile adds a file to scan
+func (s *Scanner) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	s.files = append(s.files, &file{path: path, root: root})
+}
+
+// Scan returns the findings of all the files, ordered by file and line
+func (s *Scanner) Scan() []*Finding {
+	var findings []*Finding
+	for _, f := range s.files {
+		v := &literalVisitor{}
+		f.root.WalkPrefix(v)
+		for _, n := range v.literals {
+			if finding := s.check(f, n); finding != nil {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+type literalVisitor struct {
+	literals []*ast.Node
+}
+
+func (v *literalVisitor) VisitNode(n *ast.Node) {
+	if literal.IsString(n) {
+		v.literals = append(v.literals, n)
+	}
+}
+
+// segments returns the constant parts of a literal. Interpolated expressions split the literal.
+func segments(n *ast.Node) []string {
+	if value, ok := literal.String(n); ok {
+		return []string{value}
+	}
+	doubleQuoted := n.Kind == "encapsed_string" || n.Kind == "heredoc"
+	var result []string
+	var sb strings.Builder
+	for _, part := range literal.Parts(n) {
+		switch part.Kind {
+		case "string_content", "nowdoc_string", "string_value":
+			sb.WriteString(part.Text)
+		case "escape_sequence":
+			sb.WriteString(literal.Unescape(part.Text, doubleQuoted))
+		default:
+			if sb.Len() > 0 {
+				result = append(result, sb.String())
+				sb.Reset()
+			}
+		}
+	}
+	if sb.Len() > 0 {
+		result = append(result, sb.String())
+	}
+	return result
+}
+
+// check applies the rules to a literal: the known token formats first, then the credential names,
+// then the entropy
+func (s *Scanner) check(f *file, n *ast.Node) *Finding {
+	name := contextName(n)
+	for _, pattern := range s.options.AllowNames {
+		if name != "" && pattern.MatchString(name) {
+			return nil
+		}
+	}
+	newFinding := func(rule string, confidence Confidence, value string) *Finding {
+		for _, pattern := range s.options.AllowValues {
+			if pattern.MatchString(value) {
+				return nil
+			}
+		}
+		if !confidence.AtLeast(s.options.MinConfidence) {
+			return nil
+		}
+		return &Finding{
+			Rule:       rule,
+			Confidence: confidence,
+			File:       f.path,
+			Line:       n.StartPosition.Row + 1,
+			Column:     n.StartPosition.Column + 1,
+			Kind:       n.Kind,
+			Name:       name,
+			Value:      Redact(rule, value),
+			Length:     len([]rune(value)),
+			Entropy:    float64(int(Entropy(value)*100)) / 100,
+			Node:       n,
+		}
+	}
+
+	parts := segments(n)
+	for _, rule := range formatRules {
+		for _, part := range parts {
+			match := rule.pattern.FindStringSubmatch(part)
+			if match == nil {
+				continue
+			}
+			secret := match[rule.group]
+			if rule.group > 0 && placeholder.MatchString(secret) {
+				continue
+			}
+			return newFinding(rule.name, rule.confidence, secret)
+		}
+	}
+	value, constant := literal.String(n)
+	if !constant {
+		// Interpolated values are not credentials themselves
+		return nil
+	}
+	// A part of a concatenation, as a "Bearer " prefix, is a credential only if it looks random
+	concatenated := n.Parent != nil && n.Parent.Kind == "binary_expression" && operator(n.Parent) == "."
+	if name != "" && credentialName.MatchString(name) && !ignoredName.MatchString(name) && plausible(value) &&
+		(!concatenated || highEntropy(value)) {
+		confidence := Medium
+		if highEntropy(value) {
+			confidence = High
+		}
+		return newFinding(NamedCredential, confidence, value)
+	}
+	if highEntropy(value) {
+		return newFinding(HighEntropy, Low, value)
+	}
+	return nil
+}
+
+// credentialArguments maps the functions and classes taking credentials to the index and name of
+// the credential argument
+var credentialArguments = map[string]struct {
+	index int
+	name  string
+}{
+	"mysql_connect":      {2, "password"},
+	"mysqli_connect":     {2, "password"},
+	"mysqli":             {2, "password"},
+	"pdo":                {2, "password"},
+	"ldap_bind":          {2, "password"},
+	"ftp_login":          {2, "password"},
+	"ssh2_auth_password": {2, "password"},
+	"imap_open":          {2, "password"},
+	"hash_hmac":          {2, "key"},
+	"openssl_encrypt":    {2, "key"},
+	"openssl_decrypt":    {2, "key"},
+	"wp_set_password":    {0, "password"},
+	"wp_check_password":  {1, "password"},
+	"password_verify":    {0, "password"},
+}
+
+// contextName returns the name a literal is bound to: the variable, property or array key it is
+// assigned to, the constant it defines, the key of its array element, the parameter it is the
+// default value or the argument of, or the variable it is compared with
+func contextName(n *ast.Node) string {
+	current := n
+	parent := n.Parent
+	// Concatenations and parentheses keep the context of the whole expression
+	for parent != nil && (parent.Kind == "parenthesized_expression" || (parent.Kind == "binary_expression" && operator(parent) == ".")) {
+		current, parent = parent, parent.Parent
+	}
+	if parent == nil {
+		return ""
+	}
+	children := parent.NamedChildren()
+	switch parent.Kind {
+	case "assignment_expression", "augmented_assignment_expression":
+		if len(children) == 2 && children[1] == current {
+			return targetName(children[0])
+		}
+	case "const_element", "property_element", "simple_parameter", "property_promotion_parameter", "static_variable_declaration":
+		if target := parent.ChildOfKind("variable_name", "name"); target != nil && target != current {
+			return targetName(target)
+		}
+	case "pair", "array_element_initializer":
+		if len(children) == 2 && children[1] == current {
+			return keyName(children[0])
+		}
+	case "binary_expression":
+		switch operator(parent) {
+		case "==", "===", "!=", "!==", "<>":
+			for _, child := range children {
+				if child != current {
+					return targetName(child)
+				}
+			}
+		}
+	case "return_statement":
+		for cur := parent.Parent; cur != nil; cur = cur.Parent {
+			if cur.Kind == "function_definition" || cur.Kind == "method_declaration" {
+				if name := cur.ChildOfKind("name"); name != nil {
+					return name.Text
+				}
+				break
+			}
+		}
+	case "argument":
+		if parts := parent.Descendants; len(parts) > 1 && parts[0].Kind == "name" && parts[1].Kind == ":" {
+			return parts[0].Text
+		}
+		return argumentName(parent)
+	}
+	return ""
+}
+
+// argumentName returns the name of an argument of define() or of a function taking a credential
+func argumentName(argument *ast.Node) string {
+	arguments := argument.Parent
+	if arguments == nil || arguments.Parent == nil {
+		return ""
+	}
+	call := arguments.Parent
+	index := -1
+	for i, a := range call.Arguments() {
+		if a == argument || a.Parent == argument {
+			index = i
+		}
+	}
+	name := call.ChildOfKind("name", "qualified_name")
+	if name == nil || index < 0 {
+		return ""
+	}
+	function := strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+	if function == "define" && index == 1 {
+		if constant, ok := literal.String(call.Arguments()[0]); ok {
+			return constant
+		}
+	}
+	if function == "curl_setopt" && index == 2 && strings.HasSuffix(call.Arguments()[1].Text, "CURLOPT_USERPWD") {
+		return "userpwd"
+	}
+	if credential, ok := credentialArguments[function]; ok && credential.index == index {
+		return credential.name
+	}
+	return ""
+}
+
+// targetName returns the name of an assignment target: a variable, a property, a constant or the
+// string key of an array element
+func targetName(n *ast.Node) string {
+	switch n.Kind {
+	case "variable_name", "name":
+		return strings.TrimPrefix(n.Text, "$")
+	case "member_access_expression", "nullsafe_member_access_expression", "scoped_property_access_expression":
+		children := n.NamedChildren()
+		return targetName(children[len(children)-1])
+	case "subscript_expression":
+		children := n.NamedChildren()
+		if len(children) == 2 {
+			if key := keyName(children[1]); key != "" {
+				return key
+			}
+			return targetName(children[0])
+		}
+	}
+	return ""
+}
+
+func keyName(n *ast.Node) string {
+	if key, ok := literal.String(n); ok {
+		return key
+	}
+	if n.Kind == "name" {
+		return n.Text
+	}
+	return ""
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
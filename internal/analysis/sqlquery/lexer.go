@@ -0,0 +1,229 @@
+package sqlquery
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+)
+
+type TokenKind string
+
+const (
+	Word        TokenKind = "word"
+	Number      TokenKind = "number"
+	String      TokenKind = "string"
+	Identifier  TokenKind = "identifier"
+	Placeholder TokenKind = "placeholder"
+	Operator    TokenKind = "operator"
+	Punctuation TokenKind = "punctuation"
+	// Dynamic is a part of the query only known at runtime, outside quotes
+	Dynamic TokenKind = "dynamic"
+)
+
+// Token is a token of a query. Words, strings and identifiers may contain dynamic parts.
+type Token struct {
+	Kind TokenKind
+	Text string
+	// Dynamic reports whether the token contains dynamic parts, whose bytes are Chars
+	Dynamic bool
+	Chars   values.Charset
+	// Unterminated is set on strings and identifiers missing their closing quote
+	Unterminated bool
+	// Breakout is set on strings and identifiers with a dynamic part that may contain their
+	// quote unescaped, and so close them
+	Breakout bool
+}
+
+// item is a byte of the query, or one of its dynamic parts
+type item struct {
+	b       byte
+	dynamic *values.Part
+}
+
+func items(parts []values.Part) []item {
+	var result []item
+	for i := range parts {
+		if parts[i].Unknown {
+			result = append(result, item{dynamic: &parts[i]})
+			continue
+		}
+		for j := 0; j < len(parts[i].Literal); j++ {
+			result = append(result, item{b: parts[i].Literal[j]})
+		}
+	}
+	return result
+}
+
+// Render returns the text of a query with its dynamic parts replaced by {int} when they are
+// numeric and {var} otherwise
+func Render(parts []values.Part) string {
+	var sb strings.Builder
+	for _, part := range parts {
+		switch {
+		case !part.Unknown:
+			sb.WriteString(part.Literal)
+		case numeric(part.Chars):
+			sb.WriteString("{int}")
+		default:
+			sb.WriteString("{var}")
+		}
+	}
+	return sb.String()
+}
+
+// numeric reports whether a dynamic part can only be a number
+func numeric(chars values.Charset) bool {
+	return chars.Minus(values.FloatChars).IsEmpty()
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+func isOperatorByte(b byte) bool {
+	return strings.IndexByte("<>=!|&+-*/%^~", b) >= 0
+}
+
+// Tokenize splits a query into tokens, skipping whitespace and comments. Placeholders are ?,
+// :name and the %s, %d, %f and %i of WordPress.
+func Tokenize(parts []values.Part) []Token {
+	in := items(parts)
+	var tokens []Token
+	at := func(i int) byte {
+		if i < len(in) && in[i].dynamic == nil {
+			return in[i].b
+		}
+		return 0
+	}
+	for i := 0; i < len(in); {
+		cur := in[i]
+		b := cur.b
+		switch {
+		case cur.dynamic == nil && (b == ' ' || b == '\t' || b == '\n' || b == '\r'):
+			i++
+		case cur.dynamic == nil && (b == '#' || (b == '-' && at(i+1) == '-')):
+			for i < len(in) && at(i) != '\n' {
+				i++
+			}
+		case cur.dynamic == nil && b == '/' && at(i+1) == '*':
+			i += 2
+			for i < len(in) && !(at(i) == '*' && at(i+1) == '/') {
+				i++
+			}
+			i += 2
+		case cur.dynamic == nil && (b == '\'' || b == '"' || b == '`'):
+			var token Token
+			token, i = quoted(in, i)
+			tokens = append(tokens, token)
+		case cur.dynamic != nil || isWordByte(b):
+			token := Token{Kind: Word}
+			var sb strings.Builder
+			onlyDynamic := true
+			for ; i < len(in) && (in[i].dynamic != nil || isWordByte(in[i].b)); i++ {
+				if in[i].dynamic != nil {
+					token.Dynamic = true
+					token.Chars = token.Chars.Union(in[i].dynamic.Chars)
+					sb.WriteString(Render([]values.Part{*in[i].dynamic}))
+					continue
+				}
+				onlyDynamic = false
+				sb.WriteByte(in[i].b)
+			}
+			token.Text = sb.String()
+			switch {
+			case onlyDynamic:
+				token.Kind = Dynamic
+			case !token.Dynamic && token.Text[0] >= '0' && token.Text[0] <= '9':
+				token.Kind = Number
+			}
+			tokens = append(tokens, token)
+		case b == '?':
+			tokens = append(tokens, Token{Kind: Placeholder, Text: "?"})
+			i++
+		case b == ':' && isWordByte(at(i+1)):
+			start := i
+			for i++; i < len(in) && in[i].dynamic == nil && isWordByte(in[i].b); i++ {
+			}
+			tokens = append(tokens, Token{Kind: Placeholder, Text: text(in[start:i])})
+		case b == '%' && strings.IndexByte("sdfiF", at(i+1)) >= 0:
+			tokens = append(tokens, Token{Kind: Placeholder, Text: text(in[i : i+2])})
+			i += 2
+		case strings.IndexByte("(),.;", b) >= 0:
+			tokens = append(tokens, Token{Kind: Punctuation, Text: string(b)})
+			i++
+		case isOperatorByte(b):
+			start := i
+			for i < len(in) && in[i].dynamic == nil && isOperatorByte(in[i].b) {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Operator, Text: text(in[start:i])})
+		default:
+			tokens = append(tokens, Token{Kind: Operator, Text: string(b)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// quoted scans a string literal or a quoted identifier starting at in[start]. Quotes are escaped
+// by doubling them or, except in identifiers, with a backslash. The quotes of the dynamic parts
+// escaped by addslashes and the like do not close strings, unless a backslash before the part
+// escapes its first backslash instead.
+func quoted(in []item, start int) (Token, int) {
+	quote := in[start].b
+	token := Token{Kind: String, Unterminated: true}
+	if quote == '`' {
+		token.Kind = Identifier
+	}
+	var sb strings.Builder
+	sb.WriteByte(quote)
+	i := start + 1
+	backslash := false
+	for i < len(in) {
+		cur := in[i]
+		if cur.dynamic != nil {
+			token.Dynamic = true
+			token.Chars = token.Chars.Union(cur.dynamic.Chars)
+			escaped := cur.dynamic.Escaped && quote != '`' && !backslash
+			if cur.dynamic.Chars.Intersects(values.CharsOf(string(quote))) && !escaped {
+				token.Breakout = true
+			}
+			sb.WriteString(Render([]values.Part{*cur.dynamic}))
+			backslash = false
+			i++
+			continue
+		}
+		sb.WriteByte(cur.b)
+		i++
+		if cur.b == '\\' && quote != '`' && i < len(in) {
+			if in[i].dynamic == nil {
+				sb.WriteByte(in[i].b)
+				i++
+			} else {
+				backslash = true
+			}
+			continue
+		}
+		if cur.b == quote {
+			if i < len(in) && in[i].dynamic == nil && in[i].b == quote {
+				sb.WriteByte(quote)
+				i++
+				continue
+			}
+			token.Unterminated = false
+			break
+		}
+	}
+	token.Text = sb.String()
+	return token, i
+}
+
+func text(in []item) string {
+	var sb strings.Builder
+	for _, it := range in {
+		if it.dynamic == nil {
+			sb.WriteByte(it.b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package sqlquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// InterpolatedValue is a non-numeric value interpolated outside quotes
+	InterpolatedValue Category = "interpolated-value"
+	// QuoteBreakout is a value interpolated between quotes that may contain the quote
+	QuoteBreakout Category = "quote-breakout"
+	// MissingPrepare is a query built with dynamic values that is not a prepared statement
+	MissingPrepare Category = "missing-prepare"
+	// MissingWhere is a DELETE or UPDATE statement without a WHERE clause
+	MissingWhere Category = "missing-where"
+	// Destructive is a DROP or TRUNCATE statement
+	Destructive Category = "destructive-statement"
+	// PlaceholderMismatch is a prepare call whose placeholders do not match its arguments
+	PlaceholderMismatch Category = "placeholder-mismatch"
+)
+
+// Categories are all the categories of the findings
+var Categories = []Category{InterpolatedValue, QuoteBreakout, MissingPrepare, MissingWhere, Destructive, PlaceholderMismatch}
+
+// identifierKeywords are the keywords followed by a table name, which is commonly built from a
+// prefix and cannot be a placeholder
+var identifierKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true, "EXISTS": true,
+}
+
+// lint checks the tokens of one alternative of a query
+func lint(tokens []Token, prepared bool) []*Finding {
+	var findings []*Finding
+	previous := ""
+	dynamicValues := false
+	for _, token := range tokens {
+		switch {
+		case token.Kind == Dynamic && identifierKeywords[previous]:
+		case token.Kind == Dynamic && !numeric(token.Chars):
+			dynamicValues = true
+			message := fmt.Sprintf("value %s interpolated outside quotes", token.Text)
+			if prepared {
+				message += ", use a placeholder"
+			}
+			findings = append(findings, &Finding{Category: InterpolatedValue, Message: message})
+		case token.Kind == String && token.Dynamic:
+			dynamicValues = true
+			if quote := token.Text[:1]; token.Breakout {
+				findings = append(findings, &Finding{
+					Category: QuoteBreakout,
+					Message:  fmt.Sprintf("value interpolated in %s may contain %s and close the string", shorten(token.Text), quote),
+				})
+			} else if prepared {
+				findings = append(findings, &Finding{
+					Category: InterpolatedValue,
+					Message:  fmt.Sprintf("value interpolated in %s, use a placeholder", shorten(token.Text)),
+				})
+			}
+		}
+		if token.Kind == Word {
+			previous = strings.ToUpper(token.Text)
+		} else if token.Kind != Punctuation || token.Text != "." {
+			previous = ""
+		}
+	}
+	if dynamicValues && !prepared {
+		findings = append(findings, &Finding{
+			Category: MissingPrepare,
+			Message:  "query built with dynamic values instead of a prepared statement",
+		})
+	}
+	for _, statement := range statements(tokens) {
+		findings = append(findings, checkStatement(statement)...)
+	}
+	return findings
+}
+
+// statements splits the tokens on semicolons
+func statements(tokens []Token) [][]Token {
+	var result [][]Token
+	start := 0
+	for i, token := range tokens {
+		if token.Kind == Punctuation && token.Text == ";" {
+			if i > start {
+				result = append(result, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		result = append(result, tokens[start:])
+	}
+	return result
+}
+
+// checkStatement reports the destructive statements and the DELETE and UPDATE statements
+// without a top level WHERE clause. A dynamic part may hide the clause.
+func checkStatement(tokens []Token) []*Finding {
+	if len(tokens) == 0 || tokens[0].Kind != Word {
+		return nil
+	}
+	verb := strings.ToUpper(tokens[0].Text)
+	switch verb {
+	case "DROP", "TRUNCATE":
+		object := ""
+		if len(tokens) > 1 && tokens[1].Kind == Word {
+			object = " " + strings.ToUpper(tokens[1].Text)
+		}
+		return []*Finding{{Category: Destructive, Message: fmt.Sprintf("%s%s statement", verb, object)}}
+	case "DELETE", "UPDATE":
+		depth := 0
+		for _, token := range tokens[1:] {
+			switch {
+			case token.Kind == Punctuation && token.Text == "(":
+				depth++
+			case token.Kind == Punctuation && token.Text == ")":
+				depth--
+			case depth > 0:
+			case token.Kind == Word && strings.EqualFold(token.Text, "WHERE"):
+				return nil
+			case token.Kind == Dynamic:
+				return nil
+			}
+		}
+		return []*Finding{{Category: MissingWhere, Message: fmt.Sprintf("%s statement without a WHERE clause", verb)}}
+	}
+	return nil
+}
+
+// placeholders counts the placeholders of a query
+func placeholders(tokens []Token) int {
+	count := 0
+	for _, token := range tokens {
+		if token.Kind == Placeholder {
+			count++
+		}
+	}
+	return count
+}
+
+func shorten(s string) string {
+	if len(s) > 40 {
+		return s[:37] + "..."
+	}
+	return s
+}
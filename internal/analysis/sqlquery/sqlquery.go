@@ -0,0 +1,275 @@
+package sqlquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// sink is a function or method sending a query to the database
+type sink struct {
+	// argument is the index of the query argument, -1 for the last one
+	argument int
+	prepared bool
+}
+
+var functionSinks = map[string]sink{
+	"mysql_query":            {argument: 0},
+	"mysql_unbuffered_query": {argument: 0},
+	"mysql_db_query":         {argument: 1},
+	"mysqli_query":           {argument: 1},
+	"mysqli_real_query":      {argument: 1},
+	"mysqli_multi_query":     {argument: 1},
+	"mysqli_prepare":         {argument: 1, prepared: true},
+	"pg_query":               {argument: -1},
+	"pg_send_query":          {argument: -1},
+	"pg_prepare":             {argument: -1, prepared: true},
+	"sqlsrv_query":           {argument: 1},
+	"odbc_exec":              {argument: 1},
+	"db2_exec":               {argument: 1},
+	"oci_parse":              {argument: 1, prepared: true},
+}
+
+// methodSinks are the methods of PDO, mysqli, SQLite3 and $wpdb taking a query
+var methodSinks = map[string]sink{
+	"query":       {argument: 0},
+	"exec":        {argument: 0},
+	"real_query":  {argument: 0},
+	"multi_query": {argument: 0},
+	"querysingle": {argument: 0},
+	"get_results": {argument: 0},
+	"get_var":     {argument: 0},
+	"get_row":     {argument: 0},
+	"get_col":     {argument: 0},
+	"prepare":     {argument: 0, prepared: true},
+}
+
+// Finding is a problem of a query
+type Finding struct {
+	Category Category `json:"category"`
+	Message  string   `json:"message"`
+}
+
+// Query is a query sent to the database, with one SQL text per alternative of its value
+type Query struct {
+	File     string     `json:"file"`
+	Line     uint       `json:"line"`
+	Function string     `json:"function"`
+	Prepared bool       `json:"prepared"`
+	SQL      []string   `json:"sql"`
+	Findings []*Finding `json:"findings"`
+	Node     *ast.Node  `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer extracts and checks the queries of the files added to it
+type Analyzer struct {
+	evaluator *values.Evaluator
+	files     []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{evaluator: values.New()}
+}
+
+// AddFile adds a file to the project, whose constants and classes are used to evaluate the queries
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.evaluator.AddFile(path, root)
+}
+
+// Analyze returns the queries of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Query {
+	a.evaluator.Resolve()
+	var queries []*Query
+	for _, f := range a.files {
+		v := &sinkVisitor{}
+		f.root.WalkPrefix(v)
+		for _, call := range v.calls {
+			if q := a.query(f.path, call); q != nil {
+				queries = append(queries, q)
+			}
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		if queries[i].File != queries[j].File {
+			return queries[i].File < queries[j].File
+		}
+		return queries[i].Line < queries[j].Line
+	})
+	return queries
+}
+
+type sinkVisitor struct {
+	calls []*ast.Node
+}
+
+func (v *sinkVisitor) VisitNode(n *ast.Node) {
+	if _, _, ok := sinkOf(n); ok {
+		v.calls = append(v.calls, n)
+	}
+}
+
+// sinkOf returns the sink called by a node and its name
+func sinkOf(n *ast.Node) (sink, string, bool) {
+	switch n.Kind {
+	case "function_call_expression":
+		if name := n.ChildOfKind("name", "qualified_name"); name != nil {
+			short := strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+			s, ok := functionSinks[short]
+			return s, name.Text, ok
+		}
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		children := n.NamedChildren()
+		for i := len(children) - 1; i >= 0; i-- {
+			if children[i].Kind == "name" {
+				s, ok := methodSinks[strings.ToLower(children[i].Text)]
+				return s, "->" + children[i].Text, ok
+			}
+		}
+	}
+	return sink{}, "", false
+}
+
+func (a *Analyzer) query(path string, call *ast.Node) *Query {
+	s, name, _ := sinkOf(call)
+	arguments := call.Arguments()
+	index := s.argument
+	if index < 0 {
+		index = len(arguments) - 1
+	}
+	if index < 0 || index >= len(arguments) {
+		return nil
+	}
+	argument := arguments[index]
+	if !s.prepared && prepares(argument) {
+		// The prepare call is checked on its own
+		return nil
+	}
+	q := &Query{
+		File:     path,
+		Line:     call.StartPosition.Row + 1,
+		Function: name,
+		Prepared: s.prepared,
+		Node:     call,
+		Findings: []*Finding{},
+	}
+	value := a.evaluator.Eval(argument)
+	seen := make(map[Finding]bool)
+	for _, alternative := range value.Alternatives() {
+		q.SQL = append(q.SQL, Render(alternative))
+		tokens := Tokenize(alternative)
+		findings := lint(tokens, s.prepared)
+		if s.prepared && name == "->prepare" && len(arguments) > 1 && wpdbReceiver(call) {
+			findings = append(findings, checkPlaceholders(tokens, arguments[1:])...)
+		}
+		for _, finding := range findings {
+			if !seen[*finding] {
+				seen[*finding] = true
+				q.Findings = append(q.Findings, finding)
+			}
+		}
+	}
+	return q
+}
+
+// prepares reports whether an expression is the result of a prepare call, directly or through
+// the definitions of a variable
+func prepares(n *ast.Node) bool {
+	switch n.Kind {
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return prepares(children[0])
+		}
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression", "function_call_expression":
+		s, _, ok := sinkOf(n)
+		return ok && s.prepared
+	case "variable_name":
+		sc := scope.Of(n)
+		if sc == nil {
+			return false
+		}
+		defs := sc.DefsOf(n)
+		for _, def := range defs {
+			assignment := def.Parent
+			if assignment == nil || assignment.Kind != "assignment_expression" {
+				return false
+			}
+			children := assignment.NamedChildren()
+			if children[0] != def || !prepares(children[len(children)-1]) {
+				return false
+			}
+		}
+		return len(defs) > 0
+	}
+	return false
+}
+
+// wpdbReceiver reports whether a method is called on $wpdb or on an object inferred to be a wpdb.
+// The placeholders of PDO and mysqli are bound after prepare.
+func wpdbReceiver(call *ast.Node) bool {
+	children := call.NamedChildren()
+	if len(children) == 0 {
+		return false
+	}
+	object := children[0]
+	if object.Kind == "variable_name" && scope.VariableName(object) == "wpdb" {
+		return true
+	}
+	for _, t := range types.TypeOf(object) {
+		if strings.EqualFold(t, "wpdb") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlaceholders compares the placeholders of a $wpdb->prepare query with the values passed
+// to it, as a list of arguments or as one array literal
+func checkPlaceholders(tokens []Token, arguments []*ast.Node) []*Finding {
+	count := len(arguments)
+	if len(arguments) == 1 {
+		switch arguments[0].Kind {
+		case "array_creation_expression":
+			count = len(arguments[0].ChildrenOfKind("array_element_initializer"))
+		case "variable_name":
+			// An array built elsewhere
+			return nil
+		}
+	}
+	for _, argument := range arguments {
+		if strings.HasPrefix(argument.Parent.Text, "...") {
+			return nil
+		}
+	}
+	for _, token := range tokens {
+		if token.Kind == Dynamic || (token.Kind == Word && token.Dynamic) {
+			// Placeholders may be built dynamically
+			return nil
+		}
+	}
+	if expected := placeholders(tokens); expected != count {
+		return []*Finding{{
+			Category: PlaceholderMismatch,
+			Message:  "prepare called with " + plural(count, "value") + " for " + plural(expected, "placeholder"),
+		}}
+	}
+	return nil
+}
+
+func plural(n int, word string) string {
+	s := fmt.Sprintf("%d %s", n, word)
+	if n != 1 {
+		s += "s"
+	}
+	return s
+}
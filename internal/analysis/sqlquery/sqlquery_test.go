@@ -0,0 +1,87 @@
+package sqlquery
+
+import (
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		category Category
+		want     bool
+	}{
+		{
+			name:     "request data between quotes",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE name = '" . $_GET['x'] . "'");`,
+			category: QuoteBreakout, want: true,
+		},
+		{
+			name:     "quotemeta does not escape quotes",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE name = '" . quotemeta($_GET['x']) . "'");`,
+			category: QuoteBreakout, want: true,
+		},
+		{
+			name:     "addslashes escapes quotes",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE name = '" . addslashes($_GET['x']) . "'");`,
+			category: QuoteBreakout, want: false,
+		},
+		{
+			name:     "a backslash before an escaped value escapes its escape",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE name = '\\" . addslashes($_GET['x']) . "'");`,
+			category: QuoteBreakout, want: true,
+		},
+		{
+			name:     "request data outside quotes",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE id = " . $_GET['id']);`,
+			category: InterpolatedValue, want: true,
+		},
+		{
+			name:     "integer outside quotes",
+			source:   `<?php $wpdb->query("SELECT * FROM t WHERE id = " . intval($_GET['id']));`,
+			category: InterpolatedValue, want: false,
+		},
+		{
+			name:     "delete without where",
+			source:   `<?php $wpdb->query("DELETE FROM t");`,
+			category: MissingWhere, want: true,
+		},
+		{
+			name:     "delete with where",
+			source:   `<?php $wpdb->query("DELETE FROM t WHERE id = 1");`,
+			category: MissingWhere, want: false,
+		},
+		{
+			name:     "wpdb prepare with a missing value",
+			source:   `<?php $wpdb->prepare("SELECT * FROM t WHERE a = %s AND b = %d", 'a');`,
+			category: PlaceholderMismatch, want: true,
+		},
+		{
+			name:     "wpdb typed parameter prepare with a missing value",
+			source:   `<?php function f(wpdb $db) { return $db->prepare("SELECT * FROM t WHERE a = %s AND b = %d", 'a'); }`,
+			category: PlaceholderMismatch, want: true,
+		},
+		{
+			name:     "PDO prepare takes driver options, not values",
+			source:   `<?php $pdo->prepare("SELECT * FROM t WHERE a = ? AND b = ?", [PDO::ATTR_CURSOR => PDO::CURSOR_FWDONLY]);`,
+			category: PlaceholderMismatch, want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			found := false
+			for _, q := range a.Analyze() {
+				for _, finding := range q.Findings {
+					found = found || finding.Category == tt.category
+				}
+			}
+			if found != tt.want {
+				t.Errorf("%s reported = %t, want %t", tt.category, found, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package taint
+
+import "strings"
+
+// SourceKind is the origin of attacker-controlled data
+type SourceKind string
+
+const (
+	// Request is a query string or body parameter: $_GET, $_POST, $_REQUEST, php://input, REST parameters
+	Request SourceKind = "request"
+	Cookie  SourceKind = "cookie"
+	// Header is a request header or another client-controlled entry of $_SERVER
+	Header SourceKind = "header"
+	// Upload is the client-supplied name or type of an uploaded file
+	Upload SourceKind = "upload"
+	// Unknown is an expression past the depth bound of the analysis, assumed to hold request data
+	Unknown SourceKind = "unknown"
+	// parameter is the placeholder source of the parameters in function summaries
+	parameter SourceKind = "parameter"
+)
+
+// Severity ranks the findings of the analyses built on the taints
+type Severity string
+
+const (
+	High   Severity = "high"
+	Medium Severity = "medium"
+	Low    Severity = "low"
+)
+
+// superglobals maps the superglobals holding request data to their source kind. $_SERVER is
+// handled by key.
+var superglobals = map[string]SourceKind{
+	"_GET":     Request,
+	"_POST":    Request,
+	"_REQUEST": Request,
+	"_COOKIE":  Cookie,
+	"_FILES":   Upload,
+}
+
+// serverKeys are the entries of $_SERVER controlled by the client besides the HTTP_ headers
+var serverKeys = map[string]bool{
+	"REQUEST_URI":     true,
+	"QUERY_STRING":    true,
+	"PHP_SELF":        true,
+	"PATH_INFO":       true,
+	"PATH_TRANSLATED": true,
+	"ORIG_PATH_INFO":  true,
+	"REDIRECT_URL":    true,
+	"CONTENT_TYPE":    true,
+	"argv":            true,
+}
+
+// serverSource reports whether an entry of $_SERVER is client-controlled. Unknown keys are.
+func serverSource(key string, known bool) bool {
+	return !known || strings.HasPrefix(key, "HTTP_") || serverKeys[key]
+}
+
+// uploadMetadata are the entries of $_FILES set by PHP rather than by the client
+var uploadMetadata = map[string]bool{"tmp_name": true, "size": true, "error": true}
+
+// sourceFunctions maps the functions returning request data to their source kind
+var sourceFunctions = map[string]SourceKind{
+	"getallheaders":          Header,
+	"apache_request_headers": Header,
+	"get_query_var":          Request,
+}
+
+// sourceMethods maps the methods returning request data, such as those of WP_REST_Request, to
+// their source kind
+var sourceMethods = map[string]SourceKind{
+	"get_param":          Request,
+	"get_params":         Request,
+	"get_query_params":   Request,
+	"get_body_params":    Request,
+	"get_json_params":    Request,
+	"get_file_params":    Upload,
+	"get_body":           Request,
+	"get_header":         Header,
+	"get_headers":        Header,
+	"get_url_params":     Request,
+	"get_default_params": Request,
+}
+
+// inputTypes maps the INPUT_ constants of filter_input to their source kind
+var inputTypes = map[string]SourceKind{
+	"INPUT_GET":     Request,
+	"INPUT_POST":    Request,
+	"INPUT_REQUEST": Request,
+	"INPUT_COOKIE":  Cookie,
+	"INPUT_SERVER":  Header,
+}
+
+// Sanitizers are the functions and methods that escape or restrict their first argument for some
+// context. Their applications are recorded on the taints going through them, and each analysis
+// decides which of them protect its sinks.
+var Sanitizers = map[string]bool{
+	"htmlspecialchars": true, "htmlentities": true, "strip_tags": true,
+	"esc_html": true, "esc_attr": true, "esc_url": true, "esc_url_raw": true, "esc_js": true,
+	"esc_textarea": true, "esc_sql": true, "esc_xml": true, "esc_html__": true, "esc_attr__": true,
+	"esc_html_e": true, "esc_attr_e": true, "wp_kses": true, "wp_kses_post": true, "wp_kses_data": true,
+	"sanitize_text_field": true, "sanitize_textarea_field": true, "sanitize_email": true,
+	"sanitize_file_name": true, "sanitize_key": true, "sanitize_title": true, "sanitize_user": true,
+	"sanitize_url": true, "sanitize_html_class": true, "sanitize_mime_type": true, "sanitize_option": true,
+	"sanitize_meta": true, "sanitize_sql_orderby": true, "wp_strip_all_tags": true,
+	"addslashes": true, "mysql_real_escape_string": true, "mysql_escape_string": true,
+	"mysqli_real_escape_string": true, "mysqli_escape_string": true, "pg_escape_string": true,
+	"pg_escape_literal": true, "sqlite_escape_string": true,
+	"escapeshellarg": true, "escapeshellcmd": true,
+	"basename": true, "pathinfo": true, "realpath": true, "urlencode": true, "rawurlencode": true, "json_encode": true,
+	"wp_json_encode": true, "filter_var": true, "preg_quote": true, "validate_file": true,
+	// Methods
+	"prepare": true, "esc_like": true, "quote": true, "real_escape_string": true, "escape_string": true,
+	"escape": true,
+}
+
+// cleansers are the functions whose result carries no attacker-controlled string, such as casts,
+// hashes, predicates and counts
+var cleansers = map[string]bool{
+	"intval": true, "floatval": true, "doubleval": true, "boolval": true, "absint": true,
+	"count": true, "sizeof": true, "strlen": true, "mb_strlen": true, "is_numeric": true, "is_int": true,
+	"is_string": true, "is_array": true, "is_email": true, "ctype_digit": true, "ctype_alnum": true,
+	"ctype_alpha": true, "in_array": true, "array_key_exists": true,
+	"md5": true, "sha1": true, "crc32": true, "hash": true, "hash_hmac": true, "password_hash": true,
+	"password_verify": true, "wp_hash": true, "wp_hash_password": true, "wp_create_nonce": true,
+	"wp_verify_nonce": true, "check_ajax_referer": true, "check_admin_referer": true,
+	"current_user_can": true, "number_format": true, "round": true, "floor": true, "ceil": true,
+	"abs": true, "time": true, "strtotime": true, "checkdate": true, "preg_match": true,
+	"preg_match_all": true, "strpos": true, "stripos": true, "strcmp": true, "strcasecmp": true,
+	"file_exists": true, "is_file": true, "is_dir": true, "uniqid": true, "rand": true, "mt_rand": true,
+	"random_int": true, "wp_rand": true, "get_current_user_id": true, "is_user_logged_in": true,
+}
+
+// resultFunctions are the functions returning stored or computed data rather than their arguments
+var resultFunctions = map[string]bool{
+	"get_option": true, "get_site_option": true, "get_post_meta": true, "get_user_meta": true,
+	"get_term_meta": true, "get_transient": true, "get_post": true, "get_userdata": true,
+	"file_get_contents": true, "fread": true, "fgets": true, "file": true, "mysqli_query": true,
+	"mysql_query": true, "wp_remote_get": true, "wp_remote_post": true, "curl_exec": true,
+}
+
+// resultMethods are the methods returning stored data, such as the query methods of $wpdb
+var resultMethods = map[string]bool{
+	"get_var": true, "get_row": true, "get_col": true, "get_results": true, "query": true,
+	"fetch": true, "fetchall": true, "fetchcolumn": true, "fetch_assoc": true, "fetch_array": true,
+	"fetch_row": true, "fetch_object": true, "execute": true,
+}
+
+// numericCasts are the cast types whose result carries no attacker-controlled string
+var numericCasts = map[string]bool{
+	"int": true, "integer": true, "float": true, "double": true, "real": true, "bool": true, "boolean": true,
+	"unset": true,
+}
@@ -0,0 +1,917 @@
+package taint
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// maxDepth bounds the propagation through nested expressions, definitions and calls
+const maxDepth = 256
+
+// maxTaints bounds the taints kept for an expression
+const maxTaints = 8
+
+// Source is an expression reading attacker-controlled data
+type Source struct {
+	Kind SourceKind `json:"kind"`
+	// Name is the text of the source expression, such as $_GET['id']
+	Name string    `json:"name"`
+	File string    `json:"file"`
+	Line uint      `json:"line"`
+	Node *ast.Node `json:"-"`
+	// function and index identify the parameter of a placeholder source
+	function *ast.Node
+	index    int
+}
+
+// Step is an expression the data flows through, from the source to the tainted expression
+type Step struct {
+	File string    `json:"file"`
+	Line uint      `json:"line"`
+	Text string    `json:"text"`
+	Node *ast.Node `json:"-"`
+}
+
+// Sanitizer is the application of a sanitizing function on the way from the source
+type Sanitizer struct {
+	// Name is the lowercase name of the function or method
+	Name string    `json:"name"`
+	Node *ast.Node `json:"-"`
+}
+
+// Taint is a flow of attacker-controlled data into an expression
+type Taint struct {
+	Source *Source `json:"source"`
+	// Path is the flow from the source: the assignments, parameters and returns the data went through
+	Path       []*Step      `json:"path"`
+	Sanitizers []*Sanitizer `json:"sanitizers,omitempty"`
+}
+
+// SanitizedBy reports whether one of the functions was applied on the way from the source
+func (t *Taint) SanitizedBy(names ...string) bool {
+	for _, s := range t.Sanitizers {
+		for _, name := range names {
+			if s.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Last returns the last sanitizer applied, nil if none
+func (t *Taint) Last() *Sanitizer {
+	if len(t.Sanitizers) == 0 {
+		return nil
+	}
+	return t.Sanitizers[len(t.Sanitizers)-1]
+}
+
+// key identifies a taint by its source and sanitizers, the paths of the same flow being redundant
+func (t *Taint) key() string {
+	var sb strings.Builder
+	sb.WriteString(t.Source.File)
+	if t.Source.Node != nil {
+		sb.WriteString(":" + strconv.Itoa(int(t.Source.Node.StartByte)))
+	}
+	for _, s := range t.Sanitizers {
+		sb.WriteString("|" + s.Name)
+	}
+	return sb.String()
+}
+
+// Unsanitized returns the taints none of the sanitizers was applied to
+func Unsanitized(taints []*Taint, sanitizers ...string) []*Taint {
+	var result []*Taint
+	for _, t := range taints {
+		if !t.SanitizedBy(sanitizers...) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// Analyzer computes the flows of request data into the expressions of a project. Variables are
+// followed through the scope def-use chains, properties of $this through their assignments in the
+// class, and calls to the functions and methods of the project through summaries of their returns,
+// in which the parameters are substituted with the arguments of each call.
+type Analyzer struct {
+	files     map[*ast.Node]string
+	roots     []*ast.Node
+	evaluator *values.Evaluator
+	inferer   *types.Inferer
+	// functions maps the lowercase names of the functions of the project to their declaration
+	functions map[string]*ast.Node
+	// callSites maps the function and method declarations to the calls that may reach them
+	callSites map[*ast.Node][]*ast.Node
+	// properties maps the lowercase property names to the values assigned to them through $this
+	properties map[string][]propertyAssignment
+	// objectProperties maps the lowercase property names to the assignments to them through the
+	// other variables
+	objectProperties map[string][]*ast.Node
+	cache            map[*ast.Node][]*Taint
+	// definitions caches the taints of the definitions, with the step of the definition
+	definitions map[*ast.Node][]*Taint
+	summaries   map[*ast.Node][]*Taint
+	// inProgress holds the expressions and functions being computed, which reach themselves in
+	// loops and recursions
+	inProgress map[*ast.Node]bool
+	// cycles counts the computations that reached an expression in progress; their results are not cached
+	cycles int
+	depth  int
+}
+
+type propertyAssignment struct {
+	class *classes.Class
+	value *ast.Node
+}
+
+func New() *Analyzer {
+	return &Analyzer{
+		files:            make(map[*ast.Node]string),
+		evaluator:        values.New(),
+		inferer:          types.New(),
+		functions:        make(map[string]*ast.Node),
+		callSites:        make(map[*ast.Node][]*ast.Node),
+		properties:       make(map[string][]propertyAssignment),
+		objectProperties: make(map[string][]*ast.Node),
+		cache:            make(map[*ast.Node][]*Taint),
+		definitions:      make(map[*ast.Node][]*Taint),
+		summaries:        make(map[*ast.Node][]*Taint),
+		inProgress:       make(map[*ast.Node]bool),
+	}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	a.files[root] = path
+	a.roots = append(a.roots, root)
+	a.evaluator.AddFile(path, root)
+	a.inferer.AddFile(path, root)
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+	root.WalkPrefix(v)
+	for _, fn := range v.Nodes {
+		if name := fn.ChildOfKind("name"); name != nil {
+			a.functions[strings.ToLower(name.Text)] = fn
+		}
+	}
+}
+
+// Resolve links the classes of the project and indexes its calls and property assignments
+func (a *Analyzer) Resolve() {
+	a.evaluator.Resolve()
+	a.inferer.Resolve()
+	for _, root := range a.roots {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{
+			"function_call_expression": true, "member_call_expression": true, "nullsafe_member_call_expression": true,
+			"scoped_call_expression": true, "object_creation_expression": true, "assignment_expression": true,
+			"property_promotion_parameter": true,
+		}}
+		root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			switch n.Kind {
+			case "assignment_expression", "property_promotion_parameter":
+				a.indexProperty(n)
+			default:
+				for _, target := range a.Targets(n) {
+					a.callSites[target] = append(a.callSites[target], n)
+				}
+			}
+		}
+	}
+}
+
+func (a *Analyzer) indexProperty(n *ast.Node) {
+	children := n.NamedChildren()
+	if n.Kind == "assignment_expression" && len(children) == 2 && children[0].Kind == "member_access_expression" {
+		target := children[0].NamedChildren()
+		if len(target) == 2 && target[0].Kind == "variable_name" && target[0].Text != "$this" && target[1].Kind == "name" {
+			key := strings.ToLower(target[1].Text)
+			a.objectProperties[key] = append(a.objectProperties[key], n)
+			return
+		}
+	}
+	class := a.inferer.Hierarchy().EnclosingClass(n)
+	if class == nil {
+		return
+	}
+	if n.Kind == "property_promotion_parameter" {
+		if name := n.ChildOfKind("variable_name"); name != nil {
+			key := strings.ToLower(scope.VariableName(name))
+			a.properties[key] = append(a.properties[key], propertyAssignment{class, name})
+		}
+		return
+	}
+	if len(children) < 2 || children[0].Kind != "member_access_expression" {
+		return
+	}
+	target := children[0].NamedChildren()
+	if len(target) != 2 || target[0].Text != "$this" || target[1].Kind != "name" {
+		return
+	}
+	key := strings.ToLower(target[1].Text)
+	a.properties[key] = append(a.properties[key], propertyAssignment{class, children[len(children)-1]})
+}
+
+// Targets returns the declarations of the functions, methods and constructors of the project a
+// call may reach
+func (a *Analyzer) Targets(call *ast.Node) []*ast.Node {
+	var targets []*ast.Node
+	switch call.Kind {
+	case "function_call_expression":
+		if fn, ok := a.functions[FunctionName(call)]; ok {
+			targets = append(targets, fn)
+		}
+	case "object_creation_expression":
+		for _, class := range a.inferer.TypeOf(call).Classes() {
+			if c := a.inferer.Hierarchy().Lookup(class); c != nil {
+				if m := c.Method("__construct"); m != nil {
+					targets = append(targets, m.Declaration.Node)
+				}
+			}
+		}
+	default:
+		methods := a.inferer.ResolveCall(call)
+		if len(methods) == 0 {
+			methods = a.inferer.Hierarchy().ResolveCall(call)
+		}
+		for _, m := range methods {
+			targets = append(targets, m.Node)
+		}
+	}
+	return targets
+}
+
+// Evaluator returns the string value evaluator of the project
+func (a *Analyzer) Evaluator() *values.Evaluator {
+	return a.evaluator
+}
+
+// Inferer returns the type inferer of the project
+func (a *Analyzer) Inferer() *types.Inferer {
+	return a.inferer
+}
+
+// File returns the path of the file of a node
+func (a *Analyzer) File(n *ast.Node) string {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return a.files[root]
+}
+
+// Of returns the flows of request data into an expression. The parameters of the enclosing
+// functions are followed to the arguments of their calls.
+func (a *Analyzer) Of(n *ast.Node) []*Taint {
+	return a.resolve(a.taints(n), make(map[*ast.Node]bool))
+}
+
+// Tainted reports whether request data may flow into an expression
+func (a *Analyzer) Tainted(n *ast.Node) bool {
+	return len(a.Of(n)) > 0
+}
+
+// resolve substitutes the parameter placeholders with the taints of the arguments of the calls
+// of their function
+func (a *Analyzer) resolve(taints []*Taint, visited map[*ast.Node]bool) []*Taint {
+	var result []*Taint
+	for _, t := range taints {
+		if t.Source.Kind != parameter {
+			result = append(result, t)
+			continue
+		}
+		fn := t.Source.function
+		if visited[fn] {
+			continue
+		}
+		visited[fn] = true
+		for _, call := range a.callSites[fn] {
+			arguments := call.Arguments()
+			if t.Source.index >= len(arguments) {
+				continue
+			}
+			argument := a.resolve(a.taints(arguments[t.Source.index]), visited)
+			result = append(result, combine(argument, []*Taint{t})...)
+		}
+		delete(visited, fn)
+	}
+	return union(result)
+}
+
+// combine prefixes the taints of a function summary that come from a parameter with the taints of
+// the corresponding argument
+func combine(arguments []*Taint, summary []*Taint) []*Taint {
+	var result []*Taint
+	for _, t := range summary {
+		for _, argument := range arguments {
+			result = append(result, &Taint{
+				Source:     argument.Source,
+				Path:       append(append([]*Step{}, argument.Path...), t.Path...),
+				Sanitizers: append(append([]*Sanitizer{}, argument.Sanitizers...), t.Sanitizers...),
+			})
+		}
+	}
+	return result
+}
+
+// taints computes the taints of an expression, with placeholders for the parameters of the
+// enclosing functions
+func (a *Analyzer) taints(n *ast.Node) []*Taint {
+	if t, ok := a.cache[n]; ok {
+		return t
+	}
+	if a.inProgress[n] {
+		a.cycles++
+		return nil
+	}
+	if a.depth > maxDepth {
+		// Giving up is not a proof of cleanliness. The expression is not cached, to be followed
+		// from a shallower query, but the expressions using it are.
+		t := a.source(Unknown, n)
+		t.Source.Name += " (not followed further)"
+		return []*Taint{t}
+	}
+	a.depth++
+	a.inProgress[n] = true
+	cycles := a.cycles
+	t := union(a.expression(n))
+	delete(a.inProgress, n)
+	a.depth--
+	if a.cycles == cycles {
+		a.cache[n] = t
+	}
+	return t
+}
+
+func (a *Analyzer) expression(n *ast.Node) []*Taint {
+	children := n.NamedChildren()
+	switch n.Kind {
+	case "variable_name":
+		return a.variable(n)
+	case "subscript_expression":
+		return a.subscript(n)
+	case "member_access_expression", "nullsafe_member_access_expression":
+		return a.property(n)
+	case "function_call_expression":
+		return a.functionCall(n)
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		return a.methodCall(n)
+	case "binary_expression":
+		switch operator(n) {
+		case ".", "??":
+			return a.all(children)
+		}
+	case "conditional_expression":
+		if len(children) == 3 {
+			return a.all(children[1:])
+		}
+		return a.all(children)
+	case "parenthesized_expression", "argument", "error_suppression_expression", "clone_expression", "by_ref",
+		"sequence_expression":
+		return a.all(children)
+	case "unary_op_expression":
+		if len(n.Descendants) > 0 && n.Descendants[0].Kind == "@" {
+			return a.all(children)
+		}
+	case "cast_expression":
+		if cast := n.ChildOfKind("cast_type"); cast != nil && numericCasts[strings.ToLower(strings.TrimSpace(cast.Text))] {
+			return nil
+		}
+		if len(children) > 0 {
+			return a.taints(children[len(children)-1])
+		}
+	case "encapsed_string", "heredoc":
+		var parts []*ast.Node
+		for _, part := range literal.Parts(n) {
+			switch part.Kind {
+			case "string_content", "escape_sequence", "string_value", "nowdoc_string":
+			default:
+				parts = append(parts, part)
+			}
+		}
+		return a.all(parts)
+	case "array_creation_expression":
+		var elements []*ast.Node
+		for _, element := range children {
+			if parts := element.NamedChildren(); len(parts) > 0 {
+				elements = append(elements, parts[len(parts)-1])
+			}
+		}
+		return a.all(elements)
+	case "assignment_expression", "augmented_assignment_expression", "reference_assignment_expression":
+		if len(children) >= 2 {
+			return a.taints(children[len(children)-1])
+		}
+	case "match_expression":
+		var arms []*ast.Node
+		if block := n.ChildOfKind("match_block"); block != nil {
+			for _, arm := range block.NamedChildren() {
+				if parts := arm.NamedChildren(); len(parts) > 0 {
+					arms = append(arms, parts[len(parts)-1])
+				}
+			}
+		}
+		return a.all(arms)
+	}
+	return nil
+}
+
+// all returns the union of the taints of expressions
+func (a *Analyzer) all(nodes []*ast.Node) []*Taint {
+	var result []*Taint
+	for _, n := range nodes {
+		result = append(result, a.taints(n)...)
+	}
+	return result
+}
+
+func (a *Analyzer) variable(n *ast.Node) []*Taint {
+	name := scope.VariableName(n)
+	if kind, ok := superglobals[name]; ok {
+		return []*Taint{a.source(kind, n)}
+	}
+	switch name {
+	case "_SERVER":
+		return []*Taint{a.source(Header, n)}
+	case "GLOBALS", "_SESSION", "_ENV", "this":
+		return nil
+	}
+	var result []*Taint
+	defs, _ := scope.DefsOf(n)
+	for _, def := range defs {
+		result = append(result, a.reaching(def)...)
+	}
+	return result
+}
+
+// reaching returns the taints of a definition reaching a variable, memoized so that the branches
+// joining on the same definitions are followed once
+func (a *Analyzer) reaching(def *ast.Node) []*Taint {
+	if t, ok := a.definitions[def]; ok {
+		return t
+	}
+	if a.inProgress[def] {
+		// A definition reaching itself through a loop, such as $s .= $x
+		a.cycles++
+		return nil
+	}
+	a.inProgress[def] = true
+	cycles := a.cycles
+	t := a.extend(a.definition(def), stepNode(def))
+	delete(a.inProgress, def)
+	if a.cycles == cycles {
+		a.definitions[def] = t
+	}
+	return t
+}
+
+// definition returns the taints of the value written by a definition
+func (a *Analyzer) definition(def *ast.Node) []*Taint {
+	parent := def.Parent
+	if parent == nil {
+		return nil
+	}
+	switch parent.Kind {
+	case "assignment_expression", "reference_assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) >= 2 && children[0] == def {
+			return a.taints(children[len(children)-1])
+		}
+	case "augmented_assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) < 2 || children[0] != def || len(parent.Descendants) < 2 {
+			return nil
+		}
+		switch parent.Descendants[1].Kind {
+		case ".=", "??=":
+			// The target is also read before the assignment
+			return append(a.variable(def), a.taints(children[len(children)-1])...)
+		}
+	case "subscript_expression", "array_element_initializer", "pair", "list_literal", "by_ref":
+		// An element write or a destructuring assignment: the array is tainted by the assigned value
+		target := parent
+		for target.Parent != nil && isTargetPart(target.Parent.Kind) {
+			target = target.Parent
+		}
+		var result []*Taint
+		if parent.Kind == "subscript_expression" {
+			// The other elements of the array are kept
+			result = a.variable(def)
+		}
+		if assignment := target.Parent; assignment != nil && assignment.Kind == "assignment_expression" {
+			children := assignment.NamedChildren()
+			if len(children) >= 2 && children[0] == target {
+				result = append(result, a.taints(children[len(children)-1])...)
+			}
+		} else if assignment != nil && assignment.Kind == "foreach_statement" {
+			result = append(result, a.foreachSubject(assignment)...)
+		}
+		return result
+	case "foreach_statement":
+		return a.foreachSubject(parent)
+	case "simple_parameter", "variadic_parameter", "property_promotion_parameter":
+		return a.parameter(parent)
+	case "anonymous_function_use_clause":
+		// Captured by value: the value of the enclosing variable when the closure is created
+		return a.variable(def)
+	}
+	return nil
+}
+
+// isTargetPart reports whether a node kind may be part of an assignment target around a variable
+func isTargetPart(kind string) bool {
+	switch kind {
+	case "subscript_expression", "array_element_initializer", "pair", "list_literal", "array_creation_expression", "by_ref":
+		return true
+	}
+	return false
+}
+
+// foreachSubject returns the taints of the array iterated by a foreach statement
+func (a *Analyzer) foreachSubject(n *ast.Node) []*Taint {
+	for _, child := range n.Descendants {
+		if child.Kind == "as" {
+			break
+		}
+		if child.IsNamed {
+			return a.taints(child)
+		}
+	}
+	return nil
+}
+
+// parameter returns the placeholder taint of a parameter, substituted by the arguments of the calls
+func (a *Analyzer) parameter(n *ast.Node) []*Taint {
+	list := n.Parent
+	if list == nil || list.Parent == nil {
+		return nil
+	}
+	index := 0
+	for _, p := range list.NamedChildren() {
+		if p == n {
+			break
+		}
+		index++
+	}
+	name := n.ChildOfKind("variable_name")
+	if name == nil {
+		return nil
+	}
+	source := &Source{
+		Kind:     parameter,
+		Name:     name.Text,
+		File:     a.File(n),
+		Line:     n.StartPosition.Row + 1,
+		Node:     name,
+		function: list.Parent,
+		index:    index,
+	}
+	return []*Taint{{Source: source, Path: []*Step{a.step(n)}}}
+}
+
+func (a *Analyzer) subscript(n *ast.Node) []*Taint {
+	children := n.NamedChildren()
+	if len(children) == 0 {
+		return nil
+	}
+	base := children[0]
+	key := ""
+	known := false
+	if len(children) > 1 {
+		key, known = a.evaluator.Eval(children[1]).Constant()
+	}
+	root := base
+	for root.Kind == "subscript_expression" && len(root.NamedChildren()) > 0 {
+		root = root.NamedChildren()[0]
+	}
+	if root.Kind == "variable_name" {
+		switch name := scope.VariableName(root); {
+		case name == "_SERVER" && base == root:
+			if !serverSource(key, known) {
+				return nil
+			}
+			return []*Taint{a.source(Header, n)}
+		case name == "_FILES" && known && uploadMetadata[key]:
+			return nil
+		case base == root:
+			if kind, ok := superglobals[name]; ok {
+				return []*Taint{a.source(kind, n)}
+			}
+		}
+	}
+	return a.taints(base)
+}
+
+// property returns the taints of a property read: the values assigned to the property of $this in
+// the class hierarchy, and for the other receivers the taints of the object and the values
+// assigned to the property of the same variable
+func (a *Analyzer) property(n *ast.Node) []*Taint {
+	children := n.NamedChildren()
+	if len(children) != 2 {
+		return nil
+	}
+	if children[1].Kind != "name" {
+		return a.taints(children[0])
+	}
+	if children[0].Text != "$this" {
+		result := a.taints(children[0])
+		for _, assignment := range a.objectProperties[strings.ToLower(children[1].Text)] {
+			values := assignment.NamedChildren()
+			if sameObject(values[0].NamedChildren()[0], children[0]) {
+				result = append(result, a.extend(a.taints(values[1]), assignment)...)
+			}
+		}
+		return result
+	}
+	class := a.inferer.Hierarchy().EnclosingClass(n)
+	if class == nil {
+		return nil
+	}
+	var result []*Taint
+	for _, assignment := range a.properties[strings.ToLower(children[1].Text)] {
+		if assignment.class != class && !class.IsSubclassOf(assignment.class) && !assignment.class.IsSubclassOf(class) {
+			continue
+		}
+		if assignment.value.Kind == "variable_name" {
+			// Promoted constructor parameter
+			result = append(result, a.definition(assignment.value)...)
+		} else {
+			result = append(result, a.extend(a.taints(assignment.value), assignment.value.Parent)...)
+		}
+	}
+	return result
+}
+
+// sameObject reports whether two variables of the same function hold the same object: they share
+// a reaching definition, or are both undefined. Properties are written through object handles,
+// so the writes to a property are read from any point of the function.
+func sameObject(a, b *ast.Node) bool {
+	if a.Kind != "variable_name" || b.Kind != "variable_name" || scope.VariableName(a) != scope.VariableName(b) ||
+		scope.EnclosingFunction(a) != scope.EnclosingFunction(b) {
+		return false
+	}
+	defsA, _ := scope.DefsOf(a)
+	defsB, _ := scope.DefsOf(b)
+	if len(defsA) == 0 && len(defsB) == 0 {
+		return true
+	}
+	for _, def := range defsA {
+		if slices.Contains(defsB, def) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analyzer) functionCall(n *ast.Node) []*Taint {
+	arguments := n.Arguments()
+	name := FunctionName(n)
+	switch {
+	case name == "":
+		// Variable function
+		return a.all(arguments)
+	case name == "filter_input" || name == "filter_input_array":
+		if len(arguments) > 0 {
+			if kind, ok := inputTypes[strings.TrimPrefix(arguments[0].Text, "\\")]; ok {
+				if len(arguments) > 2 && strings.Contains(strings.ToUpper(arguments[2].Text), "FILTER_VALIDATE_INT") {
+					return nil
+				}
+				return []*Taint{a.source(kind, n)}
+			}
+		}
+		return nil
+	case name == "file_get_contents" && len(arguments) > 0:
+		if path, ok := a.evaluator.Eval(arguments[0]).Constant(); ok && strings.EqualFold(path, "php://input") {
+			return []*Taint{a.source(Request, n)}
+		}
+		return nil
+	}
+	if kind, ok := sourceFunctions[name]; ok {
+		return []*Taint{a.source(kind, n)}
+	}
+	if cleansers[name] || resultFunctions[name] {
+		return nil
+	}
+	if Sanitizers[name] {
+		if len(arguments) == 0 {
+			return nil
+		}
+		return sanitize(a.taints(arguments[0]), name, n)
+	}
+	if fn, ok := a.functions[name]; ok {
+		return a.call(n, []*ast.Node{fn})
+	}
+	return a.all(arguments)
+}
+
+func (a *Analyzer) methodCall(n *ast.Node) []*Taint {
+	name := strings.ToLower(n.MemberName())
+	arguments := n.Arguments()
+	if kind, ok := sourceMethods[name]; ok {
+		return []*Taint{a.source(kind, n)}
+	}
+	if Sanitizers[name] {
+		if len(arguments) == 0 {
+			return nil
+		}
+		if name == "prepare" {
+			// The arguments are escaped, the query itself is not
+			return append(a.taints(arguments[0]), sanitize(a.all(arguments[1:]), name, n)...)
+		}
+		return sanitize(a.taints(arguments[0]), name, n)
+	}
+	if resultMethods[name] || cleansers[name] {
+		return nil
+	}
+	if targets := a.Targets(n); len(targets) > 0 {
+		return a.call(n, targets)
+	}
+	return a.all(arguments)
+}
+
+// call returns the taints of the returns of the functions a call may reach, with the parameters
+// substituted by the arguments of the call
+func (a *Analyzer) call(n *ast.Node, targets []*ast.Node) []*Taint {
+	arguments := n.Arguments()
+	var result []*Taint
+	for _, fn := range targets {
+		for _, t := range a.summary(fn) {
+			if t.Source.Kind != parameter || t.Source.function != fn {
+				result = append(result, t)
+				continue
+			}
+			if t.Source.index < len(arguments) {
+				result = append(result, combine(a.taints(arguments[t.Source.index]), []*Taint{t})...)
+			}
+		}
+	}
+	return a.extend(result, n)
+}
+
+// summary returns the taints of the values returned by a function, with placeholders for its parameters
+func (a *Analyzer) summary(fn *ast.Node) []*Taint {
+	if t, ok := a.summaries[fn]; ok {
+		return t
+	}
+	if a.inProgress[fn] {
+		a.cycles++
+		return nil
+	}
+	a.inProgress[fn] = true
+	cycles := a.cycles
+	var result []*Taint
+	if fn.Kind == "arrow_function" {
+		if children := fn.NamedChildren(); len(children) > 0 {
+			result = a.taints(children[len(children)-1])
+		}
+	} else {
+		v := &returnVisitor{function: fn}
+		fn.WalkPrefix(v)
+		for _, r := range v.returns {
+			if children := r.NamedChildren(); len(children) > 0 {
+				result = append(result, a.extend(a.taints(children[0]), r)...)
+			}
+		}
+	}
+	result = union(result)
+	delete(a.inProgress, fn)
+	if a.cycles == cycles {
+		a.summaries[fn] = result
+	}
+	return result
+}
+
+type returnVisitor struct {
+	function *ast.Node
+	returns  []*ast.Node
+}
+
+func (v *returnVisitor) VisitNode(n *ast.Node) {
+	if n.Kind != "return_statement" {
+		return
+	}
+	// Returns of nested functions belong to them
+	for cur := n.Parent; cur != nil; cur = cur.Parent {
+		switch cur.Kind {
+		case "function_definition", "method_declaration", "anonymous_function", "arrow_function":
+			if cur == v.function {
+				v.returns = append(v.returns, n)
+			}
+			return
+		}
+	}
+}
+
+func (a *Analyzer) source(kind SourceKind, n *ast.Node) *Taint {
+	source := &Source{Kind: kind, Name: n.Text, File: a.File(n), Line: n.StartPosition.Row + 1, Node: n}
+	return &Taint{Source: source, Path: []*Step{a.step(n)}}
+}
+
+func (a *Analyzer) step(n *ast.Node) *Step {
+	text := strings.TrimSpace(n.Text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i] + " ..."
+	}
+	if len(text) > 80 {
+		text = text[:77] + "..."
+	}
+	return &Step{File: a.File(n), Line: n.StartPosition.Row + 1, Text: text, Node: n}
+}
+
+// extend appends a step to the paths of taints
+func (a *Analyzer) extend(taints []*Taint, n *ast.Node) []*Taint {
+	if n == nil || len(taints) == 0 {
+		return taints
+	}
+	step := a.step(n)
+	var result []*Taint
+	for _, t := range taints {
+		if last := t.Path[len(t.Path)-1]; last.Node == n {
+			result = append(result, t)
+			continue
+		}
+		result = append(result, &Taint{Source: t.Source, Path: append(append([]*Step{}, t.Path...), step), Sanitizers: t.Sanitizers})
+	}
+	return result
+}
+
+// sanitize records the application of a sanitizer on taints
+func sanitize(taints []*Taint, name string, n *ast.Node) []*Taint {
+	var result []*Taint
+	for _, t := range taints {
+		result = append(result, &Taint{
+			Source:     t.Source,
+			Path:       t.Path,
+			Sanitizers: append(append([]*Sanitizer{}, t.Sanitizers...), &Sanitizer{Name: name, Node: n}),
+		})
+	}
+	return result
+}
+
+// union removes the redundant taints, keeping the shortest path of each flow, and bounds their number
+func union(taints []*Taint) []*Taint {
+	var result []*Taint
+	index := make(map[string]int)
+	for _, t := range taints {
+		key := t.key()
+		if i, ok := index[key]; ok {
+			if len(t.Path) < len(result[i].Path) {
+				result[i] = t
+			}
+			continue
+		}
+		if len(result) == maxTaints {
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, t)
+	}
+	return result
+}
+
+// stepNode returns the statement-level node of a definition shown in the paths: the assignment,
+// foreach or parameter
+func stepNode(def *ast.Node) *ast.Node {
+	for cur := def.Parent; cur != nil; cur = cur.Parent {
+		switch cur.Kind {
+		case "assignment_expression", "augmented_assignment_expression", "reference_assignment_expression",
+			"simple_parameter", "variadic_parameter", "property_promotion_parameter", "anonymous_function_use_clause":
+			return cur
+		case "foreach_statement":
+			return def
+		}
+		if !isTargetPart(cur.Kind) {
+			break
+		}
+	}
+	return def
+}
+
+// FunctionName returns the lowercase name of the function called by a function call expression,
+// without namespace, or the empty string for a variable function
+func FunctionName(call *ast.Node) string {
+	name := call.ChildOfKind("name", "qualified_name")
+	if name == nil {
+		return ""
+	}
+	return strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
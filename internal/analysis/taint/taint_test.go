@@ -0,0 +1,154 @@
+package taint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// echoed returns the taints of the expression of the last echo statement of a source
+func echoed(t *testing.T, source string) []*Taint {
+	t.Helper()
+	root := ast.ParseSource([]byte(source))
+	a := New()
+	a.AddFile("test.php", root)
+	a.Resolve()
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"echo_statement": true}}
+	root.WalkPrefix(v)
+	if len(v.Nodes) == 0 {
+		t.Fatal("no echo statement")
+	}
+	return a.Of(v.Nodes[len(v.Nodes)-1].NamedChildren()[0])
+}
+
+// sources returns the sorted names of the sources of taints
+func sources(taints []*Taint) []string {
+	names := []string{}
+	for _, t := range taints {
+		if !slices.Contains(names, t.Source.Name) {
+			names = append(names, t.Source.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		sources []string
+	}{
+		{
+			name:    "superglobal",
+			source:  "<?php\necho $_GET['x'];",
+			sources: []string{"$_GET['x']"},
+		},
+		{
+			name:    "through assignments",
+			source:  "<?php\n$a = $_POST['a'];\n$b = 'x' . $a;\necho $b;",
+			sources: []string{"$_POST['a']"},
+		},
+		{
+			name:    "through a function and its return",
+			source:  "<?php\nfunction id($v) {\nreturn $v;\n}\necho id($_COOKIE['c']);",
+			sources: []string{"$_COOKIE['c']"},
+		},
+		{
+			name:    "through a property of a local object",
+			source:  "<?php\n$o = new stdClass();\n$o->p = $_GET['d'];\necho $o->p;",
+			sources: []string{"$_GET['d']"},
+		},
+		{
+			name:    "another property of the object is clean",
+			source:  "<?php\n$o = new stdClass();\n$o->p = $_GET['d'];\n$o->q = 'safe';\necho $o->q;",
+			sources: []string{},
+		},
+		{
+			name:    "branches join their sources",
+			source:  "<?php\nif ($c) {\n$a = $_GET['a'];\n} else {\n$a = $_GET['b'];\n}\necho $a;",
+			sources: []string{"$_GET['a']", "$_GET['b']"},
+		},
+		{
+			name:    "a redefinition kills the taint",
+			source:  "<?php\n$a = $_GET['a'];\n$a = 'safe';\necho $a;",
+			sources: []string{},
+		},
+		{
+			name:    "hashing cleanses the data",
+			source:  "<?php\necho md5($_GET['a']);",
+			sources: []string{},
+		},
+		{
+			name:    "literals are not tainted",
+			source:  "<?php\necho 'a' . 1;",
+			sources: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sources(echoed(t, tt.source)); !slices.Equal(got, tt.sources) {
+				t.Errorf("sources = %v, want %v", got, tt.sources)
+			}
+		})
+	}
+}
+
+func TestSanitizers(t *testing.T) {
+	taints := echoed(t, "<?php\n$a = htmlspecialchars($_GET['a']);\necho $a;")
+	if len(taints) != 1 || !taints[0].SanitizedBy("htmlspecialchars") {
+		t.Fatalf("taints = %v, want one sanitized by htmlspecialchars", taints)
+	}
+	if len(Unsanitized(taints, "htmlspecialchars")) != 0 {
+		t.Error("Unsanitized() kept a taint sanitized by htmlspecialchars")
+	}
+}
+
+// chain is a source assigning request data through a chain of n variables
+func chain(n int) string {
+	var sb strings.Builder
+	sb.WriteString("<?php\n$v0 = $_GET['x'];\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "$v%d = $v%d;\n", i, i-1)
+	}
+	fmt.Fprintf(&sb, "echo $v%d;\n", n)
+	return sb.String()
+}
+
+// branches is a source redefining request data in both branches of n successive if statements,
+// the two definitions of each one reaching both definitions of the next
+func branches(n int) string {
+	var sb strings.Builder
+	sb.WriteString("<?php\n$a = $_GET['x'];\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "if ($c%d) {\n$a = $a . 'x';\n} else {\n$a = 'y' . $a;\n}\n", i)
+	}
+	sb.WriteString("echo $a;\n")
+	return sb.String()
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		kind   SourceKind
+	}{
+		{name: "a long chain is followed to its source", source: chain(60), kind: Request},
+		{name: "a chain past the depth bound is assumed tainted", source: chain(maxDepth * 2), kind: Unknown},
+		{name: "joining branches are followed once", source: branches(60), kind: Request},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taints := echoed(t, tt.source)
+			if len(taints) == 0 {
+				t.Fatal("no taint")
+			}
+			if taints[0].Source.Kind != tt.kind {
+				t.Errorf("source kind = %s, want %s", taints[0].Source.Kind, tt.kind)
+			}
+		})
+	}
+}
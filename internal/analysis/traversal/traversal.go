@@ -0,0 +1,295 @@
+package traversal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// PathTraversal is request data reaching the path of a file operation
+	PathTraversal Category = "path-traversal"
+	// FileInclusion is request data reaching the path of include or require
+	FileInclusion Category = "file-inclusion"
+	// MissingPrefixCheck is a path resolved with realpath but not checked against its base directory
+	MissingPrefixCheck Category = "missing-prefix-check"
+	// UnvalidatedUpload is an uploaded file moved without checking its extension or content type
+	UnvalidatedUpload Category = "unvalidated-upload"
+)
+
+// fileFunctions maps the file operations to the indexes of their path arguments, and whether they
+// write or remove the file
+var fileFunctions = map[string]struct {
+	paths []int
+	write bool
+}{
+	"file_get_contents": {[]int{0}, false}, "fopen": {[]int{0}, false}, "readfile": {[]int{0}, false},
+	"file": {[]int{0}, false}, "highlight_file": {[]int{0}, false}, "show_source": {[]int{0}, false},
+	"parse_ini_file": {[]int{0}, false}, "opendir": {[]int{0}, false}, "scandir": {[]int{0}, false},
+	"glob": {[]int{0}, false}, "simplexml_load_file": {[]int{0}, false}, "gzopen": {[]int{0}, false},
+	"file_put_contents": {[]int{0}, true}, "unlink": {[]int{0}, true}, "rmdir": {[]int{0}, true},
+	"mkdir": {[]int{0}, true}, "touch": {[]int{0}, true}, "chmod": {[]int{0}, true}, "chown": {[]int{0}, true},
+	"copy": {[]int{0, 1}, true}, "rename": {[]int{0, 1}, true}, "symlink": {[]int{0, 1}, true},
+	"move_uploaded_file": {[]int{1}, true}, "tempnam": {[]int{0}, true},
+}
+
+// includeKinds are the node kinds of include and require
+var includeKinds = map[string]string{
+	"include_expression": "include", "include_once_expression": "include_once",
+	"require_expression": "require", "require_once_expression": "require_once",
+}
+
+// pathSanitizers are the sanitizers that remove the directories of a path
+var pathSanitizers = []string{"basename", "pathinfo", "sanitize_file_name", "validate_file", "sanitize_key", "sanitize_title"}
+
+// prefixChecks are the functions comparing the start of a resolved path with its base directory
+var prefixChecks = map[string]bool{
+	"strpos": true, "stripos": true, "str_starts_with": true, "strncmp": true, "strncasecmp": true,
+	"substr": true, "substr_compare": true, "preg_match": true,
+}
+
+// uploadValidators are the functions checking the extension or the content of an uploaded file
+var uploadValidators = map[string]bool{
+	"pathinfo": true, "wp_check_filetype": true, "wp_check_filetype_and_ext": true, "finfo_file": true,
+	"finfo_buffer": true, "mime_content_type": true, "getimagesize": true, "exif_imagetype": true,
+	"wp_handle_upload": true, "wp_handle_sideload": true, "media_handle_upload": true,
+}
+
+// extensionPattern matches the regular expressions checking a file extension
+var extensionPattern = regexp.MustCompile(`\\\.\(?[a-zA-Z0-9|]+\)?\$`)
+
+// Finding is request data reaching the path of a file operation, or an unvalidated upload
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	Sink     string         `json:"sink"`
+	File     string         `json:"file"`
+	Line     uint           `json:"line"`
+	Message  string         `json:"message"`
+	Taint    *taint.Taint   `json:"taint,omitempty"`
+	Node     *ast.Node      `json:"-"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the path traversals of a project: the request data reaching the paths of the
+// file operations and includes without basename, or realpath and a check of the base directory,
+// and the uploads moved without validating their extension or content type
+type Analyzer struct {
+	taint *taint.Analyzer
+	files []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{taint: taint.New()}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.taint.AddFile(path, root)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.taint.Resolve()
+	var findings []*Finding
+	for _, f := range a.files {
+		kinds := map[string]bool{"function_call_expression": true}
+		for kind := range includeKinds {
+			kinds[kind] = true
+		}
+		v := &ast.VisitorKinds{Kinds: kinds}
+		f.root.WalkPrefix(v)
+		for _, n := range v.Nodes {
+			findings = append(findings, a.check(f.path, n)...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func (a *Analyzer) check(path string, n *ast.Node) []*Finding {
+	if sink, ok := includeKinds[n.Kind]; ok {
+		if children := n.NamedChildren(); len(children) > 0 {
+			if finding := a.path(path, sink, n, children[0], FileInclusion); finding != nil {
+				return []*Finding{finding}
+			}
+		}
+		return nil
+	}
+	name := taint.FunctionName(n)
+	function, ok := fileFunctions[name]
+	if !ok {
+		return nil
+	}
+	var findings []*Finding
+	arguments := n.Arguments()
+	for _, index := range function.paths {
+		if index >= len(arguments) {
+			continue
+		}
+		if finding := a.path(path, name, n, arguments[index], PathTraversal); finding != nil {
+			if function.write && finding.Category == PathTraversal {
+				finding.Message += ", and the file is written or removed"
+			}
+			findings = append(findings, finding)
+			break
+		}
+	}
+	if name == "move_uploaded_file" {
+		if finding := a.upload(path, n); finding != nil {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// path returns the finding of request data reaching the path of a file operation, nil if it is
+// reduced to a file name or resolved and checked against its base directory
+func (a *Analyzer) path(path, sink string, n, argument *ast.Node, category Category) *Finding {
+	for _, t := range taint.Unsanitized(a.taint.Of(argument), pathSanitizers...) {
+		finding := &Finding{
+			Category: category,
+			Severity: taint.High,
+			Sink:     sink,
+			File:     path,
+			Line:     n.StartPosition.Row + 1,
+			Taint:    t,
+			Node:     n,
+		}
+		if t.SanitizedBy("realpath") {
+			if a.prefixChecked(argument) {
+				continue
+			}
+			finding.Category = MissingPrefixCheck
+			finding.Severity = taint.Medium
+			finding.Message = fmt.Sprintf("path from request data %s is resolved with realpath but not checked against its base directory before %s",
+				t.Source.Name, sink)
+			return finding
+		}
+		finding.Message = fmt.Sprintf("request data %s reaches the path of %s without basename or a realpath prefix check", t.Source.Name, sink)
+		if category == FileInclusion {
+			finding.Message = fmt.Sprintf("request data %s chooses the file included by %s", t.Source.Name, sink)
+			if a.taint.Evaluator().Eval(argument).Prefix() == "" {
+				finding.Message += ", a URL includes remote code where allow_url_include is set"
+			}
+		}
+		return finding
+	}
+	return nil
+}
+
+// prefixChecked reports whether the variables of a path are compared with a prefix, with strpos or
+// str_starts_with, in the function of the file operation
+func (a *Analyzer) prefixChecked(argument *ast.Node) bool {
+	variables := make(map[string]bool)
+	collect(argument, variables)
+	if len(variables) == 0 {
+		return false
+	}
+	body := scope.EnclosingFunction(argument)
+	if body == nil {
+		body = root(argument)
+	}
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true}}
+	body.WalkPrefix(v)
+	for _, call := range v.Nodes {
+		if !prefixChecks[taint.FunctionName(call)] || call.StartByte > argument.StartByte {
+			continue
+		}
+		for _, arg := range call.Arguments() {
+			used := make(map[string]bool)
+			collect(arg, used)
+			for name := range used {
+				if variables[name] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// upload returns the finding of a move_uploaded_file whose function checks neither the
+// extension nor the content of the file before. The type entry of $_FILES is sent by the client.
+func (a *Analyzer) upload(path string, call *ast.Node) *Finding {
+	body := scope.EnclosingFunction(call)
+	if body == nil {
+		body = root(call)
+	}
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_call_expression": true, "subscript_expression": true}}
+	body.WalkPrefix(v)
+	clientType := false
+	for _, n := range v.Nodes {
+		if n.StartByte > call.StartByte {
+			continue
+		}
+		if n.Kind == "subscript_expression" {
+			if index := n.NamedChildren(); len(index) == 2 && strings.Trim(index[1].Text, `'"`) == "type" {
+				clientType = true
+			}
+			continue
+		}
+		name := taint.FunctionName(n)
+		if uploadValidators[name] {
+			return nil
+		}
+		if arguments := n.Arguments(); name == "preg_match" && len(arguments) > 0 && extensionPattern.MatchString(arguments[0].Text) {
+			return nil
+		}
+	}
+	finding := &Finding{
+		Category: UnvalidatedUpload,
+		Severity: taint.High,
+		Sink:     "move_uploaded_file",
+		File:     path,
+		Line:     call.StartPosition.Row + 1,
+		Message:  "uploaded file moved without checking its extension or content type",
+		Node:     call,
+	}
+	if clientType {
+		finding.Severity = taint.Medium
+		finding.Message = "uploaded file only checked with the type sent by the client, check its extension and content with finfo_file"
+	}
+	if arguments := call.Arguments(); len(arguments) > 1 {
+		if taints := a.taint.Of(arguments[1]); len(taints) > 0 {
+			finding.Taint = taints[0]
+		}
+	}
+	return finding
+}
+
+// collect gathers the names of the variables of an expression
+func collect(n *ast.Node, names map[string]bool) {
+	if n.Kind == "variable_name" {
+		names[n.Text] = true
+		return
+	}
+	for _, child := range n.NamedChildren() {
+		collect(child, names)
+	}
+}
+
+func root(n *ast.Node) *ast.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
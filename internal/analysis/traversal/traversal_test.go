@@ -0,0 +1,105 @@
+package traversal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// category, severity and sink of the only finding expected, no finding when category is empty
+		category Category
+		severity taint.Severity
+		sink     string
+		// message is a part of the message of the finding
+		message string
+	}{
+		{
+			name:     "request path read",
+			source:   "<?php\necho file_get_contents('/var/data/' . $_GET['file']);",
+			category: PathTraversal, severity: taint.High, sink: "file_get_contents",
+			message: "without basename",
+		},
+		{
+			name:     "request path removed",
+			source:   "<?php\nunlink(\"/tmp/{$_POST['name']}\");",
+			category: PathTraversal, severity: taint.High, sink: "unlink",
+			message: "written or removed",
+		},
+		{
+			name:   "request path reduced to its base name",
+			source: "<?php\necho file_get_contents('/var/data/' . basename($_GET['file']));",
+		},
+		{
+			name:     "request path resolved without a prefix check",
+			source:   "<?php\n$p = realpath('/var/data/' . $_GET['file']);\nreadfile($p);",
+			category: MissingPrefixCheck, severity: taint.Medium, sink: "readfile",
+		},
+		{
+			name:   "request path resolved and checked",
+			source: "<?php\n$p = realpath('/var/data/' . $_GET['file']);\nif (str_starts_with($p, '/var/data/')) {\nreadfile($p);\n}",
+		},
+		{
+			name:     "request path included",
+			source:   "<?php\ninclude $_GET['page'] . '.php';",
+			category: FileInclusion, severity: taint.High, sink: "include",
+			message: "allow_url_include",
+		},
+		{
+			name:     "request path included from a directory",
+			source:   "<?php\nrequire_once __DIR__ . '/pages/' . $_GET['page'];",
+			category: FileInclusion, severity: taint.High, sink: "require_once",
+		},
+		{
+			name:   "page chosen from a list",
+			source: "<?php\n$page = in_array($_GET['page'], ['home', 'about'], true) ? $_GET['page'] : 'home';\ninclude 'pages/' . basename($page) . '.php';",
+		},
+		{
+			name:     "upload moved without checks",
+			source:   "<?php\nmove_uploaded_file($_FILES['f']['tmp_name'], 'uploads/' . md5($_FILES['f']['name']));",
+			category: UnvalidatedUpload, severity: taint.High, sink: "move_uploaded_file",
+		},
+		{
+			name:     "upload checked with the client type",
+			source:   "<?php\nif ($_FILES['f']['type'] === 'image/png') {\nmove_uploaded_file($_FILES['f']['tmp_name'], 'uploads/a.png');\n}",
+			category: UnvalidatedUpload, severity: taint.Medium, sink: "move_uploaded_file",
+			message: "type sent by the client",
+		},
+		{
+			name:   "upload moved after an extension check",
+			source: "<?php\n$ext = pathinfo($_FILES['f']['name'], PATHINFO_EXTENSION);\nif (in_array($ext, ['png', 'jpg'], true)) {\nmove_uploaded_file($_FILES['f']['tmp_name'], 'uploads/' . md5($_FILES['f']['name']) . '.' . $ext);\n}",
+		},
+		{
+			name:   "upload moved after a regular expression check",
+			source: "<?php\nif (preg_match('/\\.(png|jpg)$/', $_FILES['f']['name'])) {\nmove_uploaded_file($_FILES['f']['tmp_name'], 'uploads/a.png');\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			findings := a.Analyze()
+			if tt.category == "" {
+				for _, f := range findings {
+					t.Errorf("unexpected %s finding: %s", f.Category, f.Message)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %d, want 1", len(findings))
+			}
+			f := findings[0]
+			if f.Category != tt.category || f.Severity != tt.severity || f.Sink != tt.sink {
+				t.Errorf("finding = %s %s %s, want %s %s %s", f.Category, f.Severity, f.Sink, tt.category, tt.severity, tt.sink)
+			}
+			if !strings.Contains(f.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", f.Message, tt.message)
+			}
+		})
+	}
+}
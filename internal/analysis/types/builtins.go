@@ -0,0 +1,109 @@
+package types
+
+// builtinFunctions are the return types of the PHP and WordPress functions, keyed by lowercase name
+var builtinFunctions = map[string]string{
+	// Databases
+	"mysqli_connect": "mysqli|false", "mysqli_init": "mysqli|false", "mysqli_query": "mysqli_result|bool",
+	"mysqli_prepare": "mysqli_stmt|false", "mysqli_stmt_init": "mysqli_stmt", "mysqli_stmt_get_result": "mysqli_result|false",
+	"mysqli_fetch_assoc": "array|null|false", "mysqli_fetch_array": "array|null|false", "mysqli_fetch_row": "array|null|false",
+	"mysqli_fetch_object": "object|null|false", "mysqli_real_escape_string": "string", "mysqli_num_rows": "int|string",
+	"mysql_connect": "resource|false", "mysql_pconnect": "resource|false", "mysql_query": "resource|bool",
+	"mysql_fetch_assoc": "array|false", "mysql_fetch_array": "array|false", "mysql_fetch_row": "array|false",
+	"mysql_real_escape_string": "string|false", "pg_connect": "PgSql\\Connection|false", "pg_query": "PgSql\\Result|false",
+	"pg_fetch_assoc": "array|false", "pg_escape_string": "string",
+	// Files and network
+	"fopen": "resource|false", "fsockopen": "resource|false", "popen": "resource|false", "proc_open": "resource|false",
+	"opendir": "resource|false", "tmpfile": "resource|false", "fread": "string|false", "fgets": "string|false",
+	"fwrite": "int|false", "file_get_contents": "string|false", "file_put_contents": "int|false", "file": "string[]|false",
+	"file_exists": "bool", "is_file": "bool", "is_dir": "bool", "realpath": "string|false", "basename": "string",
+	"dirname": "string", "tempnam": "string|false", "glob": "string[]|false", "scandir": "string[]|false",
+	"curl_init": "CurlHandle|false", "curl_exec": "string|bool", "curl_error": "string",
+	// Strings
+	"strlen": "int", "mb_strlen": "int", "strpos": "int|false", "stripos": "int|false", "strrpos": "int|false",
+	"substr": "string", "mb_substr": "string", "str_replace": "string|array", "str_ireplace": "string|array",
+	"sprintf": "string", "vsprintf": "string", "printf": "int", "implode": "string", "join": "string",
+	"explode": "string[]", "str_split": "string[]", "trim": "string", "ltrim": "string", "rtrim": "string",
+	"strtolower": "string", "strtoupper": "string", "ucfirst": "string", "lcfirst": "string", "str_repeat": "string",
+	"str_pad": "string", "strrev": "string", "nl2br": "string", "strip_tags": "string", "addslashes": "string",
+	"stripslashes": "string", "htmlspecialchars": "string", "htmlentities": "string", "html_entity_decode": "string",
+	"urlencode": "string", "urldecode": "string", "rawurlencode": "string", "http_build_query": "string",
+	"md5": "string", "sha1": "string", "crc32": "int", "hash": "string", "hash_hmac": "string", "password_hash": "string",
+	"password_verify": "bool", "hash_equals": "bool", "base64_encode": "string", "base64_decode": "string|false",
+	"bin2hex": "string", "hex2bin": "string|false", "serialize": "string", "unserialize": "mixed",
+	"json_encode": "string|false", "json_decode": "mixed", "preg_match": "int|false", "preg_match_all": "int|false",
+	"preg_replace": "string|array|null", "preg_split": "string[]|false", "preg_quote": "string",
+	"number_format": "string", "chr": "string", "ord": "int", "uniqid": "string", "escapeshellarg": "string",
+	"escapeshellcmd": "string", "shell_exec": "string|false|null", "exec": "string|false", "system": "string|false",
+	// Numbers, arrays and variables
+	"intval": "int", "floatval": "float", "boolval": "bool", "strval": "string", "settype": "bool", "gettype": "string",
+	"count": "int", "sizeof": "int", "time": "int", "mktime": "int|false", "strtotime": "int|false", "date": "string",
+	"microtime": "string|float", "rand": "int", "mt_rand": "int", "random_int": "int", "random_bytes": "string",
+	"abs": "int|float", "floor": "float", "ceil": "float", "round": "float", "max": "mixed", "min": "mixed",
+	"in_array": "bool", "array_key_exists": "bool", "array_search": "int|string|false", "array_keys": "array",
+	"array_values": "array", "array_merge": "array", "array_map": "array", "array_filter": "array", "array_slice": "array",
+	"array_combine": "array", "array_flip": "array", "array_unique": "array", "range": "array", "compact": "array",
+	"func_get_args": "array", "is_array": "bool", "is_string": "bool", "is_int": "bool", "is_numeric": "bool",
+	"is_object": "bool", "is_null": "bool", "isset": "bool", "empty": "bool", "is_callable": "bool",
+	"date_create": "DateTime|false", "simplexml_load_string": "SimpleXMLElement|false",
+	"simplexml_load_file": "SimpleXMLElement|false", "dom_import_simplexml": "DOMElement",
+	"get_class": "string", "spl_object_hash": "string", "filter_var": "mixed", "filter_input": "mixed",
+	// WordPress
+	"get_option": "mixed", "update_option": "bool", "add_option": "bool", "delete_option": "bool",
+	"get_post": "WP_Post|array|null", "get_posts": "WP_Post[]", "get_user_by": "WP_User|false",
+	"get_userdata": "WP_User|false", "wp_get_current_user": "WP_User", "get_current_user_id": "int",
+	"current_user_can": "bool", "is_user_logged_in": "bool", "is_admin": "bool", "wp_verify_nonce": "int|false",
+	"check_ajax_referer": "int|false", "check_admin_referer": "int|false", "wp_create_nonce": "string",
+	"wp_remote_get": "array|WP_Error", "wp_remote_post": "array|WP_Error", "wp_remote_retrieve_body": "string",
+	"is_wp_error": "bool", "sanitize_text_field": "string", "sanitize_key": "string", "sanitize_email": "string",
+	"sanitize_file_name": "string", "sanitize_title": "string", "esc_html": "string", "esc_attr": "string",
+	"esc_url": "string", "esc_url_raw": "string", "esc_js": "string", "esc_textarea": "string", "esc_sql": "string",
+	"wp_kses": "string", "wp_kses_post": "string", "absint": "int", "wp_unslash": "mixed", "get_post_meta": "mixed",
+	"get_user_meta": "mixed", "get_transient": "mixed", "set_transient": "bool", "wp_insert_post": "int|WP_Error",
+	"wp_json_encode": "string|false", "home_url": "string", "admin_url": "string", "plugins_url": "string",
+	"plugin_dir_path": "string", "get_template_directory": "string", "__": "string", "_e": "null",
+	"apply_filters": "mixed", "do_action": "null", "add_action": "true", "add_filter": "true",
+}
+
+// builtinMethods are the return types of the methods of the PHP and WordPress classes, keyed by
+// lowercase class::method
+var builtinMethods = map[string]string{
+	"pdo::query": "PDOStatement|false", "pdo::prepare": "PDOStatement|false", "pdo::exec": "int|false",
+	"pdo::quote": "string|false", "pdo::lastinsertid": "string|false", "pdo::begintransaction": "bool",
+	"pdo::commit": "bool", "pdo::rollback": "bool",
+	"pdostatement::execute": "bool", "pdostatement::fetch": "mixed", "pdostatement::fetchall": "array",
+	"pdostatement::fetchcolumn": "mixed", "pdostatement::fetchobject": "object|false", "pdostatement::rowcount": "int",
+	"pdostatement::bindparam": "bool", "pdostatement::bindvalue": "bool",
+	"mysqli::query": "mysqli_result|bool", "mysqli::prepare": "mysqli_stmt|false", "mysqli::real_query": "bool",
+	"mysqli::multi_query": "bool", "mysqli::real_escape_string": "string", "mysqli::escape_string": "string",
+	"mysqli::stmt_init": "mysqli_stmt", "mysqli::store_result": "mysqli_result|false",
+	"mysqli_stmt::execute": "bool", "mysqli_stmt::bind_param": "bool", "mysqli_stmt::get_result": "mysqli_result|false",
+	"mysqli_stmt::prepare": "bool", "mysqli_stmt::fetch": "bool|null",
+	"mysqli_result::fetch_assoc": "array|null|false", "mysqli_result::fetch_array": "array|null|false",
+	"mysqli_result::fetch_row": "array|null|false", "mysqli_result::fetch_object": "object|null|false",
+	"mysqli_result::fetch_all": "array",
+	"sqlite3::query":           "SQLite3Result|false", "sqlite3::prepare": "SQLite3Stmt|false", "sqlite3::exec": "bool",
+	"sqlite3::escapestring": "string", "sqlite3::querysingle": "mixed", "sqlite3stmt::execute": "SQLite3Result|false",
+	"sqlite3result::fetcharray": "array|false",
+	"datetime::format":          "string", "datetime::modify": "DateTime|false", "datetime::settimezone": "DateTime",
+	"datetime::gettimestamp": "int", "datetimeimmutable::format": "string", "datetimeimmutable::modify": "DateTimeImmutable|false",
+	"simplexmlelement::xpath": "SimpleXMLElement[]|null|false", "simplexmlelement::asxml": "string|bool",
+	"domdocument::loadxml": "bool", "domdocument::loadhtml": "bool", "domdocument::savexml": "string|false",
+	"domdocument::getelementbyid": "DOMElement|null", "domxpath::query": "DOMNodeList|false",
+	"ziparchive::open": "bool|int", "ziparchive::extractto": "bool",
+	"wpdb::prepare": "string", "wpdb::query": "int|bool", "wpdb::get_results": "array|object|null",
+	"wpdb::get_var": "string|null", "wpdb::get_row": "array|object|null", "wpdb::get_col": "array",
+	"wpdb::insert": "int|false", "wpdb::update": "int|false", "wpdb::delete": "int|false", "wpdb::replace": "int|false",
+	"wpdb::esc_like":       "string",
+	"wp_query::have_posts": "bool", "wp_query::get_posts": "WP_Post[]", "wp_user::has_cap": "bool",
+	"wp_error::get_error_message": "string", "wp_rest_request::get_param": "mixed", "wp_rest_request::get_params": "array",
+}
+
+// knownGlobals are the types of the global variables of WordPress
+var knownGlobals = map[string]string{
+	"wpdb":          "wpdb",
+	"wp_query":      "WP_Query",
+	"post":          "WP_Post",
+	"current_user":  "WP_User",
+	"wp_rewrite":    "WP_Rewrite",
+	"wp_filesystem": "WP_Filesystem_Base",
+}
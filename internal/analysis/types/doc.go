@@ -0,0 +1,28 @@
+package types
+
+import (
+	"github.com/28Pollux28/log6302-parser/internal/analysis/phpdoc"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// docType returns the type given by a tag of the doc comment of a node. For @param and @var, name
+// selects the variable; a @var without variable applies to any.
+func docType(n *ast.Node, tag, name string) (string, bool) {
+	doc := phpdoc.Of(n)
+	if doc == nil {
+		return "", false
+	}
+	var found *phpdoc.Tag
+	switch tag {
+	case "param":
+		found = doc.Param(name)
+	case "return":
+		found = doc.Return()
+	case "var":
+		found = doc.Var(name)
+	}
+	if found == nil || found.Type == "" {
+		return "", false
+	}
+	return found.Type, true
+}
@@ -0,0 +1,612 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the inferer of a file is stored on its program node
+const AttributeKey = "types"
+
+// maxDepth bounds the inference through variable definitions, calls and nested expressions
+const maxDepth = 32
+
+// typeKinds are the node kinds of declared types
+var typeKinds = map[string]bool{
+	"named_type": true, "primitive_type": true, "optional_type": true, "union_type": true,
+	"intersection_type": true, "disjunctive_normal_form_type": true, "bottom_type": true, "type_list": true,
+}
+
+func init() {
+	ast.HasType = func(n *ast.Node, name string) bool {
+		return inferer(n).HasType(n, name)
+	}
+}
+
+// Inferer infers the types of the expressions of a project from literals, casts, declared types,
+// doc comments and the signatures of the builtin functions. Variables are typed by the definitions
+// reaching each occurrence, narrowed by instanceof conditions.
+type Inferer struct {
+	hierarchy *classes.Hierarchy
+	// functions maps the lowercase names of the functions to their declaration
+	functions map[string]*ast.Node
+	cache     map[*ast.Node]Set
+	// inProgress holds the definitions being inferred, which reach themselves in loops
+	inProgress map[*ast.Node]bool
+	cycles     int
+	depth      int
+}
+
+func New() *Inferer {
+	return &Inferer{
+		hierarchy:  classes.New(),
+		functions:  make(map[string]*ast.Node),
+		cache:      make(map[*ast.Node]Set),
+		inProgress: make(map[*ast.Node]bool),
+	}
+}
+
+// inferer returns the inferer of the file of a node, created on first use unless the file was
+// added to a project inferer. Parent links must be set.
+func inferer(n *ast.Node) *Inferer {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	i, ok := root.GetAttribute(AttributeKey).(*Inferer)
+	if !ok {
+		i = New()
+		i.AddFile("", root)
+		i.Resolve()
+	}
+	return i
+}
+
+// TypeOf infers the types of an expression with the inferer of its file
+func TypeOf(n *ast.Node) Set {
+	return inferer(n).TypeOf(n)
+}
+
+// AddFile collects the functions and classes of a file and computes its scopes if needed
+func (i *Inferer) AddFile(file string, root *ast.Node) {
+	root.SetParents()
+	if _, ok := root.GetAttribute(scope.AttributeKey).(*scope.Scope); !ok {
+		scope.Analyze(root)
+	}
+	i.hierarchy.AddFile(file, root)
+	v := &functionVisitor{}
+	root.WalkPrefix(v)
+	for _, f := range v.functions {
+		if name := f.ChildOfKind("name"); name != nil {
+			i.functions[strings.ToLower(name.Text)] = f
+		}
+	}
+	root.SetAttribute(AttributeKey, i)
+}
+
+// Resolve links the classes of the files added to the inferer
+func (i *Inferer) Resolve() {
+	i.hierarchy.Resolve()
+}
+
+// Hierarchy returns the class hierarchy of the files added to the inferer
+func (i *Inferer) Hierarchy() *classes.Hierarchy {
+	return i.hierarchy
+}
+
+type functionVisitor struct {
+	functions []*ast.Node
+}
+
+func (v *functionVisitor) VisitNode(n *ast.Node) {
+	if n.Kind == "function_definition" {
+		v.functions = append(v.functions, n)
+	}
+}
+
+// TypeOf infers the types of an expression
+func (i *Inferer) TypeOf(n *ast.Node) Set {
+	if t, ok := i.cache[n]; ok {
+		return t
+	}
+	if i.depth > maxDepth {
+		return Set{Mixed}
+	}
+	i.depth++
+	cycles := i.cycles
+	t := i.infer(n)
+	i.depth--
+	if i.cycles == cycles {
+		i.cache[n] = t
+	}
+	return t
+}
+
+func (i *Inferer) infer(n *ast.Node) Set {
+	switch n.Kind {
+	case "string", "encapsed_string", "heredoc", "nowdoc", "shell_command_expression":
+		return Of("string")
+	case "integer":
+		return Of("int")
+	case "float":
+		return Of("float")
+	case "boolean":
+		return Of("bool")
+	case "null":
+		return Of("null")
+	case "array_creation_expression":
+		return Of("array")
+	case "anonymous_function", "arrow_function":
+		return Of("Closure")
+	case "print_intrinsic":
+		return Of("int")
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return i.TypeOf(children[0])
+		}
+	case "cast_expression":
+		if castType := n.ChildOfKind("cast_type"); castType != nil {
+			name := strings.ToLower(castType.Text)
+			switch name {
+			case "real":
+				return Of("float")
+			case "binary":
+				return Of("string")
+			case "unset":
+				return Of("null")
+			}
+			return Of(typeName(name, func(string) string { return "object" }))
+		}
+	case "object_creation_expression":
+		return i.creation(n)
+	case "clone_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return i.TypeOf(children[0])
+		}
+	case "variable_name":
+		return i.variable(n)
+	case "assignment_expression":
+		if children := n.NamedChildren(); len(children) >= 2 {
+			return i.TypeOf(children[len(children)-1])
+		}
+	case "augmented_assignment_expression":
+		if children := n.NamedChildren(); len(children) >= 2 {
+			return i.definition(children[0])
+		}
+	case "reference_assignment_expression":
+		if children := n.NamedChildren(); len(children) >= 2 {
+			return i.TypeOf(children[len(children)-1])
+		}
+	case "update_expression":
+		return Of("int", "float")
+	case "binary_expression":
+		return i.binary(n)
+	case "unary_op_expression":
+		if len(n.Descendants) > 0 && n.Descendants[0].Kind == "!" {
+			return Of("bool")
+		}
+		return Of("int", "float")
+	case "conditional_expression":
+		children := n.NamedChildren()
+		var branches []Set
+		if len(children) == 2 {
+			// Short ternary: the condition is the value of the first branch when it is truthy
+			branches = append(branches, i.TypeOf(children[0]).Without("null", "false"))
+		}
+		for _, branch := range children[1:] {
+			branches = append(branches, i.TypeOf(branch))
+		}
+		return Union(branches...)
+	case "match_expression":
+		var arms []Set
+		if block := n.ChildOfKind("match_block"); block != nil {
+			for _, arm := range block.NamedChildren() {
+				if children := arm.NamedChildren(); len(children) > 0 {
+					arms = append(arms, i.TypeOf(children[len(children)-1]))
+				}
+			}
+		}
+		return Union(arms...)
+	case "function_call_expression":
+		return i.functionCall(n)
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+		return i.methodCall(n)
+	case "member_access_expression", "nullsafe_member_access_expression", "scoped_property_access_expression":
+		return i.propertyAccess(n)
+	case "class_constant_access_expression":
+		return i.classConstant(n)
+	case "subscript_expression":
+		if children := n.NamedChildren(); len(children) > 0 {
+			base := i.TypeOf(children[0])
+			if len(base) == 1 && base[0] == "string" {
+				return base
+			}
+			return base.Elements()
+		}
+	case "name", "qualified_name":
+		switch strings.ToLower(n.Text) {
+		case "php_eol", "directory_separator", "__file__", "__dir__", "__class__", "__function__", "__method__", "__namespace__":
+			return Of("string")
+		case "__line__", "php_int_max", "php_int_size", "e_all":
+			return Of("int")
+		}
+	}
+	return Set{Mixed}
+}
+
+func (i *Inferer) binary(n *ast.Node) Set {
+	children := n.Descendants
+	if len(children) != 3 {
+		return Set{Mixed}
+	}
+	switch children[1].Kind {
+	case ".":
+		return Of("string")
+	case "??":
+		return Union(i.TypeOf(children[0]).Without("null"), i.TypeOf(children[2]))
+	case "+", "-", "*", "**":
+		left, right := i.TypeOf(children[0]), i.TypeOf(children[2])
+		if left.String() == "int" && right.String() == "int" {
+			return Of("int")
+		}
+		if left.String() == "array" && right.String() == "array" && children[1].Kind == "+" {
+			return Of("array")
+		}
+		return Of("int", "float")
+	case "/":
+		return Of("int", "float")
+	case "%", "<<", ">>", "&", "|", "^", "<=>":
+		return Of("int")
+	}
+	// Comparisons, logical operators and instanceof
+	return Of("bool")
+}
+
+// creation returns the class of a new expression
+func (i *Inferer) creation(n *ast.Node) Set {
+	children := n.NamedChildren()
+	if len(children) == 0 {
+		return Of("object")
+	}
+	switch children[0].Kind {
+	case "name", "qualified_name":
+		return Of(i.className(n, children[0].Text))
+	case "anonymous_class":
+		return Of("object")
+	}
+	return Of("object")
+}
+
+// className resolves a class name written in the context of a node: self, static and parent denote
+// the enclosing class and its parent, other names are resolved against the namespace and imports
+func (i *Inferer) className(context *ast.Node, name string) string {
+	enclosing := i.hierarchy.EnclosingClass(context)
+	switch strings.ToLower(name) {
+	case "self", "static", "$this":
+		if enclosing != nil {
+			return enclosing.Name
+		}
+		return "object"
+	case "parent":
+		if enclosing != nil && enclosing.Parent != nil {
+			return enclosing.Parent.Name
+		}
+		if enclosing != nil && len(enclosing.Extends) > 0 {
+			return enclosing.Extends[0]
+		}
+		return "object"
+	}
+	qualified := strings.TrimPrefix(name, "\\")
+	if ns := i.hierarchy.Namespace(context); ns != nil {
+		qualified = ns.Resolve(name)
+	}
+	if c := i.hierarchy.Lookup(qualified); c != nil {
+		return c.Name
+	}
+	if c := i.hierarchy.Lookup(name); c != nil {
+		return c.Name
+	}
+	return qualified
+}
+
+// declared returns the types of a declared type node
+func (i *Inferer) declared(n *ast.Node) Set {
+	switch n.Kind {
+	case "primitive_type", "bottom_type":
+		return Of(typeName(n.Text, func(string) string { return "object" }))
+	case "named_type":
+		return Of(i.className(n, n.Text))
+	case "optional_type":
+		result := Set{"null"}
+		for _, child := range n.NamedChildren() {
+			result = Union(result, i.declared(child))
+		}
+		return result
+	}
+	var result []Set
+	for _, child := range n.NamedChildren() {
+		if typeKinds[child.Kind] {
+			result = append(result, i.declared(child))
+		}
+	}
+	if len(result) == 0 {
+		return Set{Mixed}
+	}
+	return Union(result...)
+}
+
+// typeChild returns the declared type among the children of a node, or nil
+func typeChild(n *ast.Node) *ast.Node {
+	for _, child := range n.NamedChildren() {
+		if typeKinds[child.Kind] {
+			return child
+		}
+	}
+	return nil
+}
+
+// doc parses a doc comment type written in the context of a node
+func (i *Inferer) doc(context *ast.Node, text string) Set {
+	return parseDoc(text, func(name string) string {
+		return i.className(context, name)
+	})
+}
+
+// builtin parses the type of a builtin signature, whose class names are fully qualified
+func (i *Inferer) builtin(text string) Set {
+	return parseDoc(text, func(name string) string {
+		if c := i.hierarchy.Lookup(name); c != nil {
+			return c.Name
+		}
+		return name
+	})
+}
+
+// returnType returns the declared or documented return type of a function or method
+func (i *Inferer) returnType(f *ast.Node) Set {
+	var declared *ast.Node
+	afterParameters := false
+	for _, child := range f.NamedChildren() {
+		if child.Kind == "formal_parameters" {
+			afterParameters = true
+		} else if afterParameters && typeKinds[child.Kind] {
+			declared = child
+		}
+	}
+	var t Set
+	if declared != nil {
+		t = i.declared(declared)
+	}
+	if text, ok := docType(f, "return", ""); ok && vague(t) {
+		return i.doc(f, text)
+	}
+	if t == nil {
+		return Set{Mixed}
+	}
+	return t
+}
+
+// vague reports whether a declared type is absent or less precise than a doc comment can be, as
+// array for Foo[]
+func vague(declared Set) bool {
+	switch declared.String() {
+	case "", Mixed, "array", "iterable", "object", "array|null", "iterable|null", "null|object":
+		return true
+	}
+	return false
+}
+
+// parameterType returns the declared or documented type of a parameter
+func (i *Inferer) parameterType(parameter *ast.Node) Set {
+	var t Set
+	if declared := typeChild(parameter); declared != nil {
+		t = i.declared(declared)
+	}
+	if f := parameter.Parent; vague(t) && f != nil && f.Parent != nil {
+		name := ""
+		if variable := parameter.ChildOfKind("variable_name"); variable != nil {
+			name = scope.VariableName(variable)
+		}
+		if text, ok := docType(f.Parent, "param", name); ok {
+			t = i.doc(parameter, text)
+		}
+	}
+	if t == nil {
+		t = Set{Mixed}
+	}
+	if parameter.Kind == "variadic_parameter" && !t.IsMixed() {
+		var arrays Set
+		for _, name := range t {
+			arrays = append(arrays, name+"[]")
+		}
+		return Union(arrays)
+	}
+	if value := parameter.ChildOfKind("null"); value != nil {
+		t = Union(t, Of("null"))
+	}
+	return t
+}
+
+// variable infers the type of a variable occurrence from the definitions reaching it
+func (i *Inferer) variable(n *ast.Node) Set {
+	name := scope.VariableName(n)
+	if name == "this" {
+		if c := i.hierarchy.EnclosingClass(n); c != nil {
+			return Of(c.Name)
+		}
+		return Of("object")
+	}
+	if narrowed, ok := i.narrowed(n); ok {
+		return narrowed
+	}
+	defs, ok := scope.DefsOf(n)
+	if !ok {
+		// Occurrences that only define the variable
+		return Union(i.definition(n))
+	}
+	if len(defs) == 0 {
+		if t, known := knownGlobals[name]; known {
+			return i.builtin(t)
+		}
+		return Set{Mixed}
+	}
+	var result []Set
+	for _, def := range defs {
+		result = append(result, i.definition(def))
+	}
+	return Union(result...)
+}
+
+// narrowed returns the class of an occurrence in the body of an if statement testing the variable
+// with instanceof, when no definition of the variable inside the statement reaches it
+func (i *Inferer) narrowed(n *ast.Node) (Set, bool) {
+	for cur := n; cur.Parent != nil; cur = cur.Parent {
+		statement := cur.Parent
+		if statement.Kind != "if_statement" || cur.Kind != "compound_statement" {
+			continue
+		}
+		condition := statement.ChildOfKind("parenthesized_expression")
+		if condition == nil || len(condition.NamedChildren()) != 1 {
+			continue
+		}
+		test := condition.NamedChildren()[0]
+		if test.Kind != "binary_expression" || len(test.Descendants) != 3 || test.Descendants[1].Kind != "instanceof" {
+			continue
+		}
+		subject, class := test.Descendants[0], test.Descendants[2]
+		if subject.Kind != "variable_name" || subject.Text != n.Text {
+			continue
+		}
+		if class.Kind != "name" && class.Kind != "qualified_name" {
+			continue
+		}
+		defs, _ := scope.DefsOf(n)
+		for _, def := range defs {
+			if def.StartByte > statement.StartByte {
+				return nil, false
+			}
+		}
+		return Of(i.className(n, class.Text)), true
+	}
+	return nil, false
+}
+
+// definition infers the type written by a definition
+func (i *Inferer) definition(def *ast.Node) Set {
+	if i.inProgress[def] {
+		i.cycles++
+		return nil
+	}
+	if t, ok := i.cache[def]; ok {
+		return t
+	}
+	i.inProgress[def] = true
+	cycles := i.cycles
+	t := i.assigned(def)
+	delete(i.inProgress, def)
+	if i.cycles == cycles {
+		i.cache[def] = t
+	}
+	return t
+}
+
+func (i *Inferer) assigned(def *ast.Node) Set {
+	parent := def.Parent
+	if parent == nil {
+		return Set{Mixed}
+	}
+	if parent.Kind == "by_ref" {
+		parent = parent.Parent
+	}
+	switch parent.Kind {
+	case "assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) < 2 || children[0] != def {
+			return Set{Mixed}
+		}
+		if annotated, ok := i.annotation(parent, scope.VariableName(def)); ok {
+			return annotated
+		}
+		return i.TypeOf(children[len(children)-1])
+	case "augmented_assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) < 2 || len(parent.Descendants) < 2 {
+			return Set{Mixed}
+		}
+		switch parent.Descendants[1].Kind {
+		case ".=":
+			return Of("string")
+		case "??=":
+			return Union(i.variable(def).Without("null"), i.TypeOf(children[len(children)-1]))
+		}
+		return Of("int", "float")
+	case "update_expression":
+		return Of("int", "float")
+	case "simple_parameter", "variadic_parameter", "property_promotion_parameter":
+		return i.parameterType(parent)
+	case "catch_clause":
+		if list := parent.ChildOfKind("type_list"); list != nil {
+			return i.declared(list)
+		}
+	case "global_declaration":
+		if t, ok := knownGlobals[scope.VariableName(def)]; ok {
+			return i.builtin(t)
+		}
+	case "anonymous_function_use_clause":
+		// Captured by value or by reference: the enclosing variable when the closure is created
+		return i.variable(def)
+	case "foreach_statement", "pair":
+		return i.foreachBinding(def, parent)
+	}
+	// Static declarations, list() destructuring and references
+	return Set{Mixed}
+}
+
+// annotation returns the type of an inline @var annotation on the statement of an assignment
+func (i *Inferer) annotation(assignment *ast.Node, name string) (Set, bool) {
+	statement := assignment.Parent
+	if statement == nil || statement.Kind != "expression_statement" {
+		return nil, false
+	}
+	if text, ok := docType(statement, "var", name); ok {
+		return i.doc(statement, text), true
+	}
+	return nil, false
+}
+
+// foreachBinding infers the type of the key or of the value bound by a foreach statement
+func (i *Inferer) foreachBinding(def, parent *ast.Node) Set {
+	statement := parent
+	if parent.Kind == "pair" {
+		statement = parent.Parent
+		if children := parent.NamedChildren(); len(children) > 0 && children[0] == def {
+			return Of("int", "string")
+		}
+	}
+	if statement == nil || statement.Kind != "foreach_statement" {
+		return Set{Mixed}
+	}
+	if children := statement.NamedChildren(); len(children) > 0 {
+		return i.TypeOf(children[0]).Elements()
+	}
+	return Set{Mixed}
+}
+
+// functionCall infers the return type of a function call
+func (i *Inferer) functionCall(n *ast.Node) Set {
+	nameNode := n.ChildOfKind("name", "qualified_name")
+	if nameNode == nil {
+		return Set{Mixed}
+	}
+	name := strings.ToLower(nameNode.Text[strings.LastIndex(nameNode.Text, "\\")+1:])
+	if f, ok := i.functions[name]; ok {
+		return i.returnType(f)
+	}
+	if t, ok := builtinFunctions[name]; ok {
+		return i.builtin(t)
+	}
+	return Set{Mixed}
+}
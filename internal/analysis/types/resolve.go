@@ -0,0 +1,245 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// memberName returns the name of the method or property accessed by a node, the variable name of
+// static properties included
+func memberName(n *ast.Node) string {
+	if n.Kind == "scoped_property_access_expression" {
+		if children := n.NamedChildren(); len(children) == 2 && children[1].Kind == "variable_name" {
+			return scope.VariableName(children[1])
+		}
+	}
+	return n.MemberName()
+}
+
+// receiver infers the types of the object or class a member is accessed on
+func (i *Inferer) receiver(n *ast.Node) Set {
+	children := n.NamedChildren()
+	if len(children) == 0 {
+		return Set{Mixed}
+	}
+	object := children[0]
+	if strings.HasPrefix(n.Kind, "scoped_") && (object.Kind == "name" || object.Kind == "qualified_name" || object.Kind == "relative_scope") {
+		return Of(i.className(n, object.Text))
+	}
+	return i.TypeOf(object)
+}
+
+// ancestors returns the names of a class and of its ancestors, ending with the first class
+// extended outside of the project, which may be a builtin class
+func (i *Inferer) ancestors(name string) []string {
+	c := i.hierarchy.Lookup(name)
+	if c == nil {
+		return []string{name}
+	}
+	var result []string
+	seen := make(map[*classes.Class]bool)
+	for ; c != nil && !seen[c]; c = c.Parent {
+		seen[c] = true
+		result = append(result, c.Name)
+		if c.Parent == nil {
+			result = append(result, c.Extends...)
+			result = append(result, c.Implements...)
+		}
+	}
+	return result
+}
+
+// methodCall infers the return type of a method call from the methods it may dispatch to
+func (i *Inferer) methodCall(n *ast.Node) Set {
+	name := memberName(n)
+	receiver := i.receiver(n)
+	if name == "" || receiver.IsMixed() {
+		return Set{Mixed}
+	}
+	var result []Set
+	for _, class := range receiver.Classes() {
+		result = append(result, i.methodReturn(class, name))
+	}
+	if len(result) == 0 {
+		return Set{Mixed}
+	}
+	if n.Kind == "nullsafe_member_call_expression" {
+		result = append(result, Of("null"))
+	}
+	return Union(result...)
+}
+
+// methodReturn returns the return type of a method of a class, declared in the project or builtin
+func (i *Inferer) methodReturn(class, method string) Set {
+	if c := i.hierarchy.Lookup(class); c != nil {
+		if m := c.Method(method); m != nil {
+			t := i.returnType(m.Declaration.Node)
+			if declared := m.Declaration.Class.Name; declared != c.Name && fluent(m.Declaration.Node) {
+				// static and $this denote the class of the receiver
+				t = Union(t.Without(declared), Of(c.Name))
+			}
+			return t
+		}
+	}
+	for _, ancestor := range i.ancestors(class) {
+		if t, ok := builtinMethods[strings.ToLower(ancestor+"::"+method)]; ok {
+			return i.builtin(t)
+		}
+	}
+	return Set{Mixed}
+}
+
+// fluent reports whether a method is declared or documented to return static or $this
+func fluent(method *ast.Node) bool {
+	if declared := typeChild(method); declared != nil && strings.EqualFold(declared.Text, "static") {
+		return true
+	}
+	text, _ := docType(method, "return", "")
+	return text == "static" || text == "$this"
+}
+
+// propertyAccess infers the type of a property from its declaration or its @var doc comment
+func (i *Inferer) propertyAccess(n *ast.Node) Set {
+	name := memberName(n)
+	receiver := i.receiver(n)
+	if name == "" || receiver.IsMixed() {
+		return Set{Mixed}
+	}
+	var result []Set
+	for _, class := range receiver.Classes() {
+		c := i.hierarchy.Lookup(class)
+		if c == nil {
+			return Set{Mixed}
+		}
+		p, ok := c.EffectiveProps[name]
+		if !ok {
+			return Set{Mixed}
+		}
+		result = append(result, i.propertyType(p.Declaration.Node))
+	}
+	if len(result) == 0 {
+		return Set{Mixed}
+	}
+	if n.Kind == "nullsafe_member_access_expression" {
+		result = append(result, Of("null"))
+	}
+	return Union(result...)
+}
+
+// propertyType returns the type of a property element or of a promoted constructor parameter
+func (i *Inferer) propertyType(n *ast.Node) Set {
+	if n.Kind == "property_promotion_parameter" {
+		return i.parameterType(n)
+	}
+	declaration := n.Parent
+	if declaration == nil {
+		return Set{Mixed}
+	}
+	var t Set
+	if declared := typeChild(declaration); declared != nil {
+		t = i.declared(declared)
+	}
+	name := ""
+	if variable := n.ChildOfKind("variable_name"); variable != nil {
+		name = scope.VariableName(variable)
+	}
+	if text, ok := docType(declaration, "var", name); ok && vague(t) {
+		return i.doc(declaration, text)
+	}
+	if t == nil {
+		return Set{Mixed}
+	}
+	return t
+}
+
+// classConstant infers the type of a class constant, an enum case being an instance of its enum
+func (i *Inferer) classConstant(n *ast.Node) Set {
+	name := memberName(n)
+	if strings.EqualFold(name, "class") {
+		return Of("string")
+	}
+	for _, class := range i.receiver(n).Classes() {
+		c := i.hierarchy.Lookup(class)
+		for ; c != nil; c = c.Parent {
+			declaration, ok := c.Constants[name]
+			if !ok {
+				continue
+			}
+			if declaration.Kind == "enum_case" {
+				return Of(c.Name)
+			}
+			if children := declaration.NamedChildren(); len(children) == 2 {
+				return i.TypeOf(children[1])
+			}
+		}
+	}
+	return Set{Mixed}
+}
+
+// ResolveCall returns the methods a method call may dispatch to according to the inferred type of
+// its receiver: the method of each class, and the methods overriding it in the subclasses
+func (i *Inferer) ResolveCall(call *ast.Node) []*classes.Method {
+	switch call.Kind {
+	case "member_call_expression", "nullsafe_member_call_expression", "scoped_call_expression":
+	default:
+		return nil
+	}
+	name := memberName(call)
+	if name == "" {
+		return nil
+	}
+	var result []*classes.Method
+	seen := make(map[*classes.Method]bool)
+	add := func(m *classes.Member[*classes.Method]) {
+		if m != nil && !seen[m.Declaration] {
+			seen[m.Declaration] = true
+			result = append(result, m.Declaration)
+		}
+	}
+	parentCall := call.Kind == "scoped_call_expression" && len(call.NamedChildren()) > 0 &&
+		strings.EqualFold(call.NamedChildren()[0].Text, "parent")
+	for _, class := range i.receiver(call).Classes() {
+		c := i.hierarchy.Lookup(class)
+		if c == nil {
+			continue
+		}
+		add(c.Method(name))
+		if parentCall {
+			continue
+		}
+		for _, sub := range c.Descendants() {
+			if m := sub.Method(name); m != nil && m.Origin != "inherited" {
+				add(m)
+			}
+		}
+	}
+	return result
+}
+
+// HasType reports whether an expression may have a type: one of its inferred types is the type or
+// a subclass of it. array matches the typed arrays and bool matches true and false.
+func (i *Inferer) HasType(n *ast.Node, name string) bool {
+	name = strings.TrimPrefix(name, "\\")
+	for _, t := range i.TypeOf(n) {
+		switch {
+		case strings.EqualFold(t, name):
+			return true
+		case strings.EqualFold(name, "array") && strings.HasSuffix(t, "[]"):
+			return true
+		case strings.EqualFold(name, "bool") && (t == "true" || t == "false"):
+			return true
+		}
+		for _, ancestor := range i.ancestors(t) {
+			if strings.EqualFold(ancestor, name) {
+				return true
+			}
+		}
+		if c, target := i.hierarchy.Lookup(t), i.hierarchy.Lookup(name); c != nil && target != nil && c.IsSubclassOf(target) {
+			return true
+		}
+	}
+	return false
+}
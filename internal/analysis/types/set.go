@@ -0,0 +1,183 @@
+package types
+
+import (
+	"sort"
+	"strings"
+)
+
+// Mixed is the type of the expressions nothing is known about
+const Mixed = "mixed"
+
+// Set is the set of the types an expression may have: scalar types, array, null, class names and
+// arrays of a type written T[]. It is sorted and never empty except for unreachable code.
+type Set []string
+
+// scalars are the builtin type names, which are written in lowercase
+var scalars = map[string]string{
+	"int": "int", "integer": "int", "float": "float", "double": "float", "string": "string",
+	"bool": "bool", "boolean": "bool", "true": "true", "false": "false", "array": "array",
+	"null": "null", "void": "null", "callable": "callable", "iterable": "iterable",
+	"object": "object", "mixed": Mixed, "resource": "resource", "never": "never",
+}
+
+// Of returns the set of the given types
+func Of(names ...string) Set {
+	return Union(Set(names))
+}
+
+// Union returns the union of sets. It is mixed as soon as one of them is.
+func Union(sets ...Set) Set {
+	seen := make(map[string]bool)
+	var result Set
+	for _, s := range sets {
+		for _, name := range s {
+			if name == Mixed {
+				return Set{Mixed}
+			}
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// IsMixed reports whether nothing is known about the type
+func (s Set) IsMixed() bool {
+	return len(s) == 1 && s[0] == Mixed
+}
+
+// Without returns the set without some types, such as null after a null coalescing operator
+func (s Set) Without(names ...string) Set {
+	if s.IsMixed() {
+		return s
+	}
+	var result Set
+	for _, name := range s {
+		removed := false
+		for _, other := range names {
+			removed = removed || strings.EqualFold(name, other)
+		}
+		if !removed {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Classes returns the class names of the set
+func (s Set) Classes() []string {
+	var result []string
+	for _, name := range s {
+		if _, scalar := scalars[strings.ToLower(name)]; !scalar && !strings.HasSuffix(name, "[]") {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Elements returns the types of the elements of the arrays of the set
+func (s Set) Elements() Set {
+	var result Set
+	for _, name := range s {
+		if element, ok := strings.CutSuffix(name, "[]"); ok {
+			result = append(result, element)
+		} else {
+			return Set{Mixed}
+		}
+	}
+	return Union(result)
+}
+
+func (s Set) String() string {
+	return strings.Join(s, "|")
+}
+
+// parseDoc parses a PHPDoc type such as ?int, Foo|null, Foo[] or array<int, Foo>. Class names are
+// resolved with resolve.
+func parseDoc(text string, resolve func(string) string) Set {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Set{Mixed}
+	}
+	var result Set
+	if nullable, ok := strings.CutPrefix(text, "?"); ok {
+		result = append(result, "null")
+		text = nullable
+	}
+	for _, part := range splitTopLevel(strings.Trim(text, "()"), '|') {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case strings.HasSuffix(part, "[]"):
+			element := parseDoc(strings.TrimSuffix(part, "[]"), resolve)
+			for _, name := range element {
+				result = append(result, name+"[]")
+			}
+		case strings.Contains(part, "<"):
+			base := strings.ToLower(part[:strings.Index(part, "<")])
+			arguments := splitTopLevel(part[strings.Index(part, "<")+1:strings.LastIndex(part, ">")], ',')
+			if (base == "array" || base == "list" || base == "iterable") && len(arguments) > 0 {
+				for _, name := range parseDoc(arguments[len(arguments)-1], resolve) {
+					result = append(result, name+"[]")
+				}
+			} else {
+				result = append(result, parseDoc(part[:strings.Index(part, "<")], resolve)...)
+			}
+		case strings.Contains(part, "&"):
+			for _, name := range strings.Split(part, "&") {
+				result = append(result, parseDoc(name, resolve)...)
+			}
+		default:
+			result = append(result, typeName(part, resolve))
+		}
+	}
+	if len(result) == 0 {
+		return Set{Mixed}
+	}
+	return Union(result)
+}
+
+// typeName normalizes a scalar type name and resolves a class name
+func typeName(name string, resolve func(string) string) string {
+	lower := strings.ToLower(name)
+	if scalar, ok := scalars[lower]; ok {
+		return scalar
+	}
+	switch lower {
+	case "list", "non-empty-array":
+		return "array"
+	case "positive-int", "negative-int", "non-negative-int", "class-string", "non-empty-string", "numeric-string":
+		if strings.HasSuffix(lower, "int") {
+			return "int"
+		}
+		return "string"
+	case "scalar":
+		return Mixed
+	case "static", "self", "$this", "parent":
+		return resolve(lower)
+	}
+	return resolve(name)
+}
+
+// splitTopLevel splits on a separator outside angle brackets and parentheses
+func splitTopLevel(s string, separator byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<', '(', '{':
+			depth++
+		case '>', ')', '}':
+			depth--
+		case separator:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
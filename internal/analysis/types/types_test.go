@@ -0,0 +1,141 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// project parses a source as the only file of an inferer
+func project(source string) (*Inferer, *ast.Node) {
+	root := ast.ParseSource([]byte(source))
+	i := New()
+	i.AddFile("test.php", root)
+	i.Resolve()
+	return i, root
+}
+
+// nodesOf returns the nodes of a kind of a tree, in source order
+func nodesOf(root *ast.Node, kind string) []*ast.Node {
+	v := &ast.VisitorKinds{Kinds: map[string]bool{kind: true}}
+	root.WalkPrefix(v)
+	return v.Nodes
+}
+
+func TestTypeOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "literal",
+			source: "<?php\n$a = 'x';\necho $a;",
+			want:   "string",
+		},
+		{
+			name:   "new expression",
+			source: "<?php\nclass Foo {}\n$a = new Foo();\necho $a;",
+			want:   "Foo",
+		},
+		{
+			name:   "declared parameter type",
+			source: "<?php\nclass Foo {}\nfunction f(?Foo $a) {\necho $a;\n}",
+			want:   "Foo|null",
+		},
+		{
+			name:   "phpdoc parameter type",
+			source: "<?php\nclass Foo {}\n/**\n * @param Foo $a\n */\nfunction f($a) {\necho $a;\n}",
+			want:   "Foo",
+		},
+		{
+			name:   "inline var annotation",
+			source: "<?php\nclass Foo {}\n/** @var Foo $a */\n$a = get();\necho $a;",
+			want:   "Foo",
+		},
+		{
+			name:   "declared return type of a function",
+			source: "<?php\nfunction f(): int {\nreturn 1;\n}\n$a = f();\necho $a;",
+			want:   "int",
+		},
+		{
+			name:   "branches join their types",
+			source: "<?php\nif ($c) {\n$a = 1;\n} else {\n$a = 'x';\n}\necho $a;",
+			want:   "int|string",
+		},
+		{
+			name:   "instanceof narrows the type",
+			source: "<?php\nclass A {}\nclass B {}\nfunction f(A|B $a) {\nif ($a instanceof A) {\necho $a;\n}\n}",
+			want:   "A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, root := project(tt.source)
+			echoes := nodesOf(root, "echo_statement")
+			if len(echoes) == 0 {
+				t.Fatal("no echo statement")
+			}
+			got := i.TypeOf(echoes[len(echoes)-1].NamedChildren()[0])
+			slices.Sort(got)
+			if got.String() != tt.want {
+				t.Errorf("TypeOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCall(t *testing.T) {
+	i, root := project(`<?php
+class A { function m() {} }
+class B extends A { function m() {} }
+class C extends B {}
+class D { function m() {} }
+function f(A $a) { $a->m(); }
+`)
+	calls := nodesOf(root, "member_call_expression")
+	var got []string
+	for _, m := range i.ResolveCall(calls[0]) {
+		got = append(got, m.Class.Name+"::"+m.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"A::m", "B::m"}; !slices.Equal(got, want) {
+		t.Errorf("ResolveCall() = %v, want %v", got, want)
+	}
+}
+
+func TestKindTreeType(t *testing.T) {
+	var tree ast.KindTree
+	err := json.Unmarshal([]byte(`{
+		"kind": "member_call_expression",
+		"children": [
+			{"kind": "variable_name", "attributes": {"type": "mysqli"}},
+			{"kind": "name", "attributes": {"text": "query"}}
+		]
+	}`), &tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		{name: "declared type", source: "<?php\nfunction f(mysqli $m) { $m->query('x'); }", want: true},
+		{name: "subclass", source: "<?php\nclass Db extends mysqli {}\nfunction f(Db $m) { $m->query('x'); }", want: true},
+		{name: "builtin return type", source: "<?php\n$m = mysqli_connect();\n$m->query('x');", want: true},
+		{name: "other type", source: "<?php\nfunction f(PDO $m) { $m->query('x'); }", want: false},
+		{name: "unknown type", source: "<?php\n$m->query('x');", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, root := project(tt.source)
+			calls := nodesOf(root, "member_call_expression")
+			if got := tree.Match(calls[0]); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package values
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Charset is a set of bytes
+type Charset [4]uint64
+
+func (c Charset) Has(b byte) bool {
+	return c[b/64]&(1<<(b%64)) != 0
+}
+
+func (c *Charset) Add(b byte) {
+	c[b/64] |= 1 << (b % 64)
+}
+
+func (c Charset) Union(other Charset) Charset {
+	for i := range c {
+		c[i] |= other[i]
+	}
+	return c
+}
+
+func (c Charset) Minus(other Charset) Charset {
+	for i := range c {
+		c[i] &^= other[i]
+	}
+	return c
+}
+
+func (c Charset) Intersects(other Charset) bool {
+	for i := range c {
+		if c[i]&other[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Charset) IsEmpty() bool {
+	return c == Charset{}
+}
+
+func (c Charset) IsFull() bool {
+	return c == AllChars
+}
+
+func (c Charset) Len() int {
+	n := 0
+	for _, word := range c {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// CharsOf returns the set of the bytes of a string
+func CharsOf(s string) Charset {
+	var c Charset
+	for i := 0; i < len(s); i++ {
+		c.Add(s[i])
+	}
+	return c
+}
+
+// charRange returns the set of the bytes between two bytes, included
+func charRange(from, to byte) Charset {
+	var c Charset
+	for b := int(from); b <= int(to); b++ {
+		c.Add(byte(b))
+	}
+	return c
+}
+
+var (
+	AllChars    = Charset{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}
+	Digits      = charRange('0', '9')
+	Letters     = charRange('a', 'z').Union(charRange('A', 'Z'))
+	HexDigits   = Digits.Union(charRange('a', 'f')).Union(charRange('A', 'F'))
+	IntChars    = Digits.Union(CharsOf("-"))
+	FloatChars  = IntChars.Union(CharsOf(".eE+INFA"))
+	Base64Chars = Letters.Union(Digits).Union(CharsOf("+/="))
+	URLChars    = Letters.Union(Digits).Union(CharsOf("%-._~+"))
+	// HTMLSpecialChars are the characters htmlspecialchars with ENT_QUOTES encodes
+	HTMLSpecialChars = CharsOf("<>\"'")
+	Quotes           = CharsOf("'\"`")
+)
+
+// String renders the set as a regular expression character class
+func (c Charset) String() string {
+	switch {
+	case c.IsFull():
+		return "."
+	case c.IsEmpty():
+		return "[]"
+	}
+	negate := c.Len() > 128
+	set := c
+	if negate {
+		set = AllChars.Minus(c)
+	}
+	var sb strings.Builder
+	sb.WriteString("[")
+	if negate {
+		sb.WriteString("^")
+	}
+	for b := 0; b < 256; b++ {
+		if !set.Has(byte(b)) {
+			continue
+		}
+		end := b
+		for end+1 < 256 && set.Has(byte(end+1)) {
+			end++
+		}
+		sb.WriteString(classChar(byte(b)))
+		if end > b+1 {
+			sb.WriteString("-")
+		}
+		if end > b {
+			sb.WriteString(classChar(byte(end)))
+		}
+		b = end
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func classChar(b byte) string {
+	switch {
+	case b == '\\' || b == ']' || b == '[' || b == '^' || b == '-':
+		return `\` + string(b)
+	case b < 0x20 || b >= 0x7f:
+		return fmt.Sprintf(`\x%02x`, b)
+	}
+	return string(b)
+}
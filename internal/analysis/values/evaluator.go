@@ -0,0 +1,525 @@
+package values
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// AttributeKey is the node attribute under which the evaluator of a file is stored on its program node
+const AttributeKey = "values"
+
+// maxDepth bounds the evaluation through variable definitions, constants and nested expressions
+const maxDepth = 32
+
+func init() {
+	ast.StringValueOf = func(n *ast.Node) ast.StringValue {
+		return Of(n)
+	}
+}
+
+// Evaluator approximates the string values of the expressions of a project. Variables are
+// evaluated through the definitions reaching them, constants through their define() call or
+// const declaration and class constants through the class hierarchy.
+type Evaluator struct {
+	hierarchy *classes.Hierarchy
+	constants map[string]*ast.Node
+	cache     map[*ast.Node]*Value
+	// loops holds the value assumed for the definitions being evaluated, which reach themselves in loops
+	loops map[*ast.Node]*Value
+	// cycles counts the evaluations that reached a definition being evaluated; their results are not cached
+	cycles int
+	depth  int
+}
+
+func New() *Evaluator {
+	return &Evaluator{
+		hierarchy: classes.New(),
+		constants: make(map[string]*ast.Node),
+		cache:     make(map[*ast.Node]*Value),
+		loops:     make(map[*ast.Node]*Value),
+	}
+}
+
+// Of approximates the string values of an expression. The evaluator of the file is created on
+// first use unless the file was added to a project evaluator. Parent links must be set.
+func Of(n *ast.Node) *Value {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	e, ok := root.GetAttribute(AttributeKey).(*Evaluator)
+	if !ok {
+		e = New()
+		e.AddFile("", root)
+		e.Resolve()
+	}
+	return e.Eval(n)
+}
+
+// AddFile collects the constants and classes of a file and computes its scopes if needed
+func (e *Evaluator) AddFile(file string, root *ast.Node) {
+	root.SetParents()
+	if _, ok := root.GetAttribute(scope.AttributeKey).(*scope.Scope); !ok {
+		scope.Analyze(root)
+	}
+	e.hierarchy.AddFile(file, root)
+	v := &constantVisitor{}
+	root.WalkPrefix(v)
+	for _, n := range v.constants {
+		e.addConstant(n)
+	}
+	root.SetAttribute(AttributeKey, e)
+}
+
+// Resolve links the classes of the files added to the evaluator
+func (e *Evaluator) Resolve() {
+	e.hierarchy.Resolve()
+}
+
+type constantVisitor struct {
+	constants []*ast.Node
+}
+
+func (v *constantVisitor) VisitNode(n *ast.Node) {
+	switch n.Kind {
+	case "const_element":
+		// Class constants are looked up through the hierarchy
+		if declaration := n.Parent; declaration != nil && declaration.Parent != nil && declaration.Parent.Kind != "declaration_list" {
+			v.constants = append(v.constants, n)
+		}
+	case "function_call_expression":
+		if name := n.ChildOfKind("name"); name != nil && strings.EqualFold(name.Text, "define") {
+			v.constants = append(v.constants, n)
+		}
+	}
+}
+
+func (e *Evaluator) addConstant(n *ast.Node) {
+	if n.Kind == "const_element" {
+		children := n.NamedChildren()
+		if len(children) == 2 && children[0].Kind == "name" {
+			e.constants[children[0].Text] = children[1]
+		}
+		return
+	}
+	arguments := n.Arguments()
+	if len(arguments) < 2 {
+		return
+	}
+	if name, ok := literal.String(arguments[0]); ok {
+		e.constants[strings.TrimPrefix(name, "\\")] = arguments[1]
+	}
+}
+
+// Eval approximates the string values of an expression, as PHP converts them to strings
+func (e *Evaluator) Eval(n *ast.Node) *Value {
+	if v, ok := e.cache[n]; ok {
+		return v
+	}
+	if e.depth > maxDepth {
+		return Top()
+	}
+	e.depth++
+	cycles := e.cycles
+	v := e.eval(n)
+	e.depth--
+	if e.cycles == cycles {
+		e.cache[n] = v
+	}
+	return v
+}
+
+func (e *Evaluator) eval(n *ast.Node) *Value {
+	switch n.Kind {
+	case "string", "encapsed_string", "heredoc", "nowdoc":
+		return e.stringLiteral(n)
+	case "integer":
+		if i, err := strconv.ParseInt(strings.ReplaceAll(n.Text, "_", ""), 0, 64); err == nil {
+			return Constant(strconv.FormatInt(i, 10))
+		}
+		return Any(IntChars)
+	case "float":
+		return float(n.Text)
+	case "boolean":
+		if strings.EqualFold(n.Text, "true") {
+			return Constant("1")
+		}
+		return Constant("")
+	case "null":
+		return Constant("")
+	case "parenthesized_expression":
+		if children := n.NamedChildren(); len(children) == 1 {
+			return e.Eval(children[0])
+		}
+	case "binary_expression":
+		return e.binary(n)
+	case "unary_op_expression":
+		if len(n.Descendants) > 0 && n.Descendants[0].Kind == "!" {
+			return booleans()
+		}
+		return Any(FloatChars)
+	case "conditional_expression":
+		children := n.NamedChildren()
+		var branches []*Value
+		if len(children) == 2 {
+			// Short ternary: the condition is the value of the first branch
+			branches = append(branches, e.Eval(children[0]))
+		}
+		for _, branch := range children[1:] {
+			branches = append(branches, e.Eval(branch))
+		}
+		return Join(branches...)
+	case "match_expression":
+		return e.match(n)
+	case "assignment_expression":
+		if children := n.NamedChildren(); len(children) >= 2 {
+			return e.Eval(children[len(children)-1])
+		}
+	case "augmented_assignment_expression":
+		if children := n.NamedChildren(); len(children) >= 2 {
+			return e.definition(children[0])
+		}
+	case "cast_expression":
+		return e.cast(n)
+	case "variable_name":
+		return e.variable(n)
+	case "name", "qualified_name":
+		return e.constant(n)
+	case "class_constant_access_expression":
+		return e.classConstant(n)
+	case "function_call_expression":
+		return e.call(n)
+	case "subscript_expression":
+		return e.subscript(n)
+	case "print_intrinsic":
+		return Constant("1")
+	case "update_expression":
+		return Any(FloatChars)
+	}
+	return Top()
+}
+
+func booleans() *Value {
+	return Join(Constant("1"), Constant(""))
+}
+
+// float converts a float literal the way PHP prints it, for the usual magnitudes
+func float(text string) *Value {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return Any(FloatChars)
+	}
+	abs := math.Abs(f)
+	switch {
+	case f == math.Trunc(f) && abs < 1e15:
+		return Constant(strconv.FormatInt(int64(f), 10))
+	case abs >= 1e-4 && abs < 1e15:
+		return Constant(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return Any(FloatChars)
+}
+
+func (e *Evaluator) stringLiteral(n *ast.Node) *Value {
+	doubleQuoted := n.Kind == "encapsed_string" || n.Kind == "heredoc"
+	var parts []*Value
+	for _, part := range literal.Parts(n) {
+		switch part.Kind {
+		case "string_content", "nowdoc_string", "string_value":
+			parts = append(parts, Constant(part.Text))
+		case "escape_sequence":
+			parts = append(parts, Constant(literal.Unescape(part.Text, doubleQuoted)))
+		default:
+			parts = append(parts, e.Eval(part))
+		}
+	}
+	return Concat(parts...)
+}
+
+func (e *Evaluator) binary(n *ast.Node) *Value {
+	children := n.Descendants
+	if len(children) != 3 {
+		return Top()
+	}
+	switch children[1].Kind {
+	case ".":
+		return Concat(e.Eval(children[0]), e.Eval(children[2]))
+	case "??":
+		return Join(e.Eval(children[0]), e.Eval(children[2]))
+	case "<=>":
+		return Join(Constant("-1"), Constant("0"), Constant("1"))
+	case "+", "-", "*", "/", "%", "**":
+		return Any(FloatChars)
+	case "<<", ">>", "&", "|", "^":
+		return Any(IntChars)
+	}
+	// Comparisons, logical operators and instanceof
+	return booleans()
+}
+
+func (e *Evaluator) match(n *ast.Node) *Value {
+	block := n.ChildOfKind("match_block")
+	if block == nil {
+		return Top()
+	}
+	var arms []*Value
+	for _, arm := range block.NamedChildren() {
+		if children := arm.NamedChildren(); len(children) > 0 {
+			arms = append(arms, e.Eval(children[len(children)-1]))
+		}
+	}
+	return Join(arms...)
+}
+
+func (e *Evaluator) cast(n *ast.Node) *Value {
+	castType := n.ChildOfKind("cast_type")
+	children := n.NamedChildren()
+	if castType == nil || len(children) == 0 {
+		return Top()
+	}
+	switch strings.ToLower(castType.Text) {
+	case "int", "integer":
+		return Any(IntChars)
+	case "float", "double", "real":
+		return Any(FloatChars)
+	case "bool", "boolean":
+		return booleans()
+	case "string", "binary":
+		return e.Eval(children[len(children)-1])
+	}
+	return Top()
+}
+
+// variable evaluates a variable occurrence as the union of the definitions reaching it
+func (e *Evaluator) variable(n *ast.Node) *Value {
+	switch scope.VariableName(n) {
+	case "GLOBALS", "_SERVER", "_GET", "_POST", "_FILES", "_COOKIE", "_SESSION", "_REQUEST", "_ENV", "this":
+		return Top()
+	}
+	defs, ok := scope.DefsOf(n)
+	if !ok || len(defs) == 0 {
+		return Top()
+	}
+	var values []*Value
+	for _, def := range defs {
+		values = append(values, e.definition(def))
+	}
+	return Join(values...)
+}
+
+// definition evaluates the value written by a definition. A definition reaching itself through a
+// loop is evaluated twice: first assuming the empty set for itself, then assuming any string made
+// of the bytes the first evaluation may contain, and the result is widened.
+func (e *Evaluator) definition(def *ast.Node) *Value {
+	if v, ok := e.loops[def]; ok {
+		e.cycles++
+		return v
+	}
+	if v, ok := e.cache[def]; ok {
+		return v
+	}
+	cycles := e.cycles
+	e.loops[def] = Bottom()
+	v := e.assigned(def)
+	if e.cycles != cycles {
+		e.loops[def] = Any(v.Chars())
+		v = e.assigned(def).Widen()
+	}
+	delete(e.loops, def)
+	if e.cycles == cycles {
+		e.cache[def] = v
+	}
+	return v
+}
+
+func (e *Evaluator) assigned(def *ast.Node) *Value {
+	parent := def.Parent
+	if parent == nil {
+		return Top()
+	}
+	switch parent.Kind {
+	case "assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) >= 2 && children[0] == def {
+			return e.Eval(children[len(children)-1])
+		}
+	case "augmented_assignment_expression":
+		children := parent.NamedChildren()
+		if len(children) < 2 || children[0] != def || len(parent.Descendants) < 2 {
+			return Top()
+		}
+		switch parent.Descendants[1].Kind {
+		case ".=":
+			// The target is also read before the assignment
+			return Concat(e.variable(def), e.Eval(children[len(children)-1]))
+		case "??=":
+			return Join(e.variable(def), e.Eval(children[len(children)-1]))
+		}
+		return Any(FloatChars)
+	case "update_expression":
+		return Any(FloatChars)
+	case "anonymous_function_use_clause":
+		// Captured by value: the value of the enclosing variable when the closure is created
+		return e.variable(def)
+	}
+	// Parameters, foreach bindings, global and static declarations, list() and references
+	return Top()
+}
+
+// constant evaluates a constant, magic constants included
+func (e *Evaluator) constant(n *ast.Node) *Value {
+	name := n.Text
+	if n.Kind == "qualified_name" {
+		name = name[strings.LastIndex(name, "\\")+1:]
+	}
+	switch name {
+	case "PHP_EOL":
+		return Constant("\n")
+	case "DIRECTORY_SEPARATOR":
+		return Constant("/")
+	case "PATH_SEPARATOR":
+		return Constant(":")
+	case "PHP_INT_MAX", "PHP_INT_MIN", "PHP_INT_SIZE", "E_ALL", "E_ERROR", "E_WARNING", "E_NOTICE", "ENT_QUOTES":
+		return Any(IntChars)
+	case "__LINE__":
+		return Constant(strconv.Itoa(int(n.StartPosition.Row + 1)))
+	case "__CLASS__":
+		if c := e.hierarchy.EnclosingClass(n); c != nil {
+			return Constant(c.Name)
+		}
+		return Constant("")
+	case "__NAMESPACE__":
+		if ns := e.hierarchy.Namespace(n); ns != nil {
+			return Constant(ns.Name)
+		}
+		return Constant("")
+	case "__FUNCTION__", "__METHOD__":
+		if s := scope.Of(n); s != nil && s.Node.Kind != "program" {
+			if name == "__METHOD__" {
+				if c := e.hierarchy.EnclosingClass(s.Node); c != nil {
+					return Constant(c.Name + "::" + s.Name)
+				}
+			}
+			return Constant(s.Name)
+		}
+		return Constant("")
+	}
+	if value, ok := e.constants[name]; ok {
+		return e.Eval(value)
+	}
+	return Top()
+}
+
+// classConstant evaluates Class::CONSTANT and Class::class. static:: constants may be redefined
+// by the subclasses.
+func (e *Evaluator) classConstant(n *ast.Node) *Value {
+	children := n.NamedChildren()
+	if len(children) != 2 {
+		return Top()
+	}
+	class, name := children[0], children[1].Text
+	var candidates []*classes.Class
+	switch strings.ToLower(class.Text) {
+	case "self":
+		if c := e.hierarchy.EnclosingClass(n); c != nil {
+			candidates = append(candidates, c)
+		}
+	case "static":
+		if c := e.hierarchy.EnclosingClass(n); c != nil {
+			candidates = append(append(candidates, c), c.Descendants()...)
+		}
+	case "parent":
+		if c := e.hierarchy.EnclosingClass(n); c != nil && c.Parent != nil {
+			candidates = append(candidates, c.Parent)
+		}
+	default:
+		if class.Kind != "name" && class.Kind != "qualified_name" {
+			return Top()
+		}
+		qualified := class.Text
+		if ns := e.hierarchy.Namespace(n); ns != nil {
+			qualified = ns.Resolve(class.Text)
+		}
+		if strings.EqualFold(name, "class") {
+			return Constant(strings.TrimPrefix(qualified, "\\"))
+		}
+		if c := e.hierarchy.Lookup(qualified); c != nil {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return Top()
+	}
+	var values []*Value
+	for _, c := range candidates {
+		if strings.EqualFold(name, "class") {
+			values = append(values, Constant(c.Name))
+			continue
+		}
+		declaration := lookupConstant(c, name, make(map[*classes.Class]bool))
+		if declaration == nil || declaration.Kind != "const_element" {
+			return Top()
+		}
+		if children := declaration.NamedChildren(); len(children) == 2 {
+			values = append(values, e.Eval(children[1]))
+		}
+	}
+	return Join(values...)
+}
+
+// lookupConstant finds the declaration of a constant in a class, its ancestors, interfaces and traits
+func lookupConstant(c *classes.Class, name string, visited map[*classes.Class]bool) *ast.Node {
+	if c == nil || visited[c] {
+		return nil
+	}
+	visited[c] = true
+	if declaration, ok := c.Constants[name]; ok {
+		return declaration
+	}
+	for _, ancestor := range append(append([]*classes.Class{c.Parent}, c.Interfaces...), c.Traits...) {
+		if declaration := lookupConstant(ancestor, name, visited); declaration != nil {
+			return declaration
+		}
+	}
+	return nil
+}
+
+// subscript evaluates the element of an array literal read with a constant key
+func (e *Evaluator) subscript(n *ast.Node) *Value {
+	children := n.NamedChildren()
+	if len(children) != 2 || children[0].Kind != "array_creation_expression" {
+		return Top()
+	}
+	key, ok := e.Eval(children[1]).Constant()
+	if !ok {
+		return Top()
+	}
+	for i, element := range elements(children[0]) {
+		parts := element.NamedChildren()
+		if len(parts) == 0 {
+			continue
+		}
+		elementKey := strconv.Itoa(i)
+		if len(parts) == 2 {
+			if k, ok := e.Eval(parts[0]).Constant(); ok {
+				elementKey = k
+			} else {
+				return Top()
+			}
+		}
+		if elementKey == key {
+			return e.Eval(parts[len(parts)-1])
+		}
+	}
+	return Top()
+}
+
+// elements returns the element initializers of an array literal
+func elements(array *ast.Node) []*ast.Node {
+	return array.ChildrenOfKind("array_element_initializer")
+}
@@ -0,0 +1,317 @@
+package values
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+var (
+	// entityChars are the characters of the HTML entities produced by the escaping functions
+	entityChars = Letters.Union(Digits).Union(CharsOf("&#;"))
+	slugChars   = charRange('a', 'z').Union(Digits).Union(CharsOf("_-"))
+	fileChars   = Letters.Union(Digits).Union(CharsOf("._-"))
+	jsonChars   = CharsOf("\"\\{}[]:,").Union(Letters).Union(Digits)
+)
+
+// resultChars are the functions whose result is made of a fixed set of bytes whatever their arguments
+var resultChars = map[string]Charset{
+	"intval": IntChars, "absint": IntChars, "count": IntChars, "sizeof": IntChars, "strlen": IntChars,
+	"mb_strlen": IntChars, "strpos": IntChars, "stripos": IntChars, "strrpos": IntChars, "time": IntChars,
+	"rand": IntChars, "mt_rand": IntChars, "random_int": IntChars, "crc32": IntChars, "ord": IntChars,
+	"intdiv": IntChars, "printf": IntChars, "array_sum": FloatChars, "floatval": FloatChars,
+	"floor": FloatChars, "ceil": FloatChars, "round": FloatChars, "abs": FloatChars, "microtime": FloatChars,
+	"number_format": Digits.Union(CharsOf(",.-")),
+	"md5":           HexDigits, "sha1": HexDigits, "hash": HexDigits, "hash_hmac": HexDigits, "md5_file": HexDigits,
+	"sha1_file": HexDigits, "bin2hex": HexDigits, "dechex": HexDigits, "uniqid": HexDigits.Union(CharsOf(".")),
+	"base64_encode": Base64Chars, "urlencode": URLChars, "rawurlencode": URLChars,
+	"http_build_query": URLChars.Union(CharsOf("&=")),
+	"sanitize_key":     slugChars, "sanitize_title": slugChars, "sanitize_file_name": fileChars,
+}
+
+// escapers are the functions encoding the HTML special characters of their first argument as entities
+var escapers = map[string]bool{
+	"htmlspecialchars": true, "htmlentities": true, "esc_html": true, "esc_attr": true,
+	"esc_textarea": true, "esc_js": true, "esc_url": true, "esc_html__": true, "esc_attr__": true,
+}
+
+// slashers are the functions escaping the quotes of their argument with backslashes. Their result
+// still contains the quotes, escaped.
+var slashers = map[string]int{
+	"addslashes": 0, "esc_sql": 0, "mysql_real_escape_string": 0, "mysql_escape_string": 0,
+	"mysqli_real_escape_string": 1, "pg_escape_string": 0,
+}
+
+// call evaluates the calls to the functions whose result is known from the values of their arguments
+func (e *Evaluator) call(n *ast.Node) *Value {
+	name := ""
+	if nameNode := n.ChildOfKind("name", "qualified_name"); nameNode != nil {
+		name = strings.ToLower(nameNode.Text[strings.LastIndex(nameNode.Text, "\\")+1:])
+	}
+	arguments := n.Arguments()
+	if chars, ok := resultChars[name]; ok {
+		return Any(chars)
+	}
+	if len(arguments) == 0 {
+		return Top()
+	}
+	argument := func(i int) *Value {
+		if i < len(arguments) {
+			return e.Eval(arguments[i])
+		}
+		return Constant("")
+	}
+	if escapers[name] {
+		return Any(argument(0).Chars().Minus(HTMLSpecialChars).Union(entityChars))
+	}
+	if i, ok := slashers[name]; ok {
+		return Escaped(argument(i).Chars().Union(CharsOf("\\")))
+	}
+	switch name {
+	case "sprintf":
+		return e.sprintf(argument(0), arguments[1:])
+	case "vsprintf":
+		if len(arguments) == 2 && arguments[1].Kind == "array_creation_expression" {
+			var values []*ast.Node
+			for _, element := range elements(arguments[1]) {
+				if parts := element.NamedChildren(); len(parts) > 0 {
+					values = append(values, parts[len(parts)-1])
+				}
+			}
+			return e.sprintf(argument(0), values)
+		}
+		return Top()
+	case "implode", "join":
+		return e.implode(arguments)
+	case "strval", "trailingslashit":
+		if name == "trailingslashit" {
+			return Concat(argument(0).Map(func(s string) string { return strings.TrimRight(s, "/\\") }, same), Constant("/"))
+		}
+		return argument(0)
+	case "strtolower", "mb_strtolower":
+		return argument(0).Map(strings.ToLower, withCase)
+	case "strtoupper", "mb_strtoupper":
+		return argument(0).Map(strings.ToUpper, withCase)
+	case "trim", "ltrim", "rtrim", "chop":
+		value := argument(0)
+		if s, ok := value.Constant(); ok && len(arguments) == 1 {
+			const whitespace = " \t\n\r\x00\x0B"
+			switch name {
+			case "trim":
+				return Constant(strings.Trim(s, whitespace))
+			case "ltrim":
+				return Constant(strings.TrimLeft(s, whitespace))
+			}
+			return Constant(strings.TrimRight(s, whitespace))
+		}
+		return Any(value.Chars())
+	case "strrev":
+		if s, ok := argument(0).Constant(); ok {
+			reversed := []byte(s)
+			for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+				reversed[i], reversed[j] = reversed[j], reversed[i]
+			}
+			return Constant(string(reversed))
+		}
+		return Any(argument(0).Chars())
+	case "str_repeat":
+		s, ok := argument(0).Constant()
+		times, err := strconv.Atoi(arguments[len(arguments)-1].Text)
+		if ok && err == nil && times >= 0 && times <= 64 {
+			return Constant(strings.Repeat(s, times))
+		}
+		return Any(argument(0).Chars())
+	case "str_replace", "str_ireplace":
+		if len(arguments) < 3 {
+			return Top()
+		}
+		search, searchOK := argument(0).Constant()
+		replace, replaceOK := argument(1).Constant()
+		subject, subjectOK := argument(2).Constant()
+		if searchOK && replaceOK && subjectOK && name == "str_replace" {
+			return Constant(strings.ReplaceAll(subject, search, replace))
+		}
+		return Any(argument(2).Chars().Union(argument(1).Chars()))
+	case "substr", "mb_substr", "strip_tags", "stripslashes", "basename", "dirname", "wordwrap",
+		"sanitize_text_field", "sanitize_email", "wp_unslash", "ucfirst", "lcfirst", "ucwords":
+		value := argument(0)
+		if s, ok := value.Constant(); ok {
+			switch name {
+			case "ucfirst":
+				if s != "" {
+					return Constant(strings.ToUpper(s[:1]) + s[1:])
+				}
+			case "lcfirst":
+				if s != "" {
+					return Constant(strings.ToLower(s[:1]) + s[1:])
+				}
+			}
+		}
+		chars := value.Chars()
+		if name == "ucfirst" || name == "lcfirst" || name == "ucwords" {
+			chars = withCase(chars)
+		}
+		return Any(chars)
+	case "nl2br":
+		return Any(argument(0).Chars().Union(CharsOf("<br />")))
+	case "json_encode", "wp_json_encode":
+		return Any(argument(0).Chars().Union(jsonChars))
+	case "escapeshellarg":
+		return Concat(Constant("'"), Any(argument(0).Chars().Union(CharsOf("'\\"))), Constant("'"))
+	case "constant":
+		if constantName, ok := argument(0).Constant(); ok {
+			if value, ok := e.constants[strings.TrimPrefix(constantName, "\\")]; ok {
+				return e.Eval(value)
+			}
+		}
+	}
+	return Top()
+}
+
+func same(c Charset) Charset {
+	return c
+}
+
+// withCase adds the other case of the letters of a charset
+func withCase(c Charset) Charset {
+	result := c
+	for b := 'a'; b <= 'z'; b++ {
+		if c.Has(byte(b)) || c.Has(byte(b-'a'+'A')) {
+			result.Add(byte(b))
+			result.Add(byte(b - 'a' + 'A'))
+		}
+	}
+	return result
+}
+
+// formatSpecifier matches a conversion specification of a printf format
+var formatSpecifier = regexp.MustCompile(`%(?:(\d+)\$)?((?:[-+ 0]|'.)*)(\d+)?(?:\.(\d+))?([bcdeEfFgGhHosuxX%])`)
+
+// sprintf evaluates a sprintf call for each of the constant formats it may be given
+func (e *Evaluator) sprintf(format *Value, arguments []*ast.Node) *Value {
+	formats, ok := format.Constants()
+	if !ok {
+		return Top()
+	}
+	var results []*Value
+	for _, f := range formats {
+		results = append(results, e.format(f, arguments))
+	}
+	return Join(results...)
+}
+
+func (e *Evaluator) format(format string, arguments []*ast.Node) *Value {
+	var parts []*Value
+	next := 0
+	last := 0
+	for _, match := range formatSpecifier.FindAllStringSubmatchIndex(format, -1) {
+		parts = append(parts, Constant(format[last:match[0]]))
+		last = match[1]
+		group := func(i int) string {
+			if match[2*i] < 0 {
+				return ""
+			}
+			return format[match[2*i]:match[2*i+1]]
+		}
+		specifier := group(5)
+		if specifier == "%" {
+			parts = append(parts, Constant("%"))
+			continue
+		}
+		index := next
+		if position := group(1); position != "" {
+			index, _ = strconv.Atoi(position)
+			index--
+		} else {
+			next++
+		}
+		if index < 0 || index >= len(arguments) {
+			// Too few arguments is an error
+			return Top()
+		}
+		var value *Value
+		switch specifier {
+		case "s":
+			value = e.Eval(arguments[index])
+			if group(4) != "" && !value.IsConstant() {
+				value = Any(value.Chars())
+			} else if precision, err := strconv.Atoi(group(4)); err == nil {
+				if s, _ := value.Constant(); len(s) > precision {
+					value = Constant(s[:precision])
+				}
+			}
+		case "d", "u", "i":
+			value = Any(IntChars)
+			if s, ok := e.Eval(arguments[index]).Constant(); ok {
+				if i, err := strconv.Atoi(s); err == nil {
+					value = Constant(strconv.Itoa(i))
+				}
+			}
+		case "e", "E", "f", "F", "g", "G", "h", "H":
+			value = Any(FloatChars)
+		case "x", "X":
+			value = Any(HexDigits)
+		case "o":
+			value = Any(charRange('0', '7'))
+		case "b":
+			value = Any(CharsOf("01"))
+		default:
+			value = Top()
+		}
+		if group(3) != "" {
+			// Padding to the width
+			pad := CharsOf(" ")
+			flags := group(2)
+			for i := 0; i < len(flags); i++ {
+				switch flags[i] {
+				case '0':
+					pad = CharsOf("0")
+				case '\'':
+					i++
+					pad = CharsOf(flags[i : i+1])
+				case '+':
+					pad = pad.Union(CharsOf("+"))
+				}
+			}
+			if strings.Contains(flags, "-") {
+				value = Concat(value, Any(pad))
+			} else {
+				value = Concat(Any(pad), value)
+			}
+		}
+		parts = append(parts, value)
+	}
+	parts = append(parts, Constant(format[last:]))
+	return Concat(parts...)
+}
+
+// implode evaluates the joining of an array literal, with the glue first or, as older PHP allowed, last
+func (e *Evaluator) implode(arguments []*ast.Node) *Value {
+	var glue *Value
+	var array *ast.Node
+	switch {
+	case len(arguments) == 1:
+		glue, array = Constant(""), arguments[0]
+	case arguments[0].Kind == "array_creation_expression":
+		glue, array = e.Eval(arguments[1]), arguments[0]
+	default:
+		glue, array = e.Eval(arguments[0]), arguments[1]
+	}
+	if array.Kind != "array_creation_expression" {
+		return Top()
+	}
+	var parts []*Value
+	for i, element := range elements(array) {
+		children := element.NamedChildren()
+		if len(children) == 0 || strings.HasPrefix(element.Text, "...") {
+			return Top()
+		}
+		if i > 0 {
+			parts = append(parts, glue)
+		}
+		parts = append(parts, e.Eval(children[len(children)-1]))
+	}
+	return Concat(parts...)
+}
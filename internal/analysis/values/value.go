@@ -0,0 +1,472 @@
+package values
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxAlternatives bounds the number of alternatives of a value before it is widened
+const maxAlternatives = 8
+
+// atom is a literal string, or any string made of the bytes of a charset when repeat is set.
+// escaped is set on the repetitions whose quotes are all escaped by a backslash.
+type atom struct {
+	literal string
+	chars   Charset
+	repeat  bool
+	escaped bool
+}
+
+// sequence is a concatenation of atoms. Adjacent literals are merged, as are adjacent repeats.
+type sequence []atom
+
+// Value approximates the set of strings an expression may evaluate to by a regular language:
+// a union of alternatives, each one a concatenation of literal strings and of repetitions of
+// character classes. A value with no alternative is the empty set, the value of dead code.
+type Value struct {
+	alternatives []sequence
+}
+
+// Constant returns the value of a single known string
+func Constant(s string) *Value {
+	return &Value{alternatives: []sequence{normalize(sequence{{literal: s}})}}
+}
+
+// Any returns the value of any string made of the bytes of a charset, including the empty string
+func Any(chars Charset) *Value {
+	return &Value{alternatives: []sequence{normalize(sequence{{chars: chars, repeat: true}})}}
+}
+
+// Escaped returns the value of any string made of the bytes of a charset whose quotes are all
+// escaped by a backslash, as returned by addslashes
+func Escaped(chars Charset) *Value {
+	return &Value{alternatives: []sequence{normalize(sequence{{chars: chars, repeat: true, escaped: true}})}}
+}
+
+// Top returns the value of an unknown string
+func Top() *Value {
+	return Any(AllChars)
+}
+
+// Bottom returns the empty set of strings
+func Bottom() *Value {
+	return &Value{}
+}
+
+func normalize(s sequence) sequence {
+	var result sequence
+	for _, a := range s {
+		if (!a.repeat && a.literal == "") || (a.repeat && a.chars.IsEmpty()) {
+			continue
+		}
+		if len(result) > 0 {
+			last := &result[len(result)-1]
+			if last.repeat == a.repeat {
+				if a.repeat {
+					last.chars = last.chars.Union(a.chars)
+					last.escaped = last.escaped && a.escaped
+				} else {
+					last.literal += a.literal
+				}
+				continue
+			}
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+func (s sequence) key() string {
+	var sb strings.Builder
+	for _, a := range s {
+		if a.repeat {
+			sb.WriteString("\x00" + a.chars.String() + "\x00")
+			if a.escaped {
+				sb.WriteString("\\")
+			}
+		} else {
+			sb.WriteString(strconv.Quote(a.literal))
+		}
+	}
+	return sb.String()
+}
+
+func (s sequence) isLiteral() bool {
+	return len(s) == 0 || (len(s) == 1 && !s[0].repeat)
+}
+
+func (s sequence) literal() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0].literal
+}
+
+// chars returns the bytes the strings of the sequence may contain
+func (s sequence) chars() Charset {
+	var c Charset
+	for _, a := range s {
+		if a.repeat {
+			c = c.Union(a.chars)
+		} else {
+			c = c.Union(CharsOf(a.literal))
+		}
+	}
+	return c
+}
+
+// prefix returns the literal every string of the sequence starts with
+func (s sequence) prefix() string {
+	if len(s) == 0 || s[0].repeat {
+		return ""
+	}
+	return s[0].literal
+}
+
+func (s sequence) suffix() string {
+	if len(s) == 0 || s[len(s)-1].repeat {
+		return ""
+	}
+	return s[len(s)-1].literal
+}
+
+// Join returns the union of values
+func Join(values ...*Value) *Value {
+	result := &Value{}
+	seen := make(map[string]bool)
+	for _, v := range values {
+		for _, s := range v.alternatives {
+			if key := s.key(); !seen[key] {
+				seen[key] = true
+				result.alternatives = append(result.alternatives, s)
+			}
+		}
+	}
+	if len(result.alternatives) > maxAlternatives {
+		return result.Widen()
+	}
+	return result
+}
+
+// Concat returns the concatenation of values
+func Concat(values ...*Value) *Value {
+	result := Constant("")
+	for _, v := range values {
+		if len(result.alternatives)*len(v.alternatives) > maxAlternatives {
+			result, v = result.Widen(), v.Widen()
+		}
+		next := &Value{}
+		seen := make(map[string]bool)
+		for _, left := range result.alternatives {
+			for _, right := range v.alternatives {
+				s := normalize(append(append(sequence{}, left...), right...))
+				if key := s.key(); !seen[key] {
+					seen[key] = true
+					next.alternatives = append(next.alternatives, s)
+				}
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// Widen over-approximates the value by a single alternative: the common prefix and suffix of
+// all the alternatives around the repetition of all the bytes they may contain in between.
+// It is also used to approximate values built in loops.
+func (v *Value) Widen() *Value {
+	if len(v.alternatives) <= 1 {
+		return v
+	}
+	prefix := v.alternatives[0].prefix()
+	for _, s := range v.alternatives[1:] {
+		prefix = commonPrefix(prefix, s.prefix())
+	}
+	var chars Charset
+	var rests []sequence
+	for _, s := range v.alternatives {
+		rest := trimPrefix(s, prefix)
+		rests = append(rests, rest)
+	}
+	suffix := rests[0].suffix()
+	for _, s := range rests[1:] {
+		suffix = commonSuffix(suffix, s.suffix())
+	}
+	for _, s := range rests {
+		chars = chars.Union(trimSuffix(s, suffix).chars())
+	}
+	return &Value{alternatives: []sequence{normalize(sequence{
+		{literal: prefix},
+		{chars: chars, repeat: true},
+		{literal: suffix},
+	})}}
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+func commonSuffix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[len(a)-1-i] == b[len(b)-1-i] {
+		i++
+	}
+	return a[len(a)-i:]
+}
+
+func trimPrefix(s sequence, prefix string) sequence {
+	if prefix == "" {
+		return s
+	}
+	rest := append(sequence{}, s...)
+	rest[0].literal = strings.TrimPrefix(rest[0].literal, prefix)
+	return normalize(rest)
+}
+
+func trimSuffix(s sequence, suffix string) sequence {
+	if suffix == "" {
+		return s
+	}
+	rest := append(sequence{}, s...)
+	rest[len(rest)-1].literal = strings.TrimSuffix(rest[len(rest)-1].literal, suffix)
+	return normalize(rest)
+}
+
+// Map applies a string function to the literals of the value and a charset function to its
+// repetitions. It is only exact for functions mapping each byte independently, such as strtolower.
+func (v *Value) Map(literal func(string) string, chars func(Charset) Charset) *Value {
+	result := &Value{}
+	for _, s := range v.alternatives {
+		mapped := make(sequence, len(s))
+		for i, a := range s {
+			if a.repeat {
+				mapped[i] = atom{chars: chars(a.chars), repeat: true, escaped: a.escaped}
+			} else {
+				mapped[i] = atom{literal: literal(a.literal)}
+			}
+		}
+		result.alternatives = append(result.alternatives, normalize(mapped))
+	}
+	return Join(result)
+}
+
+// Part is a piece of an alternative of a value: a literal string, or an unknown string made of
+// the bytes of a charset
+type Part struct {
+	Literal string
+	Unknown bool
+	Chars   Charset
+	// Escaped is set on the unknown parts whose quotes are all escaped by a backslash
+	Escaped bool
+}
+
+// Alternatives returns the alternatives of the value as sequences of parts
+func (v *Value) Alternatives() [][]Part {
+	var result [][]Part
+	for _, s := range v.alternatives {
+		parts := []Part{}
+		for _, a := range s {
+			if a.repeat {
+				parts = append(parts, Part{Unknown: true, Chars: a.chars, Escaped: a.escaped})
+			} else {
+				parts = append(parts, Part{Literal: a.literal, Chars: CharsOf(a.literal)})
+			}
+		}
+		result = append(result, parts)
+	}
+	return result
+}
+
+// IsBottom reports whether the value is the empty set, which happens for unreachable code
+func (v *Value) IsBottom() bool {
+	return len(v.alternatives) == 0
+}
+
+// IsConstant reports whether the value is a single known string
+func (v *Value) IsConstant() bool {
+	return len(v.alternatives) == 1 && v.alternatives[0].isLiteral()
+}
+
+// Constant returns the string of a constant value
+func (v *Value) Constant() (string, bool) {
+	if !v.IsConstant() {
+		return "", false
+	}
+	return v.alternatives[0].literal(), true
+}
+
+// Constants returns the strings of a value that is a finite set of known strings
+func (v *Value) Constants() ([]string, bool) {
+	if v.IsBottom() {
+		return nil, false
+	}
+	var result []string
+	for _, s := range v.alternatives {
+		if !s.isLiteral() {
+			return nil, false
+		}
+		result = append(result, s.literal())
+	}
+	sort.Strings(result)
+	return result, true
+}
+
+// IsKnown reports whether anything is known about the value, that is whether it is not any string
+func (v *Value) IsKnown() bool {
+	for _, s := range v.alternatives {
+		if len(s) == 1 && s[0].repeat && s[0].chars.IsFull() {
+			return false
+		}
+	}
+	return true
+}
+
+// MayContain reports whether one of the strings of the value may contain a substring.
+// The unknown parts of the value may contain it as soon as they may contain one of its bytes.
+func (v *Value) MayContain(substring string) bool {
+	if substring == "" {
+		return !v.IsBottom()
+	}
+	needed := CharsOf(substring)
+	for _, s := range v.alternatives {
+		var literals strings.Builder
+		for _, a := range s {
+			if a.repeat {
+				if a.chars.Intersects(needed) {
+					return true
+				}
+				// A repetition separates the literals around it
+				literals.WriteString("\x00")
+				continue
+			}
+			literals.WriteString(a.literal)
+		}
+		if strings.Contains(literals.String(), substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// MayContainAny reports whether one of the strings of the value may contain one of the bytes of a charset
+func (v *Value) MayContainAny(chars Charset) bool {
+	return v.Chars().Intersects(chars)
+}
+
+// MayContainQuote reports whether one of the strings of the value may contain a single quote,
+// a double quote or a backtick
+func (v *Value) MayContainQuote() bool {
+	return v.MayContainAny(Quotes)
+}
+
+// Chars returns the bytes the strings of the value may contain
+func (v *Value) Chars() Charset {
+	var c Charset
+	for _, s := range v.alternatives {
+		c = c.Union(s.chars())
+	}
+	return c
+}
+
+// Prefix returns the longest string all the strings of the value start with
+func (v *Value) Prefix() string {
+	if v.IsBottom() {
+		return ""
+	}
+	prefix := v.alternatives[0].prefix()
+	for _, s := range v.alternatives[1:] {
+		prefix = commonPrefix(prefix, s.prefix())
+	}
+	return prefix
+}
+
+// Suffix returns the longest string all the strings of the value end with
+func (v *Value) Suffix() string {
+	if v.IsBottom() {
+		return ""
+	}
+	suffix := v.alternatives[0].suffix()
+	for _, s := range v.alternatives[1:] {
+		suffix = commonSuffix(suffix, s.suffix())
+	}
+	return suffix
+}
+
+// Regex returns a regular expression matching exactly the strings of the value
+func (v *Value) Regex() string {
+	if v.IsBottom() {
+		return "[^\\x00-\\x{10FFFF}]"
+	}
+	var alternatives []string
+	for _, s := range v.alternatives {
+		var sb strings.Builder
+		for _, a := range s {
+			if a.repeat {
+				if a.chars.IsFull() {
+					sb.WriteString(`(?s:.)*`)
+				} else {
+					sb.WriteString(a.chars.String() + "*")
+				}
+			} else {
+				sb.WriteString(regexp.QuoteMeta(a.literal))
+			}
+		}
+		alternatives = append(alternatives, sb.String())
+	}
+	return "^(?:" + strings.Join(alternatives, "|") + ")$"
+}
+
+// String renders the value with literals quoted and unknown parts as character classes,
+// for instance "SELECT * FROM t WHERE id = " [\-0-9]*
+func (v *Value) String() string {
+	if v.IsBottom() {
+		return "⊥"
+	}
+	var alternatives []string
+	for _, s := range v.alternatives {
+		if len(s) == 0 {
+			alternatives = append(alternatives, `""`)
+			continue
+		}
+		var parts []string
+		for _, a := range s {
+			if a.repeat {
+				parts = append(parts, a.chars.String()+"*")
+			} else {
+				parts = append(parts, strconv.Quote(a.literal))
+			}
+		}
+		alternatives = append(alternatives, strings.Join(parts, " "))
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+type valueJSON struct {
+	Value           string   `json:"value"`
+	Regex           string   `json:"regex"`
+	Constant        bool     `json:"constant"`
+	Constants       []string `json:"constants,omitempty"`
+	Prefix          string   `json:"prefix,omitempty"`
+	Suffix          string   `json:"suffix,omitempty"`
+	MayContainQuote bool     `json:"may_contain_quote"`
+}
+
+func (v *Value) MarshalJSON() ([]byte, error) {
+	constants, _ := v.Constants()
+	return json.Marshal(valueJSON{
+		Value:           v.String(),
+		Regex:           v.Regex(),
+		Constant:        v.IsConstant(),
+		Constants:       constants,
+		Prefix:          v.Prefix(),
+		Suffix:          v.Suffix(),
+		MayContainQuote: v.MayContainQuote(),
+	})
+}
@@ -0,0 +1,154 @@
+package values
+
+import (
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// echoed evaluates the expression of the last echo statement of a source
+func echoed(t *testing.T, source string) *Value {
+	t.Helper()
+	root := ast.ParseSource([]byte(source))
+	e := New()
+	e.AddFile("test.php", root)
+	e.Resolve()
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"echo_statement": true}}
+	root.WalkPrefix(v)
+	if len(v.Nodes) == 0 {
+		t.Fatal("no echo statement")
+	}
+	return e.Eval(v.Nodes[len(v.Nodes)-1].NamedChildren()[0])
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		value  string
+		quote  bool
+	}{
+		{
+			name:   "literal",
+			source: `<?php echo 'a' . "b";`,
+			value:  `"ab"`,
+		},
+		{
+			name:   "variable through its definition",
+			source: "<?php\n$t = 'users';\necho \"SELECT * FROM $t\";",
+			value:  `"SELECT * FROM users"`,
+		},
+		{
+			name:   "branches",
+			source: "<?php\nif ($c) {\n$t = 'a';\n} else {\n$t = 'b';\n}\necho $t;",
+			value:  `"a" | "b"`,
+		},
+		{
+			name:   "define constant",
+			source: "<?php\ndefine('PREFIX', 'wp_');\necho PREFIX . 'posts';",
+			value:  `"wp_posts"`,
+		},
+		{
+			name:   "class constant",
+			source: "<?php\nclass C { const T = 'x'; }\necho C::T;",
+			value:  `"x"`,
+		},
+		{
+			name:   "request data is any string",
+			source: "<?php\necho 'id = ' . $_GET['id'];",
+			value:  `"id = " .*`,
+			quote:  true,
+		},
+		{
+			name:   "intval keeps digits only",
+			source: "<?php\necho 'id = ' . intval($_GET['id']);",
+			value:  `"id = " [\-0-9]*`,
+		},
+		{
+			name:   "htmlspecialchars removes the special characters but not the backtick",
+			source: "<?php\necho htmlspecialchars($_GET['x']);",
+			value:  `[^"'<>]*`,
+			quote:  true,
+		},
+		{
+			name:   "addslashes keeps escaped quotes",
+			source: "<?php\necho addslashes($_GET['x']);",
+			quote:  true,
+		},
+		{
+			name:   "quotemeta does not escape quotes",
+			source: "<?php\necho quotemeta($_GET['x']);",
+			quote:  true,
+		},
+		{
+			name:   "sprintf",
+			source: "<?php\necho sprintf('%s-%d', 'a', $n);",
+			value:  `"a-" [\-0-9]*`,
+		},
+		{
+			name:   "implode of a literal array",
+			source: "<?php\necho implode(',', ['a', 'b']);",
+			value:  `"a,b"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := echoed(t, tt.source)
+			if tt.value != "" && value.String() != tt.value {
+				t.Errorf("value = %s, want %s", value, tt.value)
+			}
+			if value.MayContainQuote() != tt.quote {
+				t.Errorf("MayContainQuote() = %t, want %t for %s", value.MayContainQuote(), tt.quote, value)
+			}
+		})
+	}
+}
+
+func TestConcatAndJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *Value
+		want     string
+		constant bool
+		prefix   string
+	}{
+		{
+			name:     "constants concatenate",
+			value:    Concat(Constant("a"), Constant("b")),
+			want:     `"ab"`,
+			constant: true,
+			prefix:   "ab",
+		},
+		{
+			name:   "a prefix survives an unknown part",
+			value:  Concat(Constant("/var/www/"), Top()),
+			want:   `"/var/www/" .*`,
+			prefix: "/var/www/",
+		},
+		{
+			name:   "join keeps the common prefix",
+			value:  Join(Constant("ab"), Constant("ac")),
+			want:   `"ab" | "ac"`,
+			prefix: "a",
+		},
+		{
+			name:   "widening keeps the common prefix and suffix",
+			value:  Join(Constant("a1z"), Constant("a22z")).Widen(),
+			want:   `"a" [12]* "z"`,
+			prefix: "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+			if got := tt.value.IsConstant(); got != tt.constant {
+				t.Errorf("IsConstant() = %t, want %t", got, tt.constant)
+			}
+			if got := tt.value.Prefix(); got != tt.prefix {
+				t.Errorf("Prefix() = %q, want %q", got, tt.prefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package webshell
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/secrets"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Signal is a feature of webshells and obfuscated code
+type Signal string
+
+const (
+	// DecodedEval is eval, assert or create_function run on decoded data
+	DecodedEval Signal = "decoded-eval"
+	// VariableFunction is a call of a function whose name is built from strings
+	VariableFunction Signal = "variable-function"
+	// PregReplaceEval is preg_replace with the /e modifier, which evaluates the replacement
+	PregReplaceEval Signal = "preg-replace-eval"
+	// CreateFunction is create_function, an eval in disguise
+	CreateFunction Signal = "create-function"
+	// LongString is a very long string literal, such as an encoded payload
+	LongString Signal = "long-string"
+	// ObfuscatedIdentifier is a random-looking or deliberately confusing identifier
+	ObfuscatedIdentifier Signal = "obfuscated-identifier"
+	// RequestExecution is request data reaching an execution sink
+	RequestExecution Signal = "request-execution"
+	// Stealth is a call hiding the activity of the script, such as error_reporting(0)
+	Stealth Signal = "stealth"
+)
+
+// weights are the score of each occurrence of a signal, and caps the total of a signal in a file
+var weights = map[Signal]struct{ each, max int }{
+	DecodedEval:          {40, 60},
+	VariableFunction:     {15, 30},
+	PregReplaceEval:      {35, 35},
+	CreateFunction:       {20, 20},
+	LongString:           {10, 30},
+	ObfuscatedIdentifier: {5, 20},
+	RequestExecution:     {40, 60},
+	Stealth:              {5, 10},
+}
+
+// Verdicts of the score of a file
+const (
+	Likely     = "likely webshell"
+	Suspicious = "suspicious"
+	Clean      = "clean"
+)
+
+// decoders are the functions decoding or unscrambling a string
+var decoders = map[string]bool{
+	"base64_decode": true, "gzinflate": true, "gzuncompress": true, "gzdecode": true, "str_rot13": true,
+	"strrev": true, "hex2bin": true, "convert_uudecode": true, "urldecode": true, "rawurldecode": true,
+	"pack": true, "chr": true, "bzdecompress": true,
+}
+
+// evaluators maps the functions evaluating code to the index of the code
+var evaluators = map[string]int{"eval": 0, "assert": 0, "create_function": 1}
+
+// executors are the functions executing commands or code
+var executors = map[string]bool{
+	"eval": true, "assert": true, "create_function": true, "system": true, "exec": true, "passthru": true,
+	"shell_exec": true, "popen": true, "proc_open": true, "pcntl_exec": true, "call_user_func": true,
+	"call_user_func_array": true, "array_map": true, "usort": true, "preg_replace": true,
+}
+
+// stealthCalls are the calls hiding errors or keeping the script running
+var stealthCalls = map[string]bool{
+	"error_reporting": true, "set_time_limit": true, "ignore_user_abort": true, "ini_set": true,
+}
+
+// longString is the length from which a string literal counts as a payload
+const longString = 1000
+
+var (
+	confusable = regexp.MustCompile(`^_*[O0Il1_]{5,}$`)
+	hexName    = regexp.MustCompile(`^_*0x[0-9a-fA-F]{3,}$`)
+)
+
+// Evidence is a node contributing to the score of a file. Its score is 0 once the total of its
+// signal is capped.
+type Evidence struct {
+	Signal Signal    `json:"signal"`
+	Score  int       `json:"score"`
+	Line   uint      `json:"line"`
+	Text   string    `json:"text"`
+	Node   *ast.Node `json:"-"`
+}
+
+// Report is the score of a file, from 0 to 100, with the evidence behind it
+type Report struct {
+	File     string      `json:"file"`
+	Score    int         `json:"score"`
+	Verdict  string      `json:"verdict"`
+	Evidence []*Evidence `json:"evidence"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Scanner scores the files of a project on the features of webshells: evaluated decoded data,
+// variable functions built from strings, preg_replace /e, create_function, long literals,
+// obfuscated identifiers and request data reaching the execution sinks
+type Scanner struct {
+	taint *taint.Analyzer
+	files []*file
+}
+
+func New() *Scanner {
+	return &Scanner{taint: taint.New()}
+}
+
+// AddFile adds a file to the project
+func (s *Scanner) AddFile(path string, root *ast.Node) {
+	s.files = append(s.files, &file{path: path, root: root})
+	s.taint.AddFile(path, root)
+}
+
+// Scan returns the reports of all the files, from the highest score
+func (s *Scanner) Scan() []*Report {
+	s.taint.Resolve()
+	var reports []*Report
+	for _, f := range s.files {
+		reports = append(reports, s.scan(f))
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Score != reports[j].Score {
+			return reports[i].Score > reports[j].Score
+		}
+		return reports[i].File < reports[j].File
+	})
+	return reports
+}
+
+func (s *Scanner) scan(f *file) *Report {
+	v := &ast.VisitorKinds{Kinds: map[string]bool{
+		"function_call_expression": true, "shell_command_expression": true, "string": true, "encapsed_string": true,
+		"heredoc": true, "nowdoc": true, "variable_name": true, "function_definition": true,
+	}}
+	f.root.WalkPrefix(v)
+	report := &Report{File: f.path, Evidence: []*Evidence{}}
+	totals := make(map[Signal]int)
+	add := func(signal Signal, n *ast.Node) {
+		weight := weights[signal]
+		score := max(0, min(weight.each, weight.max-totals[signal]))
+		totals[signal] += score
+		report.Evidence = append(report.Evidence, &Evidence{
+			Signal: signal, Score: score, Line: n.StartPosition.Row + 1, Text: shorten(n.Text), Node: n,
+		})
+	}
+	seen := make(map[string]bool)
+	for _, n := range v.Nodes {
+		switch n.Kind {
+		case "function_call_expression":
+			for _, signal := range s.call(n) {
+				add(signal, n)
+			}
+		case "shell_command_expression":
+			if s.taint.Tainted(n) || s.requestParts(n) {
+				add(RequestExecution, n)
+			}
+		case "string", "encapsed_string", "heredoc", "nowdoc":
+			if value, ok := literal.String(n); ok && len(value) >= longString && !strings.ContainsAny(value, " \n") {
+				add(LongString, n)
+			}
+		case "variable_name", "function_definition":
+			name := n.ChildOfKind("name")
+			if name == nil || seen[name.Text] || !obfuscated(name.Text) {
+				continue
+			}
+			seen[name.Text] = true
+			add(ObfuscatedIdentifier, n)
+		}
+	}
+	for _, total := range totals {
+		report.Score += total
+	}
+	report.Score = min(report.Score, 100)
+	switch {
+	case report.Score >= 60:
+		report.Verdict = Likely
+	case report.Score >= 30:
+		report.Verdict = Suspicious
+	default:
+		report.Verdict = Clean
+	}
+	return report
+}
+
+// call returns the signals of a function call
+func (s *Scanner) call(n *ast.Node) []Signal {
+	var signals []Signal
+	name := taint.FunctionName(n)
+	arguments := n.Arguments()
+	if name == "" {
+		callee := n.NamedChildren()[0]
+		if s.built(callee, 0) {
+			signals = append(signals, VariableFunction)
+		}
+		if s.taint.Tainted(callee) {
+			return append(signals, RequestExecution)
+		}
+		for _, argument := range arguments {
+			if s.taint.Tainted(argument) {
+				return append(signals, RequestExecution)
+			}
+		}
+		return signals
+	}
+	if index, ok := evaluators[name]; ok && index < len(arguments) && s.decoded(arguments[index], 0) {
+		signals = append(signals, DecodedEval)
+	}
+	if name == "create_function" {
+		signals = append(signals, CreateFunction)
+	}
+	if name == "preg_replace" && len(arguments) > 0 {
+		if pattern, ok := s.taint.Evaluator().Eval(arguments[0]).Constant(); ok && evalModifier(pattern) {
+			signals = append(signals, PregReplaceEval)
+		}
+	}
+	if executors[name] {
+		for _, argument := range arguments {
+			if s.taint.Tainted(argument) {
+				signals = append(signals, RequestExecution)
+				break
+			}
+		}
+	}
+	if stealthCalls[name] && len(arguments) > 0 {
+		if value := strings.TrimSpace(arguments[len(arguments)-1].Text); value == "0" || value == "true" || value == "1" {
+			signals = append(signals, Stealth)
+		}
+	}
+	return signals
+}
+
+// decoded reports whether an expression is the result of a decoding function, directly, through
+// other calls or through the definitions of a variable
+func (s *Scanner) decoded(n *ast.Node, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+	switch n.Kind {
+	case "function_call_expression":
+		if decoders[taint.FunctionName(n)] {
+			return true
+		}
+	case "variable_name":
+		sc := scope.Of(n)
+		if sc == nil {
+			return false
+		}
+		for _, def := range sc.DefsOf(n) {
+			if def.Parent != nil && def.Parent.Kind == "assignment_expression" {
+				values := def.Parent.NamedChildren()
+				if len(values) > 1 && s.decoded(values[len(values)-1], depth+1) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, child := range n.NamedChildren() {
+		if s.decoded(child, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// built reports whether the name of a variable function is built from string concatenation,
+// decoding or string functions rather than taken from a single literal
+func (s *Scanner) built(n *ast.Node, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+	switch n.Kind {
+	case "binary_expression", "encapsed_string":
+		return true
+	case "function_call_expression":
+		return true
+	case "parenthesized_expression":
+		for _, child := range n.NamedChildren() {
+			return s.built(child, depth+1)
+		}
+	case "variable_name":
+		sc := scope.Of(n)
+		if sc == nil {
+			return false
+		}
+		for _, def := range sc.DefsOf(n) {
+			if def.Parent == nil {
+				continue
+			}
+			switch def.Parent.Kind {
+			case "augmented_assignment_expression":
+				return true
+			case "assignment_expression":
+				values := def.Parent.NamedChildren()
+				if len(values) > 1 && s.built(values[len(values)-1], depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// requestParts reports whether a backtick command interpolates a superglobal directly
+func (s *Scanner) requestParts(n *ast.Node) bool {
+	for _, part := range n.NamedChildren() {
+		if strings.HasPrefix(part.Text, "$_") {
+			return true
+		}
+	}
+	return false
+}
+
+// evalModifier reports whether a regular expression has the e modifier after its closing delimiter
+func evalModifier(pattern string) bool {
+	if len(pattern) < 2 {
+		return false
+	}
+	delimiter := pattern[0]
+	switch delimiter {
+	case '(':
+		delimiter = ')'
+	case '{':
+		delimiter = '}'
+	case '[':
+		delimiter = ']'
+	case '<':
+		delimiter = '>'
+	}
+	end := strings.LastIndexByte(pattern, delimiter)
+	return end > 0 && strings.ContainsRune(pattern[end+1:], 'e')
+}
+
+// obfuscated reports whether an identifier looks generated to hide the code: made of confusable
+// characters such as O0Il1, hexadecimal like _0x3f2a, non-ASCII bytes, or random letters and digits
+func obfuscated(name string) bool {
+	if confusable.MatchString(name) || hexName.MatchString(name) {
+		return true
+	}
+	letters, vowels, digits := 0, 0, 0
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 0x80:
+			return true
+		case c >= '0' && c <= '9':
+			digits++
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letters++
+			if strings.IndexByte("aeiouyAEIOUY", c) >= 0 {
+				vowels++
+			}
+		}
+	}
+	return len(name) >= 8 && digits >= 2 && letters > 0 && vowels*5 < letters && secrets.Entropy(name) >= 3.0
+}
+
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 80 {
+		return text[:77] + "..."
+	}
+	return text
+}
+
+// String renders the evidence as a line of the report
+func (e *Evidence) String() string {
+	return fmt.Sprintf("Line %d: [%s +%d] %s", e.Line, e.Signal, e.Score, e.Text)
+}
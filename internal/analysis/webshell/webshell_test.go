@@ -0,0 +1,135 @@
+package webshell
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		verdict string
+		// evidence is the line of an expected piece of evidence, 0 for none
+		evidence uint
+	}{
+		{
+			name:     "decoded eval of request data",
+			source:   "<?php\n@error_reporting(0);\neval(base64_decode($_POST['p']));",
+			verdict:  Likely,
+			evidence: 3,
+		},
+		{
+			name:     "evidence past the cap of its signal is kept",
+			source:   "<?php\neval(base64_decode($_POST['a']));\neval(gzinflate(base64_decode($_POST['b'])));\nsystem($_REQUEST['cmd']);",
+			verdict:  Likely,
+			evidence: 4,
+		},
+		{
+			name:    "ordinary code",
+			source:  "<?php\nfunction greet($name) {\nreturn 'Hello ' . htmlspecialchars($name);\n}\necho greet('world');",
+			verdict: Clean,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			report := s.Scan()[0]
+			if report.Verdict != tt.verdict {
+				t.Errorf("verdict = %s with score %d, want %s", report.Verdict, report.Score, tt.verdict)
+			}
+			if tt.evidence == 0 && len(report.Evidence) > 0 {
+				t.Errorf("evidence = %v, want none", report.Evidence)
+			}
+			found := false
+			for _, e := range report.Evidence {
+				found = found || e.Line == tt.evidence
+			}
+			if tt.evidence != 0 && !found {
+				t.Errorf("no evidence on line %d in %v", tt.evidence, report.Evidence)
+			}
+			if report.Score > 100 {
+				t.Errorf("score = %d, want at most 100", report.Score)
+			}
+		})
+	}
+}
+
+func TestSignals(t *testing.T) {
+	payload := "'" + strings.Repeat("QUFB", 250) + "'"
+	tests := []struct {
+		name   string
+		source string
+		// scores are the total score of each signal
+		scores map[Signal]int
+	}{
+		{
+			name:   "variable function built by concatenation",
+			source: "<?php\n$f = 'sys' . 'tem';\n$f('ls');",
+			scores: map[Signal]int{VariableFunction: 15},
+		},
+		{
+			name:   "variable functions past the cap",
+			source: "<?php\n$f = 'ass';\n$f .= 'ert';\n$f('1');\n$g = str_rot13('flfgrz');\n$g('ls');\n(\"ex\" . \"ec\")('id');",
+			scores: map[Signal]int{VariableFunction: 30},
+		},
+		{
+			name:   "variable function from a single literal",
+			source: "<?php\n$f = 'strlen';\n$f('abc');",
+			scores: map[Signal]int{},
+		},
+		{
+			name:   "preg_replace with the e modifier",
+			source: "<?php\npreg_replace('/.*/e', $code, '');\npreg_replace('#a#i', 'b', $s);",
+			scores: map[Signal]int{PregReplaceEval: 35},
+		},
+		{
+			name:   "create_function",
+			source: "<?php\n$f = create_function('$a', 'return $a;');",
+			scores: map[Signal]int{CreateFunction: 20},
+		},
+		{
+			name:   "create_function of decoded code",
+			source: "<?php\n$f = create_function('', base64_decode($p));",
+			scores: map[Signal]int{CreateFunction: 20, DecodedEval: 40},
+		},
+		{
+			name:   "long literals",
+			source: "<?php\n$a = " + payload + ";\n$b = " + payload + ";\n$c = " + payload + ";\n$d = " + payload + ";\n$e = '" + strings.Repeat("a b ", 300) + "';",
+			scores: map[Signal]int{LongString: 30},
+		},
+		{
+			name:   "high-entropy and confusable identifiers",
+			source: "<?php\n$x9kQz7Wp = 1;\necho $x9kQz7Wp;\nfunction _0x3f2a() {}\n$O0O0O0 = 2;\n$userName2 = 3;",
+			scores: map[Signal]int{ObfuscatedIdentifier: 15},
+		},
+		{
+			name:   "stealth calls",
+			source: "<?php\nerror_reporting(0);\nset_time_limit(0);\nini_set('display_errors', 0);\nerror_reporting(E_ALL);",
+			scores: map[Signal]int{Stealth: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			report := s.Scan()[0]
+			scores := make(map[Signal]int)
+			total := 0
+			for _, e := range report.Evidence {
+				scores[e.Signal] += e.Score
+				total += e.Score
+			}
+			if !maps.Equal(scores, tt.scores) {
+				t.Errorf("scores = %v, want %v", scores, tt.scores)
+			}
+			if report.Score != total {
+				t.Errorf("score = %d, want the total of the evidence %d", report.Score, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package wordpress
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type dispatchJSON struct {
+	Hook     string   `json:"hook"`
+	Kind     HookKind `json:"kind"`
+	Function string   `json:"function"`
+	Caller   string   `json:"caller,omitempty"`
+	File     string   `json:"file"`
+	Line     uint     `json:"line"`
+	Handlers []string `json:"handlers"`
+}
+
+// MarshalJSON exports the dispatch with the names of its handlers
+func (d *Dispatch) MarshalJSON() ([]byte, error) {
+	out := dispatchJSON{
+		Hook:     d.Hook,
+		Kind:     d.Kind,
+		Function: d.Function,
+		Caller:   d.Caller,
+		File:     d.File,
+		Line:     d.Line,
+		Handlers: []string{},
+	}
+	for _, r := range d.Handlers {
+		out.Handlers = append(out.Handlers, r.Callback.Name)
+	}
+	return json.Marshal(out)
+}
+
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	edges := g.Edges
+	if edges == nil {
+		edges = []*Edge{}
+	}
+	return json.Marshal(struct {
+		Hooks []*Hook `json:"hooks"`
+		Edges []*Edge `json:"edges"`
+	}{g.Hooks(), edges})
+}
+
+// DOT exports the hook graph in the Graphviz format: hooks are ellipses, functions boxes and files
+// notes. Handler edges are labelled with their priority, partial name matches are dashed and
+// direct calls are dotted.
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph hooks {\n  rankdir=LR;\n")
+	declared := make(map[string]bool)
+	for _, e := range g.Edges {
+		for _, v := range []Vertex{e.From, e.To} {
+			if declared[v.key()] {
+				continue
+			}
+			declared[v.key()] = true
+			shape := "box"
+			switch v.Kind {
+			case HookVertex:
+				shape = "ellipse"
+			case FileVertex:
+				shape = "note"
+			}
+			fmt.Fprintf(&sb, "  %q [shape=%s];\n", v.String(), shape)
+		}
+	}
+	for _, e := range g.Edges {
+		attributes := ""
+		switch e.Kind {
+		case Handles:
+			attributes = fmt.Sprintf(" [label=%q]", fmt.Sprint(e.Priority))
+		case Matches:
+			attributes = " [style=dashed]"
+		case Calls:
+			attributes = " [style=dotted]"
+		}
+		fmt.Fprintf(&sb, "  %q -> %q%s;\n", e.From.String(), e.To.String(), attributes)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,435 @@
+package wordpress
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/classes"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/types"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+type VertexKind string
+
+const (
+	HookVertex VertexKind = "hook"
+	// FunctionVertex is a function, method or closure
+	FunctionVertex VertexKind = "function"
+	// FileVertex is the top level code of a file
+	FileVertex VertexKind = "file"
+)
+
+// Vertex is a node of the hook graph
+type Vertex struct {
+	Kind VertexKind `json:"kind"`
+	Name string     `json:"name"`
+}
+
+// key identifies a vertex. Function and method names are case-insensitive, hook names are not.
+func (v Vertex) key() string {
+	if v.Kind == FunctionVertex {
+		return string(v.Kind) + ":" + strings.ToLower(v.Name)
+	}
+	return string(v.Kind) + ":" + v.Name
+}
+
+func (v Vertex) String() string {
+	if v.Kind == FunctionVertex {
+		return v.Name
+	}
+	return string(v.Kind) + ":" + v.Name
+}
+
+type EdgeKind string
+
+const (
+	// Dispatches links a function to the hooks it runs
+	Dispatches EdgeKind = "dispatches"
+	// Handles links a hook to its handlers
+	Handles EdgeKind = "handles"
+	// Matches links a dispatched hook to a registered hook whose name may be the same, when one of
+	// the names is only partially known
+	Matches EdgeKind = "matches"
+	// Calls links a function to the functions and methods it calls directly
+	Calls EdgeKind = "calls"
+)
+
+// Edge is a link of the hook graph, located at the dispatch, registration or call it comes from
+type Edge struct {
+	From Vertex   `json:"from"`
+	To   Vertex   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	File string   `json:"file"`
+	Line uint     `json:"line"`
+	// Priority is the priority of the handler of a Handles edge
+	Priority int `json:"priority,omitempty"`
+}
+
+// Hook is a hook name with its registrations and dispatches
+type Hook struct {
+	Name          string          `json:"name"`
+	Registrations []*Registration `json:"registrations"`
+	Dispatches    []*Dispatch     `json:"dispatches"`
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Graph is the hook graph of a WordPress project: it connects the do_action and apply_filters
+// dispatches to the handlers registered with add_action and add_filter, and the functions to
+// the hooks they dispatch and the functions they call
+type Graph struct {
+	Registrations []*Registration
+	Dispatches    []*Dispatch
+	Edges         []*Edge
+	files         []*file
+	evaluator     *values.Evaluator
+	inferer       *types.Inferer
+	// functions maps the lowercase names of the functions of the project to their declaration
+	functions map[string]*ast.Node
+	vertices  map[string]Vertex
+	out       map[string][]*Edge
+	in        map[string][]*Edge
+}
+
+func New() *Graph {
+	return &Graph{
+		evaluator: values.New(),
+		inferer:   types.New(),
+		functions: make(map[string]*ast.Node),
+	}
+}
+
+// AddFile adds a file to the project
+func (g *Graph) AddFile(path string, root *ast.Node) {
+	root.SetParents()
+	g.files = append(g.files, &file{path: path, root: root})
+	g.evaluator.AddFile(path, root)
+	g.inferer.AddFile(path, root)
+	v := &ast.VisitorKinds{Kinds: map[string]bool{"function_definition": true}}
+	root.WalkPrefix(v)
+	for _, fn := range v.Nodes {
+		if name := fn.ChildOfKind("name"); name != nil {
+			g.functions[strings.ToLower(name.Text)] = fn
+		}
+	}
+}
+
+// Build collects the registrations, dispatches and calls of the files and connects them
+func (g *Graph) Build() {
+	g.evaluator.Resolve()
+	g.inferer.Resolve()
+	g.Registrations, g.Dispatches, g.Edges = nil, nil, nil
+	g.vertices = make(map[string]Vertex)
+	g.out = make(map[string][]*Edge)
+	g.in = make(map[string][]*Edge)
+	var calls []*Edge
+	for _, f := range g.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{
+			"function_call_expression": true, "member_call_expression": true,
+			"nullsafe_member_call_expression": true, "scoped_call_expression": true, "object_creation_expression": true,
+		}}
+		f.root.WalkPrefix(v)
+		for _, call := range v.Nodes {
+			function := functionName(call)
+			if kind, ok := registrationFunctions[function]; ok {
+				g.addRegistration(f, call, kind, function)
+			} else if kind, ok := dispatchFunctions[function]; ok {
+				g.addDispatch(f, call, kind, function)
+			}
+			calls = append(calls, g.calls(f, call)...)
+		}
+	}
+
+	registered := make(map[string][]*Registration)
+	var names []string
+	for _, r := range g.Registrations {
+		if _, ok := registered[r.Hook]; !ok {
+			names = append(names, r.Hook)
+		}
+		registered[r.Hook] = append(registered[r.Hook], r)
+		g.addEdge(&Edge{
+			From:     Vertex{HookVertex, r.Hook},
+			To:       g.callbackVertex(r),
+			Kind:     Handles,
+			File:     r.File,
+			Line:     r.Line,
+			Priority: r.Priority,
+		})
+	}
+	for _, d := range g.Dispatches {
+		g.addEdge(&Edge{From: g.callerVertex(d), To: Vertex{HookVertex, d.Hook}, Kind: Dispatches, File: d.File, Line: d.Line})
+		for _, name := range names {
+			if !Match(d.Hook, name) {
+				continue
+			}
+			d.Handlers = append(d.Handlers, registered[name]...)
+			if name != d.Hook {
+				g.addEdge(&Edge{From: Vertex{HookVertex, d.Hook}, To: Vertex{HookVertex, name}, Kind: Matches, File: d.File, Line: d.Line})
+			}
+		}
+		sort.SliceStable(d.Handlers, func(i, j int) bool {
+			return d.Handlers[i].Priority < d.Handlers[j].Priority
+		})
+	}
+	for _, e := range calls {
+		g.addEdge(e)
+	}
+}
+
+func (g *Graph) addRegistration(f *file, call *ast.Node, kind HookKind, function string) {
+	arguments := call.Arguments()
+	if len(arguments) < 2 {
+		return
+	}
+	priority, acceptedArgs := 10, 1
+	if len(arguments) > 2 {
+		priority = integer(g.evaluator, arguments[2], priority)
+	}
+	if len(arguments) > 3 {
+		acceptedArgs = integer(g.evaluator, arguments[3], acceptedArgs)
+	}
+	callback := g.resolveCallback(f.path, arguments[1])
+	for _, hook := range hookNames(g.evaluator, arguments[0]) {
+		g.Registrations = append(g.Registrations, &Registration{
+			Hook:         hook,
+			Kind:         kind,
+			Callback:     callback,
+			Priority:     priority,
+			AcceptedArgs: acceptedArgs,
+			File:         f.path,
+			Line:         call.StartPosition.Row + 1,
+			Node:         call,
+		})
+	}
+}
+
+func (g *Graph) addDispatch(f *file, call *ast.Node, kind HookKind, function string) {
+	arguments := call.Arguments()
+	if len(arguments) == 0 {
+		return
+	}
+	caller := ""
+	if fn := scope.EnclosingFunction(call); fn != nil {
+		caller = g.functionVertex(f, fn).Name
+	}
+	for _, hook := range hookNames(g.evaluator, arguments[0]) {
+		g.Dispatches = append(g.Dispatches, &Dispatch{
+			Hook:     hook,
+			Kind:     kind,
+			Function: function,
+			Caller:   caller,
+			File:     f.path,
+			Line:     call.StartPosition.Row + 1,
+			Node:     call,
+		})
+	}
+}
+
+// calls returns the call edges of a call to a function, method or constructor of the project
+func (g *Graph) calls(f *file, call *ast.Node) []*Edge {
+	var targets []Vertex
+	switch call.Kind {
+	case "function_call_expression":
+		if fn, ok := g.functions[functionName(call)]; ok {
+			targets = append(targets, Vertex{FunctionVertex, fn.ChildOfKind("name").Text})
+		}
+	case "object_creation_expression":
+		if callback := g.method(g.className(call), "__construct"); callback != nil && callback.Declaration != nil {
+			targets = append(targets, Vertex{FunctionVertex, callback.Name})
+		}
+	default:
+		methods := g.inferer.ResolveCall(call)
+		if len(methods) == 0 {
+			methods = g.inferer.Hierarchy().ResolveCall(call)
+		}
+		for _, m := range methods {
+			targets = append(targets, Vertex{FunctionVertex, m.Class.Name + "::" + m.Name})
+		}
+	}
+	from := Vertex{FileVertex, f.path}
+	if fn := scope.EnclosingFunction(call); fn != nil {
+		from = g.functionVertex(f, fn)
+	}
+	var edges []*Edge
+	for _, to := range targets {
+		edges = append(edges, &Edge{From: from, To: to, Kind: Calls, File: f.path, Line: call.StartPosition.Row + 1})
+	}
+	return edges
+}
+
+// functionVertex returns the vertex of a function, method or closure declaration
+func (g *Graph) functionVertex(f *file, fn *ast.Node) Vertex {
+	name := fn.ChildOfKind("name")
+	switch {
+	case fn.Kind == "method_declaration" && name != nil:
+		if c := g.inferer.Hierarchy().EnclosingClass(fn); c != nil {
+			return Vertex{FunctionVertex, c.Name + "::" + name.Text}
+		}
+	case fn.Kind == "function_definition" && name != nil:
+		return Vertex{FunctionVertex, name.Text}
+	}
+	return Vertex{FunctionVertex, closureName(f.path, fn)}
+}
+
+func (g *Graph) callerVertex(d *Dispatch) Vertex {
+	if d.Caller == "" {
+		return Vertex{FileVertex, d.File}
+	}
+	return Vertex{FunctionVertex, d.Caller}
+}
+
+// callbackVertex returns the vertex of the handler of a registration. Unresolved callbacks are
+// distinguished by their location.
+func (g *Graph) callbackVertex(r *Registration) Vertex {
+	if r.Callback.Kind == UnknownCallback {
+		return Vertex{FunctionVertex, r.Callback.Name + "@" + r.File + ":" + strconv.Itoa(int(r.Line))}
+	}
+	return Vertex{FunctionVertex, r.Callback.Name}
+}
+
+func (g *Graph) addEdge(e *Edge) {
+	for _, v := range []Vertex{e.From, e.To} {
+		if _, ok := g.vertices[v.key()]; !ok {
+			g.vertices[v.key()] = v
+		}
+	}
+	g.Edges = append(g.Edges, e)
+	g.out[e.From.key()] = append(g.out[e.From.key()], e)
+	g.in[e.To.key()] = append(g.in[e.To.key()], e)
+}
+
+// Hooks returns the hooks registered or dispatched, ordered by name
+func (g *Graph) Hooks() []*Hook {
+	hooks := make(map[string]*Hook)
+	get := func(name string) *Hook {
+		h, ok := hooks[name]
+		if !ok {
+			h = &Hook{Name: name, Registrations: []*Registration{}, Dispatches: []*Dispatch{}}
+			hooks[name] = h
+		}
+		return h
+	}
+	for _, r := range g.Registrations {
+		get(r.Hook).Registrations = append(get(r.Hook).Registrations, r)
+	}
+	for _, d := range g.Dispatches {
+		get(d.Hook).Dispatches = append(get(d.Hook).Dispatches, d)
+	}
+	var result []*Hook
+	for _, h := range hooks {
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Match reports whether a dispatched hook name may be a registered one. Wildcards stand for any
+// string, but a name that is entirely unknown matches nothing, which would connect every hook.
+func Match(dispatched, registered string) bool {
+	if dispatched == registered {
+		return dispatched != Wildcard
+	}
+	if dispatched == Wildcard || registered == Wildcard {
+		return false
+	}
+	dynamicDispatch, dynamicRegistration := strings.Contains(dispatched, Wildcard), strings.Contains(registered, Wildcard)
+	switch {
+	case !dynamicDispatch && !dynamicRegistration:
+		return false
+	case !dynamicRegistration:
+		return pattern(dispatched).MatchString(registered)
+	case !dynamicDispatch:
+		return pattern(registered).MatchString(dispatched)
+	}
+	// Both names are partial: their known prefixes and suffixes must be compatible
+	prefix := func(s string) string { return s[:strings.Index(s, Wildcard)] }
+	suffix := func(s string) string { return s[strings.LastIndex(s, Wildcard)+1:] }
+	compatible := func(a, b string, has func(string, string) bool) bool { return has(a, b) || has(b, a) }
+	return compatible(prefix(dispatched), prefix(registered), strings.HasPrefix) &&
+		compatible(suffix(dispatched), suffix(registered), strings.HasSuffix)
+}
+
+func pattern(name string) *regexp.Regexp {
+	parts := strings.Split(name, Wildcard)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "(?s:.*)") + "$")
+}
+
+// Vertex returns the vertex of a query: hook:<name> for a hook, file:<path> for the top level
+// code of a file, otherwise a function, Class::method or closure name
+func (g *Graph) Vertex(query string) (Vertex, bool) {
+	v := Vertex{FunctionVertex, query}
+	if name, ok := strings.CutPrefix(query, "hook:"); ok {
+		v = Vertex{HookVertex, name}
+	} else if path, ok := strings.CutPrefix(query, "file:"); ok {
+		v = Vertex{FileVertex, path}
+	}
+	found, ok := g.vertices[v.key()]
+	return found, ok
+}
+
+// Step is a vertex reached by a reachability query, with the edge it was first reached through
+type Step struct {
+	Vertex Vertex `json:"vertex"`
+	Edge   *Edge  `json:"edge"`
+	Depth  int    `json:"depth"`
+}
+
+// Reachable returns the vertices reachable from a vertex, in breadth-first order: the hooks it
+// may dispatch, their handlers and the functions they call, transitively
+func (g *Graph) Reachable(from Vertex) []*Step {
+	return g.search(from, g.out, func(e *Edge) Vertex { return e.To })
+}
+
+// Reaching returns the vertices from which a vertex is reachable, in breadth-first order
+func (g *Graph) Reaching(to Vertex) []*Step {
+	return g.search(to, g.in, func(e *Edge) Vertex { return e.From })
+}
+
+func (g *Graph) search(start Vertex, edges map[string][]*Edge, next func(*Edge) Vertex) []*Step {
+	visited := map[string]bool{start.key(): true}
+	var steps []*Step
+	queue := []*Step{{Vertex: start}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range edges[current.Vertex.key()] {
+			v := next(e)
+			if visited[v.key()] {
+				continue
+			}
+			visited[v.key()] = true
+			step := &Step{Vertex: g.vertices[v.key()], Edge: e, Depth: current.Depth + 1}
+			steps = append(steps, step)
+			queue = append(queue, step)
+		}
+	}
+	return steps
+}
+
+// Handlers returns the registrations of the handlers that may run on a hook, in registration order
+func (g *Graph) Handlers(hook string) []*Registration {
+	var result []*Registration
+	for _, r := range g.Registrations {
+		if Match(hook, r.Hook) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Hierarchy returns the class hierarchy of the project
+func (g *Graph) Hierarchy() *classes.Hierarchy {
+	return g.inferer.Hierarchy()
+}
@@ -0,0 +1,187 @@
+package wordpress
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// build returns the hook graph of a source as the only file of a project
+func build(source string) *Graph {
+	g := New()
+	g.AddFile("test.php", ast.ParseSource([]byte(source)))
+	g.Build()
+	return g
+}
+
+func TestCallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		kind     CallbackKind
+		callback string
+	}{
+		{
+			name:     "function name",
+			source:   "<?php\nfunction My_Init() {}\nadd_action('init', 'my_init');\n",
+			kind:     FunctionCallback,
+			callback: "My_Init",
+		},
+		{
+			name:     "builtin function name",
+			source:   "<?php\nadd_filter('the_title', 'trim');\n",
+			kind:     FunctionCallback,
+			callback: "trim",
+		},
+		{
+			name:     "static method string",
+			source:   "<?php\nclass Plugin { static function boot() {} }\nadd_action('init', 'Plugin::boot');\n",
+			kind:     MethodCallback,
+			callback: "Plugin::boot",
+		},
+		{
+			name:     "array of $this and a method",
+			source:   "<?php\nclass Plugin {\nfunction __construct() { add_action('init', [$this, 'setup']); }\nfunction setup() {}\n}\n",
+			kind:     MethodCallback,
+			callback: "Plugin::setup",
+		},
+		{
+			name:     "array of a class name and an inherited method",
+			source:   "<?php\nclass Base { static function run() {} }\nclass Child extends Base {}\nadd_action('init', array('Child', 'run'));\n",
+			kind:     MethodCallback,
+			callback: "Base::run",
+		},
+		{
+			name:     "array of a typed object",
+			source:   "<?php\nclass Admin { function menu() {} }\n$admin = new Admin();\nadd_action('admin_menu', [$admin, 'menu']);\n",
+			kind:     MethodCallback,
+			callback: "Admin::menu",
+		},
+		{
+			name:     "closure",
+			source:   "<?php\nadd_action('init',\nfunction () {});\n",
+			kind:     ClosureCallback,
+			callback: "{closure}@test.php:3",
+		},
+		{
+			name:     "arrow function",
+			source:   "<?php\nadd_filter('the_title', fn($t) => $t);\n",
+			kind:     ClosureCallback,
+			callback: "{closure}@test.php:2",
+		},
+		{
+			name:     "variable callback",
+			source:   "<?php\nadd_action('init', $callback);\n",
+			kind:     UnknownCallback,
+			callback: "$callback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := build(tt.source)
+			if len(g.Registrations) != 1 {
+				t.Fatalf("%d registrations, want 1", len(g.Registrations))
+			}
+			callback := g.Registrations[0].Callback
+			if callback.Kind != tt.kind || callback.Name != tt.callback {
+				t.Errorf("callback = %s %s, want %s %s", callback.Kind, callback.Name, tt.kind, tt.callback)
+			}
+		})
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	g := build(`<?php
+const LATE = 20;
+add_action('init', 'a');
+add_action('init', 'b', 5);
+add_action('init', 'c', LATE, 2);
+add_action('init', 'd', PHP_INT_MAX);
+add_action('init', 'e', 5);
+do_action('init');
+`)
+	var got []string
+	for _, r := range g.Dispatches[0].Handlers {
+		got = append(got, r.Callback.Name)
+	}
+	if want := []string{"b", "e", "a", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("handlers = %v, want %v", got, want)
+	}
+	if c := g.Registrations[2]; c.Priority != 20 || c.AcceptedArgs != 2 {
+		t.Errorf("priority, accepted args = %d, %d, want 20, 2", c.Priority, c.AcceptedArgs)
+	}
+}
+
+func TestDynamicHooks(t *testing.T) {
+	g := build(`<?php
+function save($type) {
+	do_action("save_{$type}", $type);
+}
+add_action('save_post', 'on_post');
+add_action('save_page', 'on_page');
+add_action('delete_post', 'on_delete');
+foreach ($types as $type) {
+	add_action('render_' . $type, 'on_render');
+}
+do_action('render_widget');
+`)
+	tests := []struct {
+		hook     string
+		handlers []string
+	}{
+		{hook: "save_*", handlers: []string{"on_post", "on_page"}},
+		{hook: "render_widget", handlers: []string{"on_render"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hook, func(t *testing.T) {
+			var d *Dispatch
+			for _, dispatch := range g.Dispatches {
+				if dispatch.Hook == tt.hook {
+					d = dispatch
+				}
+			}
+			if d == nil {
+				t.Fatalf("no dispatch of %s", tt.hook)
+			}
+			var got []string
+			for _, r := range d.Handlers {
+				got = append(got, r.Callback.Name)
+			}
+			if !slices.Equal(got, tt.handlers) {
+				t.Errorf("handlers = %v, want %v", got, tt.handlers)
+			}
+		})
+	}
+	save, _ := g.Vertex("save")
+	var reached []string
+	for _, step := range g.Reachable(save) {
+		reached = append(reached, step.Vertex.String())
+	}
+	if want := []string{"hook:save_*", "hook:save_post", "hook:save_page", "on_post", "on_page"}; !slices.Equal(reached, want) {
+		t.Errorf("Reachable(save) = %v, want %v", reached, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		dispatched, registered string
+		want                   bool
+	}{
+		{dispatched: "init", registered: "init", want: true},
+		{dispatched: "init", registered: "wp_loaded", want: false},
+		{dispatched: "save_*", registered: "save_post", want: true},
+		{dispatched: "save_post", registered: "*_post", want: true},
+		{dispatched: "save_*", registered: "delete_post", want: false},
+		{dispatched: "save_*_meta", registered: "save_post_*", want: true},
+		{dispatched: "save_*", registered: "*_meta", want: true},
+		{dispatched: "save_*", registered: "delete_*", want: false},
+		{dispatched: "*", registered: "init", want: false},
+		{dispatched: "*", registered: "*", want: false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.dispatched, tt.registered); got != tt.want {
+			t.Errorf("Match(%q, %q) = %t, want %t", tt.dispatched, tt.registered, got, tt.want)
+		}
+	}
+}
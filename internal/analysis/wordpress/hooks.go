@@ -0,0 +1,239 @@
+package wordpress
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/values"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// HookKind is action or filter. WordPress stores both in the same table, so that a handler added
+// with add_filter runs on do_action and conversely.
+type HookKind string
+
+const (
+	Action HookKind = "action"
+	Filter HookKind = "filter"
+)
+
+// registrationFunctions maps the functions registering hook handlers to the kind of their hook
+var registrationFunctions = map[string]HookKind{
+	"add_action": Action,
+	"add_filter": Filter,
+}
+
+// dispatchFunctions maps the functions running the handlers of a hook to the kind of their hook
+var dispatchFunctions = map[string]HookKind{
+	"do_action":                Action,
+	"do_action_ref_array":      Action,
+	"do_action_deprecated":     Action,
+	"apply_filters":            Filter,
+	"apply_filters_ref_array":  Filter,
+	"apply_filters_deprecated": Filter,
+}
+
+// Wildcard stands for the parts of a hook name that cannot be evaluated statically
+const Wildcard = "*"
+
+type CallbackKind string
+
+const (
+	FunctionCallback CallbackKind = "function"
+	MethodCallback   CallbackKind = "method"
+	ClosureCallback  CallbackKind = "closure"
+	// UnknownCallback is a callback that cannot be resolved statically, such as a variable
+	UnknownCallback CallbackKind = "unknown"
+)
+
+// Callback is the handler of a hook registration
+type Callback struct {
+	Kind CallbackKind `json:"kind"`
+	// Name is the function name, Class::method, {closure}@file:line, or the callback expression
+	// when it cannot be resolved
+	Name string `json:"name"`
+	// Declaration is the function, method or closure node, nil when it is not part of the project
+	Declaration *ast.Node `json:"-"`
+}
+
+// Registration is a call to add_action or add_filter
+type Registration struct {
+	// Hook is the hook name, with Wildcard standing for the unknown parts
+	Hook     string    `json:"hook"`
+	Kind     HookKind  `json:"kind"`
+	Callback *Callback `json:"callback"`
+	// Priority is the order of the handler among the handlers of the hook, 10 by default
+	Priority     int       `json:"priority"`
+	AcceptedArgs int       `json:"accepted_args"`
+	File         string    `json:"file"`
+	Line         uint      `json:"line"`
+	Node         *ast.Node `json:"-"`
+}
+
+// Dispatch is a call to do_action, apply_filters or one of their variants
+type Dispatch struct {
+	// Hook is the hook name, with Wildcard standing for the unknown parts
+	Hook     string   `json:"hook"`
+	Kind     HookKind `json:"kind"`
+	Function string   `json:"function"`
+	// Caller is the function, method or closure containing the dispatch, empty at the top level of a file
+	Caller string    `json:"caller,omitempty"`
+	File   string    `json:"file"`
+	Line   uint      `json:"line"`
+	Node   *ast.Node `json:"-"`
+	// Handlers are the registrations of the hook, ordered by priority then registration order
+	Handlers []*Registration `json:"-"`
+}
+
+// functionName returns the lowercase name of the function called by a call expression, without namespace
+func functionName(call *ast.Node) string {
+	if call.Kind != "function_call_expression" {
+		return ""
+	}
+	name := call.ChildOfKind("name", "qualified_name")
+	if name == nil {
+		return ""
+	}
+	return strings.ToLower(name.Text[strings.LastIndex(name.Text, "\\")+1:])
+}
+
+// hookNames evaluates a hook name argument. Each alternative value is a name, with Wildcard
+// standing for the unknown parts.
+func hookNames(evaluator *values.Evaluator, n *ast.Node) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, alternative := range evaluator.Eval(n).Alternatives() {
+		var sb strings.Builder
+		for _, part := range alternative {
+			switch {
+			case !part.Unknown:
+				sb.WriteString(part.Literal)
+			case !strings.HasSuffix(sb.String(), Wildcard):
+				sb.WriteString(Wildcard)
+			}
+		}
+		if name := sb.String(); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{Wildcard}
+	}
+	return names
+}
+
+// integer evaluates an integer argument such as a priority, or returns the default value
+func integer(evaluator *values.Evaluator, n *ast.Node, value int) int {
+	switch strings.TrimPrefix(n.Text, "\\") {
+	case "PHP_INT_MAX":
+		return math.MaxInt
+	case "PHP_INT_MIN":
+		return math.MinInt
+	}
+	if constant, ok := evaluator.Eval(n).Constant(); ok {
+		if i, err := strconv.Atoi(constant); err == nil {
+			return i
+		}
+	}
+	return value
+}
+
+// closureName names a closure by its location
+func closureName(file string, closure *ast.Node) string {
+	return "{closure}@" + file + ":" + strconv.Itoa(int(closure.StartPosition.Row+1))
+}
+
+// resolveCallback resolves the callback argument of a registration: a function name, a
+// Class::method string, an array of an object or class and a method name, or a closure
+func (g *Graph) resolveCallback(file string, n *ast.Node) *Callback {
+	switch n.Kind {
+	case "anonymous_function", "arrow_function":
+		return &Callback{Kind: ClosureCallback, Name: closureName(file, n), Declaration: n}
+	case "array_creation_expression":
+		elements := n.NamedChildren()
+		if len(elements) != 2 {
+			break
+		}
+		object, method := elements[0].NamedChildren(), elements[1].NamedChildren()
+		if len(object) != 1 || len(method) != 1 {
+			break
+		}
+		name, ok := g.evaluator.Eval(method[0]).Constant()
+		if !ok {
+			break
+		}
+		if callback := g.method(g.className(object[0]), name); callback != nil {
+			return callback
+		}
+	default:
+		if name, ok := g.evaluator.Eval(n).Constant(); ok && name != "" {
+			name = strings.TrimPrefix(name, "\\")
+			if class, method, static := strings.Cut(name, "::"); static {
+				if callback := g.method(class, method); callback != nil {
+					return callback
+				}
+				break
+			}
+			callback := &Callback{Kind: FunctionCallback, Name: name}
+			if declaration, ok := g.functions[strings.ToLower(name)]; ok {
+				callback.Declaration = declaration
+				callback.Name = declaration.ChildOfKind("name").Text
+			}
+			return callback
+		}
+	}
+	return &Callback{Kind: UnknownCallback, Name: n.Text}
+}
+
+// className returns the class of the object or class name of an array callback, empty if unknown
+func (g *Graph) className(n *ast.Node) string {
+	hierarchy := g.inferer.Hierarchy()
+	switch {
+	case n.Kind == "variable_name" && n.Text == "$this", n.Kind == "name" && n.Text == "__CLASS__":
+		if c := hierarchy.EnclosingClass(n); c != nil {
+			return c.Name
+		}
+		return ""
+	case n.Kind == "variable_name", n.Kind == "object_creation_expression", n.Kind == "member_call_expression",
+		n.Kind == "function_call_expression", n.Kind == "scoped_call_expression", n.Kind == "member_access_expression":
+		if classes := g.inferer.TypeOf(n).Classes(); len(classes) > 0 {
+			return classes[0]
+		}
+		return ""
+	}
+	name, ok := g.evaluator.Eval(n).Constant()
+	if !ok {
+		return ""
+	}
+	if ns := hierarchy.Namespace(n); ns != nil && hierarchy.Lookup(name) == nil {
+		if resolved := ns.Resolve(name); hierarchy.Lookup(resolved) != nil {
+			return resolved
+		}
+	}
+	return strings.TrimPrefix(name, "\\")
+}
+
+// method returns the callback of a method, named after the class declaring it. The method is
+// looked up in the class by name, then in the classes with the same short name.
+func (g *Graph) method(class, name string) *Callback {
+	if class == "" || name == "" {
+		return nil
+	}
+	hierarchy := g.inferer.Hierarchy()
+	candidates := hierarchy.LookupShort(class[strings.LastIndex(class, "\\")+1:])
+	if c := hierarchy.Lookup(class); c != nil {
+		candidates = append(candidates[:0:0], c)
+	}
+	for _, c := range candidates {
+		if m := c.Method(name); m != nil {
+			return &Callback{
+				Kind:        MethodCallback,
+				Name:        m.Declaration.Class.Name + "::" + m.Declaration.Name,
+				Declaration: m.Declaration.Node,
+			}
+		}
+	}
+	return &Callback{Kind: MethodCallback, Name: class + "::" + name}
+}
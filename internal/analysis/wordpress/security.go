@@ -0,0 +1,419 @@
+package wordpress
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/sqlquery"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a security finding
+type Category string
+
+const (
+	// MissingNonce is an AJAX or admin-post handler that does not verify a nonce
+	MissingNonce Category = "missing-nonce"
+	// MissingCapability is an AJAX or admin-post handler for logged-in users that does not check
+	// their capabilities
+	MissingCapability Category = "missing-capability"
+	// UnpreparedQuery is a $wpdb query built from request data or interpolated values without prepare
+	UnpreparedQuery Category = "unprepared-query"
+	// UnescapedOutput is request data printed without escaping
+	UnescapedOutput Category = "unescaped-output"
+	// OptionInjection is an option updated with a name or value coming from the request
+	OptionInjection Category = "option-injection"
+)
+
+// Categories are the categories of the security checks, in report order
+var Categories = []Category{MissingNonce, MissingCapability, UnpreparedQuery, UnescapedOutput, OptionInjection}
+
+// rules are the kind trees of the calls and statements the checks look at, see security.kt.json
+//
+//go:embed security.kt.json
+var rules []byte
+
+// handlerPrefixes are the prefixes of the hooks running a handler on a request to admin-ajax.php
+// or admin-post.php, and of their variants for visitors who are not logged in
+var handlerPrefixes = []string{"wp_ajax_", "admin_post_"}
+
+var publicPrefixes = []string{"wp_ajax_nopriv_", "admin_post_nopriv_"}
+
+// maxCallDepth bounds the calls followed from a handler to find its checks
+const maxCallDepth = 3
+
+// querySanitizers are the sanitizers that make request data safe in a query
+var querySanitizers = []string{"prepare", "esc_sql", "esc_like", "sanitize_key", "sanitize_sql_orderby"}
+
+// outputEscapers are the sanitizers that make request data safe in HTML output
+var outputEscapers = []string{
+	"esc_html", "esc_attr", "esc_url", "esc_js", "esc_textarea", "esc_xml", "esc_html__", "esc_attr__",
+	"wp_kses", "wp_kses_post", "wp_kses_data", "htmlspecialchars", "htmlentities", "sanitize_key",
+	"sanitize_html_class", "sanitize_title", "sanitize_email", "urlencode", "rawurlencode",
+}
+
+// optionSanitizers are the sanitizers that restrict request data to a safe option value
+var optionSanitizers = []string{
+	"sanitize_text_field", "sanitize_textarea_field", "sanitize_email", "sanitize_key", "sanitize_title",
+	"sanitize_file_name", "sanitize_user", "sanitize_url", "sanitize_option", "sanitize_html_class",
+	"sanitize_mime_type", "esc_url_raw", "wp_kses", "wp_kses_post", "wp_kses_data", "wp_strip_all_tags",
+}
+
+// Finding is a security problem of a WordPress project
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	File     string         `json:"file"`
+	Line     uint           `json:"line"`
+	Message  string         `json:"message"`
+	// Hook is the hook of the handler of the missing-nonce and missing-capability findings
+	Hook string `json:"hook,omitempty"`
+	// Taint is the flow of request data into the sink
+	Taint *taint.Taint `json:"taint,omitempty"`
+	Node  *ast.Node    `json:"-"`
+}
+
+// Checker runs the WordPress security checks: the kind trees of security.kt.json find the sinks
+// and checks, the hook graph the AJAX and admin-post handlers, and the taint analysis the request
+// data reaching the sinks
+type Checker struct {
+	graph   *Graph
+	taint   *taint.Analyzer
+	queries *sqlquery.Analyzer
+	files   []*file
+}
+
+func NewChecker() *Checker {
+	return &Checker{graph: New(), taint: taint.New(), queries: sqlquery.New()}
+}
+
+// AddFile adds a file to the project
+func (c *Checker) AddFile(path string, root *ast.Node) {
+	c.files = append(c.files, &file{path: path, root: root})
+	c.graph.AddFile(path, root)
+	c.taint.AddFile(path, root)
+	c.queries.AddFile(path, root)
+}
+
+// Check runs the checks on all the files and returns the findings, ordered by file and line
+func (c *Checker) Check() ([]*Finding, error) {
+	var kindTrees map[string]ast.KindTree
+	if err := json.Unmarshal(rules, &kindTrees); err != nil {
+		return nil, err
+	}
+	c.graph.Build()
+	c.taint.Resolve()
+	queries := make(map[*ast.Node]*sqlquery.Query)
+	for _, q := range c.queries.Analyze() {
+		queries[q.Node] = q
+	}
+	matches := &ast.VisitorFinds{KindTrees: kindTrees, Nodes: make(map[string][]*ast.Node)}
+	for _, f := range c.files {
+		f.root.WalkPrefix(matches)
+	}
+
+	var findings []*Finding
+	findings = append(findings, c.checkHandlers(matches.Nodes)...)
+	for _, call := range matches.Nodes["wpdb-query"] {
+		if finding := c.checkQuery(call, queries[call]); finding != nil {
+			findings = append(findings, finding)
+		}
+	}
+	for _, name := range []string{"echo", "print", "printf"} {
+		for _, n := range matches.Nodes[name] {
+			findings = append(findings, c.checkOutput(n)...)
+		}
+	}
+	for _, call := range matches.Nodes["option-update"] {
+		findings = append(findings, c.checkOption(call)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// handler is a function registered on the AJAX or admin-post hooks
+type handler struct {
+	declaration *ast.Node
+	name        string
+	hook        string
+	// registration is the first registration of the handler, on which it is reported
+	registration *Registration
+	// public is set when the handler is also registered for visitors who are not logged in
+	public  bool
+	private bool
+}
+
+// checkHandlers reports the AJAX and admin-post handlers that do not verify a nonce or the
+// capabilities of the user, in their body or in the functions they call
+func (c *Checker) checkHandlers(matches map[string][]*ast.Node) []*Finding {
+	var handlers []*handler
+	index := make(map[*ast.Node]*handler)
+	for _, r := range c.graph.Registrations {
+		public := hasPrefix(r.Hook, publicPrefixes)
+		if r.Callback.Declaration == nil || !public && !hasPrefix(r.Hook, handlerPrefixes) {
+			continue
+		}
+		h, ok := index[r.Callback.Declaration]
+		if !ok {
+			h = &handler{declaration: r.Callback.Declaration, name: r.Callback.Name, hook: r.Hook, registration: r}
+			index[h.declaration] = h
+			handlers = append(handlers, h)
+		}
+		h.public = h.public || public
+		h.private = h.private || !public
+	}
+
+	var findings []*Finding
+	for _, h := range handlers {
+		reach := c.reach(h.declaration)
+		nonce := c.guarded(matches["nonce-check"], reach)
+		capability := c.guarded(matches["capability-check"], reach)
+		changesState := c.within(matches["option-update"], reach) || c.within(matches["wpdb-query"], reach)
+		r := h.registration
+		if !nonce {
+			severity := taint.Medium
+			switch {
+			case !h.private:
+				severity = taint.Low
+			case changesState:
+				severity = taint.High
+			}
+			findings = append(findings, &Finding{
+				Category: MissingNonce,
+				Severity: severity,
+				File:     r.File,
+				Line:     r.Line,
+				Message:  fmt.Sprintf("handler %s of %s does not verify a nonce", h.name, h.hook),
+				Hook:     h.hook,
+				Node:     r.Node,
+			})
+		}
+		// Handlers also registered for visitors are public by design
+		if !capability && h.private && !h.public {
+			severity := taint.Medium
+			if changesState {
+				severity = taint.High
+			}
+			findings = append(findings, &Finding{
+				Category: MissingCapability,
+				Severity: severity,
+				File:     r.File,
+				Line:     r.Line,
+				Message:  fmt.Sprintf("handler %s of %s does not check the capabilities of the user", h.name, h.hook),
+				Hook:     h.hook,
+				Node:     r.Node,
+			})
+		}
+	}
+	return findings
+}
+
+// reach returns the functions a handler may run: its declaration and the functions of the
+// project it calls, up to maxCallDepth calls away
+func (c *Checker) reach(declaration *ast.Node) map[*ast.Node]bool {
+	reach := map[*ast.Node]bool{declaration: true}
+	frontier := []*ast.Node{declaration}
+	for depth := 0; depth < maxCallDepth && len(frontier) > 0; depth++ {
+		var next []*ast.Node
+		for _, fn := range frontier {
+			v := &ast.VisitorKinds{Kinds: map[string]bool{
+				"function_call_expression": true, "member_call_expression": true,
+				"nullsafe_member_call_expression": true, "scoped_call_expression": true,
+			}}
+			fn.WalkPrefix(v)
+			for _, call := range v.Nodes {
+				for _, target := range c.taint.Targets(call) {
+					if !reach[target] {
+						reach[target] = true
+						next = append(next, target)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return reach
+}
+
+// within reports whether one of the nodes is in one of the functions
+func (c *Checker) within(nodes []*ast.Node, functions map[*ast.Node]bool) bool {
+	for _, n := range nodes {
+		for fn := scope.EnclosingFunction(n); fn != nil; fn = scope.EnclosingFunction(fn) {
+			if functions[fn] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// guarded reports whether one of the check calls is in one of the functions and guards it: its
+// result is used, or the check stops the request itself on failure like check_ajax_referer
+func (c *Checker) guarded(checks []*ast.Node, functions map[*ast.Node]bool) bool {
+	for _, check := range checks {
+		if c.within([]*ast.Node{check}, functions) && guards(check) {
+			return true
+		}
+	}
+	return false
+}
+
+func guards(check *ast.Node) bool {
+	if check.Parent == nil || check.Parent.Kind != "expression_statement" {
+		return true
+	}
+	switch functionName(check) {
+	case "check_admin_referer":
+		return true
+	case "check_ajax_referer":
+		// The third argument, $stop, makes it return false instead of dying
+		arguments := check.Arguments()
+		return len(arguments) < 3 || !strings.EqualFold(strings.TrimSpace(arguments[2].Text), "false")
+	}
+	return false
+}
+
+// checkQuery reports a query of $wpdb whose SQL comes from the request without prepare or
+// esc_sql, or interpolates values outside of prepare
+func (c *Checker) checkQuery(call *ast.Node, q *sqlquery.Query) *Finding {
+	children := call.NamedChildren()
+	arguments := call.Arguments()
+	if len(children) == 0 || len(arguments) == 0 || !c.isWpdb(children[0]) {
+		return nil
+	}
+	method := call.MemberName()
+	finding := &Finding{
+		Category: UnpreparedQuery,
+		File:     c.taint.File(call),
+		Line:     call.StartPosition.Row + 1,
+		Node:     call,
+	}
+	taints := c.taint.Of(arguments[0])
+	if unsafe := taint.Unsanitized(taints, querySanitizers...); len(unsafe) > 0 {
+		finding.Severity = taint.High
+		finding.Taint = unsafe[0]
+		finding.Message = fmt.Sprintf("request data %s reaches $wpdb->%s without prepare", unsafe[0].Source.Name, method)
+		return finding
+	}
+	if q == nil || q.Prepared || len(taints) > 0 {
+		// The query is a prepare result, or its request data is escaped
+		return nil
+	}
+	for _, f := range q.Findings {
+		switch f.Category {
+		case sqlquery.InterpolatedValue, sqlquery.QuoteBreakout, sqlquery.MissingPrepare:
+			finding.Severity = taint.Medium
+			finding.Message = fmt.Sprintf("$wpdb->%s with interpolated SQL instead of $wpdb->prepare: %s", method, f.Message)
+			return finding
+		}
+	}
+	return nil
+}
+
+// isWpdb reports whether the receiver of a call is the global $wpdb: the variable at the top level
+// of a file or declared global in a function, $GLOBALS['wpdb'], or an expression of type wpdb
+func (c *Checker) isWpdb(n *ast.Node) bool {
+	if n.Kind == "variable_name" && n.Text == "$wpdb" {
+		s := scope.Of(n)
+		if s != nil && s.Node.Kind == "program" {
+			return true
+		}
+		if s != nil {
+			if v := s.Lookup("wpdb"); v != nil && v.Kind == scope.Global {
+				return true
+			}
+		}
+	} else if n.Kind == "subscript_expression" {
+		return true
+	}
+	for _, class := range c.taint.Inferer().TypeOf(n).Classes() {
+		if strings.EqualFold(strings.TrimPrefix(class, "\\"), "wpdb") {
+			return true
+		}
+	}
+	// $this->wpdb holds the global in the classes that keep a reference to it
+	return n.Kind == "member_access_expression"
+}
+
+// checkOutput reports the request data printed by an echo, print or printf without escaping
+func (c *Checker) checkOutput(n *ast.Node) []*Finding {
+	expressions := n.NamedChildren()
+	if n.Kind == "function_call_expression" {
+		expressions = n.Arguments()
+	}
+	var findings []*Finding
+	for _, expression := range expressions {
+		unsafe := taint.Unsanitized(c.taint.Of(expression), outputEscapers...)
+		if len(unsafe) == 0 {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Category: UnescapedOutput,
+			Severity: taint.High,
+			File:     c.taint.File(n),
+			Line:     n.StartPosition.Row + 1,
+			Message:  fmt.Sprintf("request data %s printed without esc_html, esc_attr or wp_kses", unsafe[0].Source.Name),
+			Taint:    unsafe[0],
+			Node:     expression,
+		})
+	}
+	return findings
+}
+
+// checkOption reports the options updated with a name coming from the request, which lets the
+// attacker overwrite any setting, or with an unsanitized value coming from the request
+func (c *Checker) checkOption(call *ast.Node) []*Finding {
+	arguments := call.Arguments()
+	name := functionName(call)
+	index := 0
+	if strings.HasSuffix(name, "_blog_option") {
+		// The first argument is the site
+		index = 1
+	}
+	if index+1 >= len(arguments) {
+		return nil
+	}
+	var findings []*Finding
+	if taints := c.taint.Of(arguments[index]); len(taints) > 0 {
+		findings = append(findings, &Finding{
+			Category: OptionInjection,
+			Severity: taint.High,
+			File:     c.taint.File(call),
+			Line:     call.StartPosition.Row + 1,
+			Message:  fmt.Sprintf("%s with an option name from request data %s", name, taints[0].Source.Name),
+			Taint:    taints[0],
+			Node:     call,
+		})
+	}
+	if unsafe := taint.Unsanitized(c.taint.Of(arguments[index+1]), optionSanitizers...); len(unsafe) > 0 {
+		findings = append(findings, &Finding{
+			Category: OptionInjection,
+			Severity: taint.Medium,
+			File:     c.taint.File(call),
+			Line:     call.StartPosition.Row + 1,
+			Message:  fmt.Sprintf("%s with unsanitized request data %s", name, unsafe[0].Source.Name),
+			Taint:    unsafe[0],
+			Node:     call,
+		})
+	}
+	return findings
+}
+
+func hasPrefix(hook string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(hook, prefix) {
+			return true
+		}
+	}
+	return false
+}
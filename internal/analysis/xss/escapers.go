@@ -0,0 +1,130 @@
+package xss
+
+import (
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+)
+
+// escapers maps the functions escaping or restricting a value for HTML to the contexts they make
+// it safe in. htmlspecialchars and htmlentities depend on their flags, see quoting.
+var escapers = map[string][]Context{
+	"htmlspecialchars": {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"htmlentities":     {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_html":         {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_html__":       {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_html_e":       {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_attr":         {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_attr__":       {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_attr_e":       {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_textarea":     {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_xml":          {HTMLText, QuotedAttribute, SingleQuotedAttribute, Comment},
+	"esc_url":          {HTMLText, QuotedAttribute, SingleQuotedAttribute, URL, Comment},
+	"esc_url_raw":      {URL},
+	"sanitize_url":     {URL},
+	// esc_js escapes for a string literal, and encodes the characters of HTML as well
+	"esc_js":         {HTMLText, QuotedAttribute, SingleQuotedAttribute, EventHandler, Script},
+	"json_encode":    {Script},
+	"wp_json_encode": {Script},
+	// These remove the tags but leave the quotes
+	"wp_kses":                 {HTMLText},
+	"wp_kses_post":            {HTMLText},
+	"wp_kses_data":            {HTMLText},
+	"strip_tags":              {HTMLText},
+	"wp_strip_all_tags":       {HTMLText},
+	"sanitize_text_field":     {HTMLText},
+	"sanitize_textarea_field": {HTMLText},
+	"sanitize_email":          {HTMLText, QuotedAttribute},
+	// These restrict the value to characters safe everywhere
+	"sanitize_key":        allContexts,
+	"sanitize_html_class": allContexts,
+	"sanitize_title":      allContexts,
+	"urlencode":           allContexts,
+	"rawurlencode":        allContexts,
+}
+
+var allContexts = []Context{
+	HTMLText, Tag, QuotedAttribute, SingleQuotedAttribute, UnquotedAttribute, URL, EventHandler, Script, Style, Comment,
+}
+
+// quoting returns the quotes escaped by a call to htmlspecialchars or htmlentities according to its
+// flags. Without flags, single quotes are only escaped since PHP 8.1.
+func quoting(s *taint.Sanitizer) (double, single, explicit bool) {
+	arguments := s.Node.Arguments()
+	if len(arguments) < 2 {
+		return true, false, false
+	}
+	flags := strings.ToUpper(arguments[1].Text)
+	switch {
+	case strings.Contains(flags, "ENT_QUOTES"):
+		return true, true, true
+	case strings.Contains(flags, "ENT_NOQUOTES"):
+		return false, false, true
+	}
+	return true, false, true
+}
+
+// protects reports whether a sanitizer makes a value safe in a context
+func protects(s *taint.Sanitizer, context Context) bool {
+	safe := false
+	for _, c := range escapers[s.Name] {
+		if c == context {
+			safe = true
+		}
+	}
+	if !safe || s.Node == nil || (s.Name != "htmlspecialchars" && s.Name != "htmlentities") {
+		return safe
+	}
+	double, single, _ := quoting(s)
+	switch context {
+	case QuotedAttribute:
+		return double
+	case SingleQuotedAttribute:
+		return single
+	}
+	return true
+}
+
+// escaped returns the escapers applied to a taint, and whether one of them makes it safe in a context
+func escaped(t *taint.Taint, context Context) (names []string, safe bool) {
+	// A value JSON-encoded then HTML-escaped is safe in an event handler
+	encoded, htmlEscaped := false, false
+	for _, s := range t.Sanitizers {
+		if _, ok := escapers[s.Name]; !ok {
+			continue
+		}
+		names = append(names, s.Name)
+		if protects(s, context) {
+			safe = true
+		}
+		encoded = encoded || s.Name == "json_encode" || s.Name == "wp_json_encode"
+		htmlEscaped = htmlEscaped || encoded && protects(s, QuotedAttribute)
+	}
+	if context == EventHandler && htmlEscaped {
+		safe = true
+	}
+	return names, safe
+}
+
+// advice returns the escaper to use in a context
+func advice(context Context) string {
+	switch context {
+	case HTMLText, Comment:
+		return "htmlspecialchars or esc_html"
+	case QuotedAttribute:
+		return "esc_attr"
+	case SingleQuotedAttribute:
+		return "htmlspecialchars with ENT_QUOTES or esc_attr"
+	case UnquotedAttribute:
+		return "quotes around the attribute and esc_attr"
+	case URL:
+		return "esc_url"
+	case EventHandler:
+		return "esc_js, or json_encode then htmlspecialchars"
+	case Script:
+		return "json_encode or esc_js"
+	case Style, Tag:
+		return "a whitelist of values"
+	}
+	return "htmlspecialchars"
+}
@@ -0,0 +1,246 @@
+package xss
+
+import "strings"
+
+// Context is the HTML context a value is printed in, which decides the escaping it needs
+type Context string
+
+const (
+	// HTMLText is the content of an element
+	HTMLText Context = "html"
+	// Tag is the inside of a tag between attributes, where a value adds attributes
+	Tag Context = "tag"
+	// QuotedAttribute is a double-quoted attribute value
+	QuotedAttribute Context = "attribute"
+	// SingleQuotedAttribute is a single-quoted attribute value
+	SingleQuotedAttribute Context = "single-quoted-attribute"
+	// UnquotedAttribute is an unquoted attribute value, ended by a space
+	UnquotedAttribute Context = "unquoted-attribute"
+	// URL is the start of the value of an attribute holding a URL, such as href, where a
+	// javascript: scheme runs script
+	URL Context = "url"
+	// EventHandler is the value of an on* attribute, which is decoded then run as script
+	EventHandler Context = "event-handler"
+	// Script is the content of a script element
+	Script Context = "script"
+	// Style is the content of a style element or a style attribute
+	Style Context = "style"
+	// Comment is the content of an HTML comment
+	Comment Context = "comment"
+)
+
+// urlAttributes are the attributes holding a URL
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "poster": true, "background": true,
+	"cite": true, "data": true, "codebase": true, "xlink:href": true,
+}
+
+type state int
+
+const (
+	text state = iota
+	tagName
+	tag
+	attributeName
+	afterAttributeName
+	beforeValue
+	doubleQuoted
+	singleQuoted
+	unquoted
+	comment
+	raw
+)
+
+// scanner follows the state of an HTML tokenizer through the literal parts of the output, to
+// tell the context of the dynamic parts between them
+type scanner struct {
+	state state
+	// name is the tag or attribute name being read
+	name      strings.Builder
+	tag       string
+	attribute string
+	// value is the literal start of the attribute value being read
+	value strings.Builder
+	// recent holds the last characters of the raw text or comment, to find their end
+	recent string
+}
+
+// write advances the scanner through literal HTML
+func (s *scanner) write(html string) {
+	for i := 0; i < len(html); i++ {
+		s.next(html[i])
+	}
+}
+
+func (s *scanner) next(c byte) {
+	switch s.state {
+	case text:
+		if c == '<' {
+			s.state = tagName
+			s.name.Reset()
+		}
+	case tagName:
+		switch {
+		case c == '!' && s.name.Len() == 0:
+			s.name.WriteByte(c)
+		case c == '-' && strings.HasPrefix(s.name.String(), "!"):
+			s.name.WriteByte(c)
+			if s.name.String() == "!--" {
+				s.state = comment
+				s.recent = ""
+			}
+		case isSpace(c) || c == '>' || c == '/' && s.name.Len() > 0:
+			s.tag = strings.ToLower(s.name.String())
+			s.state = tag
+			s.next(c)
+		case c == '/' || isNameByte(c):
+			s.name.WriteByte(c)
+		default:
+			// Not a tag, such as a < in text
+			s.state = text
+		}
+	case tag:
+		switch {
+		case c == '>':
+			s.endTag()
+		case isSpace(c) || c == '/':
+		default:
+			s.state = attributeName
+			s.name.Reset()
+			s.name.WriteByte(c)
+		}
+	case attributeName:
+		switch {
+		case c == '=':
+			s.attribute = strings.ToLower(s.name.String())
+			s.state = beforeValue
+			s.value.Reset()
+		case c == '>':
+			s.endTag()
+		case isSpace(c):
+			s.attribute = strings.ToLower(s.name.String())
+			s.state = afterAttributeName
+		default:
+			s.name.WriteByte(c)
+		}
+	case afterAttributeName:
+		switch {
+		case c == '=':
+			s.state = beforeValue
+			s.value.Reset()
+		case c == '>':
+			s.endTag()
+		case !isSpace(c):
+			s.state = attributeName
+			s.name.Reset()
+			s.name.WriteByte(c)
+		}
+	case beforeValue:
+		switch {
+		case c == '"':
+			s.state = doubleQuoted
+		case c == '\'':
+			s.state = singleQuoted
+		case c == '>':
+			s.endTag()
+		case !isSpace(c):
+			s.state = unquoted
+			s.value.WriteByte(c)
+		}
+	case doubleQuoted, singleQuoted:
+		if c == '"' && s.state == doubleQuoted || c == '\'' && s.state == singleQuoted {
+			s.state = tag
+		} else {
+			s.value.WriteByte(c)
+		}
+	case unquoted:
+		switch {
+		case isSpace(c):
+			s.state = tag
+		case c == '>':
+			s.endTag()
+		default:
+			s.value.WriteByte(c)
+		}
+	case comment:
+		s.recent = last(s.recent+string(c), 3)
+		if s.recent == "-->" {
+			s.state = text
+		}
+	case raw:
+		s.recent = last(s.recent+string(c), len(s.tag)+2)
+		if strings.EqualFold(s.recent, "</"+s.tag) {
+			s.state = tag
+			s.tag = ""
+		}
+	}
+}
+
+// endTag ends a start or end tag. The content of script and style elements is raw text.
+func (s *scanner) endTag() {
+	s.state = text
+	if s.tag == "script" || s.tag == "style" {
+		s.state = raw
+		s.recent = ""
+	}
+}
+
+// dynamic returns the context of a dynamic part of the output at the current position, and
+// advances the scanner past it as through an opaque value
+func (s *scanner) dynamic() Context {
+	context := s.context()
+	switch s.state {
+	case beforeValue:
+		s.state = unquoted
+		s.value.WriteByte('x')
+	case doubleQuoted, singleQuoted, unquoted:
+		s.value.WriteByte('x')
+	case attributeName, tagName:
+		s.name.WriteByte('x')
+	}
+	return context
+}
+
+func (s *scanner) context() Context {
+	switch s.state {
+	case text:
+		return HTMLText
+	case tagName, tag, attributeName, afterAttributeName:
+		return Tag
+	case comment:
+		return Comment
+	case raw:
+		if s.tag == "style" {
+			return Style
+		}
+		return Script
+	}
+	switch {
+	case strings.HasPrefix(s.attribute, "on"):
+		return EventHandler
+	case s.attribute == "style":
+		return Style
+	case urlAttributes[s.attribute] && s.value.Len() == 0:
+		return URL
+	case s.state == doubleQuoted:
+		return QuotedAttribute
+	case s.state == singleQuoted:
+		return SingleQuotedAttribute
+	}
+	return UnquotedAttribute
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+func last(s string, n int) string {
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return s
+}
@@ -0,0 +1,360 @@
+package xss
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/28Pollux28/log6302-parser/internal/analysis/literal"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/scope"
+	"github.com/28Pollux28/log6302-parser/internal/analysis/taint"
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+// Category is the kind of problem of a finding
+type Category string
+
+const (
+	// Unescaped is request data printed without any HTML escaping
+	Unescaped Category = "unescaped-output"
+	// Mismatch is request data escaped for another context than the one it is printed in
+	Mismatch Category = "context-mismatch"
+)
+
+// outputFunctions maps the functions printing their arguments to the escaper they apply, if any
+var outputFunctions = map[string]string{
+	"printf": "", "vprintf": "", "print_r": "", "_e": "", "esc_html_e": "esc_html_e", "esc_attr_e": "esc_attr_e",
+}
+
+// directive matches the conversion specifications of a printf format
+var directive = regexp.MustCompile(`%(?:(\d+)\$)?[-+ 0'.\d]*[bcdeEfFgGhHosuxX%]`)
+
+// Finding is request data printed without the escaping its HTML context needs
+type Finding struct {
+	Category Category       `json:"category"`
+	Severity taint.Severity `json:"severity"`
+	Context  Context        `json:"context"`
+	// Sink is the output construct: echo, print, <?= or the output function
+	Sink     string   `json:"sink"`
+	File     string   `json:"file"`
+	Line     uint     `json:"line"`
+	Message  string   `json:"message"`
+	Escapers []string `json:"escapers,omitempty"`
+	// Taint is the flow of request data into the output
+	Taint *taint.Taint `json:"taint"`
+	Node  *ast.Node    `json:"-"`
+}
+
+// part is a literal or dynamic part of the output
+type part struct {
+	literal string
+	dynamic *ast.Node
+}
+
+// output is a contiguous piece of the output of a function or file: an inline HTML text, or the
+// parts printed by an output statement or call
+type output struct {
+	position uint
+	sink     string
+	parts    []part
+	// escaper is applied to the dynamic parts by the output function itself
+	escaper *taint.Sanitizer
+}
+
+type file struct {
+	path string
+	root *ast.Node
+}
+
+// Analyzer finds the cross-site scripting flaws of a project: the request data printed by echo,
+// print, <?= and printf without the escaping of the HTML context around it. The context is told by
+// scanning the inline HTML and the literal strings printed before, in source order, in the same
+// function or at the top level of the same file.
+type Analyzer struct {
+	taint *taint.Analyzer
+	files []*file
+}
+
+func New() *Analyzer {
+	return &Analyzer{taint: taint.New()}
+}
+
+// AddFile adds a file to the project
+func (a *Analyzer) AddFile(path string, root *ast.Node) {
+	a.files = append(a.files, &file{path: path, root: root})
+	a.taint.AddFile(path, root)
+}
+
+// Analyze returns the findings of all the files, ordered by file and line
+func (a *Analyzer) Analyze() []*Finding {
+	a.taint.Resolve()
+	var findings []*Finding
+	for _, f := range a.files {
+		v := &ast.VisitorKinds{Kinds: map[string]bool{
+			"text": true, "echo_statement": true, "print_intrinsic": true, "expression_statement": true,
+			"function_call_expression": true,
+		}}
+		f.root.WalkPrefix(v)
+		// The outputs of each function, and of the top level of the file, form a separate document
+		documents := make(map[*ast.Node][]*output)
+		var functions []*ast.Node
+		for _, n := range v.Nodes {
+			o := a.output(n)
+			if o == nil {
+				continue
+			}
+			fn := scope.EnclosingFunction(n)
+			if _, ok := documents[fn]; !ok {
+				functions = append(functions, fn)
+			}
+			documents[fn] = append(documents[fn], o)
+		}
+		for _, fn := range functions {
+			findings = append(findings, a.scan(f.path, documents[fn])...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// output returns the output of a node, nil if it prints nothing
+func (a *Analyzer) output(n *ast.Node) *output {
+	o := &output{position: n.StartByte}
+	switch n.Kind {
+	case "text":
+		o.parts = []part{{literal: n.Text}}
+		return o
+	case "echo_statement":
+		o.sink = "echo"
+		for _, expression := range n.NamedChildren() {
+			if expression.Kind == "sequence_expression" {
+				for _, e := range expression.NamedChildren() {
+					o.parts = append(o.parts, a.flatten(e)...)
+				}
+				continue
+			}
+			o.parts = append(o.parts, a.flatten(expression)...)
+		}
+		return o
+	case "print_intrinsic":
+		o.sink = "print"
+		for _, expression := range n.NamedChildren() {
+			o.parts = append(o.parts, a.flatten(expression)...)
+		}
+		return o
+	case "expression_statement":
+		if !shortEcho(n) {
+			return nil
+		}
+		o.sink = "<?="
+		for _, expression := range n.NamedChildren() {
+			o.parts = append(o.parts, a.flatten(expression)...)
+		}
+		return o
+	}
+	name := taint.FunctionName(n)
+	escaper, ok := outputFunctions[name]
+	arguments := n.Arguments()
+	if !ok || len(arguments) == 0 {
+		return nil
+	}
+	o.sink = name
+	if escaper != "" {
+		o.escaper = &taint.Sanitizer{Name: escaper, Node: n}
+	}
+	switch name {
+	case "printf":
+		o.parts = a.format(arguments[0], arguments[1:])
+	case "print_r":
+		if len(arguments) > 1 {
+			// print_r($value, true) returns instead of printing
+			return nil
+		}
+		o.parts = []part{{dynamic: arguments[0]}}
+	default:
+		for _, argument := range arguments {
+			o.parts = append(o.parts, part{dynamic: argument})
+		}
+	}
+	return o
+}
+
+// shortEcho reports whether an expression statement is the expression of a <?= tag
+func shortEcho(n *ast.Node) bool {
+	if n.Parent == nil {
+		return false
+	}
+	var previous *ast.Node
+	for _, sibling := range n.Parent.Descendants {
+		if sibling == n {
+			break
+		}
+		previous = sibling
+	}
+	if previous != nil && previous.Kind == "text_interpolation" && len(previous.Descendants) > 0 {
+		previous = previous.Descendants[len(previous.Descendants)-1]
+	}
+	return previous != nil && previous.Kind == "php_tag" && strings.HasPrefix(previous.Text, "<?=")
+}
+
+// flatten splits a printed expression into its literal and dynamic parts, through concatenations
+// and interpolated strings
+func (a *Analyzer) flatten(n *ast.Node) []part {
+	children := n.NamedChildren()
+	switch n.Kind {
+	case "parenthesized_expression":
+		if len(children) == 1 {
+			return a.flatten(children[0])
+		}
+	case "binary_expression":
+		if len(children) == 2 && operator(n) == "." {
+			return append(a.flatten(children[0]), a.flatten(children[1])...)
+		}
+	case "encapsed_string", "heredoc":
+		var parts []part
+		for _, p := range literal.Parts(n) {
+			switch p.Kind {
+			case "string_content", "string_value":
+				parts = append(parts, part{literal: p.Text})
+			case "escape_sequence":
+				parts = append(parts, part{literal: literal.Unescape(p.Text, true)})
+			default:
+				parts = append(parts, part{dynamic: p})
+			}
+		}
+		return parts
+	}
+	if value, ok := a.taint.Evaluator().Eval(n).Constant(); ok {
+		return []part{{literal: value}}
+	}
+	return []part{{dynamic: n}}
+}
+
+// format splits a printf call into the literal parts of its format and its arguments
+func (a *Analyzer) format(format *ast.Node, arguments []*ast.Node) []part {
+	value, ok := a.taint.Evaluator().Eval(format).Constant()
+	if !ok {
+		parts := []part{{dynamic: format}}
+		for _, argument := range arguments {
+			parts = append(parts, part{dynamic: argument})
+		}
+		return parts
+	}
+	var parts []part
+	next := 0
+	previous := 0
+	for _, match := range directive.FindAllStringSubmatchIndex(value, -1) {
+		parts = append(parts, part{literal: value[previous:match[0]]})
+		previous = match[1]
+		if value[match[1]-1] == '%' {
+			parts = append(parts, part{literal: "%"})
+			continue
+		}
+		index := next
+		if match[2] >= 0 {
+			position, _ := strconv.Atoi(value[match[2]:match[3]])
+			index = position - 1
+		} else {
+			next++
+		}
+		if index >= 0 && index < len(arguments) {
+			parts = append(parts, part{dynamic: arguments[index]})
+		}
+	}
+	return append(parts, part{literal: value[previous:]})
+}
+
+// scan follows the HTML context through the outputs of a document and checks the request data
+// printed in its dynamic parts
+func (a *Analyzer) scan(path string, outputs []*output) []*Finding {
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return outputs[i].position < outputs[j].position
+	})
+	var findings []*Finding
+	s := &scanner{}
+	for _, o := range outputs {
+		for _, p := range o.parts {
+			if p.dynamic == nil {
+				s.write(p.literal)
+				continue
+			}
+			context := s.dynamic()
+			if finding := a.check(path, o, p.dynamic, context); finding != nil {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// check returns the finding of a dynamic part printed in a context, nil if it is safe
+func (a *Analyzer) check(path string, o *output, n *ast.Node, context Context) *Finding {
+	for _, t := range a.taint.Of(n) {
+		if o.escaper != nil {
+			t = &taint.Taint{Source: t.Source, Path: t.Path, Sanitizers: append(append([]*taint.Sanitizer{}, t.Sanitizers...), o.escaper)}
+		}
+		names, safe := escaped(t, context)
+		if safe {
+			continue
+		}
+		finding := &Finding{
+			Category: Unescaped,
+			Severity: taint.High,
+			Context:  context,
+			Sink:     o.sink,
+			File:     path,
+			Line:     n.StartPosition.Row + 1,
+			Escapers: names,
+			Taint:    t,
+			Node:     n,
+		}
+		if len(names) == 0 {
+			finding.Message = fmt.Sprintf("request data %s printed in %s context without escaping, use %s",
+				t.Source.Name, context, advice(context))
+			return finding
+		}
+		finding.Category = Mismatch
+		finding.Severity = mismatchSeverity(t, context)
+		finding.Message = fmt.Sprintf("request data %s escaped with %s but printed in %s context, use %s",
+			t.Source.Name, strings.Join(names, ", "), context, advice(context))
+		return finding
+	}
+	return nil
+}
+
+// mismatchSeverity is low when the escaping only fails on old PHP versions or in a comment, and
+// medium otherwise
+func mismatchSeverity(t *taint.Taint, context Context) taint.Severity {
+	if context == Comment {
+		return taint.Low
+	}
+	if context != SingleQuotedAttribute {
+		return taint.Medium
+	}
+	for _, s := range t.Sanitizers {
+		if s.Name != "htmlspecialchars" && s.Name != "htmlentities" || s.Node == nil {
+			continue
+		}
+		// Without flags, PHP 8.1 and later escape single quotes as well
+		if _, _, explicit := quoting(s); !explicit {
+			return taint.Low
+		}
+	}
+	return taint.Medium
+}
+
+func operator(n *ast.Node) string {
+	for _, child := range n.Descendants {
+		if !child.IsNamed {
+			return child.Kind
+		}
+	}
+	return ""
+}
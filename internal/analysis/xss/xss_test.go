@@ -0,0 +1,84 @@
+package xss
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/28Pollux28/log6302-parser/internal/ast"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// findings are the category, context and severity of each finding
+		findings []string
+	}{
+		{
+			name:     "request data echoed",
+			source:   "<?php\necho $_GET['name'];",
+			findings: []string{"unescaped-output html high"},
+		},
+		{
+			name:   "request data escaped for the body",
+			source: "<?php\necho htmlspecialchars($_GET['name'], ENT_QUOTES);",
+		},
+		{
+			name:     "inline HTML text",
+			source:   "<p>Hello <?= $_GET['name'] ?>, welcome</p>\n<p><?php echo esc_html($_GET['name']); ?></p>",
+			findings: []string{"unescaped-output html high"},
+		},
+		{
+			name:     "HTML printed by literal strings",
+			source:   "<?php\necho '<a title=\"' . esc_attr($_GET['t']) . '\" href=\"' . esc_html($_GET['u']) . '\">';",
+			findings: []string{"context-mismatch url medium"},
+		},
+		{
+			name:     "single-quoted attribute without flags",
+			source:   "<input value='<?php echo htmlspecialchars($_GET['v']); ?>'>",
+			findings: []string{"context-mismatch single-quoted-attribute low"},
+		},
+		{
+			name:     "single-quoted attribute without ENT_QUOTES",
+			source:   "<input value='<?php echo htmlspecialchars($_GET['v'], ENT_COMPAT); ?>'>",
+			findings: []string{"context-mismatch single-quoted-attribute medium"},
+		},
+		{
+			name:   "single-quoted attribute with ENT_QUOTES",
+			source: "<input value='<?php echo htmlspecialchars($_GET['v'], ENT_QUOTES | ENT_HTML5); ?>'>",
+		},
+		{
+			name:     "unquoted attribute",
+			source:   "<input value=<?php echo esc_attr($_GET['v']); ?>>",
+			findings: []string{"context-mismatch unquoted-attribute medium"},
+		},
+		{
+			name:     "HTML escaping in a script",
+			source:   "<script>\nvar name = \"<?php echo esc_html($_GET['n']); ?>\";\nvar id = <?php echo json_encode($_GET['id']); ?>;\n</script>",
+			findings: []string{"context-mismatch script medium"},
+		},
+		{
+			name:     "event handler",
+			source:   "<button onclick=\"go('<?php echo esc_attr($_GET['a']); ?>')\">\n<button onclick=\"go(<?php echo esc_attr(json_encode($_GET['b'])); ?>)\">",
+			findings: []string{"context-mismatch event-handler medium"},
+		},
+		{
+			name:     "printf arguments",
+			source:   "<?php\nprintf('<a href=\"%s\">%s</a>', esc_url($_GET['u']), $_GET['t']);",
+			findings: []string{"unescaped-output html high"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.AddFile("test.php", ast.ParseSource([]byte(tt.source)))
+			var findings []string
+			for _, f := range a.Analyze() {
+				findings = append(findings, string(f.Category)+" "+string(f.Context)+" "+string(f.Severity))
+			}
+			if !slices.Equal(findings, tt.findings) {
+				t.Errorf("findings = %q, want %q", findings, tt.findings)
+			}
+		})
+	}
+}
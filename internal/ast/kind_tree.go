@@ -2,13 +2,42 @@ package ast
 
 import (
 	"regexp"
+	"strings"
 )
 
 type KindTreeAttributes struct {
 	Text      *string `json:"text"`
 	TextRegex *string `json:"text_regex"`
+	// Constant matches the expressions whose string value is, or is not, a single known string
+	Constant *bool `json:"constant"`
+	// MayContain matches the expressions whose string value may contain a substring, such as a quote
+	MayContain *string `json:"may_contain"`
+	// Type matches the expressions whose inferred type may be a type or a subclass of it
+	Type *string `json:"type"`
+	// DocTag matches the declarations whose doc comment has a tag, such as deprecated, or lacks it
+	// when prefixed with !
+	DocTag *string `json:"doc_tag"`
 }
 
+// StringValue is the approximation of the string values of an expression the value attributes
+// of kind trees are matched against
+type StringValue interface {
+	IsConstant() bool
+	MayContain(substring string) bool
+}
+
+// StringValueOf approximates the string values of an expression. It is set by the package
+// implementing the approximation, internal/analysis/values, when it is linked in.
+var StringValueOf func(n *Node) StringValue
+
+// HasType reports whether an expression may have a type. It is set by the package implementing the
+// type inference, internal/analysis/types, when it is linked in.
+var HasType func(n *Node, typeName string) bool
+
+// HasDocTag reports whether the doc comment of a declaration has a tag. It is set by the package
+// parsing the doc comments, internal/analysis/phpdoc, when it is linked in.
+var HasDocTag func(n *Node, tag string) bool
+
 type KindTree struct {
 	Name       string              `json:"name"`
 	Kind       string              `json:"kind"`
@@ -71,5 +100,26 @@ func (kta *KindTreeAttributes) Match(n *Node) bool {
 			return false
 		}
 	}
+	if kta.Constant != nil || kta.MayContain != nil {
+		if StringValueOf == nil {
+			return false
+		}
+		value := StringValueOf(n)
+		if kta.Constant != nil && value.IsConstant() != *kta.Constant {
+			return false
+		}
+		if kta.MayContain != nil && !value.MayContain(*kta.MayContain) {
+			return false
+		}
+	}
+	if kta.Type != nil && (HasType == nil || !HasType(n, *kta.Type)) {
+		return false
+	}
+	if kta.DocTag != nil {
+		tag, negated := strings.CutPrefix(*kta.DocTag, "!")
+		if HasDocTag == nil || HasDocTag(n, tag) == negated {
+			return false
+		}
+	}
 	return true
 }
@@ -0,0 +1,13 @@
+package ast
+
+// VisitorKinds collects the nodes of some kinds, in the order of the walk
+type VisitorKinds struct {
+	Kinds map[string]bool
+	Nodes []*Node
+}
+
+func (v *VisitorKinds) VisitNode(n *Node) {
+	if v.Kinds[n.Kind] {
+		v.Nodes = append(v.Nodes, n)
+	}
+}
@@ -83,6 +83,137 @@ func (n *Node) GetText() string {
 	return n.Text
 }
 
+// NamedChildren returns the descendants of the node that are named in the grammar,
+// skipping anonymous tokens such as punctuation and keywords
+func (n *Node) NamedChildren() []*Node {
+	var children []*Node
+	for _, child := range n.Descendants {
+		if child.IsNamed {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// ChildOfKind returns the first direct descendant matching one of the given kinds, or nil
+func (n *Node) ChildOfKind(kinds ...string) *Node {
+	for _, child := range n.Descendants {
+		for _, kind := range kinds {
+			if child.Kind == kind {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// ChildrenOfKind returns all the direct descendants of the given kind
+func (n *Node) ChildrenOfKind(kind string) []*Node {
+	var children []*Node
+	for _, child := range n.Descendants {
+		if child.Kind == kind {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Arguments returns the argument expressions of a call node, without the names of named arguments
+func (n *Node) Arguments() []*Node {
+	var result []*Node
+	arguments := n.ChildOfKind("arguments")
+	if arguments == nil {
+		return nil
+	}
+	for _, argument := range arguments.ChildrenOfKind("argument") {
+		children := argument.NamedChildren()
+		if len(children) > 0 {
+			result = append(result, children[len(children)-1])
+		}
+	}
+	return result
+}
+
+// MemberName returns the name of the method or property of a member call, member access or scoped
+// call node, the name after its ->, ?-> or ::, or "" when the name is dynamic
+func (n *Node) MemberName() string {
+	afterOperator := false
+	for _, child := range n.Descendants {
+		switch {
+		case child.Kind == "->" || child.Kind == "?->" || child.Kind == "::":
+			afterOperator = true
+		case afterOperator && child.Kind == "name":
+			return child.Text
+		case afterOperator && child.IsNamed:
+			return ""
+		}
+	}
+	return ""
+}
+
+// ForClauses returns the initialization, condition and update expressions of a for statement, the
+// header being split by its ; tokens, and its body
+func (n *Node) ForClauses() (clauses [3][]*Node, body *Node) {
+	clause := 0
+	closed := false
+	for _, child := range n.Descendants {
+		switch {
+		case child.Kind == ";" && !closed:
+			clause++
+		case child.Kind == ")" && !closed:
+			closed = true
+		case child.IsNamed && closed:
+			body = child
+		case child.IsNamed && clause < 3:
+			clauses[clause] = append(clauses[clause], child)
+		}
+	}
+	return clauses, body
+}
+
+// ForeachParts returns the iterated expression, the binding after as and the body of a foreach statement
+func (n *Node) ForeachParts() (subject, binding, body *Node) {
+	afterAs := false
+	for _, child := range n.Descendants {
+		switch {
+		case child.Kind == "as":
+			afterAs = true
+		case !child.IsNamed:
+		case !afterAs:
+			subject = child
+		case binding == nil:
+			binding = child
+		default:
+			body = child
+		}
+	}
+	return subject, binding, body
+}
+
+// SetParents restores the Parent links of the subtree, as they are not kept in the JSON AST
+func (n *Node) SetParents() {
+	for _, child := range n.Descendants {
+		child.Parent = n
+		child.SetParents()
+	}
+}
+
+// SetAttribute stores an analysis result on the node
+func (n *Node) SetAttribute(key string, value any) {
+	if n.Attributes == nil {
+		n.Attributes = make(map[string]Attribute[any])
+	}
+	n.Attributes[key] = Attribute[any]{V: value}
+}
+
+// GetAttribute returns an analysis result stored on the node, or nil
+func (n *Node) GetAttribute(key string) any {
+	if n.Attributes == nil {
+		return nil
+	}
+	return n.Attributes[key].V
+}
+
 func (n *Node) PrintTree() {
 	n.printTree(0)
 }
@@ -1,6 +1,21 @@
 package ast
 
-import ts "github.com/tree-sitter/go-tree-sitter"
+import (
+	ts "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_php "github.com/tree-sitter/tree-sitter-php/bindings/go"
+)
+
+// ParseSource parses PHP source code into a tree
+func ParseSource(source []byte) *Node {
+	parser := ts.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(ts.NewLanguage(tree_sitter_php.LanguagePHP()))
+
+	treesitterTree := parser.Parse(source, nil)
+	defer treesitterTree.Close()
+
+	return WalkTreeSitterTree(treesitterTree.RootNode(), &source)
+}
 
 func WalkTreeSitterTree(node *ts.Node, source *[]byte) *Node {
 	return walkFromNode(node, nil, source)